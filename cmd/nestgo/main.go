@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/bench"
+	"github.com/kevenmiano/nestgo/pkg/bindgen"
+)
+
+const usage = `Usage: nestgo <command> [flags]
+
+Commands:
+  bench    Generate load against a running application and report latency per route
+  bind     Generate BindRoutes methods binding route fields and //nestgo:route methods
+
+Run "nestgo <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "bench":
+		os.Exit(runBench(os.Args[2:]))
+	case "bind":
+		os.Exit(runBind(os.Args[2:]))
+	case "-h", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "nestgo: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+// runBench runs the bench command and returns the exit code
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	url := flags.String("url", "http://localhost:3000", "base URL of the running application")
+	routes := flags.String("routes", "", `comma separated routes, e.g. "GET /users/,GET /users/1"`)
+	concurrency := flags.Int("c", bench.DefaultConcurrency, "number of concurrent workers")
+	requests := flags.Int("n", 0, "total number of requests (default 1000 unless -d is set)")
+	duration := flags.Duration("d", 0, "run for a duration instead of a request count, e.g. 30s")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of each request")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	parsed, err := bench.ParseRoutes(*routes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nestgo bench: %v\n", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := bench.Run(ctx, bench.Options{
+		URL:         *url,
+		Routes:      parsed,
+		Concurrency: *concurrency,
+		Requests:    *requests,
+		Duration:    *duration,
+		Client:      bench.NewClient(*timeout, *concurrency),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nestgo bench: %v\n", err)
+		return 1
+	}
+
+	report.Print(os.Stdout)
+	fmt.Printf("\n%d workers, %s elapsed\n", *concurrency, report.Elapsed.Round(time.Millisecond))
+	return 0
+}
+
+// runBind runs the bind command and returns the exit code
+func runBind(args []string) int {
+	flags := flag.NewFlagSet("bind", flag.ContinueOnError)
+	dir := flags.String("dir", ".", "directory of the package declaring the controllers")
+	out := flags.String("out", bindgen.DefaultOutput, "name of the generated file, in the package directory")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	result, err := bindgen.Scan(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nestgo bind: %v\n", err)
+		return 1
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(os.Stderr, "nestgo bind: skipping %s\n", skipped)
+	}
+
+	path := filepath.Join(*dir, *out)
+	if len(result.Controllers) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "nestgo bind: %v\n", err)
+			return 1
+		}
+		fmt.Println("No route fields with handler methods nor route directives found")
+		return 0
+	}
+
+	source, err := bindgen.Generate(result)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nestgo bind: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, source, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "nestgo bind: %v\n", err)
+		return 1
+	}
+
+	bindings, routes := 0, 0
+	for _, ctrl := range result.Controllers {
+		bindings += len(ctrl.Bindings)
+		routes += len(ctrl.Routes)
+	}
+	fmt.Printf("Wrote %s: %d route fields and %d method routes of %d controllers\n", path, bindings, routes, len(result.Controllers))
+	return 0
+}
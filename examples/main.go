@@ -1,14 +1,19 @@
+//go:generate go run github.com/kevenmiano/nestgo/cmd/nestgo bind
+
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"sync"
+	"time"
 
-	"github.com/gorilla/mux"
 	"github.com/kevenmiano/nestgo/pkg/application"
 	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/domain"
 	"github.com/kevenmiano/nestgo/pkg/logger"
 	"github.com/kevenmiano/nestgo/pkg/module"
 	"github.com/kevenmiano/nestgo/pkg/service"
@@ -17,11 +22,25 @@ import (
 // User model
 type User struct {
 	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	Name  string `json:"name" pii:"name"`
+	Email string `json:"email" pii:"email"`
 	Age   int    `json:"age,omitempty"`
 }
 
+// CreateUserDTO is the request body for creating or replacing a user
+type CreateUserDTO struct {
+	Name  string `json:"name" validate:"required,min=2"`
+	Email string `json:"email" validate:"required,email"`
+	Age   int    `json:"age" validate:"min=0,max=150"`
+}
+
+// PatchUserDTO is the request body for partially updating a user
+type PatchUserDTO struct {
+	Name  *string `json:"name,omitempty" validate:"min=2"`
+	Email *string `json:"email,omitempty" validate:"email"`
+	Age   *int    `json:"age,omitempty" validate:"min=0,max=150"`
+}
+
 // FakeDatabase - Database em memória
 type FakeDatabase struct {
 	users  map[int]*User
@@ -46,6 +65,15 @@ func NewFakeDatabase() *FakeDatabase {
 	return db
 }
 
+// OnApplicationShutdown registra o estado final do database ao encerrar
+func (db *FakeDatabase) OnApplicationShutdown(ctx context.Context, signal string) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	logger.Info("FakeDatabase closed", "users", len(db.users), "signal", signal)
+	return nil
+}
+
 // CreateUser cria um novo usuário no database
 func (db *FakeDatabase) CreateUser(name, email string, age int) *User {
 	db.mutex.Lock()
@@ -135,14 +163,14 @@ type UserController struct {
 	UserService *UserService `inject:"UserService"`
 
 	// Route fields with explicit HTTP method and path tags
-	GetUsers     func() `route:"GET /"`
-	CreateUser   func() `route:"POST /"`
-	GetUser      func() `route:"GET /:id"`
-	UpdateUser   func() `route:"PUT /:id"`
-	DeleteUser   func() `route:"DELETE /:id"`
-	PatchUser    func() `route:"PATCH /:id"`
-	HeadUsers    func() `route:"HEAD /"`
-	OptionsUsers func() `route:"OPTIONS /"`
+	GetUsers     func() interface{}                           `route:"GET /"`
+	CreateUser   func(body CreateUserDTO) controller.Response `route:"POST /"`
+	GetUser      func(id int) (interface{}, error)            `route:"GET /:id"`
+	UpdateUser   func(id int, body CreateUserDTO)             `route:"PUT /:id"`
+	DeleteUser   func(id int)                                 `route:"DELETE /:id"`
+	PatchUser    func(id int, body PatchUserDTO)              `route:"PATCH /:id"`
+	HeadUsers    func()                                       `route:"HEAD /"`
+	OptionsUsers func()                                       `route:"OPTIONS /"`
 }
 
 // UserService methods
@@ -169,13 +197,17 @@ func (s *UserService) CreateUser(name, email string, age int) *User {
 	return user
 }
 
-func (s *UserService) GetUserByID(id int) *User {
+func (s *UserService) GetUserByID(id int) (*User, error) {
 	if s.Database == nil {
 		logger.Error("Database is nil in UserService")
-		return nil
+		return nil, errors.New("database not available")
 	}
 
-	return s.Database.GetUserByID(id)
+	user := s.Database.GetUserByID(id)
+	if user == nil {
+		return nil, fmt.Errorf("user %d: %w", id, domain.ErrNotFound)
+	}
+	return user, nil
 }
 
 func (s *UserService) UpdateUser(id int, name, email string, age int) *User {
@@ -197,98 +229,59 @@ func (s *UserService) DeleteUser(id int) bool {
 }
 
 // HTTP method implementations
-func (c *UserController) getUsersHandler() {
+func (c *UserController) GetUsersHandler() interface{} {
 	users := c.UserService.GetAllUsers()
 	logger.Info("GET /users", "count", len(users))
 
-	c.JSON(map[string]interface{}{
+	return map[string]interface{}{
 		"data":  users,
 		"count": len(users),
-	})
-}
-
-func (c *UserController) createUserHandler() {
-	// Parse request body
-	var requestData struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-		Age   int    `json:"age"`
-	}
-
-	if c.Request != nil && c.Request.Body != nil {
-		if err := json.NewDecoder(c.Request.Body).Decode(&requestData); err != nil {
-			logger.Error("Failed to parse request body", "error", err)
-			c.JSON(map[string]interface{}{
-				"error": "Invalid request body",
-			})
-			return
-		}
-	} else {
-		// Default values for testing
-		requestData.Name = "Test User"
-		requestData.Email = "test@example.com"
-		requestData.Age = 25
 	}
+}
 
+func (c *UserController) CreateUserHandler(requestData CreateUserDTO) controller.Response {
 	user := c.UserService.CreateUser(requestData.Name, requestData.Email, requestData.Age)
 	if user == nil {
 		logger.Error("Failed to create user")
-		c.JSON(map[string]interface{}{
-			"error": "Failed to create user",
-		})
-		return
+		return controller.Response{
+			Status: http.StatusInternalServerError,
+			Body:   map[string]interface{}{"error": "Failed to create user"},
+		}
 	}
 
 	logger.Info("POST /users", "user", user)
-	c.JSON(map[string]interface{}{
-		"message": "User created successfully",
-		"data":    user,
-		"status":  "success",
-	})
+	return controller.Response{
+		Body: map[string]interface{}{
+			"message": "User created successfully",
+			"data":    user,
+			"status":  "success",
+		},
+	}.WithHeader("Location", "/users/"+strconv.Itoa(user.ID))
 }
 
-func (c *UserController) getUserHandler() {
+func (c *UserController) GetUserHandler(userID int) (interface{}, error) {
 	if c.UserService == nil {
 		logger.Error("UserService is nil - dependency injection failed")
-		c.JSON(map[string]interface{}{
-			"error": "Internal server error - service not available",
-		})
-		return
-	}
-
-	// Extract ID from URL path using Gorilla Mux
-	var userID int
-	if c.Request != nil {
-		vars := mux.Vars(c.Request)
-		if idStr, exists := vars["id"]; exists {
-			if id, err := strconv.Atoi(idStr); err == nil {
-				userID = id
-			}
-		}
+		return nil, controller.NewHTTPError(http.StatusInternalServerError, "Internal server error - service not available")
 	}
 
 	if userID == 0 {
-		c.JSON(map[string]interface{}{
-			"error": "Invalid user ID",
-		})
-		return
+		return nil, controller.BadRequest("Invalid user ID")
 	}
 
-	user := c.UserService.GetUserByID(userID)
-	if user == nil {
-		c.JSON(map[string]interface{}{
-			"error": "User not found",
-		})
-		return
+	// Domain errors map to their problem response, e.g. 404 for ErrNotFound
+	user, err := c.UserService.GetUserByID(userID)
+	if err != nil {
+		return nil, err
 	}
 
 	logger.Info("GET /users/:id", "user", user)
-	c.JSON(map[string]interface{}{
+	return map[string]interface{}{
 		"data": user,
-	})
+	}, nil
 }
 
-func (c *UserController) updateUserHandler() {
+func (c *UserController) UpdateUserHandler(userID int, requestData CreateUserDTO) {
 	if c.UserService == nil {
 		logger.Error("UserService is nil - dependency injection failed")
 		c.JSON(map[string]interface{}{
@@ -297,17 +290,6 @@ func (c *UserController) updateUserHandler() {
 		return
 	}
 
-	// Extract ID from URL path
-	var userID int
-	if c.Request != nil {
-		path := c.Request.URL.Path
-		if len(path) > 7 && path[:7] == "/users/" {
-			if id, err := strconv.Atoi(path[7:]); err == nil {
-				userID = id
-			}
-		}
-	}
-
 	if userID == 0 {
 		c.JSON(map[string]interface{}{
 			"error": "Invalid user ID",
@@ -315,23 +297,6 @@ func (c *UserController) updateUserHandler() {
 		return
 	}
 
-	// Parse request body
-	var requestData struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-		Age   int    `json:"age"`
-	}
-
-	if c.Request != nil && c.Request.Body != nil {
-		if err := json.NewDecoder(c.Request.Body).Decode(&requestData); err != nil {
-			logger.Error("Failed to parse request body", "error", err)
-			c.JSON(map[string]interface{}{
-				"error": "Invalid request body",
-			})
-			return
-		}
-	}
-
 	user := c.UserService.UpdateUser(userID, requestData.Name, requestData.Email, requestData.Age)
 	if user == nil {
 		c.JSON(map[string]interface{}{
@@ -348,7 +313,7 @@ func (c *UserController) updateUserHandler() {
 	})
 }
 
-func (c *UserController) deleteUserHandler() {
+func (c *UserController) DeleteUserHandler(userID int) {
 	if c.UserService == nil {
 		logger.Error("UserService is nil - dependency injection failed")
 		c.JSON(map[string]interface{}{
@@ -357,17 +322,6 @@ func (c *UserController) deleteUserHandler() {
 		return
 	}
 
-	// Extract ID from URL path
-	var userID int
-	if c.Request != nil {
-		path := c.Request.URL.Path
-		if len(path) > 7 && path[:7] == "/users/" {
-			if id, err := strconv.Atoi(path[7:]); err == nil {
-				userID = id
-			}
-		}
-	}
-
 	if userID == 0 {
 		c.JSON(map[string]interface{}{
 			"error": "Invalid user ID",
@@ -390,7 +344,7 @@ func (c *UserController) deleteUserHandler() {
 	})
 }
 
-func (c *UserController) patchUserHandler() {
+func (c *UserController) PatchUserHandler(userID int, requestData PatchUserDTO) {
 	if c.UserService == nil {
 		logger.Error("UserService is nil - dependency injection failed")
 		c.JSON(map[string]interface{}{
@@ -399,17 +353,6 @@ func (c *UserController) patchUserHandler() {
 		return
 	}
 
-	// Extract ID from URL path
-	var userID int
-	if c.Request != nil {
-		path := c.Request.URL.Path
-		if len(path) > 7 && path[:7] == "/users/" {
-			if id, err := strconv.Atoi(path[7:]); err == nil {
-				userID = id
-			}
-		}
-	}
-
 	if userID == 0 {
 		c.JSON(map[string]interface{}{
 			"error": "Invalid user ID",
@@ -418,31 +361,14 @@ func (c *UserController) patchUserHandler() {
 	}
 
 	// Get existing user
-	existingUser := c.UserService.GetUserByID(userID)
-	if existingUser == nil {
+	existingUser, err := c.UserService.GetUserByID(userID)
+	if err != nil {
 		c.JSON(map[string]interface{}{
 			"error": "User not found",
 		})
 		return
 	}
 
-	// Parse request body for partial update
-	var requestData struct {
-		Name  *string `json:"name,omitempty"`
-		Email *string `json:"email,omitempty"`
-		Age   *int    `json:"age,omitempty"`
-	}
-
-	if c.Request != nil && c.Request.Body != nil {
-		if err := json.NewDecoder(c.Request.Body).Decode(&requestData); err != nil {
-			logger.Error("Failed to parse request body", "error", err)
-			c.JSON(map[string]interface{}{
-				"error": "Invalid request body",
-			})
-			return
-		}
-	}
-
 	// Update only provided fields
 	name := existingUser.Name
 	email := existingUser.Email
@@ -474,26 +400,23 @@ func (c *UserController) patchUserHandler() {
 	})
 }
 
-func (c *UserController) headUsersHandler() {
+func (c *UserController) HeadUsersHandler() {
 	users := c.UserService.GetAllUsers()
 
 	// Set headers for HEAD request
-	if c.ResponseWriter != nil {
-		c.ResponseWriter.Header().Set("Content-Type", "application/json")
-		c.ResponseWriter.Header().Set("X-Total-Count", strconv.Itoa(len(users)))
-		c.ResponseWriter.WriteHeader(http.StatusOK)
-	}
+	c.SetHeader("Content-Type", "application/json")
+	c.SetHeader("X-Total-Count", strconv.Itoa(len(users)))
+	c.CacheControl(controller.NoMaxAge, controller.CacheNoCache)
 
 	logger.Info("HEAD /users", "count", len(users))
 }
 
-func (c *UserController) optionsUsersHandler() {
-	if c.ResponseWriter != nil {
-		c.ResponseWriter.Header().Set("Allow", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
-		c.ResponseWriter.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
-		c.ResponseWriter.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		c.ResponseWriter.WriteHeader(http.StatusOK)
-	}
+func (c *UserController) OptionsUsersHandler() {
+	c.SetHeader("Allow", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
+	c.SetHeader("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
+	c.SetHeader("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	c.CacheControl(24*time.Hour, controller.CachePublic)
+	c.Vary("Origin")
 
 	logger.Info("OPTIONS /users")
 }
@@ -501,21 +424,10 @@ func (c *UserController) optionsUsersHandler() {
 // UserModule follows NestJS pattern with decorators
 type UserModule struct{}
 
-// Create controller instance with initialized route handlers
+// Create controller instance. Route handlers are bound on registration by
+// the BindRoutes method generated in nestgo_routes.go.
 func NewUserController() *UserController {
-	controller := &UserController{}
-
-	// Initialize route handlers
-	controller.GetUsers = func() { controller.getUsersHandler() }
-	controller.CreateUser = func() { controller.createUserHandler() }
-	controller.GetUser = func() { controller.getUserHandler() }
-	controller.UpdateUser = func() { controller.updateUserHandler() }
-	controller.DeleteUser = func() { controller.deleteUserHandler() }
-	controller.PatchUser = func() { controller.patchUserHandler() }
-	controller.HeadUsers = func() { controller.headUsersHandler() }
-	controller.OptionsUsers = func() { controller.optionsUsersHandler() }
-
-	return controller
+	return &UserController{}
 }
 
 // Define the module with decorator (like NestJS @Module)
@@ -525,6 +437,8 @@ var _ = module.New(module.ModuleConfig{
 		NewFakeDatabase(),
 		NewUserService(), // Service registrado depois para receber a injeção
 	},
+	Owner: "identity",
+	Tags:  []string{"users"},
 })(&UserModule{})
 
 func main() {
@@ -0,0 +1,29 @@
+// Code generated by nestgo bind; DO NOT EDIT.
+
+package main
+
+// BindRoutes binds the routes of UserController to their handler methods
+func (c *UserController) BindRoutes() {
+	c.GetUsers = c.GetUsersHandler
+	c.CreateUser = c.CreateUserHandler
+	c.GetUser = c.GetUserHandler
+	c.UpdateUser = c.UpdateUserHandler
+	c.DeleteUser = c.DeleteUserHandler
+	c.PatchUser = c.PatchUserHandler
+	c.HeadUsers = c.HeadUsersHandler
+	c.OptionsUsers = c.OptionsUsersHandler
+}
+
+// RouteMethods names the handler methods of the route fields of UserController
+func (c *UserController) RouteMethods() map[string]string {
+	return map[string]string{
+		"GetUsers":     "GetUsersHandler",
+		"CreateUser":   "CreateUserHandler",
+		"GetUser":      "GetUserHandler",
+		"UpdateUser":   "UpdateUserHandler",
+		"DeleteUser":   "DeleteUserHandler",
+		"PatchUser":    "PatchUserHandler",
+		"HeadUsers":    "HeadUsersHandler",
+		"OptionsUsers": "OptionsUsersHandler",
+	}
+}
@@ -0,0 +1,204 @@
+package abuse
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+// Decision is the action taken for a scored request
+type Decision string
+
+const (
+	DecisionAllow    Decision = "allow"
+	DecisionFlag     Decision = "flag"
+	DecisionThrottle Decision = "throttle"
+	DecisionBlock    Decision = "block"
+)
+
+// Default thresholds applied to the summed request score
+const (
+	DefaultFlagThreshold     = 0.5
+	DefaultThrottleThreshold = 0.8
+	DefaultBlockThreshold    = 1.0
+	DefaultThrottleDelay     = 500 * time.Millisecond
+)
+
+// Score is the anomaly score produced by a Scorer
+type Score struct {
+	Value   float64
+	Reasons []string
+}
+
+// Scorer computes an anomaly score for a request
+type Scorer interface {
+	Score(r *http.Request) Score
+}
+
+// ScorerFunc adapts a function to the Scorer interface
+type ScorerFunc func(r *http.Request) Score
+
+// Score calls the function
+func (f ScorerFunc) Score(r *http.Request) Score {
+	return f(r)
+}
+
+// Event describes a decision taken for a request
+type Event struct {
+	Time     time.Time
+	IP       string
+	Method   string
+	Path     string
+	Score    float64
+	Reasons  []string
+	Decision Decision
+}
+
+// Listener receives decision events
+type Listener func(Event)
+
+// Options configures the detector
+type Options struct {
+	Scorers           []Scorer
+	FlagThreshold     float64
+	ThrottleThreshold float64
+	BlockThreshold    float64
+	// ThrottleDelay is how long throttled requests are held before being served
+	ThrottleDelay time.Duration
+	// Listeners receive every non-allow decision
+	Listeners []Listener
+}
+
+// Stats holds decision counters
+type Stats struct {
+	Allowed   int64 `json:"allowed"`
+	Flagged   int64 `json:"flagged"`
+	Throttled int64 `json:"throttled"`
+	Blocked   int64 `json:"blocked"`
+}
+
+// Detector scores requests and applies the resulting decision
+type Detector struct {
+	options   Options
+	allowed   atomic.Int64
+	flagged   atomic.Int64
+	throttled atomic.Int64
+	blocked   atomic.Int64
+}
+
+// NewDetector creates a detector, filling in default thresholds
+func NewDetector(opts Options) *Detector {
+	if opts.FlagThreshold <= 0 {
+		opts.FlagThreshold = DefaultFlagThreshold
+	}
+	if opts.ThrottleThreshold <= 0 {
+		opts.ThrottleThreshold = DefaultThrottleThreshold
+	}
+	if opts.BlockThreshold <= 0 {
+		opts.BlockThreshold = DefaultBlockThreshold
+	}
+	if opts.ThrottleDelay <= 0 {
+		opts.ThrottleDelay = DefaultThrottleDelay
+	}
+
+	return &Detector{options: opts}
+}
+
+// Subscribe adds a listener for decision events
+func (d *Detector) Subscribe(listener Listener) {
+	d.options.Listeners = append(d.options.Listeners, listener)
+}
+
+// Evaluate scores a request and returns the resulting event
+func (d *Detector) Evaluate(r *http.Request) Event {
+	event := Event{
+		Time:     clock.Now(),
+		IP:       realip.ClientIP(r),
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Reasons:  make([]string, 0),
+		Decision: DecisionAllow,
+	}
+
+	for _, scorer := range d.options.Scorers {
+		score := scorer.Score(r)
+		event.Score += score.Value
+		event.Reasons = append(event.Reasons, score.Reasons...)
+	}
+
+	switch {
+	case event.Score >= d.options.BlockThreshold:
+		event.Decision = DecisionBlock
+	case event.Score >= d.options.ThrottleThreshold:
+		event.Decision = DecisionThrottle
+	case event.Score >= d.options.FlagThreshold:
+		event.Decision = DecisionFlag
+	}
+
+	return event
+}
+
+// Middleware returns an HTTP middleware applying the detector decisions
+func (d *Detector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := d.Evaluate(r)
+		d.record(event)
+
+		switch event.Decision {
+		case DecisionBlock:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "Request blocked"}`))
+			return
+		case DecisionThrottle:
+			select {
+			case <-time.After(d.options.ThrottleDelay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stats returns a snapshot of the decision counters
+func (d *Detector) Stats() Stats {
+	return Stats{
+		Allowed:   d.allowed.Load(),
+		Flagged:   d.flagged.Load(),
+		Throttled: d.throttled.Load(),
+		Blocked:   d.blocked.Load(),
+	}
+}
+
+// record updates the counters and notifies listeners
+func (d *Detector) record(event Event) {
+	switch event.Decision {
+	case DecisionAllow:
+		d.allowed.Add(1)
+		return
+	case DecisionFlag:
+		d.flagged.Add(1)
+	case DecisionThrottle:
+		d.throttled.Add(1)
+	case DecisionBlock:
+		d.blocked.Add(1)
+	}
+
+	logger.Warn("Abuse detection decision",
+		"decision", event.Decision,
+		"score", event.Score,
+		"ip", event.IP,
+		"method", event.Method,
+		"path", event.Path,
+		"reasons", event.Reasons)
+
+	for _, listener := range d.options.Listeners {
+		listener(event)
+	}
+}
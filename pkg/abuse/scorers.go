@@ -0,0 +1,95 @@
+package abuse
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clientinfo"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+// RateScorer scores clients exceeding a request rate per IP
+type RateScorer struct {
+	limit  int
+	window time.Duration
+	weight float64
+	hits   map[string][]time.Time
+	mutex  sync.Mutex
+}
+
+// NewRateScorer creates a scorer adding weight once an IP exceeds limit requests per window
+func NewRateScorer(limit int, window time.Duration, weight float64) *RateScorer {
+	return &RateScorer{
+		limit:  limit,
+		window: window,
+		weight: weight,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Score records the request and scores the client rate
+func (rs *RateScorer) Score(r *http.Request) Score {
+	ip := realip.ClientIP(r)
+	now := clock.Now()
+	cutoff := now.Add(-rs.window)
+
+	rs.mutex.Lock()
+	recent := rs.hits[ip][:0]
+	for _, hit := range rs.hits[ip] {
+		if hit.After(cutoff) {
+			recent = append(recent, hit)
+		}
+	}
+	recent = append(recent, now)
+	rs.hits[ip] = recent
+	count := len(recent)
+	rs.mutex.Unlock()
+
+	if count <= rs.limit {
+		return Score{}
+	}
+
+	return Score{
+		Value:   rs.weight,
+		Reasons: []string{fmt.Sprintf("rate %d/%s exceeds %d", count, rs.window, rs.limit)},
+	}
+}
+
+// FingerprintScorer scores requests with bot-like header fingerprints
+type FingerprintScorer struct {
+	weight float64
+}
+
+// NewFingerprintScorer creates a scorer weighting each suspicious header trait
+func NewFingerprintScorer(weight float64) *FingerprintScorer {
+	return &FingerprintScorer{weight: weight}
+}
+
+// Score inspects the user agent and browser headers of the request
+func (fs *FingerprintScorer) Score(r *http.Request) Score {
+	score := Score{Reasons: make([]string, 0)}
+
+	userAgent := r.UserAgent()
+	if userAgent == "" {
+		score.Value += fs.weight
+		score.Reasons = append(score.Reasons, "missing user agent")
+	} else if clientinfo.ParseUserAgent(userAgent).IsBot {
+		score.Value += fs.weight
+		score.Reasons = append(score.Reasons, "bot user agent")
+	}
+
+	if r.Header.Get("Accept") == "" {
+		score.Value += fs.weight / 2
+		score.Reasons = append(score.Reasons, "missing accept header")
+	}
+
+	if r.Header.Get("Accept-Language") == "" {
+		score.Value += fs.weight / 2
+		score.Reasons = append(score.Reasons, "missing accept-language header")
+	}
+
+	return score
+}
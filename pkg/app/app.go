@@ -2,6 +2,8 @@ package app
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"reflect"
 	"strings"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/kevenmiano/nestgo/pkg/logger"
 	"github.com/kevenmiano/nestgo/pkg/module"
 	"github.com/kevenmiano/nestgo/pkg/router"
+	"github.com/kevenmiano/nestgo/pkg/server"
 )
 
 // App represents the main application
@@ -21,10 +24,14 @@ type App struct {
 
 // NewApp creates a new application instance
 func NewApp() *App {
-	return &App{
+	app := &App{
 		diContainer: container.NewContainer(),
 		router:      router.NewRouter(),
 	}
+
+	// Guards declared by name are resolved from the DI container
+	app.router.Server().SetContainer(app.diContainer)
+	return app
 }
 
 // RegisterModule registers a module in the application
@@ -178,6 +185,9 @@ func (app *App) registerControllerRoutes(controller interface{}) {
 		return
 	}
 
+	// Bind route fields to their handler methods, e.g. with generated BindRoutes
+	controllerPkg.BindRoutes(controller)
+
 	// Get base URL
 	baseURL := controllerExtractor.GetControllerBaseURL(controller)
 	if baseURL == "" {
@@ -201,6 +211,18 @@ func (app *App) InjectDependencies() error {
 	modules := module.GetGlobalRegistry().GetAllModules()
 	var injectionErrors []string
 
+	// Modules importing each other must declare it with a forward reference
+	if err := module.CheckImportCycles(modules); err != nil {
+		logger.Error("Invalid module configuration", "error", err)
+		return err
+	}
+
+	// Restrict injections to the providers visible from each module
+	if err := app.applyModuleScopes(modules); err != nil {
+		logger.Error("Invalid module configuration", "error", err)
+		return err
+	}
+
 	for _, module := range modules {
 		controllers := module.GetControllers()
 		services := module.GetServices()
@@ -251,8 +273,18 @@ func (app *App) InjectDependencies() error {
 	return nil
 }
 
-// Start starts the application
+// Start starts the application on a TCP address such as ":3000" or a Unix
+// domain socket address such as "unix:///run/app.sock"
 func (app *App) Start(port string) error {
+	listener, err := server.Listen(port)
+	if err != nil {
+		return err
+	}
+	return app.Serve(listener)
+}
+
+// Serve starts the application on a listener
+func (app *App) Serve(listener net.Listener) error {
 	logger.Info("Starting NestGo application")
 
 	// Print modules
@@ -266,7 +298,17 @@ func (app *App) Start(port string) error {
 	app.router.PrintRoutes()
 
 	// Start server
-	return app.router.StartServer(port)
+	return app.router.ServeListener(listener)
+}
+
+// StartHost starts a named server of the application, declared with
+// server.Server.AddHost, on a TCP or Unix domain socket address
+func (app *App) StartHost(name, address string) error {
+	listener, err := server.Listen(address)
+	if err != nil {
+		return err
+	}
+	return app.router.ServeHost(name, listener)
 }
 
 // TestRoute tests a specific route
@@ -282,6 +324,16 @@ func (app *App) GetContainer() *container.Container {
 	return app.diContainer
 }
 
+// Use adds HTTP middleware applied to every route
+func (app *App) Use(middlewares ...func(http.Handler) http.Handler) {
+	app.router.Use(middlewares...)
+}
+
+// GetServer returns the underlying HTTP server
+func (app *App) GetServer() *server.Server {
+	return app.router.Server()
+}
+
 // RegisterController registers a single controller
 func (app *App) RegisterController(controller interface{}) {
 	app.registerControllerRoutes(controller)
@@ -0,0 +1,176 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kevenmiano/nestgo/pkg/container"
+	controllerPkg "github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/warmup"
+)
+
+// OnModuleInit is implemented by providers and controllers that need to
+// run setup, such as opening connections, once their dependencies are injected
+type OnModuleInit interface {
+	OnModuleInit(ctx context.Context) error
+}
+
+// OnApplicationBootstrap is implemented by providers and controllers that
+// need to run once every module is initialized, before the server listens
+type OnApplicationBootstrap interface {
+	OnApplicationBootstrap(ctx context.Context) error
+}
+
+// OnApplicationShutdown is implemented by providers and controllers that
+// need to release resources, such as closing connections or flushing
+// buffers, after the server stops. signal is empty when the server stopped
+// on its own.
+type OnApplicationShutdown interface {
+	OnApplicationShutdown(ctx context.Context, signal string) error
+}
+
+// Init calls OnModuleInit on the providers and then the controllers of every
+// module, imported modules first. The first failure aborts startup.
+func (app *App) Init(ctx context.Context) error {
+	for _, component := range app.lifecycleComponents() {
+		hook, ok := component.instance.(OnModuleInit)
+		if !ok {
+			continue
+		}
+		logger.Info("Initializing component", "module", component.module, "component", component.name)
+		if err := hook.OnModuleInit(ctx); err != nil {
+			return fmt.Errorf("module %s: %s.OnModuleInit: %w", component.module, component.name, err)
+		}
+	}
+	return nil
+}
+
+// Bootstrap calls OnApplicationBootstrap in the same order as Init. The
+// first failure aborts startup.
+func (app *App) Bootstrap(ctx context.Context) error {
+	for _, component := range app.lifecycleComponents() {
+		hook, ok := component.instance.(OnApplicationBootstrap)
+		if !ok {
+			continue
+		}
+		logger.Info("Bootstrapping component", "module", component.module, "component", component.name)
+		if err := hook.OnApplicationBootstrap(ctx); err != nil {
+			return fmt.Errorf("module %s: %s.OnApplicationBootstrap: %w", component.module, component.name, err)
+		}
+	}
+	return nil
+}
+
+// Warmup adds the providers and controllers implementing warmup.Warmer to
+// the runner, named after their module and component
+func (app *App) Warmup(runner *warmup.Runner) {
+	for _, component := range app.lifecycleComponents() {
+		if warmer, ok := component.instance.(warmup.Warmer); ok {
+			runner.Add(component.module+"."+component.name, warmer)
+		}
+	}
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is
+// done, then calls OnApplicationShutdown in the reverse order of Init.
+// Every hook runs even when others fail; their errors are joined.
+func (app *App) Shutdown(ctx context.Context, signal string) error {
+	var errs []error
+	if err := app.GetServer().Shutdown(ctx); err != nil {
+		logger.Error("Failed to shut down server gracefully", "error", err)
+		errs = append(errs, err)
+	}
+
+	components := app.lifecycleComponents()
+	for i := len(components) - 1; i >= 0; i-- {
+		component := components[i]
+		hook, ok := component.instance.(OnApplicationShutdown)
+		if !ok {
+			continue
+		}
+		logger.Info("Shutting down component", "module", component.module, "component", component.name, "signal", signal)
+		if err := hook.OnApplicationShutdown(ctx, signal); err != nil {
+			logger.Error("Component shutdown failed", "module", component.module, "component", component.name, "error", err)
+			errs = append(errs, fmt.Errorf("module %s: %s.OnApplicationShutdown: %w", component.module, component.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lifecycleComponent is a provider or controller receiving lifecycle hooks
+type lifecycleComponent struct {
+	module   string
+	name     string
+	instance interface{}
+}
+
+// lifecycleComponents lists the providers and controllers of every module,
+// imported modules first, each instance once. Lazy providers are included
+// once constructed.
+func (app *App) lifecycleComponents() []lifecycleComponent {
+	services := app.diContainer.GetAllServices()
+	extractor := controllerPkg.NewMetaExtractor()
+	seen := make(map[uintptr]bool)
+	components := make([]lifecycleComponent, 0)
+
+	add := func(moduleName, name string, instance interface{}) {
+		if value := reflect.ValueOf(instance); value.Kind() == reflect.Ptr {
+			if seen[value.Pointer()] {
+				return
+			}
+			seen[value.Pointer()] = true
+		}
+		components = append(components, lifecycleComponent{module: moduleName, name: name, instance: instance})
+	}
+
+	for _, m := range orderedModules(module.GetGlobalRegistry().GetAllModules()) {
+		for _, provider := range m.GetServices() {
+			name := container.ServiceName(provider)
+			if instance, ok := services[name]; ok {
+				provider = instance
+			}
+			add(m.GetModuleName(), name, provider)
+		}
+		for _, controller := range m.GetControllers() {
+			add(m.GetModuleName(), extractor.GetControllerName(controller), controller)
+		}
+	}
+	return components
+}
+
+// orderedModules sorts modules so every module follows the modules it
+// imports, breaking ties by name
+func orderedModules(modules map[string]module.Module) []module.Module {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ordered := make([]module.Module, 0, len(modules))
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		m, exists := modules[name]
+		if !exists || visited[name] {
+			return
+		}
+		visited[name] = true
+		if imports, err := importsOf(m); err == nil {
+			for _, imported := range moduleNames(imports) {
+				visit(imported)
+			}
+		}
+		ordered = append(ordered, m)
+	}
+
+	for _, name := range names {
+		visit(name)
+	}
+	return ordered
+}
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/container"
+	controllerPkg "github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/module"
+)
+
+// exporter is implemented by modules declaring exports
+type exporter interface {
+	GetExports() []interface{}
+}
+
+// importResolver is implemented by modules reporting invalid imports
+type importResolver interface {
+	ResolveImports() ([]module.Module, error)
+}
+
+// globalModule is implemented by modules visible without being imported
+type globalModule interface {
+	IsGlobal() bool
+}
+
+// moduleScopes computes which providers each module can inject, following
+// NestJS semantics: its own providers plus the exports of the modules it
+// imports and of global modules
+type moduleScopes struct {
+	modules map[string]module.Module
+	owners  map[string]string
+	exports map[string]map[string]bool
+}
+
+// applyModuleScopes restricts the injections of every provider and
+// controller to the providers visible from its module
+func (app *App) applyModuleScopes(modules map[string]module.Module) error {
+	scopes := &moduleScopes{
+		modules: modules,
+		owners:  make(map[string]string),
+		exports: make(map[string]map[string]bool),
+	}
+
+	for name, m := range modules {
+		for _, provider := range m.GetServices() {
+			scopes.owners[container.ServiceName(provider)] = name
+		}
+	}
+
+	for name := range modules {
+		if _, err := scopes.exportsOf(name, make(map[string]bool)); err != nil {
+			return err
+		}
+	}
+
+	globals := make([]string, 0)
+	for name, m := range modules {
+		if global, ok := m.(globalModule); ok && global.IsGlobal() {
+			globals = append(globals, name)
+		}
+	}
+
+	for name, m := range modules {
+		visible := make(map[string]bool)
+		for _, provider := range m.GetServices() {
+			visible[container.ServiceName(provider)] = true
+		}
+
+		imports, err := importsOf(m)
+		if err != nil {
+			return err
+		}
+		for _, imported := range append(moduleNames(imports), globals...) {
+			for provider := range scopes.exports[imported] {
+				visible[provider] = true
+			}
+		}
+
+		scope := scopes.scope(name, visible)
+		for _, provider := range m.GetServices() {
+			app.diContainer.SetScope(container.ServiceName(provider), scope)
+		}
+		extractor := controllerPkg.NewMetaExtractor()
+		for _, controller := range m.GetControllers() {
+			app.diContainer.SetScope(extractor.GetControllerName(controller), scope)
+		}
+	}
+	return nil
+}
+
+// exportsOf returns the provider names exported by a module, expanding
+// re-exported modules
+func (s *moduleScopes) exportsOf(name string, visiting map[string]bool) (map[string]bool, error) {
+	if exports, ok := s.exports[name]; ok {
+		return exports, nil
+	}
+	if visiting[name] {
+		return nil, fmt.Errorf("module %s re-exports itself through its imports", name)
+	}
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	m := s.modules[name]
+	exports := make(map[string]bool)
+	declarer, ok := m.(exporter)
+	if !ok {
+		s.exports[name] = exports
+		return exports, nil
+	}
+
+	imports, err := importsOf(m)
+	if err != nil {
+		return nil, err
+	}
+	imported := moduleNames(imports)
+
+	for _, entry := range declarer.GetExports() {
+		if reexported, err := module.Resolve(entry); err == nil && isModuleEntry(entry, reexported) {
+			if !containsName(imported, reexported.GetModuleName()) {
+				return nil, fmt.Errorf("module %s exports module %s without importing it", name, reexported.GetModuleName())
+			}
+			nested, err := s.exportsOf(reexported.GetModuleName(), visiting)
+			if err != nil {
+				return nil, err
+			}
+			for provider := range nested {
+				exports[provider] = true
+			}
+			continue
+		}
+
+		provider, ok := entry.(string)
+		if !ok {
+			provider = container.ServiceName(entry)
+		}
+		if s.owners[provider] != name && !s.reexportable(provider, imported, visiting) {
+			return nil, fmt.Errorf("module %s exports %s, which it neither provides nor imports", name, provider)
+		}
+		exports[provider] = true
+	}
+
+	s.exports[name] = exports
+	return exports, nil
+}
+
+// reexportable reports whether a provider is exported by an imported module
+func (s *moduleScopes) reexportable(provider string, imported []string, visiting map[string]bool) bool {
+	for _, name := range imported {
+		if visiting[name] {
+			continue
+		}
+		exports, err := s.exportsOf(name, visiting)
+		if err == nil && exports[provider] {
+			return true
+		}
+	}
+	return false
+}
+
+// scope returns the Scope of a module, explaining why invisible providers
+// cannot be injected
+func (s *moduleScopes) scope(name string, visible map[string]bool) container.Scope {
+	return func(provider string) error {
+		owner, owned := s.owners[provider]
+		if !owned || visible[provider] {
+			// Services registered outside modules are visible everywhere
+			return nil
+		}
+
+		if s.exports[owner][provider] {
+			return fmt.Errorf("provided by module %s, which module %s does not import", owner, name)
+		}
+
+		exporters := make([]string, 0)
+		for moduleName, exports := range s.exports {
+			if exports[provider] {
+				exporters = append(exporters, moduleName)
+			}
+		}
+		if len(exporters) > 0 {
+			sort.Strings(exporters)
+			return fmt.Errorf("exported by module %s, which module %s does not import", strings.Join(exporters, ", "), name)
+		}
+		return fmt.Errorf("provided by module %s but not exported; add it to the Exports of %s", owner, owner)
+	}
+}
+
+// importsOf returns the imported modules of a module
+func importsOf(m module.Module) ([]module.Module, error) {
+	if resolver, ok := m.(importResolver); ok {
+		return resolver.ResolveImports()
+	}
+	return m.GetImports(), nil
+}
+
+// isModuleEntry reports whether an export entry designates a module rather
+// than a provider sharing a module's type name
+func isModuleEntry(entry interface{}, m module.Module) bool {
+	if _, ok := entry.(module.Module); ok {
+		return true
+	}
+	if _, ok := entry.(*module.ForwardReference); ok {
+		return true
+	}
+	if _, ok := entry.(string); ok {
+		return false
+	}
+	return container.ServiceName(entry) == m.GetModuleName()
+}
+
+// moduleNames returns the names of modules
+func moduleNames(modules []module.Module) []string {
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.GetModuleName()
+	}
+	return names
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,231 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/container"
+	controllerPkg "github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/gdpr"
+	"github.com/kevenmiano/nestgo/pkg/guard"
+	"github.com/kevenmiano/nestgo/pkg/health"
+	"github.com/kevenmiano/nestgo/pkg/interceptor"
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+	"github.com/kevenmiano/nestgo/pkg/ratelimit"
+	"github.com/kevenmiano/nestgo/pkg/server"
+	"github.com/kevenmiano/nestgo/pkg/warmup"
+)
+
+// Kinds of startup issues
+const (
+	// IssueUnusedProvider is a provider never injected nor used by the framework
+	IssueUnusedProvider = "unused-provider"
+	// IssueControllerWithoutRoutes is a controller declaring no route
+	IssueControllerWithoutRoutes = "controller-without-routes"
+	// IssueNilHandler is a route field left nil by the controller factory
+	IssueNilHandler = "nil-route-handler"
+)
+
+// StartupIssue is a likely mistake in the application found by ValidateStartup
+type StartupIssue struct {
+	Module  string
+	Kind    string
+	Name    string
+	Message string
+}
+
+func (i StartupIssue) String() string {
+	return fmt.Sprintf("module %s: %s %s: %s", i.Module, i.Kind, i.Name, i.Message)
+}
+
+// discoveredInterfaces are implemented by providers the framework uses
+// without them being injected
+var discoveredInterfaces = []reflect.Type{
+	reflect.TypeOf((*OnModuleInit)(nil)).Elem(),
+	reflect.TypeOf((*OnApplicationBootstrap)(nil)).Elem(),
+	reflect.TypeOf((*OnApplicationShutdown)(nil)).Elem(),
+	reflect.TypeOf((*warmup.Warmer)(nil)).Elem(),
+	reflect.TypeOf((*health.Indicator)(nil)).Elem(),
+	reflect.TypeOf((*gdpr.DataHandler)(nil)).Elem(),
+}
+
+// ValidateStartup flags providers never injected anywhere, controllers
+// without routes and route fields left nil, once dependencies are
+// injected. Providers are used when injected, named by a guards,
+// interceptors, pipes or middleware tag or a module configuration, or
+// implementing an interface the framework discovers, such as lifecycle
+// hooks.
+func (app *App) ValidateStartup() []StartupIssue {
+	modules := orderedModules(module.GetGlobalRegistry().GetAllModules())
+	used := app.usedProviders(modules)
+
+	issues := make([]StartupIssue, 0)
+	for _, m := range modules {
+		for _, provider := range m.GetServices() {
+			name := container.ServiceName(provider)
+			if used[name] || discovered(provider) {
+				continue
+			}
+			issues = append(issues, StartupIssue{
+				Module:  m.GetModuleName(),
+				Kind:    IssueUnusedProvider,
+				Name:    name,
+				Message: "never injected; remove it or inject it where it is needed",
+			})
+		}
+
+		extractor := controllerPkg.NewMetaExtractor()
+		for _, controller := range m.GetControllers() {
+			name := extractor.GetControllerName(controller)
+			routes, nilFields := controllerRoutes(controller)
+			if routes == 0 {
+				issues = append(issues, StartupIssue{
+					Module:  m.GetModuleName(),
+					Kind:    IssueControllerWithoutRoutes,
+					Name:    name,
+					Message: "declares no route tags nor method routes",
+				})
+			}
+			for _, field := range nilFields {
+				issues = append(issues, StartupIssue{
+					Module:  m.GetModuleName(),
+					Kind:    IssueNilHandler,
+					Name:    name + "." + field,
+					Message: "route field is nil; assign its handler in the controller factory",
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// usedProviders returns the names of the providers injected or referenced
+// by name
+func (app *App) usedProviders(modules []module.Module) map[string]bool {
+	// The throttler is resolved by name by the server
+	used := map[string]bool{ratelimit.ProviderName: true}
+
+	for _, service := range app.diContainer.GetAllServices() {
+		for _, name := range container.InjectedNames(service) {
+			used[name] = true
+		}
+	}
+
+	for _, m := range modules {
+		defaults := module.RouteDefaultsOf(m)
+		entries := append(append([]interface{}{}, defaults.Guards...), defaults.Interceptors...)
+		if declared, ok := m.(interface{ GetMiddlewares() []interface{} }); ok {
+			entries = append(entries, declared.GetMiddlewares()...)
+		}
+		for _, entry := range entries {
+			if name, ok := entry.(string); ok {
+				used[name] = true
+			}
+		}
+
+		for _, controller := range m.GetControllers() {
+			for _, name := range container.InjectedNames(controller) {
+				used[name] = true
+			}
+			for _, tag := range controllerTags(controller) {
+				for _, name := range tagReferences(tag) {
+					used[name] = true
+				}
+			}
+		}
+	}
+	return used
+}
+
+// discovered reports whether the framework uses a provider without it
+// being injected
+func discovered(provider interface{}) bool {
+	providerType := container.ServiceType(provider)
+	for _, iface := range discoveredInterfaces {
+		if providerType.Implements(iface) {
+			return true
+		}
+	}
+	return false
+}
+
+// controllerRoutes counts the routes of a controller and lists its route
+// fields left nil
+func controllerRoutes(controller interface{}) (int, []string) {
+	value := reflect.ValueOf(controller)
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return 0, nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return 0, nil
+	}
+
+	routes := 0
+	nilFields := make([]string, 0)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("route") == "" || field.Type.Kind() != reflect.Func {
+			continue
+		}
+		routes++
+		if value.Field(i).IsNil() {
+			nilFields = append(nilFields, field.Name)
+		}
+	}
+
+	if router, ok := controller.(controllerPkg.MethodRouter); ok {
+		routes += len(router.MethodRoutes())
+	}
+	return routes, nilFields
+}
+
+// controllerTags returns the tags of the BaseController, route fields and
+// method routes of a controller
+func controllerTags(controller interface{}) []reflect.StructTag {
+	controllerType := reflect.TypeOf(controller)
+	if controllerType.Kind() == reflect.Ptr {
+		controllerType = controllerType.Elem()
+	}
+	if controllerType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	tags := make([]reflect.StructTag, 0)
+	for i := 0; i < controllerType.NumField(); i++ {
+		field := controllerType.Field(i)
+		if field.Name == "BaseController" || field.Tag.Get("route") != "" {
+			tags = append(tags, field.Tag)
+		}
+	}
+	if router, ok := controller.(controllerPkg.MethodRouter); ok {
+		for _, route := range router.MethodRoutes() {
+			tags = append(tags, route.Tag)
+		}
+	}
+	return tags
+}
+
+// tagReferences returns the guards, interceptors, pipes and middlewares
+// named by a tag
+func tagReferences(tag reflect.StructTag) []string {
+	names := append(guard.ParseNames(tag.Get(guard.TagGuards)), interceptor.ParseNames(tag.Get(interceptor.TagInterceptors))...)
+	names = append(names, strings.Split(tag.Get(server.TagMiddleware), ",")...)
+	all, byParam := pipe.ParseTag(tag.Get(pipe.TagPipes))
+	names = append(names, all...)
+	for _, pipes := range byParam {
+		names = append(names, pipes...)
+	}
+
+	references := make([]string, 0, len(names))
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			references = append(references, name)
+		}
+	}
+	return references
+}
@@ -0,0 +1,96 @@
+package appinfo
+
+import (
+	"os"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+)
+
+// ProviderName is the name the AppInfo is injectable under:
+//
+//	type StatusService struct {
+//		Info *appinfo.AppInfo `inject:"AppInfo"`
+//	}
+const ProviderName = "AppInfo"
+
+// AppInfo identifies a running instance of the application
+type AppInfo struct {
+	startTime  time.Time
+	instanceID string
+	hostname   string
+	pid        int
+}
+
+// New returns the info of an instance starting now, with a random instance ID
+func New() *AppInfo {
+	instanceID, err := ids.Hex(8)
+	if err != nil {
+		instanceID = "unknown"
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return &AppInfo{
+		startTime:  clock.Now(),
+		instanceID: instanceID,
+		hostname:   hostname,
+		pid:        os.Getpid(),
+	}
+}
+
+// StartTime returns when the instance started
+func (i *AppInfo) StartTime() time.Time {
+	return i.startTime
+}
+
+// Uptime returns how long the instance has been running
+func (i *AppInfo) Uptime() time.Duration {
+	return clock.Now().Sub(i.startTime)
+}
+
+// InstanceID returns the random ID of the instance, telling apart the
+// instances running on a host
+func (i *AppInfo) InstanceID() string {
+	return i.instanceID
+}
+
+// Hostname returns the host the instance runs on
+func (i *AppInfo) Hostname() string {
+	return i.hostname
+}
+
+// PID returns the process ID of the instance
+func (i *AppInfo) PID() int {
+	return i.pid
+}
+
+// Snapshot is the info of an instance at a point in time
+type Snapshot struct {
+	StartTime     time.Time `json:"startTime"`
+	Uptime        string    `json:"uptime"`
+	UptimeSeconds float64   `json:"uptimeSeconds"`
+	InstanceID    string    `json:"instanceId"`
+	Hostname      string    `json:"hostname"`
+	PID           int       `json:"pid"`
+}
+
+// Snapshot returns the info of the instance now
+func (i *AppInfo) Snapshot() Snapshot {
+	uptime := i.Uptime()
+	return Snapshot{
+		StartTime:     i.startTime,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		InstanceID:    i.instanceID,
+		Hostname:      i.hostname,
+		PID:           i.pid,
+	}
+}
+
+// LogAttrs returns the instance ID and hostname as logger attributes
+func (i *AppInfo) LogAttrs() []interface{} {
+	return []interface{}{"instance", i.instanceID, "hostname", i.hostname}
+}
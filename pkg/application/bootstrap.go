@@ -2,14 +2,27 @@ package application
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/kevenmiano/nestgo/pkg/app"
+	"github.com/kevenmiano/nestgo/pkg/appinfo"
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/container"
+	"github.com/kevenmiano/nestgo/pkg/gdpr"
+	"github.com/kevenmiano/nestgo/pkg/health"
+	"github.com/kevenmiano/nestgo/pkg/ids"
 	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/metrics"
 	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/warmup"
 )
 
 // Bootstrap creates and auto-registers a module
@@ -26,25 +39,42 @@ func Bootstrap(moduleStruct interface{}) *Application {
 	return application
 }
 
-// StartApplication starts the application with auto-discovered modules and graceful shutdown
-func StartApplication(port string) {
+// ShutdownTimeout bounds how long StartApplication waits for in-flight
+// requests and OnApplicationShutdown hooks
+const ShutdownTimeout = 10 * time.Second
+
+// StartApplication starts the application with auto-discovered modules and
+// graceful shutdown, on a TCP address such as ":3000" or a Unix domain
+// socket address such as "unix:///run/app.sock"
+func StartApplication(port string, opts ...Option) {
+	run(port, nil, opts)
+}
+
+// ServeApplication starts the application like StartApplication on a
+// listener, e.g. one passed by systemd socket activation:
+//
+//	listeners, err := server.ActivationListeners()
+//	if err != nil || len(listeners) == 0 {
+//		log.Fatal("not socket activated")
+//	}
+//	application.ServeApplication(listeners[0])
+func ServeApplication(listener net.Listener, opts ...Option) {
+	run(listener.Addr().String(), listener, opts)
+}
+
+// run starts the application, listening on port unless given a listener
+func run(port string, listener net.Listener, opts []Option) {
 	logger.Info("Starting NestGo application with auto-discovery", "port", port)
-	logger.Info("DEBUG: StartApplication called")
+	logger.Debug("StartApplication called")
 
 	// Setup graceful shutdown
-	_, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// Handle shutdown signals
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start graceful shutdown handler in goroutine
-	go func() {
-		<-sigChan
-		logger.Info("Shutdown signal received, gracefully shutting down...")
-		cancel()
-	}()
+	defer signal.Stop(sigChan)
 
 	// Get all auto-registered modules
 	registry := module.GetGlobalRegistry()
@@ -55,8 +85,45 @@ func StartApplication(port string) {
 		return
 	}
 
+	config := newOptions(opts...)
+	if config.err != nil {
+		logger.Error("FATAL: Invalid application options", "error", config.err)
+		return
+	}
+
+	if err := checkModuleServers(modules, config.servers); err != nil {
+		logger.Error("FATAL: Invalid application options", "error", err)
+		return
+	}
+
+	restoreDefaults := config.useProcessDefaults()
+	defer restoreDefaults()
+
+	// Instance info is logged with every entry and injectable as AppInfo
+	info := appinfo.New()
+	logger.With(info.LogAttrs()...)
+
 	// Create application
 	app := app.NewApp()
+	config.apply(app)
+
+	// Framework providers are injectable by name and can be replaced by module providers
+	app.GetContainer().Register(clock.ProviderName, clock.Default())
+	app.GetContainer().Register(ids.ProviderName, ids.Default())
+	app.GetContainer().Register(appinfo.ProviderName, info)
+	if config.warmup != nil {
+		app.GetContainer().Register(warmup.ProviderName, config.warmup)
+	}
+	if config.metrics != nil {
+		app.GetContainer().Register(metrics.ProviderName, config.metrics.Registry)
+	}
+	if config.health != nil {
+		config.health.SetInfo(info)
+		app.GetContainer().Register(health.ProviderName, config.health)
+	}
+	for _, cfg := range config.configs {
+		app.GetContainer().AutoRegister(cfg)
+	}
 
 	// Register all auto-discovered modules
 	for _, module := range modules {
@@ -75,24 +142,145 @@ func StartApplication(port string) {
 		}
 	}
 
-	logger.Info("DEBUG: About to inject dependencies")
+	logger.Debug("About to inject dependencies")
 	if err := app.InjectDependencies(); err != nil {
 		logger.Error("FATAL: Application startup failed due to dependency injection errors", "error", err)
 		return
 	}
-	logger.Info("DEBUG: Dependencies injected successfully")
+	logger.Debug("Dependencies injected successfully")
 
-	// Start the application
-	if err := app.Start(port); err != nil {
-		logger.Error("Failed to start application", "error", err)
+	// Connect privacy data handlers provided by the modules
+	gdpr.Discover(app.GetContainer())
+	if config.health != nil {
+		config.health.Discover(app.GetContainer())
+	}
+
+	if err := decorateCachedProviders(app.GetContainer(), config); err != nil {
+		logger.Error("FATAL: Application startup failed due to invalid cache declarations", "error", err)
+		return
+	}
+
+	issues := app.ValidateStartup()
+	for _, issue := range issues {
+		logger.Warn("Startup validation issue", "module", issue.Module, "kind", issue.Kind, "name", issue.Name, "message", issue.Message)
+	}
+	if config.strict && len(issues) > 0 {
+		logger.Error("FATAL: Application startup failed strict validation", "issues", len(issues))
+		return
+	}
+
+	if err := app.Init(ctx); err != nil {
+		logger.Error("FATAL: Application startup failed during module initialization", "error", err)
+		return
+	}
+	if err := app.Bootstrap(ctx); err != nil {
+		logger.Error("FATAL: Application startup failed during bootstrap", "error", err)
+		return
+	}
+	if err := warmUp(ctx, app, config.warmup); err != nil {
+		logger.Error("FATAL: Application startup failed during warmup", "error", err)
 		return
 	}
 
-	// Give some time for cleanup
-	time.Sleep(2 * time.Second)
+	logger.Info("NestGo application started", "port", port, "pid", info.PID(), "startTime", info.StartTime(), "startup", info.Uptime())
+
+	// Start the application
+	serverErr := make(chan error, 1+len(config.servers))
+	go func() {
+		if listener != nil {
+			serverErr <- app.Serve(listener)
+			return
+		}
+		serverErr <- app.Start(port)
+	}()
+	for _, named := range config.servers {
+		go func(named namedServer) {
+			serverErr <- fmt.Errorf("server %s: %w", named.name, app.StartHost(named.name, named.address))
+		}(named)
+	}
+
+	var received string
+	select {
+	case sig := <-sigChan:
+		received = sig.String()
+		logger.Info("Shutdown signal received, gracefully shutting down...", "signal", received)
+	case err := <-serverErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Failed to start application", "error", err)
+		}
+	}
+	cancel()
+
+	// Fail readiness probes while in-flight requests finish
+	if config.health != nil {
+		config.health.Drain()
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancelShutdown()
+	if err := app.Shutdown(shutdownCtx, received); err != nil {
+		logger.Error("Application shutdown completed with errors", "error", err)
+		return
+	}
 	logger.Info("Application shutdown complete")
 }
 
+// checkModuleServers checks that the servers named by modules are declared
+// with WithServer
+func checkModuleServers(modules map[string]module.Module, servers []namedServer) error {
+	declared := make(map[string]bool, len(servers))
+	for _, named := range servers {
+		declared[named.name] = true
+	}
+
+	var errs []error
+	for name, m := range modules {
+		if server := module.RouteDefaultsOf(m).Server; server != "" && !declared[server] {
+			errs = append(errs, fmt.Errorf("module %s: server %q is not declared with WithServer", name, server))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// warmUp runs the warmers of the application, waiting for them in Blocking
+// mode, and schedules their periodic runs
+func warmUp(ctx context.Context, a *app.App, runner *warmup.Runner) error {
+	if runner == nil {
+		return nil
+	}
+	a.Warmup(runner)
+
+	if runner.Mode() == warmup.Blocking {
+		if err := runner.Run(ctx); err != nil {
+			return err
+		}
+		runner.Schedule(ctx)
+		return nil
+	}
+	go func() {
+		runner.Run(ctx)
+		runner.Schedule(ctx)
+	}()
+	return nil
+}
+
+// decorateCachedProviders applies the cache decorator to providers declaring cache tags
+func decorateCachedProviders(c *container.Container, config *options) error {
+	for name, provider := range c.GetAllServices() {
+		if !cache.HasCacheTags(provider) {
+			continue
+		}
+
+		if config.cacheStore == nil {
+			config.cacheStore = cache.NewMemoryStore()
+		}
+		if err := cache.Decorate(provider, config.cacheStore); err != nil {
+			return fmt.Errorf("provider %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
 // CreateModule creates a module that auto-registers itself
 func CreateModule(moduleStruct interface{}) interface{} {
 	// Auto-register the module
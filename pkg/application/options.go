@@ -0,0 +1,657 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/kevenmiano/nestgo/pkg/abuse"
+	"github.com/kevenmiano/nestgo/pkg/app"
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/canary"
+	"github.com/kevenmiano/nestgo/pkg/clientinfo"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/compress"
+	"github.com/kevenmiano/nestgo/pkg/config"
+	"github.com/kevenmiano/nestgo/pkg/diagnostics"
+	"github.com/kevenmiano/nestgo/pkg/domain"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/guard"
+	"github.com/kevenmiano/nestgo/pkg/health"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/interceptor"
+	"github.com/kevenmiano/nestgo/pkg/ipfilter"
+	"github.com/kevenmiano/nestgo/pkg/limits"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/metrics"
+	"github.com/kevenmiano/nestgo/pkg/mirror"
+	"github.com/kevenmiano/nestgo/pkg/openapi"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+	"github.com/kevenmiano/nestgo/pkg/ratelimit"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+	"github.com/kevenmiano/nestgo/pkg/replay"
+	"github.com/kevenmiano/nestgo/pkg/saml"
+	"github.com/kevenmiano/nestgo/pkg/schema"
+	"github.com/kevenmiano/nestgo/pkg/server"
+	"github.com/kevenmiano/nestgo/pkg/tenant"
+	"github.com/kevenmiano/nestgo/pkg/upload"
+	"github.com/kevenmiano/nestgo/pkg/warmup"
+)
+
+// Option configures the application started by StartApplication
+type Option func(*options)
+
+// namedServer is a server declared with WithServer
+type namedServer struct {
+	name    string
+	address string
+}
+
+// namedEncoder is an encoder registered with WithEncoder
+type namedEncoder struct {
+	contentType string
+	encoder     encoder.Encoder
+}
+
+// errorMapping is a mapping registered with WithErrorMapping
+type errorMapping struct {
+	target  error
+	mapping domain.Mapping
+}
+
+// options holds the settings collected from Option values
+type options struct {
+	router            server.Router
+	diagnostics       bool
+	httpMiddlewares   []func(http.Handler) http.Handler
+	realIP            *realip.Resolver
+	replay            *replay.Protector
+	saml              *saml.ServiceProvider
+	cacheStore        cache.Store
+	namedMiddleware   map[string]server.Middleware
+	namedGuards       map[string]guard.Guard
+	globalGuards      []guard.Guard
+	namedInterceptors map[string]interceptor.Interceptor
+	interceptors      []interceptor.Interceptor
+	namedPipes        map[string]pipe.Pipe
+	pipes             []pipe.Pipe
+	configs           []interface{}
+	warmup            *warmup.Runner
+	health            *health.Checker
+	metrics           *metrics.Options
+	openAPI           *openapi.Options
+	strict            bool
+	servers           []namedServer
+	timeouts          server.Timeouts
+	tenantLabels      *tenant.Labels
+	timePolicy        *codec.TimePolicy
+	uploads           *upload.Options
+	requestLimits     *limits.Options
+	encoders          []namedEncoder
+	jsonStyle         *encoder.JSONStyle
+	errorMappings     []errorMapping
+	clock             clock.Clock
+	ids               ids.Generator
+	err               error
+}
+
+// newOptions applies the given options over the defaults
+func newOptions(opts ...Option) *options {
+	o := &options{
+		httpMiddlewares:   make([]func(http.Handler) http.Handler, 0),
+		namedMiddleware:   make(map[string]server.Middleware),
+		namedGuards:       make(map[string]guard.Guard),
+		globalGuards:      make([]guard.Guard, 0),
+		namedInterceptors: make(map[string]interceptor.Interceptor),
+		interceptors:      make([]interceptor.Interceptor, 0),
+		namedPipes:        make(map[string]pipe.Pipe),
+		pipes:             make([]pipe.Pipe, 0),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// apply configures the app with the collected options
+func (o *options) apply(a *app.App) {
+	// The routing backend must be set before any route or middleware
+	if o.router != nil {
+		a.GetServer().SetRouter(o.router)
+	}
+	for _, named := range o.servers {
+		a.GetServer().AddHost(named.name)
+	}
+	a.GetServer().SetTimeouts(o.timeouts)
+	o.applySettings(a.GetServer())
+
+	// Tenant labels must be set before metrics record them
+	if o.tenantLabels != nil {
+		a.Use(tenant.LabelsMiddleware(o.tenantLabels))
+	}
+
+	// Metrics wrap every other middleware so rejected requests are counted
+	if o.metrics != nil {
+		a.Use(metrics.Middleware(o.metrics.Registry, o.metrics.Buckets))
+		a.GetServer().RegisterRoute(http.MethodGet, o.metrics.Path, o.metrics.Registry.ServeHTTP)
+	}
+
+	// Real IP resolution must run before any middleware that reads the client IP
+	if o.realIP != nil {
+		a.Use(realip.Middleware(o.realIP))
+	}
+	a.Use(o.httpMiddlewares...)
+
+	if o.warmup != nil {
+		a.Use(o.warmup.Middleware)
+		if path := o.warmup.ReadinessPath(); path != "" {
+			a.GetServer().RegisterRoute(http.MethodGet, path, o.warmup.ServeHTTP)
+		}
+	}
+
+	if o.health != nil {
+		if o.cacheStore != nil {
+			o.health.Add(cache.NewHealthIndicator("cache", o.cacheStore))
+		}
+		a.GetServer().RegisterRoute(http.MethodGet, o.health.Path(), o.health.ServeHTTP)
+		a.GetServer().RegisterRoute(http.MethodGet, o.health.LivePath(), o.health.ServeLive)
+		a.GetServer().RegisterRoute(http.MethodGet, o.health.ReadyPath(), o.health.ServeReady)
+	}
+
+	if o.openAPI != nil {
+		spec := openapi.New(*o.openAPI, a.GetServer())
+		a.GetServer().RegisterRoute(http.MethodGet, spec.Path(), spec.ServeHTTP)
+		if path := spec.DocsPath(); path != "" {
+			a.GetServer().RegisterRoute(http.MethodGet, path, spec.ServeDocs)
+		}
+	}
+
+	if o.saml != nil {
+		a.GetServer().RegisterRoute(http.MethodGet, o.saml.MetadataPath(), o.saml.ServeMetadata)
+		a.GetServer().RegisterRoute(http.MethodGet, o.saml.LoginPath(), o.saml.ServeLogin)
+		a.GetServer().RegisterRoute(http.MethodPost, o.saml.ACSPath(), o.saml.ServeACS)
+	}
+
+	if o.diagnostics {
+		diagnostics.Register(a.GetServer(), a.GetContainer())
+	}
+
+	if o.replay != nil {
+		a.GetServer().SetReplayProtector(o.replay)
+	}
+
+	for name, middleware := range o.namedMiddleware {
+		a.GetServer().RegisterMiddleware(name, middleware)
+	}
+
+	for name, g := range o.namedGuards {
+		a.GetServer().RegisterGuard(name, g)
+	}
+	a.GetServer().UseGuards(o.globalGuards...)
+
+	for name, i := range o.namedInterceptors {
+		a.GetServer().RegisterInterceptor(name, i)
+	}
+	a.GetServer().UseInterceptors(o.interceptors...)
+
+	for name, p := range o.namedPipes {
+		a.GetServer().RegisterPipe(name, p)
+	}
+	a.GetServer().UsePipes(o.pipes...)
+}
+
+// applySettings sets the conventions of the server, before any route is
+// registered
+func (o *options) applySettings(s *server.Server) {
+	if o.timePolicy != nil {
+		s.SetTimePolicy(*o.timePolicy)
+	}
+	if o.uploads != nil {
+		s.SetUploadDefaults(*o.uploads)
+	}
+	if o.requestLimits != nil {
+		s.SetRequestLimits(*o.requestLimits)
+	}
+	for _, named := range o.encoders {
+		s.RegisterEncoder(named.contentType, named.encoder)
+	}
+	if o.jsonStyle != nil {
+		s.SetJSONStyle(*o.jsonStyle)
+	}
+	for _, m := range o.errorMappings {
+		s.RegisterErrorMapping(m.target, m.mapping)
+	}
+}
+
+// useProcessDefaults makes the clock and ID generator of the options the
+// defaults of the process, read by framework components without access to
+// the application, until the returned function restores the previous ones
+func (o *options) useProcessDefaults() func() {
+	previousClock, previousIDs := clock.Default(), ids.Default()
+	if o.clock != nil {
+		clock.SetDefault(o.clock)
+	}
+	if o.ids != nil {
+		ids.SetDefault(o.ids)
+	}
+	return func() {
+		clock.SetDefault(previousClock)
+		ids.SetDefault(previousIDs)
+	}
+}
+
+// WithRouter sets the routing backend, e.g. server.NewServeMuxRouter() to
+// route with net/http only
+func WithRouter(router server.Router) Option {
+	return func(o *options) {
+		o.router = router
+	}
+}
+
+// WithServer declares a named server listening on its own address, e.g.
+// WithServer("admin", ":9000"), for the modules setting Server to its name
+// in their configuration. Servers share the providers, lifecycle and
+// global middlewares, guards, interceptors and pipes of the application;
+// framework routes such as health checks stay on the main server.
+func WithServer(name, address string) Option {
+	return func(o *options) {
+		if name == "" {
+			o.err = errors.Join(o.err, fmt.Errorf("server on %s: missing name", address))
+			return
+		}
+		for _, named := range o.servers {
+			if named.name == name {
+				o.err = errors.Join(o.err, fmt.Errorf("server %s declared twice", name))
+				return
+			}
+		}
+		o.servers = append(o.servers, namedServer{name: name, address: address})
+	}
+}
+
+// WithServerTimeouts sets the connection timeouts of the servers. Routes
+// needing more time for a request set it with a limits tag or
+// WithRequestLimits instead.
+func WithServerTimeouts(timeouts server.Timeouts) Option {
+	return func(o *options) {
+		if timeouts.Read < 0 || timeouts.Write < 0 || timeouts.Idle < 0 {
+			o.err = errors.Join(o.err, fmt.Errorf("server timeouts: negative duration"))
+			return
+		}
+		o.timeouts = timeouts
+	}
+}
+
+// WithDiagnostics exposes the routes, providers and modules of the
+// application as JSON under /_nestgo, for debugging in development
+func WithDiagnostics() Option {
+	return func(o *options) {
+		o.diagnostics = true
+	}
+}
+
+// WithRequestBudget warns about requests allocating or leaving running more
+// than the budgets, for performance work in development
+func WithRequestBudget(budget diagnostics.BudgetOptions) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, diagnostics.BudgetMiddleware(budget))
+	}
+}
+
+// WithClientInfo enables client IP, user agent and geo enrichment for every request
+func WithClientInfo(clientOpts clientinfo.Options) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, clientinfo.Middleware(clientOpts))
+	}
+}
+
+// WithCanary tags every request with a deployment track, routing flagged or a
+// percentage of requests to canary handlers and providers
+func WithCanary(canaryOpts canary.Options) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, canary.Middleware(canaryOpts))
+	}
+}
+
+// WithTenant resolves the tenant of every request, by default from the
+// X-Tenant-ID header; add it before options keyed by tenant like WithRateLimit
+func WithTenant(resolvers ...tenant.Resolver) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, tenant.Middleware(resolvers...))
+	}
+}
+
+// WithTenantLabelLimit bounds the tenant IDs used as metric labels of the
+// application's requests to the first max distinct tenants, the rest being
+// labeled "other"
+func WithTenantLabelLimit(max int) Option {
+	return func(o *options) {
+		o.tenantLabels = tenant.NewLabels(max)
+	}
+}
+
+// WithWarmup runs the providers implementing warmup.Warmer after bootstrap,
+// gating readiness per the warmup mode
+func WithWarmup(warmupOpts warmup.Options) Option {
+	return func(o *options) {
+		o.warmup = warmup.New(warmupOpts)
+	}
+}
+
+// WithHealth serves the health of the application, checking the indicators
+// of the enabled integration modules, the cache store and the providers
+// implementing health.Indicator, along with liveness and readiness probes
+func WithHealth(healthOpts health.Options) Option {
+	return func(o *options) {
+		o.health = health.NewChecker(healthOpts)
+	}
+}
+
+// WithMetrics records the requests served by route and status and exposes
+// them with the metrics of the registry in the Prometheus text format
+func WithMetrics(metricsOpts metrics.Options) Option {
+	return func(o *options) {
+		if metricsOpts.Path == "" {
+			metricsOpts.Path = metrics.DefaultPath
+		}
+		if metricsOpts.Registry == nil {
+			metricsOpts.Registry = metrics.NewRegistry()
+		}
+		o.metrics = &metricsOpts
+	}
+}
+
+// WithOpenAPI serves an OpenAPI 3 document of the controller routes,
+// described from their handler signatures, DTOs and validation tags, and
+// the Swagger UI or Redoc page selected by Docs
+func WithOpenAPI(openAPIOpts openapi.Options) Option {
+	return func(o *options) {
+		if openAPIOpts.Docs != "" && !openAPIOpts.Docs.Known() {
+			o.err = errors.Join(o.err, fmt.Errorf("openapi: unknown docs page %q, expected %s or %s", openAPIOpts.Docs, openapi.UISwagger, openapi.UIRedoc))
+			return
+		}
+		o.openAPI = &openAPIOpts
+	}
+}
+
+// WithCompression compresses responses with gzip for clients accepting it.
+// Controllers and routes override it with compress tags.
+func WithCompression(compressOpts compress.Options) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, compress.Middleware(compressOpts))
+	}
+}
+
+// WithCacheControl sets the default Cache-Control header of every response,
+// e.g. "no-store". Controllers and routes override it with cache tags.
+func WithCacheControl(value string) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, server.CacheControlMiddleware(value))
+	}
+}
+
+// WithRateLimit limits the requests of every tenant or client IP per the
+// limits of its plan
+func WithRateLimit(rateOpts ratelimit.Options) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, ratelimit.New(rateOpts).Middleware)
+	}
+}
+
+// WithTrustedProxies configures the proxy IPs or CIDRs whose X-Forwarded-For
+// and X-Real-IP headers are honored when resolving the client IP
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(o *options) {
+		resolver, err := realip.NewResolver(cidrs...)
+		if err != nil {
+			o.err = errors.Join(o.err, err)
+			return
+		}
+		o.realIP = resolver
+	}
+}
+
+// WithIPFilter restricts access to every route using CIDR allow and deny lists
+func WithIPFilter(rules ipfilter.Rules) Option {
+	return func(o *options) {
+		filter, err := ipfilter.New("global", rules)
+		if err != nil {
+			o.err = errors.Join(o.err, err)
+			return
+		}
+		o.httpMiddlewares = append(o.httpMiddlewares, filter.Middleware)
+	}
+}
+
+// WithReplayProtection configures the store, window and signing secret used
+// by routes tagged with replay. Without a secret, replay protection only
+// deduplicates the retries of honest clients, see replay.Options.
+func WithReplayProtection(replayOpts replay.Options) Option {
+	return func(o *options) {
+		o.replay = replay.New(replayOpts)
+	}
+}
+
+// WithSAML serves a SAML service provider: its metadata, the login
+// redirecting to the identity provider and the assertion consumer service
+func WithSAML(samlOpts saml.Options) Option {
+	return func(o *options) {
+		sp, err := saml.New(samlOpts)
+		if err != nil {
+			o.err = errors.Join(o.err, err)
+			return
+		}
+		o.saml = sp
+	}
+}
+
+// WithAbuseDetection scores every request and flags, throttles or blocks suspicious clients
+func WithAbuseDetection(detector *abuse.Detector) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, detector.Middleware)
+	}
+}
+
+// WithTrafficMirror copies a sample of the requests to a shadow upstream,
+// such as a new release under test, without affecting the responses
+func WithTrafficMirror(m *mirror.Mirror) Option {
+	return func(o *options) {
+		o.httpMiddlewares = append(o.httpMiddlewares, m.Middleware)
+	}
+}
+
+// WithCacheStore sets the store used by provider fields declaring cache tags
+func WithCacheStore(store cache.Store) Option {
+	return func(o *options) {
+		o.cacheStore = store
+	}
+}
+
+// WithMiddleware adds middlewares applied to every route, before module,
+// controller and route middlewares
+func WithMiddleware(middlewares ...server.Middleware) Option {
+	return func(o *options) {
+		for _, middleware := range middlewares {
+			o.httpMiddlewares = append(o.httpMiddlewares, server.Adapt(middleware))
+		}
+	}
+}
+
+// WithNamedMiddleware registers a middleware referenced by name from module
+// configurations and middleware tags, e.g. `middleware:"auth,logging"`
+func WithNamedMiddleware(name string, middleware server.Middleware) Option {
+	return func(o *options) {
+		o.namedMiddleware[name] = middleware
+	}
+}
+
+// WithGuards adds guards evaluated for every route, before controller and route guards
+func WithGuards(guards ...guard.Guard) Option {
+	return func(o *options) {
+		o.globalGuards = append(o.globalGuards, guards...)
+	}
+}
+
+// WithNamedGuard registers a guard referenced by name from guards tags.
+// Providers implementing CanActivate are available by type name without
+// registration.
+func WithNamedGuard(name string, g guard.Guard) Option {
+	return func(o *options) {
+		o.namedGuards[name] = g
+	}
+}
+
+// WithInterceptors adds interceptors wrapping every route handler, outside
+// controller and route interceptors
+func WithInterceptors(interceptors ...interceptor.Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithNamedInterceptor registers an interceptor referenced by name from
+// interceptors tags
+func WithNamedInterceptor(name string, i interceptor.Interceptor) Option {
+	return func(o *options) {
+		o.namedInterceptors[name] = i
+	}
+}
+
+// WithPipes adds pipes applied to the arguments of every route, before route pipes
+func WithPipes(pipes ...pipe.Pipe) Option {
+	return func(o *options) {
+		o.pipes = append(o.pipes, pipes...)
+	}
+}
+
+// WithNamedPipe registers a pipe referenced by name from pipes tags. The
+// built-in ParseIntPipe, ParseUUIDPipe and ValidationPipe are always available.
+func WithNamedPipe(name string, p pipe.Pipe) Option {
+	return func(o *options) {
+		o.namedPipes[name] = p
+	}
+}
+
+// WithTimePolicy sets the timestamp formats accepted and written by routes
+// without a time tag, and the zone timestamps are normalized to
+func WithTimePolicy(policy codec.TimePolicy) Option {
+	return func(o *options) {
+		o.timePolicy = &policy
+	}
+}
+
+// WithUploads sets the limits and storage of uploaded files for routes
+// without an upload tag, and the base of upload tags
+func WithUploads(uploadOpts upload.Options) Option {
+	return func(o *options) {
+		o.uploads = &uploadOpts
+	}
+}
+
+// WithRequestLimits sets the body size, read timeout and handler deadline of
+// routes without a limits tag, and the base of limits tags
+func WithRequestLimits(limitOpts limits.Options) Option {
+	return func(o *options) {
+		o.requestLimits = &limitOpts
+	}
+}
+
+// WithEncoder registers the response encoder of a content type, selected
+// by the Accept header of requests, over the encoders of encoder.Register
+func WithEncoder(contentType string, e encoder.Encoder) Option {
+	return func(o *options) {
+		o.encoders = append(o.encoders, namedEncoder{contentType: contentType, encoder: e})
+	}
+}
+
+// WithJSONStyle sets the layout of JSON responses: pretty for development,
+// or compact without the default envelopes
+func WithJSONStyle(style encoder.JSONStyle) Option {
+	return func(o *options) {
+		o.jsonStyle = &style
+	}
+}
+
+// WithErrorMapping maps domain errors matching target to a status code and
+// problem type, before the mappings of domain.Register
+func WithErrorMapping(target error, mapping domain.Mapping) Option {
+	return func(o *options) {
+		o.errorMappings = append(o.errorMappings, errorMapping{target: target, mapping: mapping})
+	}
+}
+
+// WithClock sets the clock read by framework components for timestamps,
+// expirations and token lifetimes, e.g. a clock.Frozen in tests. Framework
+// packages read it outside requests too, so it is the clock of the process
+// while the application runs.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
+
+// WithIDGenerator sets the generator behind job IDs, UUIDs, tokens and
+// recovery codes, e.g. an ids.Sequence in tests. Like WithClock, it is the
+// generator of the process while the application runs.
+func WithIDGenerator(g ids.Generator) Option {
+	return func(o *options) {
+		o.ids = g
+	}
+}
+
+// WithConfig loads configuration structs from environment variables, see
+// config.Load. Startup fails with every missing or invalid setting before
+// the server binds its port. Loaded structs are injectable by type name.
+func WithConfig(targets ...interface{}) Option {
+	return func(o *options) {
+		for _, target := range targets {
+			if err := config.Load(target); err != nil {
+				o.err = errors.Join(o.err, err)
+				continue
+			}
+			o.configs = append(o.configs, target)
+		}
+	}
+}
+
+// WithSchemaBaseline compares the DTOs registered with schema.Register to
+// the baseline file at path, failing startup on breaking changes that are
+// not covered by a new version and its adapters. A missing baseline is
+// written from the current DTOs so it can be committed.
+func WithSchemaBaseline(path string) Option {
+	return func(o *options) {
+		baseline, err := schema.ReadBaseline(path)
+		if errors.Is(err, os.ErrNotExist) {
+			if err := schema.WriteBaseline(path, schema.Registered()); err != nil {
+				o.err = errors.Join(o.err, fmt.Errorf("schema baseline: %w", err))
+				return
+			}
+			logger.Info("Schema baseline written", "path", path)
+			return
+		}
+		if err != nil {
+			o.err = errors.Join(o.err, err)
+			return
+		}
+
+		changes, err := schema.Check(baseline)
+		if err != nil {
+			o.err = errors.Join(o.err, err)
+			return
+		}
+		for _, change := range changes {
+			logger.Warn("DTO changed since the schema baseline", "change", change.String(), "baseline", path)
+		}
+	}
+}
+
+// WithStrictStartup fails startup on the issues found by
+// app.ValidateStartup: providers never injected, controllers without routes
+// and route fields left nil. They are only logged as warnings otherwise.
+func WithStrictStartup() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// DefaultAPIKeyHeader carries the API key of a request
+const DefaultAPIKeyHeader = "X-API-Key"
+
+// APIKeyOptions configures an APIKeyStrategy
+type APIKeyOptions struct {
+	// Header carries the key, DefaultAPIKeyHeader when empty
+	Header string
+	// Lookup returns the principal of a key, or nil for unknown keys
+	Lookup func(r *http.Request, key string) (*Principal, error)
+}
+
+// APIKeyStrategy authenticates requests with a key sent in a header
+type APIKeyStrategy struct {
+	options APIKeyOptions
+}
+
+// NewAPIKeyStrategy creates an API key strategy
+func NewAPIKeyStrategy(opts APIKeyOptions) *APIKeyStrategy {
+	if opts.Header == "" {
+		opts.Header = DefaultAPIKeyHeader
+	}
+	return &APIKeyStrategy{options: opts}
+}
+
+// Name returns "apikey"
+func (s *APIKeyStrategy) Name() string {
+	return "apikey"
+}
+
+// Authenticate looks up the key of the request
+func (s *APIKeyStrategy) Authenticate(r *http.Request) (*Principal, error) {
+	key := strings.TrimSpace(r.Header.Get(s.options.Header))
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	principal, err := s.options.Lookup(r, key)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+	return principal, nil
+}
+
+// StaticKeys returns a Lookup accepting fixed keys, mapped to the subject
+// of their principal, e.g. for service to service calls. Keys are compared
+// in constant time.
+func StaticKeys(keys map[string]string) func(r *http.Request, key string) (*Principal, error) {
+	type entry struct {
+		digest  []byte
+		subject string
+	}
+	entries := make([]entry, 0, len(keys))
+	for key, subject := range keys {
+		entries = append(entries, entry{digest: digest(key), subject: subject})
+	}
+
+	return func(r *http.Request, key string) (*Principal, error) {
+		sent := digest(key)
+		for _, entry := range entries {
+			if subtle.ConstantTimeCompare(sent, entry.digest) == 1 {
+				return &Principal{Subject: entry.subject}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// digest hashes a secret so comparisons do not depend on its length
+func digest(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// Authentication errors
+var (
+	// ErrNoCredentials is returned by strategies for requests without their
+	// credentials, so the next strategy is tried
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned, possibly wrapped, by strategies
+	// rejecting the credentials of a request
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnauthenticated is the 401 answered to requests no strategy
+	// authenticates
+	ErrUnauthenticated = controller.NewHTTPError(http.StatusUnauthorized, "Unauthorized")
+)
+
+// Principal is the authenticated caller of a request, bound to handler
+// arguments declared as *auth.Principal:
+//
+//	GetProfile func(user *auth.Principal) (*Profile, error) `route:"GET /me" guards:"AuthGuard"`
+type Principal struct {
+	Subject string `json:"subject"`
+	// Strategy is the name of the strategy that authenticated the request
+	Strategy string   `json:"strategy"`
+	Roles    []string `json:"roles,omitempty"`
+	// Claims holds strategy specific attributes, e.g. the claims of a JWT
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// HasRole reports whether the principal has a role
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// contextKey is the key used to store the principal in the request context
+type contextKey struct{}
+
+// NewContext returns a context carrying the principal
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal authenticated for a context
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
+
+// FromRequest returns the principal authenticated for a request
+func FromRequest(r *http.Request) (*Principal, bool) {
+	return FromContext(r.Context())
+}
+
+// Strategy authenticates requests from one kind of credentials
+type Strategy interface {
+	// Name identifies the strategy in principals and logs
+	Name() string
+	// Authenticate returns the principal of the request credentials,
+	// ErrNoCredentials when the request has none and ErrInvalidCredentials
+	// when they are rejected. Other errors fail the request.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// AuthGuard lets through the requests authenticated by one of its
+// strategies, tried in order, and answers 401 to the others. Register it
+// as a provider or named guard and reference it from guards tags:
+//
+//	application.WithNamedGuard("AuthGuard", auth.NewAuthGuard(
+//		auth.NewJWTStrategy(auth.JWTOptions{Secret: secret}),
+//		auth.NewAPIKeyStrategy(auth.APIKeyOptions{Lookup: findKey}),
+//	))
+type AuthGuard struct {
+	strategies []Strategy
+}
+
+// NewAuthGuard creates a guard authenticating with the strategies
+func NewAuthGuard(strategies ...Strategy) *AuthGuard {
+	return &AuthGuard{strategies: strategies}
+}
+
+// CanActivate reports whether a strategy authenticates the request
+func (g *AuthGuard) CanActivate(r *http.Request) (bool, error) {
+	_, allowed, err := g.Activate(r)
+	return allowed, err
+}
+
+// Activate authenticates the request, returning it with the principal in
+// its context for the handler
+func (g *AuthGuard) Activate(r *http.Request) (*http.Request, bool, error) {
+	for _, strategy := range g.strategies {
+		principal, err := strategy.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if errors.Is(err, ErrInvalidCredentials) {
+			logger.Warn("Authentication failed", "strategy", strategy.Name(), "path", r.URL.Path, "error", err)
+			return r, false, ErrUnauthenticated
+		}
+		if err != nil {
+			return r, false, err
+		}
+		if principal == nil {
+			continue
+		}
+
+		// Strategies may return shared principals, e.g. cached by a lookup
+		authenticated := *principal
+		authenticated.Strategy = strategy.Name()
+		return r.WithContext(NewContext(r.Context(), &authenticated)), true, nil
+	}
+	return r, false, ErrUnauthenticated
+}
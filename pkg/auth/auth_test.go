@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthGuard(t *testing.T) {
+	secret := []byte("secret")
+	token := func(claims map[string]interface{}) string {
+		signed, err := SignJWT(claims, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	shared := &Principal{Subject: "billing", Roles: []string{"service"}}
+	guard := NewAuthGuard(
+		NewJWTStrategy(JWTOptions{Secret: secret, Issuer: "nestgo"}),
+		NewAPIKeyStrategy(APIKeyOptions{Lookup: func(r *http.Request, key string) (*Principal, error) {
+			switch key {
+			case "shared":
+				return shared, nil
+			case "broken":
+				return nil, errors.New("store unavailable")
+			}
+			return StaticKeys(map[string]string{"valid": "reports"})(r, key)
+		}}),
+		NewBasicStrategy(BasicOptions{Verify: func(r *http.Request, username, password string) (*Principal, error) {
+			if password != "password" {
+				return nil, nil
+			}
+			return shared, nil
+		}}),
+	)
+
+	tests := []struct {
+		name         string
+		header       string
+		value        string
+		basic        bool
+		wantAllowed  bool
+		wantErr      error
+		wantSubject  string
+		wantStrategy string
+		wantRoles    []string
+	}{
+		{name: "no credentials", wantErr: ErrUnauthenticated},
+		{
+			name: "jwt", header: "Authorization",
+			value:       token(map[string]interface{}{"sub": "ana", "iss": "nestgo", "roles": []string{"admin"}, "exp": time.Now().Add(time.Hour).Unix()}),
+			wantAllowed: true, wantSubject: "ana", wantStrategy: "jwt", wantRoles: []string{"admin"},
+		},
+		{
+			name: "expired jwt", header: "Authorization",
+			value:   token(map[string]interface{}{"sub": "ana", "iss": "nestgo", "exp": time.Now().Add(-time.Hour).Unix()}),
+			wantErr: ErrUnauthenticated,
+		},
+		{
+			name: "jwt of another issuer", header: "Authorization",
+			value:   token(map[string]interface{}{"sub": "ana", "iss": "other"}),
+			wantErr: ErrUnauthenticated,
+		},
+		{name: "tampered jwt", header: "Authorization", value: token(map[string]interface{}{"sub": "ana", "iss": "nestgo"}) + "x", wantErr: ErrUnauthenticated},
+		{name: "api key", header: DefaultAPIKeyHeader, value: "valid", wantAllowed: true, wantSubject: "reports", wantStrategy: "apikey"},
+		{name: "shared api key principal", header: DefaultAPIKeyHeader, value: "shared", wantAllowed: true, wantSubject: "billing", wantStrategy: "apikey", wantRoles: []string{"service"}},
+		{name: "unknown api key", header: DefaultAPIKeyHeader, value: "unknown", wantErr: ErrUnauthenticated},
+		{name: "failing lookup", header: DefaultAPIKeyHeader, value: "broken", wantErr: errors.New("store unavailable")},
+		{name: "basic", basic: true, value: "password", wantAllowed: true, wantSubject: "billing", wantStrategy: "basic", wantRoles: []string{"service"}},
+		{name: "wrong password", basic: true, value: "wrong", wantErr: ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/me", nil)
+			switch {
+			case tt.basic:
+				r.SetBasicAuth("ana", tt.value)
+			case tt.header != "":
+				r.Header.Set(tt.header, tt.value)
+			}
+
+			activated, allowed, err := guard.Activate(r)
+			if allowed != tt.wantAllowed {
+				t.Fatalf("allowed = %v, want %v", allowed, tt.wantAllowed)
+			}
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			principal, ok := FromRequest(activated)
+			if !ok {
+				t.Fatal("principal missing from the request context")
+			}
+			if principal.Subject != tt.wantSubject || principal.Strategy != tt.wantStrategy {
+				t.Errorf("principal = %s via %s, want %s via %s", principal.Subject, principal.Strategy, tt.wantSubject, tt.wantStrategy)
+			}
+			for _, role := range tt.wantRoles {
+				if !principal.HasRole(role) {
+					t.Errorf("principal lacks role %q", role)
+				}
+			}
+			if shared.Subject != "billing" || shared.Strategy != "" {
+				t.Errorf("shared principal was modified: %+v", *shared)
+			}
+		})
+	}
+}
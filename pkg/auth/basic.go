@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// BasicOptions configures a BasicStrategy
+type BasicOptions struct {
+	// Verify returns the principal of valid credentials, or nil
+	Verify func(r *http.Request, username, password string) (*Principal, error)
+}
+
+// BasicStrategy authenticates requests with HTTP basic credentials
+type BasicStrategy struct {
+	options BasicOptions
+}
+
+// NewBasicStrategy creates a basic authentication strategy
+func NewBasicStrategy(opts BasicOptions) *BasicStrategy {
+	return &BasicStrategy{options: opts}
+}
+
+// Name returns "basic"
+func (s *BasicStrategy) Name() string {
+	return "basic"
+}
+
+// Authenticate verifies the basic credentials of the request
+func (s *BasicStrategy) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, encoded, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Basic") {
+		return nil, ErrNoCredentials
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	username, password, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return nil, ErrInvalidCredentials
+	}
+
+	principal, err := s.options.Verify(r, username, password)
+	if err != nil {
+		return nil, err
+	}
+	if principal == nil {
+		return nil, ErrInvalidCredentials
+	}
+	if principal.Subject == "" {
+		named := *principal
+		named.Subject = username
+		return &named, nil
+	}
+	return principal, nil
+}
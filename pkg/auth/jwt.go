@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+)
+
+// DefaultRolesClaim is the JWT claim holding the roles of a principal
+const DefaultRolesClaim = "roles"
+
+// JWTOptions configures a JWTStrategy. Exactly one of Secret and PublicKey
+// is expected; it also decides the accepted algorithms, so tokens cannot
+// pick a weaker one.
+type JWTOptions struct {
+	// Secret verifies HS256, HS384 and HS512 tokens
+	Secret []byte
+	// PublicKey verifies RS256, RS384 and RS512 tokens with an
+	// *rsa.PublicKey, or ES256, ES384 and ES512 ones with an *ecdsa.PublicKey
+	PublicKey crypto.PublicKey
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf
+	Leeway time.Duration
+	// RolesClaim names the claim holding the roles, DefaultRolesClaim when
+	// empty
+	RolesClaim string
+	// Revocations rejects tokens whose jti claim was revoked, e.g. on logout
+	Revocations RevocationStore
+}
+
+// JWTStrategy authenticates requests with a JWT bearer token in the
+// Authorization header
+type JWTStrategy struct {
+	options JWTOptions
+}
+
+// NewJWTStrategy creates a JWT bearer strategy
+func NewJWTStrategy(opts JWTOptions) *JWTStrategy {
+	if opts.RolesClaim == "" {
+		opts.RolesClaim = DefaultRolesClaim
+	}
+	return &JWTStrategy{options: opts}
+}
+
+// Name returns "jwt"
+func (s *JWTStrategy) Name() string {
+	return "jwt"
+}
+
+// Authenticate verifies the bearer token of the request
+func (s *JWTStrategy) Authenticate(r *http.Request) (*Principal, error) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+	return s.Verify(strings.TrimSpace(token))
+}
+
+// Verify checks the signature and claims of a token and returns its
+// principal, with the sub claim as subject
+func (s *JWTStrategy) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidCredentials)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidCredentials)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidCredentials)
+	}
+	if err := s.verifySignature(header.Alg, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidCredentials)
+	}
+	if err := s.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	return &Principal{Subject: subject, Roles: stringList(claims[s.options.RolesClaim]), Claims: claims}, nil
+}
+
+// verifySignature checks the signature with the configured key, accepting
+// only the algorithms of its kind
+func (s *JWTStrategy) verifySignature(alg, signed string, signature []byte) error {
+	hash, ok := map[string]crypto.Hash{
+		"HS256": crypto.SHA256, "HS384": crypto.SHA384, "HS512": crypto.SHA512,
+		"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+		"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	}[alg]
+	if !ok {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidCredentials, alg)
+	}
+	digest := hash.New()
+	digest.Write([]byte(signed))
+
+	valid := false
+	switch key := s.options.PublicKey.(type) {
+	case nil:
+		if strings.HasPrefix(alg, "HS") && len(s.options.Secret) > 0 {
+			valid = hmac.Equal(signature, hmacSum(hash, s.options.Secret, signed))
+		}
+	case *rsa.PublicKey:
+		if strings.HasPrefix(alg, "RS") {
+			valid = rsa.VerifyPKCS1v15(key, hash, digest.Sum(nil), signature) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if strings.HasPrefix(alg, "ES") && len(signature) == 2*size {
+			r := new(big.Int).SetBytes(signature[:size])
+			sig := new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(key, digest.Sum(nil), r, sig)
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%w: invalid signature", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// checkClaims validates the time, issuer, audience and revocation claims
+func (s *JWTStrategy) checkClaims(claims map[string]interface{}) error {
+	now := clock.Now()
+	if exp, ok := claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(s.options.Leeway)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidCredentials)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(s.options.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidCredentials)
+	}
+	if s.options.Issuer != "" && claims["iss"] != s.options.Issuer {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if s.options.Audience != "" {
+		audiences := stringList(claims["aud"])
+		if aud, ok := claims["aud"].(string); ok {
+			audiences = []string{aud}
+		}
+		if !slices.Contains(audiences, s.options.Audience) {
+			return fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+		}
+	}
+
+	if id, ok := claims["jti"].(string); ok && s.options.Revocations != nil {
+		revoked, err := s.options.Revocations.IsRevoked(id)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return fmt.Errorf("%w: token revoked", ErrInvalidCredentials)
+		}
+	}
+	return nil
+}
+
+// SignJWT issues an HS256 token of the claims, e.g. from a login handler.
+// Expiration is up to the caller through the exp claim.
+func SignJWT(claims map[string]interface{}, secret []byte) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(hmacSum(crypto.SHA256, secret, signed)), nil
+}
+
+// hmacSum signs data with an HMAC of the hash
+func hmacSum(hash crypto.Hash, secret []byte, data string) []byte {
+	newHash := sha256.New
+	switch hash {
+	case crypto.SHA384:
+		newHash = sha512.New384
+	case crypto.SHA512:
+		newHash = sha512.New
+	}
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+// stringList converts a claim holding a list of strings
+func stringList(value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if text, ok := item.(string); ok {
+			list = append(list, text)
+		}
+	}
+	return list
+}
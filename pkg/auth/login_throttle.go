@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/service"
+)
+
+// Default login throttle settings
+const (
+	DefaultMaxAttempts   = 5
+	DefaultBaseLockout   = 30 * time.Second
+	DefaultMaxLockout    = time.Hour
+	DefaultFailureWindow = 15 * time.Minute
+
+	loginThrottleKeyPrefix = "login-throttle:"
+)
+
+// LoginThrottleOptions configures a LoginThrottle
+type LoginThrottleOptions struct {
+	// MaxAttempts is the number of failures allowed before a lockout
+	MaxAttempts int
+	// BaseLockout is the first lockout duration, doubled on each subsequent lockout
+	BaseLockout time.Duration
+	// MaxLockout caps the exponential lockout
+	MaxLockout time.Duration
+	// FailureWindow is how long failures are remembered without new attempts
+	FailureWindow time.Duration
+}
+
+// loginAttempts is the persisted throttle state of a key
+type loginAttempts struct {
+	Failures    int       `json:"failures"`
+	Lockouts    int       `json:"lockouts"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// LoginThrottle tracks failed logins per identifier and IP with exponential lockout.
+// Register it as a provider and inject it with `inject:"LoginThrottle"`.
+type LoginThrottle struct {
+	service.BaseService
+	store   cache.Store
+	options LoginThrottleOptions
+}
+
+// NewLoginThrottle creates a login throttle backed by the given store
+func NewLoginThrottle(store cache.Store, opts LoginThrottleOptions) *LoginThrottle {
+	if store == nil {
+		store = cache.NewMemoryStore()
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.BaseLockout <= 0 {
+		opts.BaseLockout = DefaultBaseLockout
+	}
+	if opts.MaxLockout <= 0 {
+		opts.MaxLockout = DefaultMaxLockout
+	}
+	if opts.FailureWindow <= 0 {
+		opts.FailureWindow = DefaultFailureWindow
+	}
+
+	return &LoginThrottle{
+		store:   store,
+		options: opts,
+	}
+}
+
+// Check reports whether a login may be attempted and, if not, how long to wait
+func (lt *LoginThrottle) Check(identifier, ip string) (bool, time.Duration, error) {
+	var retryAfter time.Duration
+
+	for _, key := range lt.keys(identifier, ip) {
+		attempts, err := lt.load(key)
+		if err != nil {
+			return false, 0, err
+		}
+
+		if wait := time.Until(attempts.LockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	return retryAfter <= 0, retryAfter, nil
+}
+
+// RegisterFailure records a failed login and returns the lockout applied, if any
+func (lt *LoginThrottle) RegisterFailure(identifier, ip string) (time.Duration, error) {
+	var lockout time.Duration
+
+	for _, key := range lt.keys(identifier, ip) {
+		attempts, err := lt.load(key)
+		if err != nil {
+			return 0, err
+		}
+
+		attempts.Failures++
+		if attempts.Failures >= lt.options.MaxAttempts {
+			duration := lt.lockoutDuration(attempts.Lockouts)
+			attempts.Failures = 0
+			attempts.Lockouts++
+			attempts.LockedUntil = clock.Now().Add(duration)
+
+			if duration > lockout {
+				lockout = duration
+			}
+			logger.Warn("Login locked out after repeated failures", "key", key, "lockout", duration.String(), "lockouts", attempts.Lockouts)
+		}
+
+		if err := lt.save(key, attempts); err != nil {
+			return 0, err
+		}
+	}
+
+	return lockout, nil
+}
+
+// RegisterSuccess clears the failure history of an identifier and IP
+func (lt *LoginThrottle) RegisterSuccess(identifier, ip string) error {
+	for _, key := range lt.keys(identifier, ip) {
+		if err := lt.store.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockoutDuration returns the exponential lockout for the given previous lockout count
+func (lt *LoginThrottle) lockoutDuration(previousLockouts int) time.Duration {
+	factor := math.Pow(2, float64(previousLockouts))
+	duration := time.Duration(float64(lt.options.BaseLockout) * factor)
+	if duration > lt.options.MaxLockout || duration <= 0 {
+		return lt.options.MaxLockout
+	}
+	return duration
+}
+
+// keys returns the store keys tracked for an identifier and IP
+func (lt *LoginThrottle) keys(identifier, ip string) []string {
+	keys := make([]string, 0, 2)
+	if identifier != "" {
+		keys = append(keys, loginThrottleKeyPrefix+"id:"+identifier)
+	}
+	if ip != "" {
+		keys = append(keys, loginThrottleKeyPrefix+"ip:"+ip)
+	}
+	return keys
+}
+
+// load reads the throttle state of a key
+func (lt *LoginThrottle) load(key string) (loginAttempts, error) {
+	var attempts loginAttempts
+	if _, err := cache.GetJSON(lt.store, key, &attempts); err != nil {
+		return attempts, fmt.Errorf("failed to load login attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// save persists the throttle state of a key, keeping it for the failure window
+// or until the lockout expires, whichever is longer
+func (lt *LoginThrottle) save(key string, attempts loginAttempts) error {
+	ttl := lt.options.FailureWindow
+	if remaining := time.Until(attempts.LockedUntil); remaining > 0 {
+		ttl += remaining
+	}
+	return cache.SetJSON(lt.store, key, attempts, ttl)
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// DefaultRefreshTTL is the lifetime of an issued refresh token
+const DefaultRefreshTTL = 30 * 24 * time.Hour
+
+// Refresh token errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	ErrRefreshTokenReuse   = errors.New("refresh token reuse detected")
+)
+
+// RefreshToken is the persisted state of an issued refresh token.
+// Tokens issued by rotating each other share a family.
+type RefreshToken struct {
+	ID        string    `json:"id"`
+	FamilyID  string    `json:"familyId"`
+	Subject   string    `json:"subject"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Used      bool      `json:"used"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// RefreshTokenStore persists refresh tokens
+type RefreshTokenStore interface {
+	// Save stores a new token
+	Save(token *RefreshToken) error
+	// Find returns a token by ID, or nil when it does not exist
+	Find(id string) (*RefreshToken, error)
+	// MarkUsed flags a token as used, returning false if it was already used
+	MarkUsed(id string) (bool, error)
+	// RevokeFamily revokes every token of a family
+	RevokeFamily(familyID string) error
+}
+
+// RevocationStore tracks revoked access token IDs until they expire
+type RevocationStore interface {
+	Revoke(tokenID string, expiresAt time.Time) error
+	IsRevoked(tokenID string) (bool, error)
+}
+
+// RefreshManager issues and rotates refresh tokens with reuse detection
+type RefreshManager struct {
+	store RefreshTokenStore
+	ttl   time.Duration
+}
+
+// NewRefreshManager creates a refresh manager; a zero ttl uses DefaultRefreshTTL
+func NewRefreshManager(store RefreshTokenStore, ttl time.Duration) *RefreshManager {
+	if store == nil {
+		store = NewMemoryRefreshTokenStore()
+	}
+	if ttl <= 0 {
+		ttl = DefaultRefreshTTL
+	}
+
+	return &RefreshManager{
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Issue creates a refresh token starting a new family and returns its opaque value
+func (rm *RefreshManager) Issue(subject string) (string, *RefreshToken, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+	return rm.issue(subject, familyID)
+}
+
+// Rotate exchanges a refresh token for a new one of the same family.
+// Presenting an already used token revokes the whole family, since it means
+// the token was stolen and replayed by either the client or an attacker.
+func (rm *RefreshManager) Rotate(value string) (string, *RefreshToken, error) {
+	token, err := rm.store.Find(hashToken(value))
+	if err != nil {
+		return "", nil, err
+	}
+	if token == nil {
+		return "", nil, ErrInvalidRefreshToken
+	}
+	if token.Revoked {
+		return "", nil, ErrRefreshTokenRevoked
+	}
+	if clock.Now().After(token.ExpiresAt) {
+		return "", nil, ErrRefreshTokenExpired
+	}
+
+	fresh, err := rm.store.MarkUsed(token.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	if !fresh {
+		logger.Warn("Refresh token reuse detected, revoking family", "subject", token.Subject, "family", token.FamilyID)
+		if err := rm.store.RevokeFamily(token.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, ErrRefreshTokenReuse
+	}
+
+	return rm.issue(token.Subject, token.FamilyID)
+}
+
+// Revoke revokes the family of a refresh token, e.g. on logout
+func (rm *RefreshManager) Revoke(value string) error {
+	token, err := rm.store.Find(hashToken(value))
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return ErrInvalidRefreshToken
+	}
+	return rm.store.RevokeFamily(token.FamilyID)
+}
+
+// issue creates and stores a token in the given family
+func (rm *RefreshManager) issue(subject, familyID string) (string, *RefreshToken, error) {
+	value, err := randomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := clock.Now()
+	token := &RefreshToken{
+		// Only the hash is stored so a leaked store cannot be replayed
+		ID:        hashToken(value),
+		FamilyID:  familyID,
+		Subject:   subject,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(rm.ttl),
+	}
+
+	if err := rm.store.Save(token); err != nil {
+		return "", nil, err
+	}
+	return value, token, nil
+}
+
+// MemoryRefreshTokenStore is an in-process RefreshTokenStore
+type MemoryRefreshTokenStore struct {
+	tokens map[string]*RefreshToken
+	mutex  sync.Mutex
+}
+
+// NewMemoryRefreshTokenStore creates an in-memory refresh token store
+func NewMemoryRefreshTokenStore() *MemoryRefreshTokenStore {
+	return &MemoryRefreshTokenStore{
+		tokens: make(map[string]*RefreshToken),
+	}
+}
+
+// Save stores a new token
+func (ms *MemoryRefreshTokenStore) Save(token *RefreshToken) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	stored := *token
+	ms.tokens[token.ID] = &stored
+	return nil
+}
+
+// Find returns a copy of a token by ID
+func (ms *MemoryRefreshTokenStore) Find(id string) (*RefreshToken, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	token, exists := ms.tokens[id]
+	if !exists {
+		return nil, nil
+	}
+	found := *token
+	return &found, nil
+}
+
+// MarkUsed flags a token as used, returning false if it was already used
+func (ms *MemoryRefreshTokenStore) MarkUsed(id string) (bool, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	token, exists := ms.tokens[id]
+	if !exists {
+		return false, ErrInvalidRefreshToken
+	}
+	if token.Used {
+		return false, nil
+	}
+	token.Used = true
+	return true, nil
+}
+
+// RevokeFamily revokes every token of a family and drops expired tokens
+func (ms *MemoryRefreshTokenStore) RevokeFamily(familyID string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	now := clock.Now()
+	for id, token := range ms.tokens {
+		if now.After(token.ExpiresAt) {
+			delete(ms.tokens, id)
+			continue
+		}
+		if token.FamilyID == familyID {
+			token.Revoked = true
+		}
+	}
+	return nil
+}
+
+// CacheRevocationStore is a RevocationStore backed by a cache store
+type CacheRevocationStore struct {
+	store cache.Store
+}
+
+// NewCacheRevocationStore creates a revocation store; a nil store uses memory
+func NewCacheRevocationStore(store cache.Store) *CacheRevocationStore {
+	if store == nil {
+		store = cache.NewMemoryStore()
+	}
+	return &CacheRevocationStore{store: store}
+}
+
+// Revoke blacklists a token ID until it expires
+func (crs *CacheRevocationStore) Revoke(tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return crs.store.Set(revocationKey(tokenID), []byte("1"), ttl)
+}
+
+// IsRevoked reports whether a token ID is blacklisted
+func (crs *CacheRevocationStore) IsRevoked(tokenID string) (bool, error) {
+	_, found, err := crs.store.Get(revocationKey(tokenID))
+	return found, err
+}
+
+// revocationKey namespaces revoked token IDs in the cache
+func revocationKey(tokenID string) string {
+	return "auth:revoked:" + tokenID
+}
+
+// randomToken returns a URL-safe random token of size bytes
+func randomToken(size int) (string, error) {
+	raw, err := ids.Bytes(size)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// hashToken returns the storage ID of an opaque token value
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
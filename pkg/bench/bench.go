@@ -0,0 +1,234 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultConcurrency is the number of concurrent workers when none is set
+const DefaultConcurrency = 10
+
+// DefaultRequests is the total number of requests when neither Requests nor
+// Duration is set
+const DefaultRequests = 1000
+
+// Route is a request exercised by the load generator
+type Route struct {
+	Method string
+	Path   string
+}
+
+// String returns the route as "METHOD /path"
+func (r Route) String() string {
+	return r.Method + " " + r.Path
+}
+
+// ParseRoutes parses a comma separated list of routes such as
+// "GET /users/,GET /users/1"; a path without a method uses GET
+func ParseRoutes(list string) ([]Route, error) {
+	routes := make([]Route, 0)
+	for _, entry := range strings.Split(list, ",") {
+		fields := strings.Fields(entry)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			routes = append(routes, Route{Method: http.MethodGet, Path: fields[0]})
+		case 2:
+			routes = append(routes, Route{Method: strings.ToUpper(fields[0]), Path: fields[1]})
+		default:
+			return nil, fmt.Errorf("invalid route %q", strings.TrimSpace(entry))
+		}
+	}
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no routes given")
+	}
+	return routes, nil
+}
+
+// Options configures a load test
+type Options struct {
+	// URL is the base URL of a live server, e.g. http://localhost:3000
+	URL string
+	// Handler serves requests in process when URL is empty, e.g. an
+	// application server
+	Handler http.Handler
+	// Routes are requested in turn by every worker
+	Routes []Route
+	// Concurrency is the number of workers; defaults to DefaultConcurrency
+	Concurrency int
+	// Requests is the total number of requests
+	Requests int
+	// Duration stops the test after a time instead of a request count
+	Duration time.Duration
+	// Client sends requests to URL; defaults to NewClient(30s, Concurrency)
+	Client *http.Client
+}
+
+// RouteReport holds the results of one route
+type RouteReport struct {
+	Route     Route
+	Requests  int
+	Errors    int
+	Latencies []time.Duration
+}
+
+// Percentile returns the latency below which p percent of requests completed
+func (rr *RouteReport) Percentile(p float64) time.Duration {
+	if len(rr.Latencies) == 0 {
+		return 0
+	}
+	index := int(float64(len(rr.Latencies))*p/100+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(rr.Latencies) {
+		index = len(rr.Latencies) - 1
+	}
+	return rr.Latencies[index]
+}
+
+// Report holds the results of a load test
+type Report struct {
+	Routes  []*RouteReport
+	Elapsed time.Duration
+}
+
+// Print writes a table of requests, errors, throughput and latency
+// percentiles per route
+func (r *Report) Print(w io.Writer) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ROUTE\tREQUESTS\tERRORS\tRPS\tP50\tP90\tP99\tMAX")
+	for _, rr := range r.Routes {
+		rps := 0.0
+		if r.Elapsed > 0 {
+			rps = float64(rr.Requests) / r.Elapsed.Seconds()
+		}
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\n",
+			rr.Route, rr.Requests, rr.Errors, rps,
+			rr.Percentile(50), rr.Percentile(90), rr.Percentile(99), rr.Percentile(100))
+	}
+	table.Flush()
+}
+
+// NewClient returns a client keeping one idle connection per worker, so
+// connections are reused instead of exhausting local ports
+func NewClient(timeout time.Duration, concurrency int) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = concurrency
+	transport.MaxIdleConnsPerHost = concurrency
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// sample is the outcome of one request
+type sample struct {
+	route   int
+	latency time.Duration
+	failed  bool
+}
+
+// Run exercises the routes until the request count or duration is reached,
+// or ctx is done. Transport errors and 5xx responses count as errors.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if len(opts.Routes) == 0 {
+		return nil, fmt.Errorf("bench: no routes given")
+	}
+	if opts.URL == "" && opts.Handler == nil {
+		return nil, fmt.Errorf("bench: a URL or a handler is required")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Requests <= 0 && opts.Duration <= 0 {
+		opts.Requests = DefaultRequests
+	}
+	if opts.Client == nil {
+		opts.Client = NewClient(30*time.Second, opts.Concurrency)
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	var issued atomic.Int64
+	samples := make(chan sample, opts.Concurrency*4)
+	var workers sync.WaitGroup
+	start := time.Now()
+
+	for i := 0; i < opts.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for ctx.Err() == nil {
+				n := issued.Add(1)
+				if opts.Requests > 0 && n > int64(opts.Requests) {
+					return
+				}
+				index := int(n-1) % len(opts.Routes)
+				latency, failed := send(ctx, opts, opts.Routes[index])
+				if ctx.Err() != nil && failed {
+					// Requests cut short by the deadline are not measured
+					return
+				}
+				samples <- sample{route: index, latency: latency, failed: failed}
+			}
+		}()
+	}
+	go func() {
+		workers.Wait()
+		close(samples)
+	}()
+
+	report := &Report{Routes: make([]*RouteReport, len(opts.Routes))}
+	for i, route := range opts.Routes {
+		report.Routes[i] = &RouteReport{Route: route, Latencies: make([]time.Duration, 0)}
+	}
+	for s := range samples {
+		rr := report.Routes[s.route]
+		rr.Requests++
+		if s.failed {
+			rr.Errors++
+		}
+		rr.Latencies = append(rr.Latencies, s.latency)
+	}
+	report.Elapsed = time.Since(start)
+
+	for _, rr := range report.Routes {
+		sort.Slice(rr.Latencies, func(i, j int) bool { return rr.Latencies[i] < rr.Latencies[j] })
+	}
+	return report, nil
+}
+
+// send performs one request against the URL or the handler
+func send(ctx context.Context, opts Options, route Route) (time.Duration, bool) {
+	start := time.Now()
+
+	if opts.URL == "" {
+		req := httptest.NewRequest(route.Method, route.Path, nil).WithContext(ctx)
+		rec := httptest.NewRecorder()
+		opts.Handler.ServeHTTP(rec, req)
+		return time.Since(start), rec.Code >= http.StatusInternalServerError
+	}
+
+	req, err := http.NewRequestWithContext(ctx, route.Method, strings.TrimSuffix(opts.URL, "/")+route.Path, nil)
+	if err != nil {
+		return time.Since(start), true
+	}
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return time.Since(start), true
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return time.Since(start), resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,306 @@
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+)
+
+// DefaultOutput is the name of the generated file
+const DefaultOutput = "nestgo_routes.go"
+
+// header marks generated files, which are skipped when parsing
+const header = "// Code generated by nestgo bind; DO NOT EDIT.\n"
+
+// Directive declares a route on the method it documents, followed by the
+// tags of the route, e.g.
+//
+//	//nestgo:route GET /:id guards:"admin" pipes:"id=positive"
+//	func (c *UserController) GetUser(id int) (*User, error)
+const Directive = "//nestgo:route "
+
+// Binding assigns a route field to its handler method
+type Binding struct {
+	Field  string
+	Method string
+}
+
+// Route is a route declared with a directive on a handler method
+type Route struct {
+	// Route is "METHOD /path", as in route tags
+	Route  string
+	Method string
+	Tag    string
+}
+
+// Controller is a controller type with route fields bound to methods and
+// routes declared on methods
+type Controller struct {
+	Type     string
+	Bindings []Binding
+	Routes   []Route
+}
+
+// Result describes the bindings found in a package
+type Result struct {
+	Package     string
+	Controllers []Controller
+	// Skipped lists route fields and directives with a handler method that
+	// cannot be bound, e.g. declared on a value receiver
+	Skipped []string
+}
+
+// routeField is a route field of a struct type
+type routeField struct {
+	typeName string
+	field    string
+}
+
+// method is a method declared in a package
+type method struct {
+	pointer bool
+}
+
+// Scan finds the route fields of the structs declared in dir that have a
+// handler method named by controller.HandlerMethodName, e.g. GetUsersHandler
+// for GetUsers, and the methods documented with a route Directive. Handler
+// methods must be exported, so the server can call them on a per-request
+// copy of the controller, and have pointer receivers: a method value of a
+// value receiver copies the controller before its dependencies are injected.
+func Scan(dir string) (*Result, error) {
+	files, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	result := &Result{Package: files[0].Name.Name}
+	fields := make([]routeField, 0)
+	methods := make(map[string]method)
+	byType := make(map[string]*Controller)
+	controllerOf := func(typeName string) *Controller {
+		ctrl, ok := byType[typeName]
+		if !ok {
+			ctrl = &Controller{Type: typeName}
+			byType[typeName] = ctrl
+		}
+		return ctrl
+	}
+
+	for _, file := range files {
+		if file.Name.Name != result.Package {
+			return nil, fmt.Errorf("%s: found packages %s and %s", dir, result.Package, file.Name.Name)
+		}
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				fields = append(fields, structRouteFields(decl)...)
+			case *ast.FuncDecl:
+				typeName, pointer, ok := receiver(decl)
+				if !ok {
+					continue
+				}
+				methods[typeName+"."+decl.Name.Name] = method{pointer: pointer}
+
+				routes, err := directives(decl)
+				if err != nil {
+					return nil, fmt.Errorf("%s.%s: %w", typeName, decl.Name.Name, err)
+				}
+				if len(routes) > 0 && !pointer {
+					result.Skipped = append(result.Skipped, fmt.Sprintf("%s.%s: route directive on a value receiver", typeName, decl.Name.Name))
+					continue
+				}
+				if len(routes) > 0 && !decl.Name.IsExported() {
+					result.Skipped = append(result.Skipped, fmt.Sprintf("%s.%s: route directive on an unexported method", typeName, decl.Name.Name))
+					continue
+				}
+				if len(routes) > 0 {
+					ctrl := controllerOf(typeName)
+					ctrl.Routes = append(ctrl.Routes, routes...)
+				}
+			}
+		}
+	}
+
+	for _, field := range fields {
+		name := controller.HandlerMethodName(field.field)
+		m, ok := methods[field.typeName+"."+name]
+		if !ok {
+			continue
+		}
+		if !m.pointer {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s.%s: %s has a value receiver", field.typeName, field.field, name))
+			continue
+		}
+		ctrl := controllerOf(field.typeName)
+		ctrl.Bindings = append(ctrl.Bindings, Binding{Field: field.field, Method: name})
+	}
+
+	for _, ctrl := range byType {
+		result.Controllers = append(result.Controllers, *ctrl)
+	}
+	sort.Slice(result.Controllers, func(i, j int) bool {
+		return result.Controllers[i].Type < result.Controllers[j].Type
+	})
+	return result, nil
+}
+
+// Generate returns the source of a file declaring a BindRoutes method for
+// every controller of the result, and a RouteMethods method naming the
+// handler methods of its route fields
+func Generate(result *Result) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	fmt.Fprintf(&buf, "\npackage %s\n", result.Package)
+	for _, ctrl := range result.Controllers {
+		fmt.Fprintf(&buf, "\n// BindRoutes binds the routes of %s to their handler methods\n", ctrl.Type)
+		fmt.Fprintf(&buf, "func (c *%s) BindRoutes() {\n", ctrl.Type)
+		for _, binding := range ctrl.Bindings {
+			fmt.Fprintf(&buf, "\tc.%s = c.%s\n", binding.Field, binding.Method)
+		}
+		for _, route := range ctrl.Routes {
+			if route.Tag == "" {
+				fmt.Fprintf(&buf, "\tc.Route(%q, %q)\n", route.Route, route.Method)
+				continue
+			}
+			fmt.Fprintf(&buf, "\tc.Route(%q, %q, %s)\n", route.Route, route.Method, quoteTag(route.Tag))
+		}
+		buf.WriteString("}\n")
+
+		if len(ctrl.Bindings) == 0 {
+			continue
+		}
+		fmt.Fprintf(&buf, "\n// RouteMethods names the handler methods of the route fields of %s\n", ctrl.Type)
+		fmt.Fprintf(&buf, "func (c *%s) RouteMethods() map[string]string {\n", ctrl.Type)
+		buf.WriteString("\treturn map[string]string{\n")
+		for _, binding := range ctrl.Bindings {
+			fmt.Fprintf(&buf, "\t\t%q: %q,\n", binding.Field, binding.Method)
+		}
+		buf.WriteString("\t}\n}\n")
+	}
+	return format.Source(buf.Bytes())
+}
+
+// parseDir parses the Go files of a package, leaving out tests and
+// generated files
+func parseDir(dir string) ([]*ast.File, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if ast.IsGenerated(file) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// structRouteFields returns the func fields with a route tag of the struct
+// types declared by decl
+func structRouteFields(decl *ast.GenDecl) []routeField {
+	fields := make([]routeField, 0)
+	if decl.Tok != token.TYPE {
+		return fields
+	}
+	for _, spec := range decl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok || typeSpec.TypeParams != nil {
+			continue
+		}
+		structType, ok := typeSpec.Type.(*ast.StructType)
+		if !ok {
+			continue
+		}
+		for _, field := range structType.Fields.List {
+			if _, ok := field.Type.(*ast.FuncType); !ok || field.Tag == nil {
+				continue
+			}
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil || reflect.StructTag(tag).Get("route") == "" {
+				continue
+			}
+			for _, name := range field.Names {
+				fields = append(fields, routeField{typeName: typeSpec.Name.Name, field: name.Name})
+			}
+		}
+	}
+	return fields
+}
+
+// directives parses the route directives documenting a method
+func directives(decl *ast.FuncDecl) ([]Route, error) {
+	routes := make([]Route, 0)
+	if decl.Doc == nil {
+		return routes, nil
+	}
+	for _, comment := range decl.Doc.List {
+		spec, ok := strings.CutPrefix(comment.Text, Directive)
+		if !ok {
+			continue
+		}
+		httpMethod, rest, _ := strings.Cut(strings.TrimSpace(spec), " ")
+		path, tag, _ := strings.Cut(strings.TrimSpace(rest), " ")
+		if httpMethod == "" || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid route directive %q, expected %sMETHOD /path [tags]", comment.Text, Directive)
+		}
+		routes = append(routes, Route{
+			Route:  strings.ToUpper(httpMethod) + " " + path,
+			Method: decl.Name.Name,
+			Tag:    strings.TrimSpace(tag),
+		})
+	}
+	return routes, nil
+}
+
+// quoteTag returns a Go literal of a tag, raw unless it holds a backquote
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// receiver returns the receiver type name of a method and whether it is a
+// pointer
+func receiver(decl *ast.FuncDecl) (string, bool, bool) {
+	if decl.Recv == nil || len(decl.Recv.List) != 1 {
+		return "", false, false
+	}
+	expr := decl.Recv.List[0].Type
+	pointer := false
+	if star, ok := expr.(*ast.StarExpr); ok {
+		pointer = true
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false, false
+	}
+	return ident.Name, pointer, true
+}
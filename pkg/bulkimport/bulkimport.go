@@ -0,0 +1,340 @@
+package bulkimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/validation"
+)
+
+// Format is the encoding of an import file
+type Format string
+
+const (
+	// FormatCSV reads a header line naming the JSON fields, then one row per line
+	FormatCSV Format = "csv"
+	// FormatJSONL reads one JSON object per line
+	FormatJSONL Format = "jsonl"
+)
+
+// DefaultChunkSize is the number of valid rows written at once
+const DefaultChunkSize = 500
+
+// DefaultMaxReportedErrors bounds the row errors kept in a job report
+const DefaultMaxReportedErrors = 1000
+
+// FormatFor returns the format of a content type, e.g. text/csv
+func FormatFor(contentType string) (Format, bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return FormatCSV, true
+	case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+		return FormatJSONL, true
+	}
+	return "", false
+}
+
+// Status is the state of an import job
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// RowError reports a row that was not imported
+type RowError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Job tracks an import. Rows failing to parse or validate are reported
+// and skipped; a failed write fails the job.
+type Job struct {
+	ID     string `json:"id"`
+	Format Format `json:"format"`
+	Status Status `json:"status"`
+	// Processed counts the rows read so far, Imported the rows written
+	// and Rejected the rows reported as errors
+	Processed int        `json:"processed"`
+	Imported  int        `json:"imported"`
+	Rejected  int        `json:"rejected"`
+	Errors    []RowError `json:"errors"`
+	// Truncated is true when more rows were rejected than reported
+	Truncated bool `json:"truncated,omitempty"`
+	// Progress is the fraction of the file read
+	Progress    float64    `json:"progress"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+
+	size int64
+	read int64
+}
+
+// progress returns the fraction of the file read, unknown for imports
+// without a size until they finish
+func (j *Job) progress() float64 {
+	switch {
+	case j.Status == StatusCompleted || j.Status == StatusFailed:
+		return 1
+	case j.size == 0:
+		return 0
+	}
+	return float64(j.read) / float64(j.size)
+}
+
+// snapshot returns a copy of the job safe to hand out
+func (j *Job) snapshot() *Job {
+	copied := *j
+	copied.Errors = append([]RowError{}, j.Errors...)
+	copied.Progress = j.progress()
+	return &copied
+}
+
+// Options configures an Importer
+type Options[T any] struct {
+	// Write stores a chunk of valid rows, e.g. in one transaction
+	Write func(ctx context.Context, rows []T) error
+	// ChunkSize defaults to DefaultChunkSize
+	ChunkSize int
+	// MaxReportedErrors defaults to DefaultMaxReportedErrors
+	MaxReportedErrors int
+	// MaxSize rejects larger uploads; zero accepts any size
+	MaxSize int64
+}
+
+// Importer imports CSV and JSON Lines files of T rows, validating each row
+// with its validate tags. It is typically held by a provider, whose
+// controller routes the upload, status and report handlers.
+type Importer[T any] struct {
+	options Options[T]
+	jobs    map[string]*Job
+	mutex   sync.RWMutex
+}
+
+// New creates an Importer
+func New[T any](options Options[T]) *Importer[T] {
+	if options.ChunkSize <= 0 {
+		options.ChunkSize = DefaultChunkSize
+	}
+	if options.MaxReportedErrors <= 0 {
+		options.MaxReportedErrors = DefaultMaxReportedErrors
+	}
+	return &Importer[T]{options: options, jobs: make(map[string]*Job)}
+}
+
+// Start stores an upload in a temporary file and imports it in the
+// background, returning the pending job
+func (im *Importer[T]) Start(format Format, body io.Reader) (*Job, error) {
+	file, err := os.CreateTemp("", "nestgo-import-*")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	reader := body
+	if im.options.MaxSize > 0 {
+		reader = io.LimitReader(body, im.options.MaxSize+1)
+	}
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if im.options.MaxSize > 0 && size > im.options.MaxSize {
+		cleanup()
+		return nil, ErrTooLarge
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	job, err := im.newJob(format, size)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	snapshot := im.snapshot(job)
+
+	go func() {
+		defer cleanup()
+		im.run(context.Background(), job, file)
+	}()
+	return snapshot, nil
+}
+
+// Import imports a file synchronously, e.g. from a command, and returns
+// the finished job
+func (im *Importer[T]) Import(ctx context.Context, format Format, reader io.Reader) (*Job, error) {
+	job, err := im.newJob(format, 0)
+	if err != nil {
+		return nil, err
+	}
+	im.run(ctx, job, reader)
+	return im.snapshot(job), nil
+}
+
+// Job returns a snapshot of a job
+func (im *Importer[T]) Job(id string) (*Job, bool) {
+	im.mutex.RLock()
+	defer im.mutex.RUnlock()
+
+	job, ok := im.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return job.snapshot(), true
+}
+
+// newJob registers a pending job
+func (im *Importer[T]) newJob(format Format, size int64) (*Job, error) {
+	if format != FormatCSV && format != FormatJSONL {
+		return nil, fmt.Errorf("bulkimport: unsupported format %q", format)
+	}
+	id, err := ids.Hex(8)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &Job{ID: id, Format: format, Status: StatusPending, Errors: make([]RowError, 0), CreatedAt: clock.Now(), size: size}
+	im.mutex.Lock()
+	im.jobs[id] = job
+	im.mutex.Unlock()
+	return job, nil
+}
+
+// snapshot copies a job under the lock
+func (im *Importer[T]) snapshot(job *Job) *Job {
+	im.mutex.RLock()
+	defer im.mutex.RUnlock()
+	return job.snapshot()
+}
+
+// update mutates a job under the lock
+func (im *Importer[T]) update(job *Job, fn func()) {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	fn()
+}
+
+// run parses, validates and writes the rows of a job
+func (im *Importer[T]) run(ctx context.Context, job *Job, reader io.Reader) {
+	im.update(job, func() { job.Status = StatusRunning })
+	logger.Info("Import started", "job", job.ID, "format", job.Format)
+
+	counter := &countingReader{reader: reader}
+	chunk := make([]T, 0, im.options.ChunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := im.options.Write(ctx, chunk); err != nil {
+			return err
+		}
+		written := len(chunk)
+		im.update(job, func() { job.Imported += written })
+		chunk = make([]T, 0, im.options.ChunkSize)
+		return nil
+	}
+
+	err := parse(job.Format, counter, func(line int, row T, rowErr error) error {
+		if rowErr == nil {
+			rowErr = validation.Validate(row)
+		}
+		im.update(job, func() {
+			job.Processed++
+			job.read = counter.read
+			if rowErr != nil {
+				im.reject(job, line, rowErr)
+			}
+		})
+		if rowErr != nil {
+			return nil
+		}
+
+		chunk = append(chunk, row)
+		if len(chunk) >= im.options.ChunkSize {
+			return flush()
+		}
+		return ctx.Err()
+	})
+	if err == nil {
+		err = flush()
+	}
+
+	im.update(job, func() {
+		now := clock.Now()
+		job.CompletedAt = &now
+		job.read = counter.read
+		job.Status = StatusCompleted
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+	})
+	if err != nil {
+		logger.Error("Import failed", "job", job.ID, "imported", job.Imported, "error", err)
+		return
+	}
+	logger.Info("Import completed", "job", job.ID, "imported", job.Imported, "rejected", job.Rejected)
+}
+
+// reject records the errors of a row, within the reported errors limit
+func (im *Importer[T]) reject(job *Job, line int, err error) {
+	job.Rejected++
+
+	rowErrors := make([]RowError, 0, 1)
+	var fieldErrors validation.Errors
+	if errors.As(err, &fieldErrors) {
+		for _, fieldErr := range fieldErrors {
+			rowErrors = append(rowErrors, RowError{Line: line, Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+	} else {
+		rowErr := RowError{Line: line, Message: err.Error()}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			rowErr.Field = typeErr.Field
+			rowErr.Message = fmt.Sprintf("%s must be %s", typeErr.Field, typeErr.Type)
+		}
+		rowErrors = append(rowErrors, rowErr)
+	}
+
+	for _, rowErr := range rowErrors {
+		if len(job.Errors) >= im.options.MaxReportedErrors {
+			job.Truncated = true
+			return
+		}
+		job.Errors = append(job.Errors, rowErr)
+	}
+}
+
+// countingReader counts the bytes read for progress reporting
+type countingReader struct {
+	reader io.Reader
+	read   int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,70 @@
+package bulkimport
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+)
+
+// ErrTooLarge is returned for uploads over Options.MaxSize
+var ErrTooLarge = controller.NewHTTPError(http.StatusRequestEntityTooLarge, "Import file too large")
+
+// Upload starts an import of the request body, in the format of the
+// format query parameter or the Content-Type header, and answers 202 with
+// the pending job. Route it with the status and report handlers:
+//
+//	c.Route("POST /imports", c.Imports.Upload)
+//	c.Route("GET /imports/:id", c.Imports.Status)
+//	c.Route("GET /imports/:id/errors", c.Imports.Report)
+func (im *Importer[T]) Upload(ctx *controller.Context) (*Job, error) {
+	format := Format(ctx.Query("format"))
+	if format == "" {
+		detected, ok := FormatFor(ctx.Request.Header.Get("Content-Type"))
+		if !ok {
+			return nil, controller.NewHTTPError(http.StatusUnsupportedMediaType, "Import files must be CSV or JSON Lines")
+		}
+		format = detected
+	}
+	if format != FormatCSV && format != FormatJSONL {
+		return nil, controller.NewHTTPError(http.StatusBadRequest, "Unknown import format "+strconv.Quote(string(format)))
+	}
+
+	job, err := im.Start(format, ctx.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Status(http.StatusAccepted)
+	return job, nil
+}
+
+// Status returns the job of the id path parameter, with its progress
+func (im *Importer[T]) Status(ctx *controller.Context) (*Job, error) {
+	job, ok := im.Job(ctx.Param("id"))
+	if !ok {
+		return nil, controller.NewHTTPError(http.StatusNotFound, "Import not found")
+	}
+	return job, nil
+}
+
+// Report writes the rejected rows of the job of the id path parameter as a
+// CSV attachment of line, field and message columns
+func (im *Importer[T]) Report(ctx *controller.Context) error {
+	job, ok := im.Job(ctx.Param("id"))
+	if !ok {
+		return controller.NewHTTPError(http.StatusNotFound, "Import not found")
+	}
+
+	ctx.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header().Set("Content-Disposition", `attachment; filename="import-`+job.ID+`-errors.csv"`)
+	ctx.Writer.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Write([]string{"line", "field", "message"})
+	for _, rowErr := range job.Errors {
+		writer.Write([]string{strconv.Itoa(rowErr.Line), rowErr.Field, rowErr.Message})
+	}
+	writer.Flush()
+	return writer.Error()
+}
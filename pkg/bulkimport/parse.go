@@ -0,0 +1,175 @@
+package bulkimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+)
+
+// MaxLineSize bounds the length of a JSON Lines row
+const MaxLineSize = 1 << 20
+
+// rowFunc receives each row with its line number, or the error that made
+// it unreadable. A returned error stops the import.
+type rowFunc[T any] func(line int, row T, err error) error
+
+// parse streams the rows of a file to fn
+func parse[T any](format Format, reader io.Reader, fn rowFunc[T]) error {
+	if format == FormatCSV {
+		return parseCSV(reader, fn)
+	}
+	return parseJSONL(reader, fn)
+}
+
+// parseJSONL decodes one JSON object per line, skipping blank lines
+func parseJSONL[T any](reader io.Reader, fn rowFunc[T]) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var row T
+		err := json.Unmarshal(text, &row)
+		if err != nil {
+			err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		if err := fn(line, row, err); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseCSV reads a header line naming the JSON fields of T, then converts
+// each row to a JSON object decoded into T, so codecs, union types and
+// json tags apply as for request bodies. Unknown columns are ignored and
+// empty cells leave their field unset.
+func parseCSV[T any](reader io.Reader, fn rowFunc[T]) error {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("bulkimport: invalid CSV header: %w", err)
+	}
+	columns, err := csvColumns[T](header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line, _ := csvReader.FieldPos(0)
+
+		var row T
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			if err := fn(parseErr.Line, row, fmt.Errorf("invalid CSV: %w", parseErr.Err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		rowErr := json.Unmarshal(csvObject(columns, record), &row)
+		if rowErr != nil {
+			rowErr = fmt.Errorf("invalid row: %w", rowErr)
+		}
+		if err := fn(line, row, rowErr); err != nil {
+			return err
+		}
+	}
+}
+
+// column is a CSV column mapped to a JSON field
+type column struct {
+	name string
+	// raw cells are written as JSON literals, for numbers and booleans
+	raw bool
+}
+
+// csvColumns maps header names to the JSON fields of T, case-insensitively
+func csvColumns[T any](header []string) ([]*column, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("bulkimport: CSV rows must be structs, got %s", t)
+	}
+
+	fields := make(map[string]codec.Field)
+	for _, field := range codec.Fields(t) {
+		fields[field.Name] = field
+	}
+
+	columns := make([]*column, len(header))
+	for i, name := range header {
+		field, key, ok := codec.Property(fields, strings.TrimSpace(name))
+		if !ok {
+			continue
+		}
+		fieldType := t.FieldByIndex(field.Index).Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		columns[i] = &column{name: key, raw: isLiteral(fieldType)}
+	}
+	return columns, nil
+}
+
+// isLiteral reports whether cells of a field type are JSON numbers or booleans
+func isLiteral(t reflect.Type) bool {
+	if _, ok := codec.Lookup(t); ok {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// csvObject writes a record as a JSON object of its mapped columns. Cells
+// of literal columns that are not valid JSON are written as strings, so
+// decoding reports the field.
+func csvObject(columns []*column, record []string) []byte {
+	object := make(map[string]json.RawMessage, len(columns))
+	for i, cell := range record {
+		if i >= len(columns) || columns[i] == nil || cell == "" {
+			continue
+		}
+		if columns[i].raw && json.Valid([]byte(cell)) {
+			object[columns[i].name] = json.RawMessage(cell)
+			continue
+		}
+		quoted, _ := json.Marshal(cell)
+		object[columns[i].name] = quoted
+	}
+	data, _ := json.Marshal(object)
+	return data
+}
@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+const (
+	// TagCache caches the result of a func field: `cache:"users:{0},ttl=30s,stale=1m"`
+	TagCache = "cache"
+	// TagCacheEvict evicts keys after a func field runs: `cacheEvict:"users:all,users:{0}"`
+	TagCacheEvict = "cacheEvict"
+)
+
+// keyPlaceholder matches {n} argument placeholders in key templates
+var keyPlaceholder = regexp.MustCompile(`\{(\d+)\}`)
+
+// errorType is used to detect trailing error results
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// cacheSpec is the parsed cache tag of a func field
+type cacheSpec struct {
+	key   string
+	ttl   time.Duration
+	stale time.Duration
+}
+
+// HasCacheTags reports whether a struct declares cache or cacheEvict func fields
+func HasCacheTags(target interface{}) bool {
+	structType := reflect.TypeOf(target)
+	if structType == nil {
+		return false
+	}
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return false
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Type.Kind() != reflect.Func {
+			continue
+		}
+		if field.Tag.Get(TagCache) != "" || field.Tag.Get(TagCacheEvict) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Decorate wraps the func fields of a struct pointer declaring cache tags.
+// Cached fields return the stored result for the same key; evicting fields
+// delete their keys after a successful call. Key templates reference the
+// call arguments by position ({0}, {1}, ...).
+func Decorate(target interface{}, store Store) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cache: target must be a pointer to a struct, got %T", target)
+	}
+
+	structValue := targetValue.Elem()
+	structType := structValue.Type()
+	loader := NewLoader(store, LoaderOptions{})
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+
+		if field.Type.Kind() != reflect.Func || !fieldValue.CanSet() {
+			continue
+		}
+
+		cacheTag := field.Tag.Get(TagCache)
+		evictTag := field.Tag.Get(TagCacheEvict)
+		if cacheTag == "" && evictTag == "" {
+			continue
+		}
+
+		if fieldValue.IsNil() {
+			return fmt.Errorf("cache: field %s.%s is nil", structType.Name(), field.Name)
+		}
+
+		// Snapshot the current function, the field itself is replaced below
+		wrapped := reflect.ValueOf(fieldValue.Interface())
+		if cacheTag != "" {
+			spec, err := parseCacheTag(cacheTag)
+			if err != nil {
+				return fmt.Errorf("cache: field %s.%s: %w", structType.Name(), field.Name, err)
+			}
+			if field.Type.NumOut() == 0 {
+				return fmt.Errorf("cache: field %s.%s has no result to cache", structType.Name(), field.Name)
+			}
+			wrapped = cachedFunc(wrapped, spec, loader)
+		}
+
+		if evictTag != "" {
+			wrapped = evictingFunc(wrapped, splitKeys(evictTag), store)
+		}
+
+		fieldValue.Set(wrapped)
+		logger.Info("Cache decorator applied", "target", structType.Name(), "field", field.Name, "cache", cacheTag, "evict", evictTag)
+	}
+
+	return nil
+}
+
+// cachedFunc returns a function serving results through the loader, so
+// concurrent misses share one call and stale results are refreshed in the background
+func cachedFunc(original reflect.Value, spec cacheSpec, loader *Loader) reflect.Value {
+	funcType := original.Type()
+	options := LoaderOptions{TTL: spec.ttl, StaleTTL: spec.stale}
+
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		key := renderKey(spec.key, args)
+
+		data, err := loader.GetWithOptions(key, options, func() ([]byte, error) {
+			results := original.Call(args)
+			if failed(funcType, results) {
+				return nil, &callError{results: results}
+			}
+			return json.Marshal(results[0].Interface())
+		})
+
+		var failure *callError
+		if errors.As(err, &failure) {
+			return failure.results
+		}
+		if err != nil {
+			logger.Warn("Failed to encode cached result", "key", key, "error", err)
+			return original.Call(args)
+		}
+
+		result := reflect.New(funcType.Out(0))
+		if err := json.Unmarshal(data, result.Interface()); err != nil {
+			logger.Warn("Failed to decode cached result", "key", key, "error", err)
+			return original.Call(args)
+		}
+		return withZeroResults(funcType, result.Elem())
+	})
+}
+
+// callError carries the results of a failed call through the loader
+type callError struct {
+	results []reflect.Value
+}
+
+func (e *callError) Error() string {
+	return "cache: decorated call failed"
+}
+
+// evictingFunc returns a function deleting keys after a successful call
+func evictingFunc(original reflect.Value, keys []string, store Store) reflect.Value {
+	funcType := original.Type()
+
+	return reflect.MakeFunc(funcType, func(args []reflect.Value) []reflect.Value {
+		results := original.Call(args)
+		if failed(funcType, results) {
+			return results
+		}
+
+		for _, template := range keys {
+			key := renderKey(template, args)
+			if err := store.Delete(key); err != nil {
+				logger.Warn("Failed to evict cache key", "key", key, "error", err)
+			}
+		}
+		return results
+	})
+}
+
+// failed reports whether the trailing error result of a call is set
+func failed(funcType reflect.Type, results []reflect.Value) bool {
+	last := funcType.NumOut() - 1
+	if last < 0 || !funcType.Out(last).Implements(errorType) {
+		return false
+	}
+	return !results[last].IsNil()
+}
+
+// withZeroResults builds a result list with the first value set and the rest zeroed
+func withZeroResults(funcType reflect.Type, first reflect.Value) []reflect.Value {
+	results := make([]reflect.Value, funcType.NumOut())
+	results[0] = first
+	for i := 1; i < funcType.NumOut(); i++ {
+		results[i] = reflect.Zero(funcType.Out(i))
+	}
+	return results
+}
+
+// renderKey replaces {n} placeholders with the call arguments
+func renderKey(template string, args []reflect.Value) string {
+	return keyPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		index, _ := strconv.Atoi(placeholder[1 : len(placeholder)-1])
+		if index >= len(args) {
+			return placeholder
+		}
+		return fmt.Sprint(args[index].Interface())
+	})
+}
+
+// parseCacheTag parses `key,ttl=30s,stale=1m`
+func parseCacheTag(tag string) (cacheSpec, error) {
+	parts := strings.Split(tag, ",")
+	spec := cacheSpec{key: strings.TrimSpace(parts[0])}
+	if spec.key == "" {
+		return spec, fmt.Errorf("cache key is required")
+	}
+
+	for _, option := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(option), "=")
+		switch name {
+		case "ttl":
+			ttl, err := time.ParseDuration(value)
+			if err != nil {
+				return spec, fmt.Errorf("invalid ttl %q: %w", value, err)
+			}
+			spec.ttl = ttl
+		case "stale":
+			stale, err := time.ParseDuration(value)
+			if err != nil {
+				return spec, fmt.Errorf("invalid stale %q: %w", value, err)
+			}
+			spec.stale = stale
+		default:
+			return spec, fmt.Errorf("unknown cache option %q", name)
+		}
+	}
+
+	return spec, nil
+}
+
+// splitKeys splits a comma separated list of key templates
+func splitKeys(tag string) []string {
+	keys := make([]string, 0)
+	for _, key := range strings.Split(tag, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
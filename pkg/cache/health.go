@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/health"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+)
+
+// healthProbeTTL bounds how long a probe value outlives a check
+const healthProbeTTL = time.Minute
+
+// HealthIndicator reports the health of a store by writing, reading back
+// and deleting a probe key
+type HealthIndicator struct {
+	name  string
+	store Store
+}
+
+// NewHealthIndicator creates a health indicator for store, reported under name
+func NewHealthIndicator(name string, store Store) *HealthIndicator {
+	return &HealthIndicator{name: name, store: store}
+}
+
+// Name returns the name of the indicator
+func (h *HealthIndicator) Name() string {
+	return h.name
+}
+
+// Check writes a probe value and reads it back. Stores do not take a
+// context, so a store that hangs is reported down once ctx is done.
+func (h *HealthIndicator) Check(ctx context.Context) (health.Details, error) {
+	details := health.Details{"store": fmt.Sprintf("%T", h.store)}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.probe()
+	}()
+
+	select {
+	case err := <-done:
+		return details, err
+	case <-ctx.Done():
+		return details, ctx.Err()
+	}
+}
+
+// probe round-trips a value through the store under a key of its own
+func (h *HealthIndicator) probe() error {
+	nonce, err := ids.Hex(8)
+	if err != nil {
+		return err
+	}
+	key := "health:probe:" + nonce
+	value := []byte(nonce)
+
+	if err := h.store.Set(key, value, healthProbeTTL); err != nil {
+		return err
+	}
+	stored, found, err := h.store.Get(key)
+	if err != nil {
+		return err
+	}
+	if !found || !bytes.Equal(stored, value) {
+		return errors.New("probe value not read back")
+	}
+	return h.store.Delete(key)
+}
@@ -0,0 +1,128 @@
+package cache
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// envelopeHeader is the size of the freshness timestamp prefixed to loader entries
+const envelopeHeader = 8
+
+// LoadFunc produces the value for a key on a cache miss or refresh
+type LoadFunc func() ([]byte, error)
+
+// LoaderOptions configures a Loader
+type LoaderOptions struct {
+	// TTL is how long a loaded value is considered fresh; zero means forever
+	TTL time.Duration
+	// StaleTTL is how long a value keeps being served after TTL while it is
+	// refreshed in the background; zero disables stale-while-revalidate
+	StaleTTL time.Duration
+}
+
+// Loader reads through a Store, collapsing concurrent misses for the same key
+// into a single load and serving stale values while hot keys are refreshed,
+// so expiring entries don't cause thundering herds on the data source
+type Loader struct {
+	store   Store
+	options LoaderOptions
+	group   Group
+}
+
+// NewLoader creates a read-through loader on top of store
+func NewLoader(store Store, options LoaderOptions) *Loader {
+	return &Loader{store: store, options: options}
+}
+
+// Get returns the value for key using the loader default TTLs
+func (l *Loader) Get(key string, load LoadFunc) ([]byte, error) {
+	return l.GetWithOptions(key, l.options, load)
+}
+
+// GetWithOptions returns the value for key, loading it on a miss. Fresh
+// values are returned as is; stale values are returned immediately while a
+// single background refresh runs.
+func (l *Loader) GetWithOptions(key string, options LoaderOptions, load LoadFunc) ([]byte, error) {
+	data, found, err := l.store.Get(key)
+	if err != nil {
+		logger.Warn("Cache read failed, loading from source", "key", key, "error", err)
+	} else if found {
+		if value, freshUntil, ok := decodeEnvelope(data); ok {
+			if freshUntil.IsZero() || clock.Now().Before(freshUntil) {
+				return value, nil
+			}
+			l.refresh(key, options, load)
+			return value, nil
+		}
+	}
+
+	value, err, shared := l.group.Do(key, func() ([]byte, error) {
+		return l.load(key, options, load)
+	})
+	if shared {
+		logger.Debug("Cache load shared", "key", key)
+	}
+	return value, err
+}
+
+// refresh reloads a stale key in the background unless a load is already running
+func (l *Loader) refresh(key string, options LoaderOptions, load LoadFunc) {
+	if l.group.InFlight(key) {
+		return
+	}
+
+	go func() {
+		_, err, _ := l.group.Do(key, func() ([]byte, error) {
+			return l.load(key, options, load)
+		})
+		if err != nil {
+			logger.Warn("Background cache refresh failed", "key", key, "error", err)
+		}
+	}()
+}
+
+// load calls the source and stores the result with its freshness deadline
+func (l *Loader) load(key string, options LoaderOptions, load LoadFunc) ([]byte, error) {
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	var freshUntil time.Time
+	ttl := time.Duration(0)
+	if options.TTL > 0 {
+		freshUntil = clock.Now().Add(options.TTL)
+		ttl = options.TTL + options.StaleTTL
+	}
+
+	if err := l.store.Set(key, encodeEnvelope(value, freshUntil), ttl); err != nil {
+		logger.Warn("Failed to store loaded value", "key", key, "error", err)
+	}
+	return value, nil
+}
+
+// encodeEnvelope prefixes value with its freshness deadline in unix nanoseconds
+func encodeEnvelope(value []byte, freshUntil time.Time) []byte {
+	data := make([]byte, envelopeHeader+len(value))
+	if !freshUntil.IsZero() {
+		binary.BigEndian.PutUint64(data, uint64(freshUntil.UnixNano()))
+	}
+	copy(data[envelopeHeader:], value)
+	return data
+}
+
+// decodeEnvelope splits a stored entry into value and freshness deadline
+func decodeEnvelope(data []byte) ([]byte, time.Time, bool) {
+	if len(data) < envelopeHeader {
+		return nil, time.Time{}, false
+	}
+
+	var freshUntil time.Time
+	if nanos := binary.BigEndian.Uint64(data); nanos != 0 {
+		freshUntil = time.Unix(0, int64(nanos))
+	}
+	return data[envelopeHeader:], freshUntil, true
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+)
+
+// DefaultCleanupInterval is how often expired entries are swept from memory
+const DefaultCleanupInterval = time.Minute
+
+// memoryEntry is a value stored in the memory store
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// expired reports whether the entry is expired at the given time
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStore is an in-process Store implementation
+type MemoryStore struct {
+	entries map[string]memoryEntry
+	mutex   sync.RWMutex
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryStore creates a memory store that sweeps expired entries periodically
+func NewMemoryStore() *MemoryStore {
+	store := &MemoryStore{
+		entries: make(map[string]memoryEntry),
+		stop:    make(chan struct{}),
+	}
+	go store.janitor(DefaultCleanupInterval)
+	return store
+}
+
+// Get returns a copy of the value for key and whether it was found
+func (ms *MemoryStore) Get(key string) ([]byte, bool, error) {
+	ms.mutex.RLock()
+	entry, exists := ms.entries[key]
+	ms.mutex.RUnlock()
+
+	if !exists || entry.expired(clock.Now()) {
+		return nil, false, nil
+	}
+	return bytes.Clone(entry.value), true, nil
+}
+
+// Set stores a copy of value for key; a zero ttl means no expiration
+func (ms *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.entries[key] = newMemoryEntry(value, ttl)
+	return nil
+}
+
+// SetIfAbsent stores a value only if key does not exist
+func (ms *MemoryStore) SetIfAbsent(key string, value []byte, ttl time.Duration) (bool, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if entry, exists := ms.entries[key]; exists && !entry.expired(clock.Now()) {
+		return false, nil
+	}
+
+	ms.entries[key] = newMemoryEntry(value, ttl)
+	return true, nil
+}
+
+// Delete removes key from the store
+func (ms *MemoryStore) Delete(key string) error {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	delete(ms.entries, key)
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not yet swept
+func (ms *MemoryStore) Len() int {
+	ms.mutex.RLock()
+	defer ms.mutex.RUnlock()
+
+	return len(ms.entries)
+}
+
+// Close stops the background cleanup
+func (ms *MemoryStore) Close() {
+	ms.once.Do(func() {
+		close(ms.stop)
+	})
+}
+
+// janitor removes expired entries until the store is closed
+func (ms *MemoryStore) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ms.deleteExpired()
+		case <-ms.stop:
+			return
+		}
+	}
+}
+
+// deleteExpired removes all expired entries
+func (ms *MemoryStore) deleteExpired() {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	now := clock.Now()
+	for key, entry := range ms.entries {
+		if entry.expired(now) {
+			delete(ms.entries, key)
+		}
+	}
+}
+
+// newMemoryEntry creates an entry with a copy of value expiring after ttl,
+// so callers may reuse the slice
+func newMemoryEntry(value []byte, ttl time.Duration) memoryEntry {
+	entry := memoryEntry{value: bytes.Clone(value)}
+	if ttl > 0 {
+		entry.expiresAt = clock.Now().Add(ttl)
+	}
+	return entry
+}
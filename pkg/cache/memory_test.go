@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+)
+
+func TestMemoryStoreCopiesValues(t *testing.T) {
+	store := NewMemoryStore()
+	defer store.Close()
+
+	tests := []struct {
+		name  string
+		store func(key string, value []byte) error
+	}{
+		{name: "Set", store: func(key string, value []byte) error { return store.Set(key, value, 0) }},
+		{name: "SetIfAbsent", store: func(key string, value []byte) error {
+			_, err := store.SetIfAbsent(key, value, 0)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := []byte("original")
+			if err := tt.store(tt.name, value); err != nil {
+				t.Fatal(err)
+			}
+			copy(value, "mutated!")
+
+			got, found, _ := store.Get(tt.name)
+			if !found || string(got) != "original" {
+				t.Fatalf("Get() = %q, %v after the caller changed its slice, want %q", got, found, "original")
+			}
+			copy(got, "mutated!")
+			if again, _, _ := store.Get(tt.name); string(again) != "original" {
+				t.Errorf("Get() = %q after the caller changed a returned slice, want %q", again, "original")
+			}
+		})
+	}
+}
+
+func TestMemoryStoreExpiration(t *testing.T) {
+	frozen := clock.NewFrozen(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	clock.SetDefault(frozen)
+	defer clock.SetDefault(nil)
+
+	store := NewMemoryStore()
+	defer store.Close()
+	store.Set("key", []byte("value"), time.Minute)
+
+	tests := []struct {
+		name      string
+		advance   time.Duration
+		wantFound bool
+		wantAdded bool
+	}{
+		{name: "before the ttl", advance: 30 * time.Second, wantFound: true},
+		{name: "after the ttl", advance: time.Minute, wantAdded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frozen.Advance(tt.advance)
+			if _, found, _ := store.Get("key"); found != tt.wantFound {
+				t.Errorf("Get() found = %v, want %v", found, tt.wantFound)
+			}
+			if added, _ := store.SetIfAbsent("key", []byte("new"), time.Minute); added != tt.wantAdded {
+				t.Errorf("SetIfAbsent() = %v, want %v", added, tt.wantAdded)
+			}
+		})
+	}
+}
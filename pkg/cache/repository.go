@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// Repository is the CRUD contract of a data source that can be cached
+type Repository[T any, ID comparable] interface {
+	GetByID(id ID) (T, error)
+	List() ([]T, error)
+	Create(entity T) (T, error)
+	Update(id ID, entity T) (T, error)
+	Delete(id ID) error
+}
+
+// RepositoryOptions configures a CachedRepository
+type RepositoryOptions struct {
+	// Prefix namespaces the cache keys of the repository, e.g. "users"
+	Prefix string
+	// TTL is how long GetByID and List results are considered fresh
+	TTL time.Duration
+	// StaleTTL serves expired results while they are refreshed in the background
+	StaleTTL time.Duration
+}
+
+// CachedRepository is a read-through cache in front of a Repository.
+// GetByID and List are served from the store; Create, Update and Delete
+// go to the repository and evict the affected keys.
+type CachedRepository[T any, ID comparable] struct {
+	repository Repository[T, ID]
+	store      Store
+	loader     *Loader
+	options    RepositoryOptions
+}
+
+// NewCachedRepository decorates repository with a read-through cache
+func NewCachedRepository[T any, ID comparable](repository Repository[T, ID], store Store, options RepositoryOptions) *CachedRepository[T, ID] {
+	if options.Prefix == "" {
+		options.Prefix = fmt.Sprintf("%T", *new(T))
+	}
+
+	return &CachedRepository[T, ID]{
+		repository: repository,
+		store:      store,
+		loader:     NewLoader(store, LoaderOptions{TTL: options.TTL, StaleTTL: options.StaleTTL}),
+		options:    options,
+	}
+}
+
+// GetByID returns the entity from the cache, loading it from the repository on a miss
+func (cr *CachedRepository[T, ID]) GetByID(id ID) (T, error) {
+	var entity T
+
+	data, err := cr.loader.Get(cr.itemKey(id), func() ([]byte, error) {
+		loaded, err := cr.repository.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(loaded)
+	})
+	if err != nil {
+		return entity, err
+	}
+
+	err = json.Unmarshal(data, &entity)
+	return entity, err
+}
+
+// List returns every entity from the cache, loading them from the repository on a miss
+func (cr *CachedRepository[T, ID]) List() ([]T, error) {
+	data, err := cr.loader.Get(cr.listKey(), func() ([]byte, error) {
+		loaded, err := cr.repository.List()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(loaded)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make([]T, 0)
+	err = json.Unmarshal(data, &entities)
+	return entities, err
+}
+
+// Create adds the entity and evicts the cached list
+func (cr *CachedRepository[T, ID]) Create(entity T) (T, error) {
+	created, err := cr.repository.Create(entity)
+	if err != nil {
+		return created, err
+	}
+
+	cr.evict(cr.listKey())
+	return created, nil
+}
+
+// Update replaces the entity and evicts it along with the cached list
+func (cr *CachedRepository[T, ID]) Update(id ID, entity T) (T, error) {
+	updated, err := cr.repository.Update(id, entity)
+	if err != nil {
+		return updated, err
+	}
+
+	cr.evict(cr.itemKey(id), cr.listKey())
+	return updated, nil
+}
+
+// Delete removes the entity and evicts it along with the cached list
+func (cr *CachedRepository[T, ID]) Delete(id ID) error {
+	if err := cr.repository.Delete(id); err != nil {
+		return err
+	}
+
+	cr.evict(cr.itemKey(id), cr.listKey())
+	return nil
+}
+
+// itemKey returns the cache key of an entity
+func (cr *CachedRepository[T, ID]) itemKey(id ID) string {
+	return fmt.Sprintf("%s:id:%v", cr.options.Prefix, id)
+}
+
+// listKey returns the cache key of the entity list
+func (cr *CachedRepository[T, ID]) listKey() string {
+	return cr.options.Prefix + ":list"
+}
+
+// evict deletes keys from the store, logging failures
+func (cr *CachedRepository[T, ID]) evict(keys ...string) {
+	for _, key := range keys {
+		if err := cr.store.Delete(key); err != nil {
+			logger.Warn("Failed to evict repository cache key", "key", key, "error", err)
+		}
+	}
+}
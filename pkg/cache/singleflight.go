@@ -0,0 +1,55 @@
+package cache
+
+import "sync"
+
+// flightCall is an in-flight or completed Group.Do call
+type flightCall struct {
+	wg    sync.WaitGroup
+	value []byte
+	err   error
+}
+
+// Group deduplicates concurrent loads of the same key so that only one
+// caller hits the underlying source while the others wait for its result
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// Do runs fn once per key among concurrent callers and reports whether the
+// result was shared with other callers
+func (g *Group) Do(key string, fn func() ([]byte, error)) ([]byte, error, bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err, true
+	}
+
+	call := &flightCall{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// Release waiters even if fn panics
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+	}()
+
+	call.value, call.err = fn()
+	return call.value, call.err, false
+}
+
+// InFlight reports whether a load is currently running for key
+func (g *Group) InFlight(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.calls[key]
+	return ok
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Store is a key/value store with expiration, implemented in memory or by
+// external backends such as Redis
+type Store interface {
+	// Get returns the value for key and whether it was found
+	Get(key string) ([]byte, bool, error)
+	// Set stores a value for key; a zero ttl means no expiration
+	Set(key string, value []byte, ttl time.Duration) error
+	// SetIfAbsent stores a value only if key does not exist, reporting whether it was stored
+	SetIfAbsent(key string, value []byte, ttl time.Duration) (bool, error)
+	// Delete removes key from the store
+	Delete(key string) error
+}
+
+// GetJSON retrieves a value from the store and decodes it into target
+func GetJSON(store Store, key string, target interface{}) (bool, error) {
+	data, found, err := store.Get(key)
+	if err != nil || !found {
+		return false, err
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetJSON encodes a value as JSON and stores it
+func SetJSON(store Store, key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return store.Set(key, data, ttl)
+}
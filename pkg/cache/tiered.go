@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// DefaultL1TTL bounds how long a value is kept in the in-process tier
+const DefaultL1TTL = 30 * time.Second
+
+// Invalidation tells the other instances to drop keys from their L1 tier
+type Invalidation struct {
+	Origin string   `json:"origin"`
+	Keys   []string `json:"keys"`
+}
+
+// InvalidationBus broadcasts invalidations between application instances.
+// Implementations wrap the transport shared by the instances, such as
+// Redis pub/sub or a message broker.
+type InvalidationBus interface {
+	Publish(message Invalidation) error
+	Subscribe(handler func(Invalidation)) error
+}
+
+// MemoryBus is an in-process InvalidationBus, useful for several stores in
+// the same process and for tests
+type MemoryBus struct {
+	handlers []func(Invalidation)
+	mutex    sync.RWMutex
+}
+
+// NewMemoryBus creates an in-process invalidation bus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{handlers: make([]func(Invalidation), 0)}
+}
+
+// Publish delivers the message to every subscriber
+func (mb *MemoryBus) Publish(message Invalidation) error {
+	mb.mutex.RLock()
+	handlers := append([]func(Invalidation){}, mb.handlers...)
+	mb.mutex.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+// Subscribe registers a handler for published messages
+func (mb *MemoryBus) Subscribe(handler func(Invalidation)) error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	mb.handlers = append(mb.handlers, handler)
+	return nil
+}
+
+// TieredOptions configures a TieredStore
+type TieredOptions struct {
+	// L1TTL caps how long values stay in the in-process tier; defaults to DefaultL1TTL
+	L1TTL time.Duration
+	// Bus broadcasts writes so other instances drop their stale L1 entries;
+	// without a bus the L1 tier is only bounded by L1TTL
+	Bus InvalidationBus
+	// InstanceID identifies this instance on the bus; generated when empty
+	InstanceID string
+}
+
+// TieredStore composes a fast in-process L1 store with a shared L2 store such
+// as Redis. Reads fill L1 from L2; writes go to both tiers and publish an
+// invalidation so every other instance evicts the key from its L1.
+type TieredStore struct {
+	l1      Store
+	l2      Store
+	options TieredOptions
+}
+
+// NewTieredStore creates a two-tier store and subscribes it to the bus
+func NewTieredStore(l1, l2 Store, options TieredOptions) (*TieredStore, error) {
+	if l1 == nil || l2 == nil {
+		return nil, fmt.Errorf("cache: tiered store requires both L1 and L2 stores")
+	}
+	if options.L1TTL <= 0 {
+		options.L1TTL = DefaultL1TTL
+	}
+	if options.InstanceID == "" {
+		id, err := newInstanceID()
+		if err != nil {
+			return nil, err
+		}
+		options.InstanceID = id
+	}
+
+	ts := &TieredStore{l1: l1, l2: l2, options: options}
+	if options.Bus != nil {
+		if err := options.Bus.Subscribe(ts.handleInvalidation); err != nil {
+			return nil, fmt.Errorf("cache: failed to subscribe to invalidation bus: %w", err)
+		}
+	}
+
+	logger.Info("Tiered cache store created", "instance", options.InstanceID, "l1TTL", options.L1TTL, "bus", options.Bus != nil)
+	return ts, nil
+}
+
+// Get returns the value from L1, falling back to L2 and filling L1 on a hit
+func (ts *TieredStore) Get(key string) ([]byte, bool, error) {
+	if value, found, err := ts.l1.Get(key); err == nil && found {
+		return value, true, nil
+	}
+
+	value, found, err := ts.l2.Get(key)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	if err := ts.l1.Set(key, value, ts.options.L1TTL); err != nil {
+		logger.Warn("Failed to fill L1 cache", "key", key, "error", err)
+	}
+	return value, true, nil
+}
+
+// Set writes the value to both tiers and invalidates it on other instances
+func (ts *TieredStore) Set(key string, value []byte, ttl time.Duration) error {
+	if err := ts.l2.Set(key, value, ttl); err != nil {
+		return err
+	}
+
+	ts.setL1(key, value, ttl)
+	ts.publish(key)
+	return nil
+}
+
+// SetIfAbsent stores the value in L2 only if absent, mirroring it to L1 when stored
+func (ts *TieredStore) SetIfAbsent(key string, value []byte, ttl time.Duration) (bool, error) {
+	stored, err := ts.l2.SetIfAbsent(key, value, ttl)
+	if err != nil || !stored {
+		return stored, err
+	}
+
+	ts.setL1(key, value, ttl)
+	ts.publish(key)
+	return true, nil
+}
+
+// Delete removes the key from both tiers and invalidates it on other instances
+func (ts *TieredStore) Delete(key string) error {
+	if err := ts.l2.Delete(key); err != nil {
+		return err
+	}
+
+	if err := ts.l1.Delete(key); err != nil {
+		logger.Warn("Failed to delete L1 cache key", "key", key, "error", err)
+	}
+	ts.publish(key)
+	return nil
+}
+
+// setL1 stores a value in L1 for at most L1TTL
+func (ts *TieredStore) setL1(key string, value []byte, ttl time.Duration) {
+	l1TTL := ts.options.L1TTL
+	if ttl > 0 && ttl < l1TTL {
+		l1TTL = ttl
+	}
+
+	if err := ts.l1.Set(key, value, l1TTL); err != nil {
+		logger.Warn("Failed to write L1 cache", "key", key, "error", err)
+	}
+}
+
+// publish broadcasts the invalidation of key to the other instances
+func (ts *TieredStore) publish(key string) {
+	if ts.options.Bus == nil {
+		return
+	}
+
+	message := Invalidation{Origin: ts.options.InstanceID, Keys: []string{key}}
+	if err := ts.options.Bus.Publish(message); err != nil {
+		logger.Warn("Failed to publish cache invalidation", "key", key, "error", err)
+	}
+}
+
+// handleInvalidation drops keys written by other instances from L1
+func (ts *TieredStore) handleInvalidation(message Invalidation) {
+	if message.Origin == ts.options.InstanceID {
+		return
+	}
+
+	for _, key := range message.Keys {
+		if err := ts.l1.Delete(key); err != nil {
+			logger.Warn("Failed to invalidate L1 cache key", "key", key, "error", err)
+		}
+	}
+}
+
+// newInstanceID generates a random identifier for this instance
+func newInstanceID() (string, error) {
+	id, err := ids.Hex(8)
+	if err != nil {
+		return "", fmt.Errorf("cache: failed to generate instance id: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,131 @@
+package canary
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+const (
+	// HeaderTrack carries the deployment track of a request. Incoming values
+	// are honored, and the resolved track is propagated to upstream calls
+	// through the request headers and echoed in the response.
+	HeaderTrack = "X-Deployment-Track"
+	// TrackStable is the track of requests served by the current release
+	TrackStable = "stable"
+	// TrackCanary is the track of requests served by the alternate release
+	TrackCanary = "canary"
+	// TagCanary names, on a route field, the controller field handling the
+	// route for canary requests, e.g. `route:"GET /" canary:"GetAllV2"`
+	TagCanary = "canary"
+)
+
+// contextKey is the key used to store the track in the request context
+type contextKey struct{}
+
+// Options configures traffic tagging
+type Options struct {
+	// Percent of untagged requests routed to the canary track, from 0 to 100
+	Percent float64
+	// IgnoreHeader disables honoring the track sent by clients, e.g. for
+	// public traffic that should not pick its own release
+	IgnoreHeader bool
+	// StickyKey returns the key hashed to pick the track of untagged
+	// requests, so a client stays on the same track. Defaults to the
+	// client IP.
+	StickyKey func(r *http.Request) string
+	// Flag resolves the track from a feature flag provider. It runs after
+	// the header and before the percentage; returning false defers to the
+	// percentage.
+	Flag func(r *http.Request) (string, bool)
+}
+
+// Middleware returns an HTTP middleware that resolves the deployment track of
+// every request and stores it in the request context
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	if opts.StickyKey == nil {
+		opts.StickyKey = realip.ClientIP
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			track := resolve(r, opts)
+
+			r.Header.Set(HeaderTrack, track)
+			w.Header().Set(HeaderTrack, track)
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), track)))
+		})
+	}
+}
+
+// resolve picks the track of a request from its header, the flag provider or
+// the percentage, in that order
+func resolve(r *http.Request, opts Options) string {
+	if !opts.IgnoreHeader {
+		if track, ok := ParseTrack(r.Header.Get(HeaderTrack)); ok {
+			return track
+		}
+	}
+
+	if opts.Flag != nil {
+		if track, ok := opts.Flag(r); ok {
+			if track, ok = ParseTrack(track); ok {
+				return track
+			}
+		}
+	}
+
+	if opts.Percent > 0 && bucket(opts.StickyKey(r)) < opts.Percent {
+		return TrackCanary
+	}
+	return TrackStable
+}
+
+// ParseTrack normalizes a track name, reporting whether it is known
+func ParseTrack(value string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case TrackStable:
+		return TrackStable, true
+	case TrackCanary:
+		return TrackCanary, true
+	}
+	return "", false
+}
+
+// bucket maps a key to a stable value in [0, 100)
+func bucket(key string) float64 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return float64(hash.Sum32()%10000) / 100
+}
+
+// NewContext returns a copy of ctx carrying the track
+func NewContext(ctx context.Context, track string) context.Context {
+	return context.WithValue(ctx, contextKey{}, track)
+}
+
+// TrackFromContext returns the track stored in ctx, TrackStable when none was
+// resolved
+func TrackFromContext(ctx context.Context) string {
+	if track, ok := ctx.Value(contextKey{}).(string); ok {
+		return track
+	}
+	return TrackStable
+}
+
+// IsCanary reports whether the request of ctx is on the canary track
+func IsCanary(ctx context.Context) bool {
+	return TrackFromContext(ctx) == TrackCanary
+}
+
+// Select returns the canary value for canary requests and the stable one
+// otherwise, e.g. to switch between two providers injected in a service
+func Select[T any](ctx context.Context, stable, canary T) T {
+	if IsCanary(ctx) {
+		return canary
+	}
+	return stable
+}
@@ -0,0 +1,101 @@
+package clientinfo
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+// contextKey is the key used to store client info in the request context
+type contextKey struct{}
+
+// Geo holds geographic information resolved for a client IP
+type Geo struct {
+	Country   string  `json:"country,omitempty"`
+	Region    string  `json:"region,omitempty"`
+	City      string  `json:"city,omitempty"`
+	Latitude  float64 `json:"latitude,omitempty"`
+	Longitude float64 `json:"longitude,omitempty"`
+}
+
+// GeoResolver resolves geographic data for an IP address
+type GeoResolver interface {
+	Resolve(ip net.IP) (*Geo, error)
+}
+
+// Info holds the enriched client information for a request
+type Info struct {
+	IP        string    `json:"ip"`
+	UserAgent UserAgent `json:"userAgent"`
+	Geo       *Geo      `json:"geo,omitempty"`
+}
+
+// Options configures the client info enrichment
+type Options struct {
+	// TrustedProxies lists proxy IPs or CIDRs whose forwarding headers are honored.
+	// Ignored when the IP was already resolved by the realip middleware.
+	TrustedProxies []string
+	// GeoResolver resolves geo data for the client IP (optional)
+	GeoResolver GeoResolver
+}
+
+// Middleware returns an HTTP middleware that stores the client info in the request context
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	resolver, err := realip.NewResolver(opts.TrustedProxies...)
+	if err != nil {
+		logger.Warn("Ignoring invalid trusted proxies for client info", "error", err)
+		resolver, _ = realip.NewResolver()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := &Info{
+				IP:        resolveIP(r, resolver),
+				UserAgent: ParseUserAgent(r.UserAgent()),
+			}
+
+			if opts.GeoResolver != nil {
+				if ip := net.ParseIP(info.IP); ip != nil {
+					geo, err := opts.GeoResolver.Resolve(ip)
+					if err != nil {
+						logger.Warn("Failed to resolve geo data", "ip", info.IP, "error", err)
+					} else {
+						info.Geo = geo
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), info)))
+		})
+	}
+}
+
+// NewContext returns a copy of ctx carrying the client info
+func NewContext(ctx context.Context, info *Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext retrieves the client info from a context
+func FromContext(ctx context.Context) (*Info, bool) {
+	info, ok := ctx.Value(contextKey{}).(*Info)
+	return info, ok
+}
+
+// FromRequest retrieves the client info from a request
+func FromRequest(r *http.Request) (*Info, bool) {
+	if r == nil {
+		return nil, false
+	}
+	return FromContext(r.Context())
+}
+
+// resolveIP prefers the IP resolved by the realip middleware over the local resolver
+func resolveIP(r *http.Request, resolver *realip.Resolver) string {
+	if ip, ok := realip.FromRequest(r); ok {
+		return ip
+	}
+	return resolver.Resolve(r)
+}
@@ -0,0 +1,102 @@
+package clientinfo
+
+import (
+	"strings"
+)
+
+// UserAgent holds the parsed fields of a User-Agent header
+type UserAgent struct {
+	Raw     string `json:"raw"`
+	Browser string `json:"browser"`
+	OS      string `json:"os"`
+	Device  string `json:"device"`
+	IsBot   bool   `json:"isBot"`
+}
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceUnknown = "unknown"
+)
+
+// browserSignatures are checked in order, since many agents embed others' tokens
+var browserSignatures = []struct {
+	token string
+	name  string
+}{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+	{"PostmanRuntime/", "Postman"},
+}
+
+// osSignatures are checked in order
+var osSignatures = []struct {
+	token string
+	name  string
+}{
+	{"Windows", "Windows"},
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iOS"},
+	{"Mac OS X", "macOS"},
+	{"Linux", "Linux"},
+}
+
+// botTokens identify crawlers and automated clients
+var botTokens = []string{"bot", "crawler", "spider", "slurp", "headless"}
+
+// ParseUserAgent parses a User-Agent header into its main components
+func ParseUserAgent(raw string) UserAgent {
+	ua := UserAgent{
+		Raw:     raw,
+		Browser: "unknown",
+		OS:      "unknown",
+		Device:  DeviceUnknown,
+	}
+
+	if raw == "" {
+		return ua
+	}
+
+	for _, signature := range browserSignatures {
+		if strings.Contains(raw, signature.token) {
+			ua.Browser = signature.name
+			break
+		}
+	}
+
+	for _, signature := range osSignatures {
+		if strings.Contains(raw, signature.token) {
+			ua.OS = signature.name
+			break
+		}
+	}
+
+	lower := strings.ToLower(raw)
+	for _, token := range botTokens {
+		if strings.Contains(lower, token) {
+			ua.IsBot = true
+			break
+		}
+	}
+
+	switch {
+	case ua.IsBot:
+		ua.Device = DeviceBot
+	case strings.Contains(raw, "iPad") || strings.Contains(raw, "Tablet"):
+		ua.Device = DeviceTablet
+	case strings.Contains(raw, "Mobile") || strings.Contains(raw, "iPhone"):
+		ua.Device = DeviceMobile
+	case ua.OS != "unknown":
+		ua.Device = DeviceDesktop
+	}
+
+	return ua
+}
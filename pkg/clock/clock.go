@@ -0,0 +1,97 @@
+package clock
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProviderName is the name the application clock is injected under, e.g.
+// Clock clock.Clock `inject:"Clock"`
+const ProviderName = "Clock"
+
+// Clock tells the current time. Framework components read it through Now
+// so tests can freeze and advance time.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock reads the wall clock
+type systemClock struct{}
+
+// Now returns the current wall clock time
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System returns the wall clock
+func System() Clock {
+	return systemClock{}
+}
+
+// Frozen is a Clock that only moves when told to
+type Frozen struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFrozen creates a clock stopped at now
+func NewFrozen(now time.Time) *Frozen {
+	return &Frozen{now: now}
+}
+
+// Now returns the frozen time
+func (f *Frozen) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to now
+func (f *Frozen) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance moves the clock forward by d
+func (f *Frozen) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// holder wraps a Clock so atomic.Value always stores the same type
+type holder struct {
+	clock Clock
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(holder{clock: System()})
+}
+
+// Default returns the clock used by framework components
+func Default() Clock {
+	return current.Load().(holder).clock
+}
+
+// SetDefault replaces the clock used by framework components; nil restores
+// the wall clock
+func SetDefault(c Clock) {
+	if c == nil {
+		c = System()
+	}
+	current.Store(holder{clock: c})
+}
+
+// Now returns the current time of the default clock
+func Now() time.Time {
+	return Default().Now()
+}
+
+// Since returns the time elapsed since t on the default clock
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
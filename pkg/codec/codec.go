@@ -0,0 +1,105 @@
+package codec
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec converts values of a scalar type to and from their text form.
+// Registered codecs are used by parameter binding, validation and, through
+// the text methods of the scalar types, JSON serialization.
+type Codec interface {
+	Parse(text string) (interface{}, error)
+	Format(value interface{}) (string, error)
+}
+
+// funcCodec adapts typed parse and format functions to Codec
+type funcCodec[T any] struct {
+	parse  func(text string) (T, error)
+	format func(value T) string
+}
+
+// Parse calls the parse function
+func (c funcCodec[T]) Parse(text string) (interface{}, error) {
+	return c.parse(text)
+}
+
+// Format calls the format function on values of type T
+func (c funcCodec[T]) Format(value interface{}) (string, error) {
+	typed, ok := value.(T)
+	if !ok {
+		var zero T
+		return "", fmt.Errorf("codec: cannot format %T as %T", value, zero)
+	}
+	return c.format(typed), nil
+}
+
+var (
+	codecs      = make(map[reflect.Type]Codec)
+	codecsMutex sync.RWMutex
+)
+
+// Register sets the codec of type T from typed functions, replacing any
+// codec registered before
+//
+//	codec.Register(decimal.NewFromString, decimal.Decimal.String)
+func Register[T any](parse func(text string) (T, error), format func(value T) string) {
+	RegisterCodec(reflect.TypeOf((*T)(nil)).Elem(), funcCodec[T]{parse: parse, format: format})
+}
+
+// RegisterCodec sets the codec of a type, replacing any codec registered before
+func RegisterCodec(t reflect.Type, c Codec) {
+	codecsMutex.Lock()
+	defer codecsMutex.Unlock()
+	codecs[t] = c
+}
+
+// Lookup returns the codec of a type
+func Lookup(t reflect.Type) (Codec, bool) {
+	codecsMutex.RLock()
+	defer codecsMutex.RUnlock()
+	c, ok := codecs[t]
+	return c, ok
+}
+
+// Parse converts text to T with the codec registered for T
+func Parse[T any](text string) (T, error) {
+	var zero T
+	c, ok := Lookup(reflect.TypeOf((*T)(nil)).Elem())
+	if !ok {
+		return zero, fmt.Errorf("codec: no codec registered for %T", zero)
+	}
+
+	parsed, err := c.Parse(text)
+	if err != nil {
+		return zero, err
+	}
+	typed, ok := parsed.(T)
+	if !ok {
+		return zero, fmt.Errorf("codec: codec for %T returned %T", zero, parsed)
+	}
+	return typed, nil
+}
+
+// Format converts a value to text with the codec registered for its type
+func Format[T any](value T) (string, error) {
+	c, ok := Lookup(reflect.TypeOf((*T)(nil)).Elem())
+	if !ok {
+		return "", fmt.Errorf("codec: no codec registered for %T", value)
+	}
+	return c.Format(value)
+}
+
+// Text returns the text form of a value whose type has a codec
+func Text(v reflect.Value) (string, bool) {
+	if !v.IsValid() {
+		return "", false
+	}
+	c, ok := Lookup(v.Type())
+	if !ok {
+		return "", false
+	}
+	text, err := c.Format(v.Interface())
+	return text, err == nil
+}
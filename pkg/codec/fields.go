@@ -0,0 +1,62 @@
+package codec
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field is a struct field as seen by encoding/json
+type Field struct {
+	// Name is the JSON property name
+	Name string
+	// Index is the field index path, through embedded structs
+	Index []int
+	// Tag is the struct tag of the field
+	Tag reflect.StructTag
+}
+
+// Fields lists the JSON fields of a struct, promoting the fields of
+// embedded structs without a JSON name and skipping fields tagged "-"
+func Fields(t reflect.Type) []Field {
+	result := make([]Field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		name, _, _ := strings.Cut(structField.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if structField.Anonymous && structField.Type.Kind() == reflect.Struct && name == "" {
+			for _, promoted := range Fields(structField.Type) {
+				promoted.Index = append([]int{i}, promoted.Index...)
+				result = append(result, promoted)
+			}
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = structField.Name
+		}
+		result = append(result, Field{Name: name, Index: []int{i}, Tag: structField.Tag})
+	}
+	return result
+}
+
+// Property returns the value of a JSON object property for a field name,
+// matching like encoding/json: exactly first, then case-insensitively
+func Property[V any](object map[string]V, name string) (V, string, bool) {
+	if value, ok := object[name]; ok {
+		return value, name, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, key, true
+		}
+	}
+
+	var zero V
+	return zero, "", false
+}
@@ -0,0 +1,425 @@
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagTime overrides the time policy of a route, e.g.
+// `time:"format=epoch,zone=America/Sao_Paulo,accept=2006-01-02|epoch"`
+const TagTime = "time"
+
+// TimeFormat is how timestamps are written in responses: one of the
+// constants below or a time layout
+type TimeFormat string
+
+const (
+	// FormatRFC3339 writes "2006-01-02T15:04:05.999999999Z07:00" strings
+	FormatRFC3339 TimeFormat = "rfc3339"
+	// FormatEpoch writes Unix seconds as JSON numbers
+	FormatEpoch TimeFormat = "epoch"
+	// FormatEpochMillis writes Unix milliseconds as JSON numbers
+	FormatEpochMillis TimeFormat = "epoch_ms"
+)
+
+// TimePolicy sets the timestamp formats accepted in requests and written in
+// responses, for time.Time values in path parameters and JSON bodies
+type TimePolicy struct {
+	// Accepted lists the layouts tried in order when parsing timestamps;
+	// "epoch" and "epoch_ms" accept Unix seconds and milliseconds
+	Accepted []string
+	// Output is the response format, FormatRFC3339 by default
+	Output TimeFormat
+	// Location normalizes parsed and written timestamps; nil keeps the
+	// offset of each timestamp
+	Location *time.Location
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	timePolicy      = DefaultTimePolicy()
+	timePolicyMutex sync.RWMutex
+
+	// timeCache caches whether a type holds time.Time values
+	timeCache sync.Map
+)
+
+// timePolicyKey is the context key of the time policy of a route
+type timePolicyKey struct{}
+
+func init() {
+	RegisterCodec(timeType, timeCodec{})
+}
+
+// DefaultTimePolicy accepts and writes RFC 3339 timestamps, like encoding/json
+func DefaultTimePolicy() TimePolicy {
+	return TimePolicy{Accepted: []string{time.RFC3339Nano}, Output: FormatRFC3339}
+}
+
+// SetTimePolicy sets the time policy of routes without a time tag, on
+// servers without their own policy
+func SetTimePolicy(policy TimePolicy) {
+	timePolicyMutex.Lock()
+	defer timePolicyMutex.Unlock()
+	timePolicy = policy.withDefaults()
+}
+
+// CurrentTimePolicy returns the time policy of routes without a time tag,
+// on servers without their own policy
+func CurrentTimePolicy() TimePolicy {
+	timePolicyMutex.RLock()
+	defer timePolicyMutex.RUnlock()
+	return timePolicy
+}
+
+// ParseTimePolicy parses a time tag over the current policy, overriding
+// the format, zone and accepted layouts it declares
+func ParseTimePolicy(tag string) (TimePolicy, error) {
+	return ParseTimeTag(tag, CurrentTimePolicy())
+}
+
+// ParseTimeTag parses a time tag over base, e.g. the policy of a server
+func ParseTimeTag(tag string, base TimePolicy) (TimePolicy, error) {
+	policy := base
+
+	for _, entry := range strings.Split(tag, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(entry, "=")
+		switch key {
+		case "format":
+			policy.Output = TimeFormat(value)
+		case "zone":
+			loc, err := time.LoadLocation(value)
+			if err != nil {
+				return policy, fmt.Errorf("invalid time zone %q: %w", value, err)
+			}
+			policy.Location = loc
+		case "accept":
+			policy.Accepted = strings.Split(value, "|")
+		default:
+			return policy, fmt.Errorf("unknown time option %q", key)
+		}
+	}
+	return policy.withDefaults(), nil
+}
+
+// WithTimePolicy returns a context carrying the time policy of a route
+func WithTimePolicy(ctx context.Context, policy TimePolicy) context.Context {
+	return context.WithValue(ctx, timePolicyKey{}, policy.withDefaults())
+}
+
+// TimePolicyFromContext returns the time policy of the route handling a
+// request, or the current policy
+func TimePolicyFromContext(ctx context.Context) TimePolicy {
+	if policy, ok := ctx.Value(timePolicyKey{}).(TimePolicy); ok {
+		return policy
+	}
+	return CurrentTimePolicy()
+}
+
+// withDefaults fills the unset fields with the defaults
+func (p TimePolicy) withDefaults() TimePolicy {
+	if len(p.Accepted) == 0 {
+		p.Accepted = []string{time.RFC3339Nano}
+	}
+	if p.Output == "" {
+		p.Output = FormatRFC3339
+	}
+	return p
+}
+
+// isDefault reports whether the policy behaves like encoding/json
+func (p TimePolicy) isDefault() bool {
+	return p.Output == FormatRFC3339 && p.Location == nil &&
+		len(p.Accepted) == 1 && (p.Accepted[0] == time.RFC3339Nano || p.Accepted[0] == time.RFC3339)
+}
+
+// Parse parses a timestamp with the accepted layouts, normalized to the
+// policy location
+func (p TimePolicy) Parse(text string) (time.Time, error) {
+	text = strings.TrimSpace(text)
+	for _, layout := range p.Accepted {
+		var parsed time.Time
+		var err error
+
+		switch TimeFormat(layout) {
+		case FormatEpoch, FormatEpochMillis:
+			var n int64
+			n, err = strconv.ParseInt(text, 10, 64)
+			if TimeFormat(layout) == FormatEpoch {
+				parsed = time.Unix(n, 0)
+			} else {
+				parsed = time.UnixMilli(n)
+			}
+		default:
+			parsed, err = time.Parse(layout, text)
+		}
+
+		if err == nil {
+			if p.Location != nil {
+				parsed = parsed.In(p.Location)
+			}
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected %s", text, strings.Join(p.Accepted, " or "))
+}
+
+// Format returns the response form of a timestamp: a string, or an int64
+// for epoch formats
+func (p TimePolicy) Format(t time.Time) interface{} {
+	if p.Location != nil {
+		t = t.In(p.Location)
+	}
+
+	switch p.Output {
+	case FormatEpoch:
+		return t.Unix()
+	case FormatEpochMillis:
+		return t.UnixMilli()
+	case FormatRFC3339, "":
+		return t.Format(time.RFC3339Nano)
+	default:
+		return t.Format(string(p.Output))
+	}
+}
+
+// NormalizeJSON rewrites the timestamps of a JSON document decoded into
+// type t from the accepted formats to RFC 3339, so encoding/json can
+// decode them
+func (p TimePolicy) NormalizeJSON(data []byte, t reflect.Type) ([]byte, error) {
+	if p.isDefault() || !ContainsTime(t) {
+		return data, nil
+	}
+
+	tree, err := decodeTree(data)
+	if err != nil {
+		// Let the decoder report the syntax error
+		return data, nil
+	}
+	tree, err = p.normalize(t, tree, "")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+// FormatJSON rewrites the timestamps of the JSON encoding of value to the
+// output format
+func (p TimePolicy) FormatJSON(data []byte, value interface{}) ([]byte, error) {
+	if p.isDefault() || value == nil || !ContainsTime(reflect.TypeOf(value)) {
+		return data, nil
+	}
+
+	tree, err := decodeTree(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(p.rewrite(reflect.ValueOf(value), tree))
+}
+
+// ContainsTime reports whether values of type t may hold time.Time values
+func ContainsTime(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if cached, ok := timeCache.Load(t); ok {
+		return cached.(bool)
+	}
+
+	// Store false first so recursive types terminate
+	timeCache.Store(t, false)
+	has := false
+	switch {
+	case t == timeType || t.Kind() == reflect.Interface:
+		has = true
+	case t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map:
+		has = ContainsTime(t.Elem())
+	case t.Kind() == reflect.Struct && !hasJSONMethods(t):
+		for _, field := range Fields(t) {
+			if ContainsTime(t.FieldByIndex(field.Index).Type) {
+				has = true
+				break
+			}
+		}
+	}
+	timeCache.Store(t, has)
+	return has
+}
+
+// normalize walks a decoded JSON tree along type t, converting timestamps
+func (p TimePolicy) normalize(t reflect.Type, node interface{}, path string) (interface{}, error) {
+	if node == nil || !ContainsTime(t) {
+		return node, nil
+	}
+
+	switch {
+	case t == timeType:
+		var text string
+		switch raw := node.(type) {
+		case string:
+			text = raw
+		case json.Number:
+			text = raw.String()
+		default:
+			return node, nil
+		}
+		parsed, err := p.Parse(text)
+		if err != nil {
+			return nil, prefixPath(path, err)
+		}
+		return parsed.Format(time.RFC3339Nano), nil
+	case t.Kind() == reflect.Ptr:
+		return p.normalize(t.Elem(), node, path)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		items, ok := node.([]interface{})
+		if !ok {
+			return node, nil
+		}
+		for i, item := range items {
+			normalized, err := p.normalize(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = normalized
+		}
+	case t.Kind() == reflect.Map:
+		entries, ok := node.(map[string]interface{})
+		if !ok {
+			return node, nil
+		}
+		for key, entry := range entries {
+			normalized, err := p.normalize(t.Elem(), entry, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			entries[key] = normalized
+		}
+	case t.Kind() == reflect.Struct:
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return node, nil
+		}
+		for _, field := range Fields(t) {
+			entry, key, ok := Property(object, field.Name)
+			if !ok {
+				continue
+			}
+			normalized, err := p.normalize(t.FieldByIndex(field.Index).Type, entry, joinPath(path, field.Name))
+			if err != nil {
+				return nil, err
+			}
+			object[key] = normalized
+		}
+	}
+	return node, nil
+}
+
+// rewrite walks a value alongside its decoded JSON tree, replacing the
+// encoding of timestamps with the output format
+func (p TimePolicy) rewrite(v reflect.Value, node interface{}) interface{} {
+	if !v.IsValid() || node == nil || !ContainsTime(v.Type()) {
+		return node
+	}
+
+	switch {
+	case v.Type() == timeType:
+		return p.Format(v.Interface().(time.Time))
+	case v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface:
+		if v.IsNil() {
+			return node
+		}
+		return p.rewrite(v.Elem(), node)
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if items, ok := node.([]interface{}); ok {
+			for i := 0; i < v.Len() && i < len(items); i++ {
+				items[i] = p.rewrite(v.Index(i), items[i])
+			}
+		}
+	case v.Kind() == reflect.Map:
+		if entries, ok := node.(map[string]interface{}); ok {
+			iter := v.MapRange()
+			for iter.Next() {
+				key := fmt.Sprint(iter.Key().Interface())
+				if entry, ok := entries[key]; ok {
+					entries[key] = p.rewrite(iter.Value(), entry)
+				}
+			}
+		}
+	case v.Kind() == reflect.Struct:
+		if object, ok := node.(map[string]interface{}); ok {
+			for _, field := range Fields(v.Type()) {
+				if entry, ok := object[field.Name]; ok {
+					object[field.Name] = p.rewrite(v.FieldByIndex(field.Index), entry)
+				}
+			}
+		}
+	}
+	return node
+}
+
+// timeCodec parses and formats time.Time parameters with the current policy
+type timeCodec struct{}
+
+// Parse parses a timestamp with the current policy
+func (timeCodec) Parse(text string) (interface{}, error) {
+	return CurrentTimePolicy().Parse(text)
+}
+
+// Format formats a timestamp with the current policy
+func (timeCodec) Format(value interface{}) (string, error) {
+	t, ok := value.(time.Time)
+	if !ok {
+		return "", fmt.Errorf("codec: cannot format %T as time.Time", value)
+	}
+	return fmt.Sprint(CurrentTimePolicy().Format(t)), nil
+}
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// hasJSONMethods reports whether a type controls its own JSON encoding
+func hasJSONMethods(t reflect.Type) bool {
+	return t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType)
+}
+
+// decodeTree decodes JSON into maps and slices, keeping numbers exact
+func decodeTree(data []byte) (interface{}, error) {
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// joinPath appends a property name to a path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// prefixPath prefixes an error with the path of the value it is about
+func prefixPath(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", path, err)
+}
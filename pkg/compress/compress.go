@@ -0,0 +1,236 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TagCompress overrides the compression of a controller or route, e.g.
+// `compress:"off"` or `compress:"level=9,min=256"`
+const TagCompress = "compress"
+
+// DefaultMinSize is the smallest response body compressed by default
+const DefaultMinSize = 1024
+
+// Options configures response compression
+type Options struct {
+	// Disabled leaves responses uncompressed
+	Disabled bool
+	// Level is the gzip level; zero uses gzip.DefaultCompression
+	Level int
+	// MinSize leaves smaller bodies uncompressed; zero uses DefaultMinSize
+	MinSize int
+}
+
+// withDefaults fills the unset options
+func (o Options) withDefaults() Options {
+	if o.Level == 0 {
+		o.Level = gzip.DefaultCompression
+	}
+	if o.MinSize <= 0 {
+		o.MinSize = DefaultMinSize
+	}
+	return o
+}
+
+// ParseTag parses a compress tag over base: "off", "on", or comma
+// separated level=N and min=N settings
+func ParseTag(tag string, base Options) (Options, error) {
+	options := base
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+		case part == "off":
+			options.Disabled = true
+		case part == "on":
+			options.Disabled = false
+		case strings.HasPrefix(part, "level="):
+			level, err := strconv.Atoi(strings.TrimPrefix(part, "level="))
+			if err != nil || level < gzip.HuffmanOnly || level > gzip.BestCompression {
+				return Options{}, fmt.Errorf("invalid compression level %q", part)
+			}
+			options.Level = level
+			options.Disabled = false
+		case strings.HasPrefix(part, "min="):
+			size, err := strconv.Atoi(strings.TrimPrefix(part, "min="))
+			if err != nil || size < 0 {
+				return Options{}, fmt.Errorf("invalid minimum size %q", part)
+			}
+			options.MinSize = size
+			options.Disabled = false
+		default:
+			return Options{}, fmt.Errorf("unknown compression setting %q", part)
+		}
+	}
+	return options, nil
+}
+
+// contextKey is the key used to store the compressing writer in the request context
+type contextKey struct{}
+
+// Middleware returns an HTTP middleware compressing responses with gzip for
+// clients accepting it. Controllers and routes override the options with
+// compress tags, see Override.
+func Middleware(options Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !acceptsGzip(r) || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &writer{ResponseWriter: w, options: options.withDefaults(), status: http.StatusOK}
+			defer cw.close()
+			next.ServeHTTP(cw, r.WithContext(context.WithValue(r.Context(), contextKey{}, cw)))
+		})
+	}
+}
+
+// Override returns an HTTP middleware replacing the compression options of
+// the request, compressing on its own when no Middleware runs before it
+func Override(options Options) func(http.Handler) http.Handler {
+	compress := Middleware(options)
+	return func(next http.Handler) http.Handler {
+		compressed := compress(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw, ok := r.Context().Value(contextKey{}).(*writer)
+			if !ok {
+				compressed.ServeHTTP(w, r)
+				return
+			}
+			if !cw.decided {
+				cw.options = options.withDefaults()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether the client accepts gzip encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		if strings.TrimSpace(encoding) != "gzip" && strings.TrimSpace(encoding) != "*" {
+			continue
+		}
+		if q := strings.TrimSpace(params); q == "q=0" || q == "q=0.0" {
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// writer buffers the beginning of a response until it knows whether to
+// compress it: below the minimum size, already encoded or without a body,
+// the response is written as is
+type writer struct {
+	http.ResponseWriter
+	options Options
+	status  int
+	buffer  []byte
+	gzip    *gzip.Writer
+	decided bool
+}
+
+func (cw *writer) WriteHeader(status int) {
+	if cw.decided {
+		return
+	}
+	cw.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < http.StatusOK {
+		cw.decide(false)
+	}
+}
+
+func (cw *writer) Write(data []byte) (int, error) {
+	if !cw.decided {
+		cw.buffer = append(cw.buffer, data...)
+		if len(cw.buffer) >= cw.options.MinSize {
+			if err := cw.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(data), nil
+	}
+	if cw.gzip != nil {
+		return cw.gzip.Write(data)
+	}
+	return cw.ResponseWriter.Write(data)
+}
+
+// Flush writes the buffered response, compressed if already large enough
+func (cw *writer) Flush() {
+	if !cw.decided {
+		cw.decide(len(cw.buffer) >= cw.options.MinSize)
+	}
+	if cw.gzip != nil {
+		cw.gzip.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets websocket upgrades through the compressing writer
+func (cw *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("compress: response writer does not support hijacking")
+	}
+	cw.decided = true
+	return hijacker.Hijack()
+}
+
+// decide writes the status line, compressing the rest of the response when
+// compress is true and the options and headers allow it
+func (cw *writer) decide(compress bool) error {
+	cw.decided = true
+	header := cw.ResponseWriter.Header()
+	if cw.options.Disabled || header.Get("Content-Encoding") != "" {
+		compress = false
+	}
+
+	if compress {
+		gz, err := gzip.NewWriterLevel(cw.ResponseWriter, cw.options.Level)
+		if err != nil {
+			return err
+		}
+		cw.gzip = gz
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+	}
+	if !cw.options.Disabled {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buffered := cw.buffer
+	cw.buffer = nil
+	if len(buffered) == 0 {
+		return nil
+	}
+	if cw.gzip != nil {
+		_, err := cw.gzip.Write(buffered)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buffered)
+	return err
+}
+
+// close writes what is still buffered and ends the compressed stream
+func (cw *writer) close() {
+	if !cw.decided {
+		cw.decide(false)
+	}
+	if cw.gzip != nil {
+		cw.gzip.Close()
+	}
+}
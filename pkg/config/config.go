@@ -0,0 +1,287 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/validation"
+)
+
+const (
+	// TagEnv names the variable of a setting; defaults to the field name in
+	// upper snake case, e.g. DatabaseURL reads DATABASE_URL
+	TagEnv = "env"
+	// TagRequired fails loading when the variable is not set ("true"),
+	// shared with request validation
+	TagRequired = controller.TagRequired
+	// TagDefault is the value used when the variable is not set, shared
+	// with query binding
+	TagDefault = controller.TagDefault
+)
+
+// Source looks up the raw value of a setting
+type Source func(key string) (string, bool)
+
+// Env reads settings from environment variables
+func Env() Source {
+	return os.LookupEnv
+}
+
+// Map reads settings from a map, e.g. in tests
+func Map(values map[string]string) Source {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// Error lists every missing or invalid setting of a configuration
+type Error struct {
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load fills the fields of the struct pointed to by target from the sources,
+// the first source defining a key winning; environment variables are used
+// when no source is given. Fields are parsed per their type: strings,
+// booleans, numbers, durations, comma separated slices, types with a codec
+// and types implementing encoding.TextUnmarshaler. Nested structs read their
+// fields with the struct's key as prefix, e.g. DATABASE_HOST. validate tags
+// are checked once every field is set.
+//
+//	type AppConfig struct {
+//		DatabaseURL string        `env:"DATABASE_URL" required:"true"`
+//		Port        int           `default:"3000" validate:"min=1,max=65535"`
+//		Timeout     time.Duration `default:"5s"`
+//	}
+func Load(target interface{}, sources ...Source) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: target must be a pointer to a struct, got %T", target)
+	}
+	if len(sources) == 0 {
+		sources = []Source{Env()}
+	}
+
+	loader := &loader{sources: sources, reported: make(map[string]bool)}
+	loader.loadStruct(value.Elem(), "", "")
+
+	var validationErrs validation.Errors
+	if err := validation.Validate(target); errors.As(err, &validationErrs) {
+		for _, fieldErr := range validationErrs {
+			// Settings already reported as missing or invalid
+			if loader.reported[fieldErr.Field] {
+				continue
+			}
+			loader.problems = append(loader.problems, fieldErr.Message)
+		}
+	} else if err != nil {
+		loader.problems = append(loader.problems, err.Error())
+	}
+
+	if len(loader.problems) > 0 {
+		return &Error{Problems: loader.problems}
+	}
+	return nil
+}
+
+// loader collects the problems found while loading a configuration
+type loader struct {
+	sources  []Source
+	problems []string
+	// reported holds the validation paths of fields with a problem
+	reported map[string]bool
+}
+
+// loadStruct sets the exported fields of a struct. prefix is prepended to
+// the keys and path to the validation paths of the fields.
+func (l *loader) loadStruct(v reflect.Value, prefix, path string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := field.Tag.Get(TagEnv)
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = upperSnake(field.Name)
+		}
+		if field.Anonymous {
+			key = ""
+		}
+		key = prefix + key
+		fieldPath := path
+		if !field.Anonymous {
+			fieldPath = joinPath(path, validationName(field))
+		}
+
+		if field.Type.Kind() == reflect.Struct && !isScalar(field.Type) {
+			nested := key
+			if nested != "" {
+				nested += "_"
+			}
+			l.loadStruct(v.Field(i), nested, fieldPath)
+			continue
+		}
+
+		raw, ok := l.lookup(key)
+		if !ok {
+			raw, ok = field.Tag.Lookup(TagDefault)
+		}
+		if !ok {
+			if field.Tag.Get(TagRequired) == controller.TagValueTrue {
+				l.problems = append(l.problems, fmt.Sprintf("%s is required", key))
+				l.reported[fieldPath] = true
+			}
+			continue
+		}
+
+		if err := setValue(v.Field(i), raw); err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s: %v", key, err))
+			l.reported[fieldPath] = true
+		}
+	}
+}
+
+// lookup returns the value of key from the first source defining it
+func (l *loader) lookup(key string) (string, bool) {
+	for _, source := range l.sources {
+		if value, ok := source(key); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isScalar reports whether a struct type is parsed from a single value
+func isScalar(t reflect.Type) bool {
+	_, ok := codec.Lookup(t)
+	return ok || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setValue parses raw into v
+func setValue(v reflect.Value, raw string) error {
+	if c, ok := codec.Lookup(v.Type()); ok {
+		parsed, err := c.Parse(raw)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	if unmarshaler, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(raw))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// validationName returns the name of a field in validation paths
+func validationName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get(controller.TagJSON), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// joinPath appends a field name to a validation path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// upperSnake converts a field name to upper snake case, keeping acronyms
+// together: DatabaseURL becomes DATABASE_URL
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				builder.WriteByte('_')
+			}
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
@@ -3,43 +3,129 @@ package container
 import (
 	"fmt"
 	"reflect"
+	"strings"
 
 	"github.com/kevenmiano/nestgo/pkg/logger"
 )
 
+// TagInject names the service injected into a field: `inject:"UserService"`
+const TagInject = "inject"
+
+// CircularDependencyError reports services depending on themselves through
+// their injected fields
+type CircularDependencyError struct {
+	// Chain lists the services of the cycle, starting and ending with the same one
+	Chain []string
+}
+
+func (e *CircularDependencyError) Error() string {
+	return "circular dependency: " + strings.Join(e.Chain, " -> ")
+}
+
+// Scope decides whether a service can be injected into a target, returning
+// the reason when it cannot
+type Scope func(service string) error
+
 // Container manages dependency injection
 type Container struct {
 	services map[string]interface{}
+	scopes   map[string]Scope
 }
 
 // NewContainer creates a new DI container
 func NewContainer() *Container {
 	return &Container{
 		services: make(map[string]interface{}),
+		scopes:   make(map[string]Scope),
 	}
 }
 
+// SetScope restricts the services injectable into targets of a type name,
+// e.g. to the providers visible from the module declaring them
+func (c *Container) SetScope(target string, scope Scope) {
+	c.scopes[target] = scope
+}
+
+// ServiceName returns the name a service is auto-registered under: its
+// type name, or the name of the type built by a factory
+func ServiceName(service interface{}) string {
+	serviceType := ServiceType(service)
+	if serviceType.Kind() == reflect.Ptr {
+		serviceType = serviceType.Elem()
+	}
+	return serviceType.Name()
+}
+
+// ServiceType returns the type of a service, or the type built by a factory
+func ServiceType(service interface{}) reflect.Type {
+	if f, ok := service.(factory); ok {
+		return f.productType()
+	}
+	return reflect.TypeOf(service)
+}
+
 // Register registers a service in the container
 func (c *Container) Register(name string, service interface{}) {
 	c.services[name] = service
 }
 
-// Get retrieves a service from the container
+// Get retrieves a service from the container, constructing lazy providers
+// on first use
 func (c *Container) Get(name string) (interface{}, bool) {
 	service, exists := c.services[name]
-	return service, exists
+	if !exists {
+		return nil, false
+	}
+
+	if f, ok := service.(factory); ok {
+		resolved, err := f.resolve(c)
+		if err != nil {
+			logger.Error("Failed to construct lazy provider", "name", name, "error", err)
+			return nil, false
+		}
+		return resolved, true
+	}
+	return service, true
 }
 
-// AutoRegister automatically registers a service based on its type
-func (c *Container) AutoRegister(service interface{}) {
-	serviceType := reflect.TypeOf(service)
-	if serviceType.Kind() == reflect.Ptr {
-		serviceType = serviceType.Elem()
+// Resolve retrieves a service like Get, reporting why it is unavailable
+func (c *Container) Resolve(name string) (interface{}, error) {
+	service, exists := c.services[name]
+	if !exists {
+		return nil, fmt.Errorf("service %s not found", name)
 	}
+	if f, ok := service.(factory); ok {
+		return f.resolve(c)
+	}
+	return service, nil
+}
 
-	serviceName := serviceType.Name()
+// AutoRegister automatically registers a service based on its type.
+// Factories are registered under the type they build.
+func (c *Container) AutoRegister(service interface{}) {
+	serviceName := ServiceName(service)
 	c.services[serviceName] = service
-	logger.Info("Service auto-registered", "name", serviceName, "type", serviceType.String())
+	logger.Info("Service auto-registered", "name", serviceName, "type", fmt.Sprintf("%T", service))
+}
+
+// InjectedNames returns the names of the services a target declares with
+// inject tags; for factories, the ones of the type they build
+func InjectedNames(target interface{}) []string {
+	targetType := ServiceType(target)
+	if targetType.Kind() == reflect.Ptr {
+		targetType = targetType.Elem()
+	}
+	if targetType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names := make([]string, 0)
+	for i := 0; i < targetType.NumField(); i++ {
+		if name := targetType.Field(i).Tag.Get(TagInject); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
 }
 
 // Inject injects dependencies into a target struct
@@ -49,21 +135,53 @@ func (c *Container) Inject(target interface{}) error {
 		return nil
 	}
 
+	// Lazy providers get their dependencies injected when constructed
+	if _, ok := target.(factory); ok {
+		return nil
+	}
+
 	targetValue = targetValue.Elem()
 	targetType := targetValue.Type()
 
 	logger.Info("Injecting dependencies", "target", targetType.Name())
 	c.DebugInjection(targetType.Name())
 
+	// Fail before injecting anything when the target depends on itself
+	if chain := c.findCycle(target, targetType, []string{targetType.Name()}, make(map[string]bool)); chain != nil {
+		err := &CircularDependencyError{Chain: chain}
+		logger.Error("Circular dependency detected", "target", targetType.Name(), "chain", strings.Join(chain, " -> "))
+		return err
+	}
+
 	var missingDependencies []string
+	scope := c.scopes[targetType.Name()]
 
 	for i := 0; i < targetValue.NumField(); i++ {
 		field := targetValue.Field(i)
 		fieldType := targetType.Field(i)
 
 		// Check if field has inject tag
-		if injectTag := fieldType.Tag.Get("inject"); injectTag != "" {
+		if injectTag := fieldType.Tag.Get(TagInject); injectTag != "" {
 			logger.Info("Found inject tag", "field", fieldType.Name, "injectTag", injectTag)
+			if scope != nil {
+				if err := scope(injectTag); err != nil {
+					logger.Error("Service not visible for injection", "service", injectTag, "target", targetType.Name(), "error", err)
+					missingDependencies = append(missingDependencies, fmt.Sprintf("service %s (%v)", injectTag, err))
+					continue
+				}
+			}
+			if binder, ok := lazyField(field); ok {
+				if _, exists := c.services[injectTag]; !exists {
+					logger.Error("Service not found for injection", "service", injectTag)
+					missingDependencies = append(missingDependencies, fmt.Sprintf("service %s (not found)", injectTag))
+					continue
+				}
+				name := injectTag
+				binder.bind(func() (interface{}, error) { return c.Resolve(name) })
+				logger.Info("Lazy dependency bound", "field", fieldType.Name, "service", injectTag)
+				continue
+			}
+
 			if service, exists := c.Get(injectTag); exists {
 				logger.Info("Service found for injection", "service", injectTag, "type", reflect.TypeOf(service))
 				if field.CanSet() {
@@ -91,9 +209,76 @@ func (c *Container) Inject(target interface{}) error {
 	return nil
 }
 
-// GetAllServices returns all registered services
+// findCycle follows the inject tags of a struct type through the registered
+// services and returns the dependency chain leading back to target, if any
+func (c *Container) findCycle(target interface{}, structType reflect.Type, chain []string, visited map[string]bool) []string {
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		dependency := structType.Field(i).Tag.Get(TagInject)
+		if dependency == "" || isLazyType(structType.Field(i).Type) {
+			// Lazy dependencies resolve after construction and break cycles
+			continue
+		}
+
+		service, exists := c.services[dependency]
+		if !exists || visited[dependency] {
+			continue
+		}
+		visited[dependency] = true
+
+		next := append(append([]string{}, chain...), dependency)
+		if service == target {
+			return next
+		}
+
+		serviceType := reflect.TypeOf(service)
+		if f, ok := service.(factory); ok {
+			serviceType = f.productType()
+		}
+		if cycle := c.findCycle(target, serviceType, next, visited); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// GetAllServices returns all registered services. Lazy providers not
+// constructed yet are returned as their factory.
 func (c *Container) GetAllServices() map[string]interface{} {
-	return c.services
+	services := make(map[string]interface{}, len(c.services))
+	for name, service := range c.services {
+		if f, ok := service.(factory); ok {
+			if resolved, built := f.resolved(); built {
+				service = resolved
+			}
+		}
+		services[name] = service
+	}
+	return services
+}
+
+// lazyField returns the Lazy of a field declared as Lazy[T] or *Lazy[T]
+func lazyField(field reflect.Value) (lazyBinder, bool) {
+	if field.Kind() == reflect.Ptr {
+		if !field.Type().Implements(lazyBinderType) || !field.CanSet() {
+			return nil, false
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		return field.Interface().(lazyBinder), true
+	}
+
+	if field.CanAddr() && field.Addr().Type().Implements(lazyBinderType) && field.CanSet() {
+		return field.Addr().Interface().(lazyBinder), true
+	}
+	return nil, false
 }
 
 // PrintServices prints all registered services
@@ -0,0 +1,122 @@
+package container
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// Factory is a provider constructed on first resolution instead of at
+// module registration, e.g. a connection pool or an external client. It is
+// registered under the type name of T and its product gets its inject
+// fields injected when built.
+type Factory[T any] struct {
+	build func() (T, error)
+	once  sync.Once
+	built atomic.Bool
+	value T
+	err   error
+}
+
+// NewFactory creates a lazy provider built by build
+//
+//	Providers: []interface{}{container.NewFactory(NewDatabasePool)}
+func NewFactory[T any](build func() (T, error)) *Factory[T] {
+	return &Factory[T]{build: build}
+}
+
+// factory is the untyped view of a Factory used by the container
+type factory interface {
+	productType() reflect.Type
+	resolve(c *Container) (interface{}, error)
+	resolved() (interface{}, bool)
+}
+
+// productType returns the type built by the factory
+func (f *Factory[T]) productType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// resolve builds the product once and injects its dependencies
+func (f *Factory[T]) resolve(c *Container) (interface{}, error) {
+	f.once.Do(func() {
+		logger.Info("Constructing lazy provider", "type", f.productType().String())
+		f.value, f.err = f.build()
+		if f.err == nil {
+			f.err = c.Inject(f.value)
+		}
+		f.built.Store(f.err == nil)
+	})
+	return f.value, f.err
+}
+
+// resolved returns the product when it has been built
+func (f *Factory[T]) resolved() (interface{}, bool) {
+	if !f.built.Load() {
+		return nil, false
+	}
+	return f.value, true
+}
+
+// Lazy defers the resolution of an injected provider to its first use.
+// Declare the field with the usual inject tag:
+//
+//	Payments container.Lazy[*PaymentClient] `inject:"PaymentClient"`
+type Lazy[T any] struct {
+	resolve func() (interface{}, error)
+	once    sync.Once
+	value   T
+	err     error
+}
+
+// lazyBinder is the untyped view of a Lazy used by the container
+type lazyBinder interface {
+	bind(resolve func() (interface{}, error))
+}
+
+var lazyBinderType = reflect.TypeOf((*lazyBinder)(nil)).Elem()
+
+// isLazyType reports whether a field type is Lazy[T] or *Lazy[T]
+func isLazyType(t reflect.Type) bool {
+	return t.Implements(lazyBinderType) || reflect.PointerTo(t).Implements(lazyBinderType)
+}
+
+// bind sets the resolution of the lazy value
+func (l *Lazy[T]) bind(resolve func() (interface{}, error)) {
+	l.resolve = resolve
+}
+
+// Get resolves the provider on first call and returns it
+func (l *Lazy[T]) Get() (T, error) {
+	l.once.Do(func() {
+		if l.resolve == nil {
+			l.err = fmt.Errorf("container: lazy %s was not injected", reflect.TypeOf((*T)(nil)).Elem())
+			return
+		}
+
+		resolved, err := l.resolve()
+		if err != nil {
+			l.err = err
+			return
+		}
+		value, ok := resolved.(T)
+		if !ok {
+			l.err = fmt.Errorf("container: provider %T is not a %s", resolved, reflect.TypeOf((*T)(nil)).Elem())
+			return
+		}
+		l.value = value
+	})
+	return l.value, l.err
+}
+
+// MustGet resolves the provider like Get, panicking on failure
+func (l *Lazy[T]) MustGet() T {
+	value, err := l.Get()
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 
+	"github.com/kevenmiano/nestgo/pkg/clientinfo"
 	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
 )
 
 const (
@@ -18,6 +21,9 @@ const (
 	TagJSON     = "json"
 	TagBaseURL  = "baseUrl"
 	TagHTTP     = "http"
+	TagQuery    = "query"
+	TagDefault  = "default"
+	TagCache    = "cache"
 
 	// Tag values
 	TagValueTrue = "true"
@@ -36,6 +42,9 @@ type BaseController struct {
 	// HTTP context (will be injected by the framework)
 	ResponseWriter http.ResponseWriter
 	Request        *http.Request
+
+	// Routes declared on methods with Route
+	methodRoutes []MethodRoute
 }
 
 // Controller interface defines the contract for all controllers
@@ -89,7 +98,12 @@ func (bc *BaseController) JSON(data interface{}) {
 		return
 	}
 
-	logger.Info("BaseController.JSON() called", "data", data)
+	if written(bc.ResponseWriter) {
+		logger.Warn("Response already written, discarding JSON", "path", bc.Request.URL.Path)
+		return
+	}
+
+	logger.Debug("BaseController.JSON() called", "data", data)
 	bc.ResponseWriter.Header().Set("Content-Type", "application/json")
 
 	jsonData, err := json.Marshal(data)
@@ -99,7 +113,7 @@ func (bc *BaseController) JSON(data interface{}) {
 		return
 	}
 
-	logger.Info("Writing JSON response", "jsonData", string(jsonData))
+	logger.Debug("Writing JSON response", "jsonData", string(jsonData))
 	bc.ResponseWriter.Write(jsonData)
 }
 
@@ -108,6 +122,10 @@ func (bc *BaseController) JSONWithStatus(statusCode int, data interface{}) {
 	if bc.ResponseWriter == nil {
 		return
 	}
+	if written(bc.ResponseWriter) {
+		logger.Warn("Response already written, discarding JSON", "path", bc.Request.URL.Path, "status", statusCode)
+		return
+	}
 
 	bc.ResponseWriter.Header().Set("Content-Type", "application/json")
 	bc.ResponseWriter.WriteHeader(statusCode)
@@ -123,10 +141,57 @@ func (bc *BaseController) JSONWithStatus(statusCode int, data interface{}) {
 
 // SetHTTPContext sets the HTTP context for the controller
 func (bc *BaseController) SetHTTPContext(w http.ResponseWriter, r *http.Request) {
-	logger.Info("BaseController.SetHTTPContext called", "responseWriter", w != nil, "request", r != nil)
+	logger.Debug("BaseController.SetHTTPContext called", "responseWriter", w != nil, "request", r != nil)
 	bc.ResponseWriter = w
 	bc.Request = r
-	logger.Info("HTTP context set successfully", "responseWriter", bc.ResponseWriter != nil)
+	logger.Debug("HTTP context set successfully", "responseWriter", bc.ResponseWriter != nil)
+}
+
+// ClientInfo returns the enriched client information for the current request
+func (bc *BaseController) ClientInfo() (*clientinfo.Info, bool) {
+	return clientinfo.FromRequest(bc.Request)
+}
+
+// ClientIP returns the real client IP, honoring the trusted proxy configuration
+func (bc *BaseController) ClientIP() string {
+	if bc.Request == nil {
+		return ""
+	}
+	return realip.ClientIP(bc.Request)
+}
+
+// QueryString returns a query parameter of the current request, or def when it is missing
+func (bc *BaseController) QueryString(name, def string) string {
+	if bc.Request == nil || !bc.Request.URL.Query().Has(name) {
+		return def
+	}
+	return bc.Request.URL.Query().Get(name)
+}
+
+// QueryInt returns a query parameter as an int, or def when it is missing or not a number
+func (bc *BaseController) QueryInt(name string, def int) int {
+	value, err := strconv.Atoi(bc.QueryString(name, ""))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// QueryBool returns a query parameter as a bool, or def when it is missing or not a boolean.
+// A parameter without value, e.g. ?active, is true.
+func (bc *BaseController) QueryBool(name string, def bool) bool {
+	if bc.Request == nil || !bc.Request.URL.Query().Has(name) {
+		return def
+	}
+	raw := bc.Request.URL.Query().Get(name)
+	if raw == "" {
+		return true
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return def
+	}
+	return value
 }
 
 // MetaExtractor extracts metadata from structs using reflection
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"reflect"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RouteBinder is implemented by controllers assigning their route fields to
+// their handler methods and declaring their method routes, typically with
+// BindRoutes methods generated by "nestgo bind". Controllers are bound when
+// registered, so factories no longer assign every route field.
+type RouteBinder interface {
+	BindRoutes()
+}
+
+// bound holds the controllers already bound, since BindRoutes may declare
+// method routes that must not be declared twice
+var bound sync.Map
+
+// BindRoutes binds the routes of a controller implementing RouteBinder,
+// once per controller
+func BindRoutes(controller interface{}) {
+	binder, ok := controller.(RouteBinder)
+	if !ok {
+		return
+	}
+	if reflect.ValueOf(controller).Kind() == reflect.Ptr {
+		if _, loaded := bound.LoadOrStore(controller, true); loaded {
+			return
+		}
+	}
+	binder.BindRoutes()
+}
+
+// RouteMethodProvider is implemented by controllers naming the exported
+// method each route field is bound to, typically with RouteMethods methods
+// generated by "nestgo bind". The server calls those methods on a
+// per-request copy of the controller, so the HTTP context of BaseController
+// is never shared between requests.
+type RouteMethodProvider interface {
+	// RouteMethods maps route field names to method names
+	RouteMethods() map[string]string
+}
+
+// HandlerMethodName returns the name of the method a route field is bound
+// to by convention, e.g. GetUsersHandler for GetUsers
+func HandlerMethodName(field string) string {
+	first, size := utf8.DecodeRuneInString(field)
+	if first == utf8.RuneError {
+		return ""
+	}
+	return string(unicode.ToUpper(first)) + field[size:] + "Handler"
+}
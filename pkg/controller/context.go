@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/guard"
+)
+
+// Context is the request a handler serves, passed to handlers declaring a
+// *controller.Context argument. Unlike the fields of BaseController it
+// belongs to a single request, so handlers using it are safe on shared
+// controllers. It holds its own copy of the path parameters and may be kept
+// past the handler, e.g. by a goroutine it started, but its Writer must not
+// be used once the handler returns, as with any http.ResponseWriter.
+//
+//	GetUser func(ctx *controller.Context, id int) `route:"GET /:id"`
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	params  map[string]string
+}
+
+// NewContext creates the Context of a request with a copy of its path
+// parameters
+func NewContext(w http.ResponseWriter, r *http.Request, params map[string]string) *Context {
+	copied := make(map[string]string, len(params))
+	for name, value := range params {
+		copied[name] = value
+	}
+	return &Context{Writer: w, Request: r, params: copied}
+}
+
+// Context returns the context of the request
+func (c *Context) Context() context.Context {
+	return c.Request.Context()
+}
+
+// Param returns a path parameter of the route
+func (c *Context) Param(name string) string {
+	return c.params[name]
+}
+
+// Query returns a query parameter, or "" when absent
+func (c *Context) Query(name string) string {
+	return c.Request.URL.Query().Get(name)
+}
+
+// Header returns the response headers
+func (c *Context) Header() http.Header {
+	return c.Writer.Header()
+}
+
+// Route returns the route serving the request, with its name, pattern,
+// controller and tags
+func (c *Context) Route() (guard.Route, bool) {
+	return guard.RouteFromRequest(c.Request)
+}
+
+// Local returns a value stashed for the request, e.g. by a middleware
+func (c *Context) Local(key string) (interface{}, bool) {
+	return Local(c.Request, key)
+}
+
+// SetLocal stashes a value for the rest of the request
+func (c *Context) SetLocal(key string, value interface{}) {
+	c.Request = WithLocal(c.Request, key, value)
+}
+
+// JSON writes a JSON response with a status code. It returns
+// ErrResponseWritten when the handler already wrote a response.
+func (c *Context) JSON(status int, data interface{}) error {
+	if written(c.Writer) {
+		return ErrResponseWritten
+	}
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	c.Writer.Header().Set("Content-Type", "application/json")
+	c.Writer.WriteHeader(status)
+	_, err = c.Writer.Write(jsonData)
+	return err
+}
+
+// localsKey is the key used to store the request locals in the request context
+type localsKey struct{}
+
+// locals are the values stashed for a request
+type locals struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// WithLocal stashes a value for the handler serving r. Middlewares pass the
+// returned request on; later middlewares and the handler read it with Local
+// or Context.Local.
+func WithLocal(r *http.Request, key string, value interface{}) *http.Request {
+	stored, ok := r.Context().Value(localsKey{}).(*locals)
+	if !ok {
+		stored = &locals{values: make(map[string]interface{})}
+		r = r.WithContext(context.WithValue(r.Context(), localsKey{}, stored))
+	}
+
+	stored.mu.Lock()
+	defer stored.mu.Unlock()
+	stored.values[key] = value
+	return r
+}
+
+// Local returns a value stashed with WithLocal
+func Local(r *http.Request, key string) (interface{}, bool) {
+	stored, ok := r.Context().Value(localsKey{}).(*locals)
+	if !ok {
+		return nil, false
+	}
+
+	stored.mu.RLock()
+	defer stored.mu.RUnlock()
+	value, ok := stored.values[key]
+	return value, ok
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cache-Control directives for CacheControl
+const (
+	CachePublic         = "public"
+	CachePrivate        = "private"
+	CacheNoCache        = "no-cache"
+	CacheNoStore        = "no-store"
+	CacheMustRevalidate = "must-revalidate"
+	CacheImmutable      = "immutable"
+)
+
+// NoMaxAge leaves max-age out of a Cache-Control header
+const NoMaxAge time.Duration = -1
+
+// SetCacheControl sets the Cache-Control header from directives and a
+// max-age, e.g. "public, max-age=300". A negative maxAge omits max-age.
+func SetCacheControl(header http.Header, maxAge time.Duration, directives ...string) {
+	values := append([]string{}, directives...)
+	if maxAge >= 0 {
+		values = append(values, "max-age="+strconv.FormatInt(int64(maxAge/time.Second), 10))
+	}
+	header.Set("Cache-Control", strings.Join(values, ", "))
+}
+
+// AddVary adds request headers to the Vary header, keeping each once. A
+// "*" replaces every other value.
+func AddVary(header http.Header, names ...string) {
+	existing := make([]string, 0)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				existing = append(existing, name)
+			}
+		}
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || containsFold(existing, name) {
+			continue
+		}
+		existing = append(existing, http.CanonicalHeaderKey(name))
+	}
+
+	if containsFold(existing, "*") {
+		existing = []string{"*"}
+	}
+	if len(existing) > 0 {
+		header.Set("Vary", strings.Join(existing, ", "))
+	}
+}
+
+// containsFold reports whether values contains value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetHeader sets a response header of the current request
+func (bc *BaseController) SetHeader(name, value string) {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	bc.ResponseWriter.Header().Set(name, value)
+}
+
+// CacheControl sets the Cache-Control header of the current request,
+// e.g. bc.CacheControl(5*time.Minute, controller.CachePublic)
+func (bc *BaseController) CacheControl(maxAge time.Duration, directives ...string) {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	SetCacheControl(bc.ResponseWriter.Header(), maxAge, directives...)
+}
+
+// NoStore forbids caching the response of the current request
+func (bc *BaseController) NoStore() {
+	bc.CacheControl(NoMaxAge, CacheNoStore)
+}
+
+// Vary adds request headers the response of the current request depends on
+func (bc *BaseController) Vary(names ...string) {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	AddVary(bc.ResponseWriter.Header(), names...)
+}
+
+// WithHeader returns a copy of the response with a header set
+func (r Response) WithHeader(name, value string) Response {
+	r.Headers = r.cloneHeaders()
+	r.Headers.Set(name, value)
+	return r
+}
+
+// WithCacheControl returns a copy of the response with a Cache-Control header
+func (r Response) WithCacheControl(maxAge time.Duration, directives ...string) Response {
+	r.Headers = r.cloneHeaders()
+	SetCacheControl(r.Headers, maxAge, directives...)
+	return r
+}
+
+// WithVary returns a copy of the response with request headers added to Vary
+func (r Response) WithVary(names ...string) Response {
+	r.Headers = r.cloneHeaders()
+	AddVary(r.Headers, names...)
+	return r
+}
+
+// cloneHeaders copies the headers so derived responses do not share them
+func (r Response) cloneHeaders() http.Header {
+	if r.Headers == nil {
+		return make(http.Header)
+	}
+	return r.Headers.Clone()
+}
@@ -0,0 +1,101 @@
+package controller
+
+import "strings"
+
+const (
+	// DefaultPageLimit is the page size of a Pagination without a limit
+	DefaultPageLimit = 20
+	// MaxPageLimit is the largest page size a client may request
+	MaxPageLimit = 100
+)
+
+// Pagination is the page a list route is asked for, bound from the query
+// string like any query argument, alone or embedded in a query struct:
+//
+//	?page=2&limit=50&sort=-createdAt,name&filter[status]=active
+//
+//	ListUsers func(page controller.Pagination) (controller.PaginatedResponse[User], error) `route:"GET /"`
+type Pagination struct {
+	Page    int               `query:"page" default:"1" validate:"min=1" desc:"Page number, starting at 1"`
+	Limit   int               `query:"limit" default:"20" validate:"min=1,max=100" desc:"Items per page"`
+	Sort    []string          `query:"sort" desc:"Fields to sort by, descending when prefixed with -"`
+	Filters map[string]string `query:"filter" desc:"Filters by field, e.g. filter[status]=active"`
+}
+
+// SortField is a field of the sort parameter
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// PageNumber returns the requested page, 1 when unset
+func (p Pagination) PageNumber() int {
+	if p.Page < 1 {
+		return 1
+	}
+	return p.Page
+}
+
+// PageLimit returns the requested page size, DefaultPageLimit when unset
+// and at most MaxPageLimit
+func (p Pagination) PageLimit() int {
+	switch {
+	case p.Limit < 1:
+		return DefaultPageLimit
+	case p.Limit > MaxPageLimit:
+		return MaxPageLimit
+	}
+	return p.Limit
+}
+
+// Offset returns the number of items before the page, for OFFSET clauses
+func (p Pagination) Offset() int {
+	return (p.PageNumber() - 1) * p.PageLimit()
+}
+
+// SortFields parses the sort parameter, e.g. -createdAt sorts by createdAt
+// descending
+func (p Pagination) SortFields() []SortField {
+	fields := make([]SortField, 0, len(p.Sort))
+	for _, field := range p.Sort {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimLeft(field, "+-")
+		if field != "" {
+			fields = append(fields, SortField{Field: field, Desc: desc})
+		}
+	}
+	return fields
+}
+
+// Filter returns the value of a filter[name] parameter
+func (p Pagination) Filter(name string) (string, bool) {
+	value, ok := p.Filters[name]
+	return value, ok
+}
+
+// PaginatedResponse is a page of items with the metadata clients need to
+// request the others
+type PaginatedResponse[T any] struct {
+	Data  []T `json:"data"`
+	Page  int `json:"page"`
+	Limit int `json:"limit"`
+	Total int `json:"total"`
+	Pages int `json:"pages"`
+}
+
+// Paginate wraps the items of a page with the total number of items across
+// all pages
+func Paginate[T any](items []T, total int, page Pagination) PaginatedResponse[T] {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	limit := page.PageLimit()
+	return PaginatedResponse[T]{
+		Data:  items,
+		Page:  page.PageNumber(),
+		Limit: limit,
+		Total: total,
+		Pages: (total + limit - 1) / limit,
+	}
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrResponseWritten is returned when writing a response after the handler
+// already wrote one
+var ErrResponseWritten = errors.New("response already written")
+
+// WriteTracker is implemented by the response writers handlers receive. It
+// reports whether the response started, so writing a second one can be
+// skipped instead of appending a JSON document to the first.
+type WriteTracker interface {
+	Written() bool
+}
+
+// written reports whether a response was already written to w
+func written(w http.ResponseWriter) bool {
+	tracker, ok := w.(WriteTracker)
+	return ok && tracker.Written()
+}
+
+// StatusSetter is implemented by the response writers handlers receive. It
+// holds the status of the value returned by the handler until it is written.
+type StatusSetter interface {
+	SetStatus(code int)
+}
+
+// setStatus sets the status of the returned value, or writes it right away
+// when the writer cannot hold it
+func setStatus(w http.ResponseWriter, code int) {
+	if setter, ok := w.(StatusSetter); ok {
+		setter.SetStatus(code)
+		return
+	}
+	w.WriteHeader(code)
+}
+
+// redirect answers a redirect, 302 Found when code is zero
+func redirect(w http.ResponseWriter, r *http.Request, url string, code int) {
+	if code == 0 {
+		code = http.StatusFound
+	}
+	http.Redirect(w, r, url, code)
+}
+
+// stream copies reader to the response, flushing after every chunk so
+// clients receive the data as it is produced
+func stream(w http.ResponseWriter, reader io.Reader, contentType string) error {
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(buffer)
+		if n > 0 {
+			if _, writeErr := w.Write(buffer[:n]); writeErr != nil {
+				return writeErr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Status sets the status code of the value the handler returns, e.g. 202
+// for an accepted job. Explicit Response statuses and errors take precedence.
+func (bc *BaseController) Status(code int) {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	setStatus(bc.ResponseWriter, code)
+}
+
+// Redirect redirects the current request, with 302 Found when code is zero
+func (bc *BaseController) Redirect(url string, code int) {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	redirect(bc.ResponseWriter, bc.Request, url, code)
+}
+
+// SendFile writes a file with its content type, honoring Range and
+// conditional requests. The path must not come unchecked from the request.
+func (bc *BaseController) SendFile(path string) {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	http.ServeFile(bc.ResponseWriter, bc.Request, path)
+}
+
+// NoContent answers 204 No Content
+func (bc *BaseController) NoContent() {
+	if bc.ResponseWriter == nil {
+		return
+	}
+	bc.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// Stream copies reader to the response as it is read
+func (bc *BaseController) Stream(reader io.Reader, contentType string) error {
+	if bc.ResponseWriter == nil {
+		return nil
+	}
+	return stream(bc.ResponseWriter, reader, contentType)
+}
+
+// Status sets the status code of the value the handler returns
+func (c *Context) Status(code int) {
+	setStatus(c.Writer, code)
+}
+
+// Redirect redirects the request, with 302 Found when code is zero
+func (c *Context) Redirect(url string, code int) {
+	redirect(c.Writer, c.Request, url, code)
+}
+
+// SendFile writes a file with its content type, see BaseController.SendFile
+func (c *Context) SendFile(path string) {
+	http.ServeFile(c.Writer, c.Request, path)
+}
+
+// NoContent answers 204 No Content
+func (c *Context) NoContent() {
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// Stream copies reader to the response as it is read
+func (c *Context) Stream(reader io.Reader, contentType string) error {
+	return stream(c.Writer, reader, contentType)
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+)
+
+// Response lets a route handler choose the status code and headers of the
+// value it returns instead of writing through the BaseController
+type Response struct {
+	// Status is the HTTP status code; zero uses the method default
+	Status int
+	// Headers are added to the response before the body is written
+	Headers http.Header
+	// Body is serialized as JSON; nil writes no body
+	Body interface{}
+}
+
+// HTTPError is an error carrying the HTTP status code it maps to when
+// returned from a route handler
+type HTTPError struct {
+	Status  int
+	Message string
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status code of the error
+func (e *HTTPError) StatusCode() int {
+	return e.Status
+}
+
+// NewHTTPError creates an error mapped to the given status code
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Message: message}
+}
+
+// BadRequest creates a 400 error
+func BadRequest(message string) *HTTPError {
+	return NewHTTPError(http.StatusBadRequest, message)
+}
+
+// Unauthorized creates a 401 error
+func Unauthorized(message string) *HTTPError {
+	return NewHTTPError(http.StatusUnauthorized, message)
+}
+
+// Forbidden creates a 403 error
+func Forbidden(message string) *HTTPError {
+	return NewHTTPError(http.StatusForbidden, message)
+}
+
+// NotFound creates a 404 error
+func NotFound(message string) *HTTPError {
+	return NewHTTPError(http.StatusNotFound, message)
+}
+
+// Conflict creates a 409 error
+func Conflict(message string) *HTTPError {
+	return NewHTTPError(http.StatusConflict, message)
+}
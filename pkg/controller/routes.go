@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// MethodRoute is a route declared with BaseController.Route
+type MethodRoute struct {
+	// Route is "METHOD /path", as in route tags
+	Route string
+	// Name is the name of the handler method
+	Name string
+	// Handler is the function serving the route, nil when the route names an
+	// exported method of the controller
+	Handler interface{}
+	// Tag holds the tags a route field would declare, e.g. guards or pipes
+	Tag reflect.StructTag
+}
+
+// MethodRouter is implemented by controllers declaring routes on methods
+type MethodRouter interface {
+	MethodRoutes() []MethodRoute
+}
+
+// Route declares a route served by a controller method, as an alternative
+// to route fields, typically from the controller constructor:
+//
+//	c.Route("GET /:id", "GetUser", `guards:"auth" pipes:"id=int"`)
+//
+// The handler is the name of an exported method of the controller or a
+// method value such as c.GetUser, resolved to its method when registered.
+// Methods are called on a per-request copy of the controller so the HTTP
+// context of BaseController is never shared between requests. Other
+// functions, such as closures, run on the shared controller one request at
+// a time unless they take a *Context. The handler accepts the same
+// signatures as route fields, and tags apply as if declared on a route
+// field.
+func (bc *BaseController) Route(route string, handler interface{}, tags ...string) {
+	methodRoute := MethodRoute{
+		Route: route,
+		Tag:   reflect.StructTag(strings.Join(tags, " ")),
+	}
+	if name, ok := handler.(string); ok {
+		methodRoute.Name = name
+	} else {
+		methodRoute.Name = handlerName(handler)
+		methodRoute.Handler = handler
+	}
+	bc.methodRoutes = append(bc.methodRoutes, methodRoute)
+}
+
+// MethodRoutes returns the routes declared with Route
+func (bc *BaseController) MethodRoutes() []MethodRoute {
+	return bc.methodRoutes
+}
+
+// handlerName returns the name of a function for logs and route listings,
+// e.g. serve for handler.serve
+func handlerName(handler interface{}) string {
+	value := reflect.ValueOf(handler)
+	if value.Kind() != reflect.Func {
+		return ""
+	}
+	fn := runtime.FuncForPC(value.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
@@ -0,0 +1,259 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Placeholder is the bind parameter style of a SQL dialect
+type Placeholder int
+
+const (
+	// PlaceholderQuestion uses ? (MySQL, SQLite)
+	PlaceholderQuestion Placeholder = iota
+	// PlaceholderDollar uses $1, $2, ... (PostgreSQL)
+	PlaceholderDollar
+)
+
+// QueryBuilder creates SQL statements for a placeholder style. It holds no
+// connection and can be registered as a provider and injected in services.
+type QueryBuilder struct {
+	placeholder Placeholder
+}
+
+// NewQueryBuilder creates a query builder for the placeholder style
+func NewQueryBuilder(placeholder Placeholder) *QueryBuilder {
+	return &QueryBuilder{placeholder: placeholder}
+}
+
+// Select starts a SELECT statement
+func (qb *QueryBuilder) Select(columns ...string) *SelectBuilder {
+	if len(columns) == 0 {
+		columns = []string{"*"}
+	}
+	return &SelectBuilder{placeholder: qb.placeholder, columns: columns}
+}
+
+// Insert starts an INSERT statement
+func (qb *QueryBuilder) Insert(table string) *InsertBuilder {
+	return &InsertBuilder{placeholder: qb.placeholder, table: table}
+}
+
+// Update starts an UPDATE statement
+func (qb *QueryBuilder) Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{placeholder: qb.placeholder, table: table}
+}
+
+// Delete starts a DELETE statement
+func (qb *QueryBuilder) Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{placeholder: qb.placeholder, table: table}
+}
+
+// condition is a WHERE clause fragment written with ? placeholders
+type condition struct {
+	expr string
+	args []interface{}
+}
+
+// whereClause collects conditions joined with AND
+type whereClause struct {
+	conditions []condition
+}
+
+// add appends a condition written with ? placeholders
+func (wc *whereClause) add(expr string, args []interface{}) {
+	wc.conditions = append(wc.conditions, condition{expr: expr, args: args})
+}
+
+// write appends the WHERE clause to the statement
+func (wc *whereClause) write(sb *strings.Builder, args []interface{}) []interface{} {
+	for i, cond := range wc.conditions {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString("(" + cond.expr + ")")
+		args = append(args, cond.args...)
+	}
+	return args
+}
+
+// SelectBuilder builds a SELECT statement
+type SelectBuilder struct {
+	placeholder Placeholder
+	columns     []string
+	table       string
+	where       whereClause
+	orderBy     []string
+	limit       int
+	offset      int
+}
+
+// From sets the table
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds a condition written with ? placeholders, joined with AND
+func (b *SelectBuilder) Where(expr string, args ...interface{}) *SelectBuilder {
+	b.where.add(expr, args)
+	return b
+}
+
+// OrderBy adds ORDER BY expressions, e.g. "created_at DESC"
+func (b *SelectBuilder) OrderBy(expressions ...string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, expressions...)
+	return b
+}
+
+// Limit sets the maximum number of rows; zero means no limit
+func (b *SelectBuilder) Limit(limit int) *SelectBuilder {
+	b.limit = limit
+	return b
+}
+
+// Offset sets the number of rows to skip
+func (b *SelectBuilder) Offset(offset int) *SelectBuilder {
+	b.offset = offset
+	return b
+}
+
+// Build returns the statement and its arguments
+func (b *SelectBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	args := make([]interface{}, 0)
+
+	sb.WriteString("SELECT " + strings.Join(b.columns, ", ") + " FROM " + b.table)
+	args = b.where.write(&sb, args)
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	if b.limit > 0 {
+		sb.WriteString(" LIMIT " + strconv.Itoa(b.limit))
+	}
+	if b.offset > 0 {
+		sb.WriteString(" OFFSET " + strconv.Itoa(b.offset))
+	}
+
+	return rebind(b.placeholder, sb.String()), args
+}
+
+// InsertBuilder builds an INSERT statement
+type InsertBuilder struct {
+	placeholder Placeholder
+	table       string
+	columns     []string
+	values      []interface{}
+	returning   []string
+}
+
+// Set adds a column value
+func (b *InsertBuilder) Set(column string, value interface{}) *InsertBuilder {
+	b.columns = append(b.columns, column)
+	b.values = append(b.values, value)
+	return b
+}
+
+// Returning adds a RETURNING clause, for dialects supporting it
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build returns the statement and its arguments
+func (b *InsertBuilder) Build() (string, []interface{}) {
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(b.columns)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", b.table, strings.Join(b.columns, ", "), marks)
+	if len(b.returning) > 0 {
+		query += " RETURNING " + strings.Join(b.returning, ", ")
+	}
+
+	return rebind(b.placeholder, query), append([]interface{}{}, b.values...)
+}
+
+// UpdateBuilder builds an UPDATE statement
+type UpdateBuilder struct {
+	placeholder Placeholder
+	table       string
+	columns     []string
+	values      []interface{}
+	where       whereClause
+}
+
+// Set adds a column value
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.columns = append(b.columns, column)
+	b.values = append(b.values, value)
+	return b
+}
+
+// Where adds a condition written with ? placeholders, joined with AND
+func (b *UpdateBuilder) Where(expr string, args ...interface{}) *UpdateBuilder {
+	b.where.add(expr, args)
+	return b
+}
+
+// Build returns the statement and its arguments
+func (b *UpdateBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	args := append([]interface{}{}, b.values...)
+
+	assignments := make([]string, len(b.columns))
+	for i, column := range b.columns {
+		assignments[i] = column + " = ?"
+	}
+
+	sb.WriteString("UPDATE " + b.table + " SET " + strings.Join(assignments, ", "))
+	args = b.where.write(&sb, args)
+
+	return rebind(b.placeholder, sb.String()), args
+}
+
+// DeleteBuilder builds a DELETE statement
+type DeleteBuilder struct {
+	placeholder Placeholder
+	table       string
+	where       whereClause
+}
+
+// Where adds a condition written with ? placeholders, joined with AND
+func (b *DeleteBuilder) Where(expr string, args ...interface{}) *DeleteBuilder {
+	b.where.add(expr, args)
+	return b
+}
+
+// Build returns the statement and its arguments
+func (b *DeleteBuilder) Build() (string, []interface{}) {
+	var sb strings.Builder
+	sb.WriteString("DELETE FROM " + b.table)
+	args := b.where.write(&sb, make([]interface{}, 0))
+
+	return rebind(b.placeholder, sb.String()), args
+}
+
+// rebind rewrites ? placeholders for the dialect, skipping quoted strings
+func rebind(placeholder Placeholder, query string) string {
+	if placeholder != PlaceholderDollar {
+		return query
+	}
+
+	var sb strings.Builder
+	position := 0
+	inQuote := false
+	for _, char := range query {
+		switch {
+		case char == '\'':
+			inQuote = !inQuote
+			sb.WriteRune(char)
+		case char == '?' && !inQuote:
+			position++
+			sb.WriteString("$" + strconv.Itoa(position))
+		default:
+			sb.WriteRune(char)
+		}
+	}
+	return sb.String()
+}
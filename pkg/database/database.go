@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// DefaultLagCheckInterval is how often replica lag is measured
+const DefaultLagCheckInterval = 5 * time.Second
+
+// LagFunc measures the replication lag of a replica, e.g. by comparing the
+// last replayed transaction timestamp with the current time
+type LagFunc func(ctx context.Context, replica *sql.DB) (time.Duration, error)
+
+// Options configures a DB
+type Options struct {
+	// Primary receives every write and transaction
+	Primary *sql.DB
+	// Replicas serve reads; without replicas every query goes to the primary
+	Replicas []*sql.DB
+	// MaxReplicaLag excludes replicas lagging behind more than this; zero
+	// disables lag checks
+	MaxReplicaLag time.Duration
+	// LagFunc measures replica lag; required when MaxReplicaLag is set
+	LagFunc LagFunc
+	// LagCheckInterval defaults to DefaultLagCheckInterval
+	LagCheckInterval time.Duration
+	// SlowQueryThreshold logs queries taking longer than this, with their
+	// parameters redacted; zero disables slow query logging
+	SlowQueryThreshold time.Duration
+	// StatsInterval logs pool statistics periodically; zero disables it
+	StatsInterval time.Duration
+	// Observer is notified of every query, e.g. to annotate tracing spans
+	Observer QueryObserver
+	// Placeholder is the bind parameter style of statements built by
+	// Insert, Update and Delete; defaults to PlaceholderQuestion
+	Placeholder Placeholder
+}
+
+// replica is a read connection pool with its last known health
+type replica struct {
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// DB routes queries between a primary and its read replicas. Writes and
+// transactions always use the primary; reads are balanced across replicas
+// within the lag budget and fall back to the primary when none qualifies.
+// Routing can be overridden per query with context hints.
+type DB struct {
+	primary  *sql.DB
+	replicas []*replica
+	options  Options
+	next     atomic.Uint64
+	stop     chan struct{}
+	once     sync.Once
+	hooks    map[Stage][]Hook
+	hooksMu  sync.RWMutex
+}
+
+// New creates a DB and starts monitoring replica lag when configured
+func New(options Options) (*DB, error) {
+	if options.Primary == nil {
+		return nil, fmt.Errorf("database: primary connection is required")
+	}
+	if options.MaxReplicaLag > 0 && options.LagFunc == nil {
+		return nil, fmt.Errorf("database: LagFunc is required when MaxReplicaLag is set")
+	}
+	if options.LagCheckInterval <= 0 {
+		options.LagCheckInterval = DefaultLagCheckInterval
+	}
+
+	db := &DB{
+		primary:  options.Primary,
+		replicas: make([]*replica, 0, len(options.Replicas)),
+		options:  options,
+		stop:     make(chan struct{}),
+		hooks:    make(map[Stage][]Hook),
+	}
+	for _, conn := range options.Replicas {
+		r := &replica{db: conn}
+		r.healthy.Store(true)
+		db.replicas = append(db.replicas, r)
+	}
+
+	if options.MaxReplicaLag > 0 && len(db.replicas) > 0 {
+		db.checkLag()
+		go db.monitor()
+	}
+	if options.StatsInterval > 0 {
+		go db.logStats()
+	}
+
+	logger.Info("Database configured", "replicas", len(db.replicas), "maxReplicaLag", options.MaxReplicaLag)
+	return db, nil
+}
+
+// Primary returns the primary connection pool
+func (db *DB) Primary() *sql.DB {
+	return db.primary
+}
+
+// Reader returns the pool a read should use for the context hints
+func (db *DB) Reader(ctx context.Context) *sql.DB {
+	conn, _ := db.reader(ctx)
+	return conn
+}
+
+// reader returns the pool for a read along with its role
+func (db *DB) reader(ctx context.Context) (*sql.DB, string) {
+	if targetFromContext(ctx) == TargetPrimary {
+		return db.primary, RolePrimary
+	}
+
+	if r := db.pickReplica(); r != nil {
+		return r.db, RoleReplica
+	}
+	return db.primary, RolePrimary
+}
+
+// Writer returns the pool writes use, always the primary
+func (db *DB) Writer(ctx context.Context) *sql.DB {
+	return db.primary
+}
+
+// QueryContext runs a read query on a replica unless hinted otherwise, or in
+// the transaction of the unit of work running in ctx
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	if tx, ok := TxFromContext(ctx); ok {
+		rows, err := tx.QueryContext(ctx, query, args...)
+		db.observe(ctx, RolePrimary, query, args, start, err)
+		return rows, err
+	}
+
+	conn, role := db.reader(ctx)
+	rows, err := conn.QueryContext(ctx, query, args...)
+	db.observe(ctx, role, query, args, start, err)
+	return rows, err
+}
+
+// QueryRowContext runs a single-row read query on a replica unless hinted
+// otherwise, or in the transaction of the unit of work running in ctx
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	if tx, ok := TxFromContext(ctx); ok {
+		row := tx.QueryRowContext(ctx, query, args...)
+		db.observe(ctx, RolePrimary, query, args, start, row.Err())
+		return row
+	}
+
+	conn, role := db.reader(ctx)
+	row := conn.QueryRowContext(ctx, query, args...)
+	db.observe(ctx, role, query, args, start, row.Err())
+	return row
+}
+
+// ExecContext runs a write statement on the primary, or in the transaction
+// of the unit of work running in ctx
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	if tx, ok := TxFromContext(ctx); ok {
+		result, err := tx.ExecContext(ctx, query, args...)
+		db.observe(ctx, RolePrimary, query, args, start, err)
+		return result, err
+	}
+
+	result, err := db.Writer(ctx).ExecContext(ctx, query, args...)
+	db.observe(ctx, RolePrimary, query, args, start, err)
+	return result, err
+}
+
+// BeginTx starts a transaction on the primary
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return db.Writer(ctx).BeginTx(ctx, opts)
+}
+
+// Close stops lag monitoring and closes every connection pool
+func (db *DB) Close() error {
+	db.once.Do(func() { close(db.stop) })
+
+	var firstErr error
+	for _, r := range db.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if err := db.primary.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// pickReplica returns the next healthy replica in round-robin order
+func (db *DB) pickReplica() *replica {
+	count := len(db.replicas)
+	if count == 0 {
+		return nil
+	}
+
+	start := db.next.Add(1)
+	for i := 0; i < count; i++ {
+		r := db.replicas[(start+uint64(i))%uint64(count)]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+
+	logger.Warn("No replica within lag budget, reading from primary", "maxReplicaLag", db.options.MaxReplicaLag)
+	return nil
+}
+
+// monitor measures replica lag periodically until Close
+func (db *DB) monitor() {
+	ticker := time.NewTicker(db.options.LagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			db.checkLag()
+		case <-db.stop:
+			return
+		}
+	}
+}
+
+// checkLag updates the health of every replica
+func (db *DB) checkLag() {
+	for i, r := range db.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), db.options.LagCheckInterval)
+		lag, err := db.options.LagFunc(ctx, r.db)
+		cancel()
+
+		healthy := err == nil && lag <= db.options.MaxReplicaLag
+		if healthy != r.healthy.Load() {
+			logger.Warn("Replica health changed", "replica", i, "healthy", healthy, "lag", lag, "error", err)
+		}
+		r.healthy.Store(healthy)
+	}
+}
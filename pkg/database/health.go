@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+
+	"github.com/kevenmiano/nestgo/pkg/health"
+	"github.com/kevenmiano/nestgo/pkg/module"
+)
+
+// HealthIndicator reports the health of a DB: the primary must answer a
+// ping; replicas are reported but never take it down, since reads fall
+// back to the primary
+type HealthIndicator struct {
+	name string
+	db   *DB
+}
+
+// NewHealthIndicator creates a health indicator for db, reported under name
+func NewHealthIndicator(name string, db *DB) *HealthIndicator {
+	return &HealthIndicator{name: name, db: db}
+}
+
+// Name returns the name of the indicator
+func (h *HealthIndicator) Name() string {
+	return h.name
+}
+
+// Check pings the primary and every replica
+func (h *HealthIndicator) Check(ctx context.Context) (health.Details, error) {
+	err := h.db.primary.PingContext(ctx)
+
+	stats := h.db.primary.Stats()
+	details := health.Details{
+		"openConnections": stats.OpenConnections,
+		"inUse":           stats.InUse,
+		"idle":            stats.Idle,
+		"waitCount":       stats.WaitCount,
+	}
+
+	if len(h.db.replicas) > 0 {
+		healthy := 0
+		for _, r := range h.db.replicas {
+			if r.healthy.Load() && r.db.PingContext(ctx) == nil {
+				healthy++
+			}
+		}
+		details["replicas"] = len(h.db.replicas)
+		details["healthyReplicas"] = healthy
+	}
+	return details, err
+}
+
+// DatabaseModule provides a DB to every module
+type DatabaseModule struct{}
+
+// NewDatabaseModule registers a global DatabaseModule providing db, injectable
+// as `inject:"DB"`, and reporting its health as "database":
+//
+//	var _ = database.NewDatabaseModule(db)
+func NewDatabaseModule(db *DB) *DatabaseModule {
+	m := &DatabaseModule{}
+	module.New(module.ModuleConfig{
+		Providers: []interface{}{db},
+		Exports:   []interface{}{db},
+		Global:    true,
+	})(m)
+	health.Register(NewHealthIndicator("database", db))
+	return m
+}
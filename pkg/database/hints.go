@@ -0,0 +1,35 @@
+package database
+
+import "context"
+
+// Target is the connection pool a query is routed to
+type Target int
+
+const (
+	// TargetAuto reads from replicas and writes to the primary
+	TargetAuto Target = iota
+	// TargetPrimary sends reads to the primary, e.g. to read your own writes
+	TargetPrimary
+)
+
+// targetKey is the context key of the routing hint
+type targetKey struct{}
+
+// WithTarget returns a context routing queries to target
+func WithTarget(ctx context.Context, target Target) context.Context {
+	return context.WithValue(ctx, targetKey{}, target)
+}
+
+// WithPrimary returns a context routing reads to the primary
+func WithPrimary(ctx context.Context) context.Context {
+	return WithTarget(ctx, TargetPrimary)
+}
+
+// targetFromContext returns the routing hint of the context
+func targetFromContext(ctx context.Context) Target {
+	if ctx == nil {
+		return TargetAuto
+	}
+	target, _ := ctx.Value(targetKey{}).(Target)
+	return target
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+)
+
+// Stage is a point in the lifecycle of a persisted entity
+type Stage string
+
+// Stages run by Insert, Update and Delete
+const (
+	StageBeforeCreate Stage = "BeforeCreate"
+	StageAfterCreate  Stage = "AfterCreate"
+	StageBeforeUpdate Stage = "BeforeUpdate"
+	StageAfterUpdate  Stage = "AfterUpdate"
+	StageBeforeDelete Stage = "BeforeDelete"
+	StageAfterDelete  Stage = "AfterDelete"
+)
+
+// BeforeCreator is implemented by entities preparing themselves for
+// insertion, e.g. generating a slug
+type BeforeCreator interface {
+	BeforeCreate(ctx context.Context) error
+}
+
+// AfterCreator is implemented by entities reacting to their insertion
+type AfterCreator interface {
+	AfterCreate(ctx context.Context) error
+}
+
+// BeforeUpdater is implemented by entities preparing themselves for an update
+type BeforeUpdater interface {
+	BeforeUpdate(ctx context.Context) error
+}
+
+// AfterUpdater is implemented by entities reacting to an update
+type AfterUpdater interface {
+	AfterUpdate(ctx context.Context) error
+}
+
+// BeforeDeleter is implemented by entities checking they can be deleted
+type BeforeDeleter interface {
+	BeforeDelete(ctx context.Context) error
+}
+
+// AfterDeleter is implemented by entities reacting to their deletion
+type AfterDeleter interface {
+	AfterDelete(ctx context.Context) error
+}
+
+// Hook runs around the persistence of an entity in a table. Repositories
+// register hooks for concerns spanning entities, e.g. denormalized
+// counters or recording domain events with RecordEvent.
+type Hook func(ctx context.Context, table string, entity interface{}) error
+
+// RegisterHook adds a hook run at stage for every entity persisted
+// through Insert, Update and Delete. Hooks run in registration order,
+// after the method implemented by the entity itself.
+func (db *DB) RegisterHook(stage Stage, hook Hook) {
+	db.hooksMu.Lock()
+	defer db.hooksMu.Unlock()
+	db.hooks[stage] = append(db.hooks[stage], hook)
+}
+
+// Insert runs the create hooks around inserting the db columns of entity,
+// a pointer to a struct. Columns tagged omitempty are left out when zero.
+func (db *DB) Insert(ctx context.Context, table string, entity interface{}) (sql.Result, error) {
+	if err := db.runHooks(ctx, StageBeforeCreate, table, entity); err != nil {
+		return nil, err
+	}
+
+	builder := NewQueryBuilder(db.options.Placeholder).Insert(table)
+	values, err := entityValues(entity)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		builder.Set(value.name, value.value)
+	}
+
+	result, err := db.Exec(ctx, builder)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.runHooks(ctx, StageAfterCreate, table, entity); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Update runs the update hooks around updating the rows matching where,
+// written with ? placeholders, to the db columns of entity
+func (db *DB) Update(ctx context.Context, table string, entity interface{}, where string, args ...interface{}) (sql.Result, error) {
+	if err := db.runHooks(ctx, StageBeforeUpdate, table, entity); err != nil {
+		return nil, err
+	}
+
+	builder := NewQueryBuilder(db.options.Placeholder).Update(table).Where(where, args...)
+	values, err := entityValues(entity)
+	if err != nil {
+		return nil, err
+	}
+	for _, value := range values {
+		builder.Set(value.name, value.value)
+	}
+
+	result, err := db.Exec(ctx, builder)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.runHooks(ctx, StageAfterUpdate, table, entity); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Delete runs the delete hooks of entity around deleting the rows matching
+// where, written with ? placeholders
+func (db *DB) Delete(ctx context.Context, table string, entity interface{}, where string, args ...interface{}) (sql.Result, error) {
+	if err := db.runHooks(ctx, StageBeforeDelete, table, entity); err != nil {
+		return nil, err
+	}
+
+	result, err := db.Exec(ctx, NewQueryBuilder(db.options.Placeholder).Delete(table).Where(where, args...))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.runHooks(ctx, StageAfterDelete, table, entity); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// runHooks runs the entity method of a stage, then the registered hooks,
+// stopping at the first error. Errors of after hooks are returned too, so
+// a unit of work running the operation rolls back.
+func (db *DB) runHooks(ctx context.Context, stage Stage, table string, entity interface{}) error {
+	if err := entityHook(ctx, stage, entity); err != nil {
+		return fmt.Errorf("database: %s hook of %T failed: %w", stage, entity, err)
+	}
+
+	db.hooksMu.RLock()
+	hooks := append([]Hook{}, db.hooks[stage]...)
+	db.hooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, table, entity); err != nil {
+			return fmt.Errorf("database: %s hook on %s failed: %w", stage, table, err)
+		}
+	}
+	return nil
+}
+
+// entityHook calls the lifecycle method of a stage when entity implements it
+func entityHook(ctx context.Context, stage Stage, entity interface{}) error {
+	switch stage {
+	case StageBeforeCreate:
+		if e, ok := entity.(BeforeCreator); ok {
+			return e.BeforeCreate(ctx)
+		}
+	case StageAfterCreate:
+		if e, ok := entity.(AfterCreator); ok {
+			return e.AfterCreate(ctx)
+		}
+	case StageBeforeUpdate:
+		if e, ok := entity.(BeforeUpdater); ok {
+			return e.BeforeUpdate(ctx)
+		}
+	case StageAfterUpdate:
+		if e, ok := entity.(AfterUpdater); ok {
+			return e.AfterUpdate(ctx)
+		}
+	case StageBeforeDelete:
+		if e, ok := entity.(BeforeDeleter); ok {
+			return e.BeforeDelete(ctx)
+		}
+	case StageAfterDelete:
+		if e, ok := entity.(AfterDeleter); ok {
+			return e.AfterDelete(ctx)
+		}
+	}
+	return nil
+}
+
+// columnValue is a column with the value of an entity field
+type columnValue struct {
+	name  string
+	value interface{}
+}
+
+// entityValues returns the db columns of entity in field order, read after
+// the before hooks so their changes are persisted
+func entityValues(entity interface{}) ([]columnValue, error) {
+	structValue := reflect.ValueOf(entity)
+	if structValue.Kind() != reflect.Ptr || structValue.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("database: entity must be a pointer to a struct, got %T", entity)
+	}
+	structValue = structValue.Elem()
+
+	columns := structColumns(structValue.Type(), nil)
+	values := make([]columnValue, 0, len(columns))
+	for _, col := range columns {
+		field := structValue.FieldByIndex(col.index)
+		if col.omitEmpty && field.IsZero() {
+			continue
+		}
+		values = append(values, columnValue{name: col.name, value: field.Interface()})
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("database: entity %T has no columns to persist", entity)
+	}
+	return values, nil
+}
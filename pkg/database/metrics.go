@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/pii"
+	"github.com/kevenmiano/nestgo/pkg/tenant"
+)
+
+const (
+	// RolePrimary labels statistics of the primary pool
+	RolePrimary = "primary"
+	// RoleReplica labels statistics of a replica pool
+	RoleReplica = "replica"
+)
+
+// PoolStats are the connection pool statistics of one database
+type PoolStats struct {
+	Role  string `json:"role"`
+	Index int    `json:"index"`
+	sql.DBStats
+}
+
+// Stats returns the pool statistics of the primary followed by the replicas
+func (db *DB) Stats() []PoolStats {
+	stats := make([]PoolStats, 0, len(db.replicas)+1)
+	stats = append(stats, PoolStats{Role: RolePrimary, DBStats: db.primary.Stats()})
+	for i, r := range db.replicas {
+		stats = append(stats, PoolStats{Role: RoleReplica, Index: i, DBStats: r.db.Stats()})
+	}
+	return stats
+}
+
+// logStats writes the pool statistics to the log periodically until Close
+func (db *DB) logStats() {
+	ticker := time.NewTicker(db.options.StatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, stats := range db.Stats() {
+				logger.Info("Database pool stats",
+					"role", stats.Role,
+					"index", stats.Index,
+					"open", stats.OpenConnections,
+					"inUse", stats.InUse,
+					"idle", stats.Idle,
+					"waitCount", stats.WaitCount,
+					"waitDuration", stats.WaitDuration,
+					"maxIdleClosed", stats.MaxIdleClosed,
+					"maxLifetimeClosed", stats.MaxLifetimeClosed)
+			}
+		case <-db.stop:
+			return
+		}
+	}
+}
+
+// QueryEvent describes an executed query. Parameters are not included so
+// events can be exported safely. Module and Owner attribute queries run
+// while serving a route to the team owning it, e.g. as metric labels.
+// Tenant is the tenant of the request, for logs and span attributes, and
+// TenantLabel its bounded counterpart for metric labels, see
+// tenant.Label.
+type QueryEvent struct {
+	Role        string
+	Query       string
+	ParamCount  int
+	Duration    time.Duration
+	Err         error
+	Module      string
+	Owner       string
+	Tenant      string
+	TenantLabel string
+}
+
+// QueryObserver receives every executed query with the query context, e.g.
+// to record it on the tracing span carried by ctx
+type QueryObserver func(ctx context.Context, event QueryEvent)
+
+// observe reports a query to the observer and logs it when it took longer
+// than the slow query threshold. Bound parameters are always redacted.
+func (db *DB) observe(ctx context.Context, role, query string, args []interface{}, start time.Time, err error) {
+	event := QueryEvent{
+		Role:       role,
+		Query:      query,
+		ParamCount: len(args),
+		Duration:   time.Since(start),
+		Err:        err,
+	}
+	if ownership, ok := module.OwnershipFromContext(ctx); ok {
+		event.Module = ownership.Module
+		event.Owner = ownership.Owner
+	}
+	if id, ok := tenant.FromContext(ctx); ok {
+		event.Tenant = id
+		event.TenantLabel = tenant.Label(ctx)
+	}
+
+	if db.options.Observer != nil {
+		db.options.Observer(ctx, event)
+	}
+
+	threshold := db.options.SlowQueryThreshold
+	if threshold <= 0 || event.Duration < threshold {
+		return
+	}
+
+	params := make([]string, event.ParamCount)
+	for i := range params {
+		params[i] = pii.RedactedValue
+	}
+
+	logger.Warn("Slow database query",
+		"role", role,
+		"query", query,
+		"params", params,
+		"duration", event.Duration,
+		"threshold", threshold,
+		"module", event.Module,
+		"owner", event.Owner,
+		tenant.AttrTenant, event.Tenant,
+		"error", err)
+}
@@ -0,0 +1,433 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+const (
+	// DefaultMigrationsTable records the applied migration versions
+	DefaultMigrationsTable = "schema_migrations"
+	// DefaultMigrationLock is the lock name serializing migrations
+	DefaultMigrationLock = "schema_migrations"
+	// DefaultLocksTable holds the locks of a TableLocker
+	DefaultLocksTable = "nestgo_locks"
+	// DefaultLockPollInterval is how often a waiting replica retries the lock
+	DefaultLockPollInterval = time.Second
+)
+
+// ErrLockTimeout is returned when the migration lock is not acquired in time
+var ErrLockTimeout = errors.New("database: timed out waiting for migration lock")
+
+// ErrLockLost is returned when the migration lock could not be renewed,
+// aborting the migration in progress before another replica takes it
+var ErrLockLost = errors.New("database: migration lock lost")
+
+// Migration is a versioned schema change, applied in its own transaction
+type Migration struct {
+	Version int64
+	Name    string
+	// SQL is executed when Func is nil
+	SQL  string
+	Func func(ctx context.Context, tx *sql.Tx) error
+}
+
+// Locker acquires named locks shared by every replica of the application
+type Locker interface {
+	// TryLock acquires the lock without waiting, reporting false when
+	// another holder has it. unlock releases an acquired lock.
+	TryLock(ctx context.Context, name string) (unlock func(ctx context.Context) error, acquired bool, err error)
+}
+
+// RenewingLocker is implemented by Lockers whose locks expire. Migrate
+// renews the lock every third of its TTL while migrating, so migrations may
+// outlast the TTL. Failed renewals are retried until the lock expires;
+// Migrate then aborts with ErrLockLost, as it does when Renew reports the
+// lock taken by another replica.
+type RenewingLocker interface {
+	Locker
+	// TTL is how long an acquired lock lasts without renewal
+	TTL() time.Duration
+	// Renew extends an acquired lock, reporting false when it was lost
+	Renew(ctx context.Context, name string) (bool, error)
+}
+
+// LockWait tells what replicas do while another one holds the migration lock
+type LockWait int
+
+const (
+	// WaitForLock waits until the holder releases the lock, then applies
+	// whatever is still pending, usually nothing
+	WaitForLock LockWait = iota
+	// SkipOnLock returns right away, leaving the migrations to the holder
+	SkipOnLock
+)
+
+// MigrateOptions configures DB.Migrate
+type MigrateOptions struct {
+	Migrations []Migration
+	// Locker serializes migrations across replicas; defaults to a
+	// TableLocker on the primary
+	Locker Locker
+	// LockName defaults to DefaultMigrationLock
+	LockName string
+	// Table defaults to DefaultMigrationsTable
+	Table string
+	Wait  LockWait
+	// Timeout bounds the wait for the lock; zero waits until ctx is done
+	Timeout time.Duration
+	// PollInterval defaults to DefaultLockPollInterval
+	PollInterval time.Duration
+}
+
+// MigrationStatus reports the outcome of DB.Migrate
+type MigrationStatus struct {
+	// Applied lists the versions applied by this replica
+	Applied []int64 `json:"applied"`
+	// Pending lists the versions not applied when Migrate returned
+	Pending []int64 `json:"pending"`
+	// Skipped is true when another replica held the lock and Wait is SkipOnLock
+	Skipped bool `json:"skipped"`
+	// Waited is how long the replica waited for the lock
+	Waited time.Duration `json:"waited"`
+}
+
+// Migrate applies the pending migrations in version order on the primary,
+// holding a lock so only one replica migrates at a time. Call it at
+// bootstrap, e.g. from OnModuleInit, before the schema is used.
+func (db *DB) Migrate(ctx context.Context, options MigrateOptions) (MigrationStatus, error) {
+	status := MigrationStatus{Applied: make([]int64, 0), Pending: make([]int64, 0)}
+	if options.Locker == nil {
+		options.Locker = NewTableLocker(db.primary, db.options.Placeholder, "", 0)
+	}
+	if options.LockName == "" {
+		options.LockName = DefaultMigrationLock
+	}
+	if options.Table == "" {
+		options.Table = DefaultMigrationsTable
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultLockPollInterval
+	}
+
+	migrations := append([]Migration(nil), options.Migrations...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return status, fmt.Errorf("database: duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	start := clock.Now()
+	unlock, err := db.acquireLock(ctx, options)
+	status.Waited = clock.Now().Sub(start)
+	if err != nil {
+		return status, err
+	}
+	if unlock == nil {
+		status.Skipped = true
+		logger.Info("Migrations skipped, another replica holds the lock", "lock", options.LockName)
+		return status, nil
+	}
+	defer func() {
+		if err := unlock(context.WithoutCancel(ctx)); err != nil {
+			logger.Error("Failed to release migration lock", "lock", options.LockName, "error", err)
+		}
+	}()
+
+	// Expiring locks are renewed while migrating, the migrations being
+	// canceled if the lock is lost
+	if renewer, ok := options.Locker.(RenewingLocker); ok {
+		var stop func()
+		ctx, stop = keepLock(ctx, renewer, options.LockName)
+		defer stop()
+	}
+
+	qb := NewQueryBuilder(db.options.Placeholder)
+	applied, err := db.appliedVersions(ctx, options.Table)
+	if err != nil {
+		return status, err
+	}
+
+	for i, migration := range migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		logger.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+		if err := db.applyMigration(ctx, qb, options.Table, migration); err != nil {
+			if cause := context.Cause(ctx); errors.Is(cause, ErrLockLost) {
+				err = fmt.Errorf("%w: %w", cause, err)
+			}
+			for _, pending := range migrations[i:] {
+				if !applied[pending.Version] {
+					status.Pending = append(status.Pending, pending.Version)
+				}
+			}
+			return status, err
+		}
+		status.Applied = append(status.Applied, migration.Version)
+	}
+
+	logger.Info("Migrations completed", "applied", len(status.Applied), "waited", status.Waited)
+	return status, nil
+}
+
+// acquireLock acquires the migration lock, retrying while another replica
+// holds it unless the options skip. A nil unlock means skipped.
+func (db *DB) acquireLock(ctx context.Context, options MigrateOptions) (func(ctx context.Context) error, error) {
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(options.PollInterval)
+	defer ticker.Stop()
+	for logged := false; ; logged = true {
+		unlock, acquired, err := options.Locker.TryLock(ctx, options.LockName)
+		if err != nil {
+			return nil, fmt.Errorf("database: failed to acquire migration lock: %w", err)
+		}
+		if acquired {
+			return unlock, nil
+		}
+		if options.Wait == SkipOnLock {
+			return nil, nil
+		}
+		if !logged {
+			logger.Info("Waiting for migration lock", "lock", options.LockName)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, ErrLockTimeout
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// keepLock renews a lock every third of its TTL until stop is called. A
+// failed renewal is retried at the next tick while the lock lasts; the
+// returned context is canceled with ErrLockLost when the lock expires
+// without renewal or another holder has it. Locks whose TTL is too short to
+// be renewed are not.
+func keepLock(ctx context.Context, locker RenewingLocker, name string) (context.Context, func()) {
+	ttl := locker.TTL()
+	if ttl/3 <= 0 {
+		logger.Warn("Migration lock TTL too short to renew, migrations may outlast it", "lock", name, "ttl", ttl)
+		return ctx, func() {}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		expiry := time.NewTimer(ttl)
+		defer expiry.Stop()
+
+		var failure error
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-expiry.C:
+				logger.Error("Migration lock expired without renewal, aborting migrations", "lock", name, "error", failure)
+				cancel(fmt.Errorf("%w: not renewed within its TTL: %v", ErrLockLost, failure))
+				return
+			case <-ticker.C:
+			}
+
+			// The lock lasts a TTL from before the renewal, at the latest
+			renewing := time.Now()
+			held, err := locker.Renew(ctx, name)
+			switch {
+			case err != nil:
+				failure = err
+				logger.Warn("Failed to renew migration lock, retrying", "lock", name, "error", err)
+			case !held:
+				logger.Error("Migration lock taken by another replica, aborting migrations", "lock", name)
+				cancel(fmt.Errorf("%w: held by another replica", ErrLockLost))
+				return
+			default:
+				failure = nil
+				expiry.Reset(ttl - time.Since(renewing))
+			}
+		}
+	}()
+
+	return ctx, func() {
+		close(done)
+		<-stopped
+		cancel(nil)
+	}
+}
+
+// appliedVersions creates the migrations table if needed and returns the
+// versions it records
+func (db *DB) appliedVersions(ctx context.Context, table string) (map[int64]bool, error) {
+	create := "CREATE TABLE IF NOT EXISTS " + table + " (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at BIGINT NOT NULL)"
+	if _, err := db.primary.ExecContext(ctx, create); err != nil {
+		return nil, fmt.Errorf("database: failed to create %s: %w", table, err)
+	}
+
+	rows, err := db.primary.QueryContext(ctx, "SELECT version FROM "+table)
+	if err != nil {
+		return nil, fmt.Errorf("database: failed to read %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("database: failed to read %s: %w", table, err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// applyMigration runs a migration and records its version in one transaction
+func (db *DB) applyMigration(ctx context.Context, qb *QueryBuilder, table string, migration Migration) error {
+	tx, err := db.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database: migration %d: %w", migration.Version, err)
+	}
+
+	if migration.Func != nil {
+		err = migration.Func(ctx, tx)
+	} else {
+		_, err = tx.ExecContext(ctx, migration.SQL)
+	}
+	if err == nil {
+		query, args := qb.Insert(table).
+			Set("version", migration.Version).
+			Set("name", migration.Name).
+			Set("applied_at", clock.Now().Unix()).
+			Build()
+		_, err = tx.ExecContext(ctx, query, args...)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("database: migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database: migration %d %s: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// TableLocker is a Locker storing locks as rows of a table, portable across
+// SQL databases. Locks expire after their TTL so a crashed holder does not
+// block the other replicas forever; Migrate renews them while migrating.
+type TableLocker struct {
+	conn   *sql.DB
+	qb     *QueryBuilder
+	table  string
+	ttl    time.Duration
+	holder string
+}
+
+// NewTableLocker creates a TableLocker on conn. table defaults to
+// DefaultLocksTable and ttl to ten minutes.
+func NewTableLocker(conn *sql.DB, placeholder Placeholder, table string, ttl time.Duration) *TableLocker {
+	if table == "" {
+		table = DefaultLocksTable
+	}
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+
+	holder, _ := os.Hostname()
+	if suffix, err := ids.Hex(4); err == nil {
+		holder += "-" + suffix
+	}
+	return &TableLocker{conn: conn, qb: NewQueryBuilder(placeholder), table: table, ttl: ttl, holder: holder}
+}
+
+// TryLock inserts the lock row, after removing it if expired
+func (tl *TableLocker) TryLock(ctx context.Context, name string) (func(ctx context.Context) error, bool, error) {
+	create := "CREATE TABLE IF NOT EXISTS " + tl.table + " (name VARCHAR(255) PRIMARY KEY, holder VARCHAR(255) NOT NULL, expires_at BIGINT NOT NULL)"
+	if _, err := tl.conn.ExecContext(ctx, create); err != nil {
+		return nil, false, err
+	}
+
+	now := clock.Now()
+	query, args := tl.qb.Delete(tl.table).Where("name = ?", name).Where("expires_at < ?", now.Unix()).Build()
+	if _, err := tl.conn.ExecContext(ctx, query, args...); err != nil {
+		return nil, false, err
+	}
+
+	query, args = tl.qb.Insert(tl.table).
+		Set("name", name).
+		Set("holder", tl.holder).
+		Set("expires_at", now.Add(tl.ttl).Unix()).
+		Build()
+	if _, err := tl.conn.ExecContext(ctx, query, args...); err != nil {
+		// A failed insert is a held lock only if the row exists
+		var holder string
+		query, args := tl.qb.Select("holder").From(tl.table).Where("name = ?", name).Build()
+		if scanErr := tl.conn.QueryRowContext(ctx, query, args...).Scan(&holder); scanErr == nil {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	unlock := func(ctx context.Context) error {
+		query, args := tl.qb.Delete(tl.table).Where("name = ?", name).Where("holder = ?", tl.holder).Build()
+		_, err := tl.conn.ExecContext(ctx, query, args...)
+		return err
+	}
+	return unlock, true, nil
+}
+
+// TTL returns how long a lock lasts without renewal
+func (tl *TableLocker) TTL() time.Duration {
+	return tl.ttl
+}
+
+// Renew pushes back the expiry of a lock held by this locker
+func (tl *TableLocker) Renew(ctx context.Context, name string) (bool, error) {
+	query, args := tl.qb.Update(tl.table).
+		Set("expires_at", clock.Now().Add(tl.ttl).Unix()).
+		Where("name = ?", name).
+		Where("holder = ?", tl.holder).
+		Build()
+	result, err := tl.conn.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected > 0 {
+		return true, nil
+	}
+
+	// Some databases count unchanged rows as unaffected
+	var holder string
+	query, args = tl.qb.Select("holder").From(tl.table).Where("name = ?", name).Build()
+	if err := tl.conn.QueryRowContext(ctx, query, args...).Scan(&holder); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return holder == tl.holder, nil
+}
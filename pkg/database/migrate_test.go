@@ -0,0 +1,289 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// schema is an in-memory database recording the versions of the migrations
+// committed to it. Statements containing "fail" fail.
+type schema struct {
+	mu       sync.Mutex
+	versions []int64
+}
+
+// committed returns the recorded versions
+func (s *schema) committed() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.versions)
+}
+
+// Connect implements driver.Connector
+func (s *schema) Connect(ctx context.Context) (driver.Conn, error) {
+	return &schemaConn{schema: s}, nil
+}
+
+// Driver implements driver.Connector
+func (s *schema) Driver() driver.Driver {
+	return nil
+}
+
+// schemaConn runs statements against a schema, buffering the versions
+// recorded in a transaction until it commits
+type schemaConn struct {
+	schema  *schema
+	pending []int64
+}
+
+func (c *schemaConn) Prepare(query string) (driver.Stmt, error) {
+	return &schemaStmt{conn: c, query: query}, nil
+}
+
+func (c *schemaConn) Close() error {
+	return nil
+}
+
+func (c *schemaConn) Begin() (driver.Tx, error) {
+	c.pending = nil
+	return c, nil
+}
+
+func (c *schemaConn) Commit() error {
+	c.schema.mu.Lock()
+	defer c.schema.mu.Unlock()
+	c.schema.versions = append(c.schema.versions, c.pending...)
+	c.pending = nil
+	return nil
+}
+
+func (c *schemaConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+// schemaStmt is a statement of a schemaConn
+type schemaStmt struct {
+	conn  *schemaConn
+	query string
+}
+
+func (s *schemaStmt) Close() error {
+	return nil
+}
+
+func (s *schemaStmt) NumInput() int {
+	return -1
+}
+
+func (s *schemaStmt) Exec(args []driver.Value) (driver.Result, error) {
+	switch {
+	case strings.Contains(s.query, "fail"):
+		return nil, errors.New("syntax error")
+	case strings.HasPrefix(s.query, "INSERT INTO "+DefaultMigrationsTable):
+		s.conn.pending = append(s.conn.pending, args[0].(int64))
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *schemaStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &versionRows{versions: s.conn.schema.committed()}, nil
+}
+
+// versionRows lists the versions of a schema
+type versionRows struct {
+	versions []int64
+}
+
+func (r *versionRows) Columns() []string {
+	return []string{"version"}
+}
+
+func (r *versionRows) Close() error {
+	return nil
+}
+
+func (r *versionRows) Next(dest []driver.Value) error {
+	if len(r.versions) == 0 {
+		return io.EOF
+	}
+	dest[0], r.versions = r.versions[0], r.versions[1:]
+	return nil
+}
+
+// renewal is the outcome of a Renew call
+type renewal struct {
+	held bool
+	err  error
+}
+
+// scriptedLocker answers renewals in order, repeating the last outcome. A
+// busy locker never acquires the lock.
+type scriptedLocker struct {
+	ttl  time.Duration
+	busy bool
+
+	mu       sync.Mutex
+	script   []renewal
+	renewals int
+}
+
+func (l *scriptedLocker) TryLock(ctx context.Context, name string) (func(ctx context.Context) error, bool, error) {
+	if l.busy {
+		return nil, false, nil
+	}
+	return func(ctx context.Context) error { return nil }, true, nil
+}
+
+func (l *scriptedLocker) TTL() time.Duration {
+	return l.ttl
+}
+
+func (l *scriptedLocker) Renew(ctx context.Context, name string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	outcome := l.script[min(l.renewals, len(l.script)-1)]
+	l.renewals++
+	return outcome.held, outcome.err
+}
+
+func (l *scriptedLocker) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.renewals
+}
+
+func TestKeepLock(t *testing.T) {
+	const ttl = 60 * time.Millisecond
+	unreachable := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		script []renewal
+		// wait is how long the migrations run
+		wait         time.Duration
+		wantLost     bool
+		wantRenewals bool
+	}{
+		{name: "zero TTL is not renewed", ttl: 0, script: []renewal{{held: true}}, wait: ttl},
+		{name: "tiny TTL is not renewed", ttl: time.Nanosecond, script: []renewal{{held: true}}, wait: ttl},
+		{name: "renewed lock outlasts its TTL", ttl: ttl, script: []renewal{{held: true}}, wait: 3 * ttl, wantRenewals: true},
+		{name: "transient failure is retried", ttl: ttl, script: []renewal{{err: unreachable}, {held: true}}, wait: 3 * ttl, wantRenewals: true},
+		{name: "failures until expiry lose the lock", ttl: ttl, script: []renewal{{err: unreachable}}, wait: 3 * ttl, wantLost: true, wantRenewals: true},
+		{name: "lock taken by another replica", ttl: ttl, script: []renewal{{held: true}, {held: false}}, wait: 3 * ttl, wantLost: true, wantRenewals: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locker := &scriptedLocker{ttl: tt.ttl, script: tt.script}
+			ctx, stop := keepLock(context.Background(), locker, DefaultMigrationLock)
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(tt.wait):
+			}
+			stop()
+
+			lost := errors.Is(context.Cause(ctx), ErrLockLost)
+			if lost != tt.wantLost {
+				t.Fatalf("cause = %v, want lock lost %v", context.Cause(ctx), tt.wantLost)
+			}
+			if renewed := locker.count() > 0; renewed != tt.wantRenewals {
+				t.Errorf("%d renewals, want renewals %v", locker.count(), tt.wantRenewals)
+			}
+		})
+	}
+}
+
+func TestKeepLockWaitsForExpiry(t *testing.T) {
+	const ttl = 90 * time.Millisecond
+	locker := &scriptedLocker{ttl: ttl, script: []renewal{{err: errors.New("connection refused")}}}
+	started := time.Now()
+	ctx, stop := keepLock(context.Background(), locker, DefaultMigrationLock)
+	defer stop()
+
+	<-ctx.Done()
+	if elapsed := time.Since(started); elapsed < ttl {
+		t.Errorf("aborted after %v, before the lock expired after %v", elapsed, ttl)
+	}
+	if locker.count() < 2 {
+		t.Errorf("%d renewals, want failed renewals retried", locker.count())
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	migrations := []Migration{
+		{Version: 3, Name: "add_index", SQL: "CREATE INDEX users_email ON users (email)"},
+		{Version: 1, Name: "create_users", SQL: "CREATE TABLE users (id BIGINT PRIMARY KEY)"},
+		{Version: 2, Name: "add_email", SQL: "ALTER TABLE users ADD email VARCHAR(255)"},
+	}
+	failing := slices.Clone(migrations)
+	failing[2].SQL = "ALTER TABLE users fail"
+	duplicated := append(slices.Clone(migrations), Migration{Version: 2, Name: "again"})
+	// The migration outlives the lock, as a stalled ALTER TABLE would
+	stalled := []Migration{{Version: 1, Name: "stalled", Func: func(ctx context.Context, tx *sql.Tx) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}}
+
+	tests := []struct {
+		name         string
+		migrations   []Migration
+		applied      []int64
+		locker       *scriptedLocker
+		options      MigrateOptions
+		wantApplied  []int64
+		wantPending  []int64
+		wantSkipped  bool
+		wantErr      bool
+		wantCause    error
+		wantVersions []int64
+	}{
+		{name: "applies in version order", migrations: migrations, wantApplied: []int64{1, 2, 3}, wantVersions: []int64{1, 2, 3}},
+		{name: "skips applied versions", migrations: migrations, applied: []int64{1}, wantApplied: []int64{2, 3}, wantVersions: []int64{1, 2, 3}},
+		{name: "nothing pending", migrations: migrations, applied: []int64{1, 2, 3}, wantVersions: []int64{1, 2, 3}},
+		{name: "failure leaves the rest pending", migrations: failing, wantApplied: []int64{1}, wantPending: []int64{2, 3}, wantErr: true, wantVersions: []int64{1}},
+		{name: "duplicate versions", migrations: duplicated, wantErr: true},
+		{name: "skips while another replica migrates", migrations: migrations, locker: &scriptedLocker{ttl: time.Hour, busy: true}, options: MigrateOptions{Wait: SkipOnLock}, wantSkipped: true},
+		{name: "times out waiting for the lock", migrations: migrations, locker: &scriptedLocker{ttl: time.Hour, busy: true}, options: MigrateOptions{Timeout: 30 * time.Millisecond, PollInterval: 5 * time.Millisecond}, wantErr: true, wantCause: ErrLockTimeout},
+		{name: "aborts when the lock is lost", migrations: stalled, locker: &scriptedLocker{ttl: 30 * time.Millisecond, script: []renewal{{held: false}}}, wantPending: []int64{1}, wantErr: true, wantCause: ErrLockLost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &schema{versions: slices.Clone(tt.applied)}
+			conn := sql.OpenDB(s)
+			defer conn.Close()
+			db, err := New(Options{Primary: conn})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			options := tt.options
+			options.Migrations = tt.migrations
+			options.Locker = tt.locker
+			if tt.locker == nil {
+				options.Locker = &scriptedLocker{ttl: time.Hour, script: []renewal{{held: true}}}
+			}
+			status, err := db.Migrate(context.Background(), options)
+
+			if (err != nil) != tt.wantErr || tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+				t.Fatalf("Migrate() error = %v, want error %v (%v)", err, tt.wantErr, tt.wantCause)
+			}
+			if !slices.Equal(status.Applied, tt.wantApplied) || !slices.Equal(status.Pending, tt.wantPending) || status.Skipped != tt.wantSkipped {
+				t.Errorf("status = %+v, want applied %v, pending %v, skipped %v", status, tt.wantApplied, tt.wantPending, tt.wantSkipped)
+			}
+			if versions := s.committed(); !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("recorded versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// TagColumn maps a struct field to a column: `db:"created_at"`. Fields
+// without the tag use the snake_case field name; "-" skips the field and
+// `db:"id,omitempty"` leaves zero values out of inserts and updates.
+const TagColumn = "db"
+
+// Statement is a built query with its arguments
+type Statement interface {
+	Build() (string, []interface{})
+}
+
+// ScanAll scans every row into dest, a pointer to a slice of structs or
+// struct pointers, and closes the rows
+func ScanAll(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	sliceValue := reflect.ValueOf(dest)
+	if sliceValue.Kind() != reflect.Ptr || sliceValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("database: dest must be a pointer to a slice, got %T", dest)
+	}
+	sliceValue = sliceValue.Elem()
+
+	elemType := sliceValue.Type().Elem()
+	structType := elemType
+	if elemType.Kind() == reflect.Ptr {
+		structType = elemType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("database: dest elements must be structs, got %s", elemType)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	indexes := columnIndexes(structType, columns)
+
+	for rows.Next() {
+		item := reflect.New(structType)
+		if err := rows.Scan(scanTargets(item.Elem(), indexes)...); err != nil {
+			return err
+		}
+
+		if elemType.Kind() == reflect.Ptr {
+			sliceValue.Set(reflect.Append(sliceValue, item))
+		} else {
+			sliceValue.Set(reflect.Append(sliceValue, item.Elem()))
+		}
+	}
+
+	return rows.Err()
+}
+
+// ScanOne scans the first row into dest, a pointer to a struct, and closes
+// the rows. It returns sql.ErrNoRows when the result is empty.
+func ScanOne(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	structValue := reflect.ValueOf(dest)
+	if structValue.Kind() != reflect.Ptr || structValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("database: dest must be a pointer to a struct, got %T", dest)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	indexes := columnIndexes(structValue.Elem().Type(), columns)
+	if err := rows.Scan(scanTargets(structValue.Elem(), indexes)...); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// Select runs a built query on the reader and scans every row into dest
+func (db *DB) Select(ctx context.Context, dest interface{}, statement Statement) error {
+	query, args := statement.Build()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return ScanAll(rows, dest)
+}
+
+// Get runs a built query on the reader and scans the first row into dest
+func (db *DB) Get(ctx context.Context, dest interface{}, statement Statement) error {
+	query, args := statement.Build()
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return ScanOne(rows, dest)
+}
+
+// Exec runs a built statement on the primary
+func (db *DB) Exec(ctx context.Context, statement Statement) (sql.Result, error) {
+	query, args := statement.Build()
+	return db.ExecContext(ctx, query, args...)
+}
+
+// column is a struct field mapped to a table column
+type column struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+// columnIndexes maps each result column to a struct field index path;
+// unknown columns map to nil and are discarded
+func columnIndexes(structType reflect.Type, columns []string) [][]int {
+	fields := make(map[string][]int)
+	for _, col := range structColumns(structType, nil) {
+		fields[strings.ToLower(col.name)] = col.index
+	}
+
+	indexes := make([][]int, len(columns))
+	for i, name := range columns {
+		indexes[i] = fields[strings.ToLower(name)]
+	}
+	return indexes
+}
+
+// structColumns lists the columns of a struct in field order, descending
+// into embedded structs. The db tag accepts an omitempty option.
+func structColumns(structType reflect.Type, parent []int) []column {
+	columns := make([]column, 0, structType.NumField())
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		index := append(append([]int{}, parent...), i)
+		tag := field.Tag.Get(TagColumn)
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && tag == "" {
+			columns = append(columns, structColumns(field.Type, index)...)
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = toSnakeCase(field.Name)
+		}
+		columns = append(columns, column{name: name, index: index, omitEmpty: options == "omitempty"})
+	}
+
+	return columns
+}
+
+// scanTargets returns the Scan destinations of a struct for the column indexes
+func scanTargets(structValue reflect.Value, indexes [][]int) []interface{} {
+	targets := make([]interface{}, len(indexes))
+	for i, index := range indexes {
+		if index == nil {
+			var discard interface{}
+			targets[i] = &discard
+			continue
+		}
+		targets[i] = structValue.FieldByIndex(index).Addr().Interface()
+	}
+	return targets
+}
+
+// toSnakeCase converts a Go field name to snake_case, e.g. CreatedAt to created_at
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if i > 0 && (unicode.IsLower(runes[i-1]) || nextLower) {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// EventPublisher publishes the domain events recorded during a unit of work
+type EventPublisher interface {
+	Publish(ctx context.Context, event interface{}) error
+}
+
+// EventPublisherFunc adapts a function to the EventPublisher interface
+type EventPublisherFunc func(ctx context.Context, event interface{}) error
+
+// Publish calls f(ctx, event)
+func (f EventPublisherFunc) Publish(ctx context.Context, event interface{}) error {
+	return f(ctx, event)
+}
+
+// unit is the transaction and pending events of a running unit of work
+type unit struct {
+	tx     *sql.Tx
+	events []interface{}
+	mutex  sync.Mutex
+}
+
+// unitKey is the context key of the running unit of work
+type unitKey struct{}
+
+// UnitOfWork groups repository operations into a single transaction.
+// Queries made through DB with the context passed to Do join the
+// transaction, and domain events recorded with RecordEvent are published
+// only after a successful commit.
+type UnitOfWork struct {
+	db        *DB
+	publisher EventPublisher
+}
+
+// NewUnitOfWork creates a unit of work on the primary of db. The publisher
+// may be nil when no domain events are recorded.
+func NewUnitOfWork(db *DB, publisher EventPublisher) *UnitOfWork {
+	return &UnitOfWork{db: db, publisher: publisher}
+}
+
+// Do runs fn in a transaction, committing when it returns nil and rolling
+// back on error or panic. Calls nested in a running unit of work join it.
+func (uow *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if unitFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := uow.db.Primary().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("database: failed to begin unit of work: %w", err)
+	}
+
+	current := &unit{tx: tx, events: make([]interface{}, 0)}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			tx.Rollback()
+			panic(recovered)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, unitKey{}, current)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Failed to roll back unit of work", "error", rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("database: failed to commit unit of work: %w", err)
+	}
+
+	uow.publish(ctx, current.events)
+	return nil
+}
+
+// publish delivers the events of a committed unit of work
+func (uow *UnitOfWork) publish(ctx context.Context, events []interface{}) {
+	if uow.publisher == nil {
+		if len(events) > 0 {
+			logger.Warn("Discarding domain events, no publisher configured", "count", len(events))
+		}
+		return
+	}
+
+	for _, event := range events {
+		if err := uow.publisher.Publish(ctx, event); err != nil {
+			logger.Error("Failed to publish domain event", "event", fmt.Sprintf("%T", event), "error", err)
+		}
+	}
+}
+
+// RecordEvent defers a domain event until the unit of work running in ctx
+// commits; events of rolled back units are dropped
+func RecordEvent(ctx context.Context, event interface{}) error {
+	current := unitFromContext(ctx)
+	if current == nil {
+		return fmt.Errorf("database: no unit of work in context")
+	}
+
+	current.mutex.Lock()
+	defer current.mutex.Unlock()
+	current.events = append(current.events, event)
+	return nil
+}
+
+// TxFromContext returns the transaction of the unit of work running in ctx
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	if current := unitFromContext(ctx); current != nil {
+		return current.tx, true
+	}
+	return nil, false
+}
+
+// unitFromContext returns the unit of work running in ctx
+func unitFromContext(ctx context.Context) *unit {
+	if ctx == nil {
+		return nil
+	}
+	current, _ := ctx.Value(unitKey{}).(*unit)
+	return current
+}
@@ -0,0 +1,68 @@
+package diagnostics
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// BudgetOptions configures the per-request budgets; zero leaves a measure
+// unchecked
+type BudgetOptions struct {
+	// MaxAllocs bounds the heap allocations of a request
+	MaxAllocs uint64
+	// MaxBytes bounds the heap bytes allocated by a request
+	MaxBytes uint64
+	// MaxGoroutines bounds the goroutines a request leaves running once
+	// its handler returns
+	MaxGoroutines int
+	// Concurrent measures requests without serializing them. Measures are
+	// process wide, so they then include the work of concurrent requests.
+	Concurrent bool
+}
+
+// BudgetMiddleware returns an HTTP middleware measuring the allocations and
+// goroutines of every request and logging a warning for requests over
+// budget, to track the cost of the reflective handler path. Reading the
+// allocation counters stops the world and requests are serialized unless
+// Concurrent is set, so only enable it in development.
+func BudgetMiddleware(opts BudgetOptions) func(http.Handler) http.Handler {
+	var mutex sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !opts.Concurrent {
+				mutex.Lock()
+				defer mutex.Unlock()
+			}
+
+			var before, after runtime.MemStats
+			goroutines := runtime.NumGoroutine()
+			runtime.ReadMemStats(&before)
+			next.ServeHTTP(w, r)
+			runtime.ReadMemStats(&after)
+
+			allocs := after.Mallocs - before.Mallocs
+			bytes := after.TotalAlloc - before.TotalAlloc
+			spawned := runtime.NumGoroutine() - goroutines
+
+			exceeded := make([]string, 0, 3)
+			if opts.MaxAllocs > 0 && allocs > opts.MaxAllocs {
+				exceeded = append(exceeded, "allocs")
+			}
+			if opts.MaxBytes > 0 && bytes > opts.MaxBytes {
+				exceeded = append(exceeded, "bytes")
+			}
+			if opts.MaxGoroutines > 0 && spawned > opts.MaxGoroutines {
+				exceeded = append(exceeded, "goroutines")
+			}
+
+			if len(exceeded) > 0 {
+				logger.Warn("Request over budget", "method", r.Method, "path", r.URL.Path, "exceeded", exceeded, "allocs", allocs, "bytes", bytes, "goroutines", spawned)
+			} else {
+				logger.Debug("Request budget", "method", r.Method, "path", r.URL.Path, "allocs", allocs, "bytes", bytes, "goroutines", spawned)
+			}
+		})
+	}
+}
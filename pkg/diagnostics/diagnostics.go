@@ -0,0 +1,160 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/kevenmiano/nestgo/pkg/container"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/server"
+)
+
+// Prefix is the path prefix of the diagnostics routes
+const Prefix = "/_nestgo"
+
+// Provider describes a provider registered in the DI container
+type Provider struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Module string `json:"module,omitempty"`
+}
+
+// Module describes a registered module and what it declares
+type Module struct {
+	module.Ownership
+	Global      bool     `json:"global,omitempty"`
+	Imports     []string `json:"imports"`
+	Exports     []string `json:"exports"`
+	Controllers []string `json:"controllers"`
+	Providers   []string `json:"providers"`
+}
+
+// Encoder describes a response encoder, with the comparison counters of
+// shadowed encoders
+type Encoder struct {
+	ContentType string               `json:"contentType"`
+	Type        string               `json:"type"`
+	Shadow      *encoder.ShadowStats `json:"shadow,omitempty"`
+}
+
+// exporter is implemented by modules declaring exports
+type exporter interface {
+	GetExports() []interface{}
+}
+
+// globalModule is implemented by modules visible without being imported
+type globalModule interface {
+	IsGlobal() bool
+}
+
+// Register adds the diagnostics routes to a server: GET /_nestgo/routes,
+// /_nestgo/providers, /_nestgo/modules and /_nestgo/encoders answer JSON
+// describing the application. They expose its internals, so only enable them in
+// development.
+func Register(s *server.Server, c *container.Container) {
+	s.RegisterRoute(http.MethodGet, Prefix+"/routes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, s.Routes())
+	})
+	s.RegisterRoute(http.MethodGet, Prefix+"/providers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, Providers(c))
+	})
+	s.RegisterRoute(http.MethodGet, Prefix+"/modules", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, Modules())
+	})
+	s.RegisterRoute(http.MethodGet, Prefix+"/encoders", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, Encoders(s.Encoders()))
+	})
+	logger.Warn("Diagnostics routes enabled; do not expose them in production", "prefix", Prefix)
+}
+
+// Providers lists the providers of a container by name
+func Providers(c *container.Container) []Provider {
+	owners := make(map[string]string)
+	for name, m := range module.GetGlobalRegistry().GetAllModules() {
+		for _, provider := range m.GetServices() {
+			owners[container.ServiceName(provider)] = name
+		}
+	}
+
+	providers := make([]Provider, 0)
+	for name, service := range c.GetAllServices() {
+		providers = append(providers, Provider{Name: name, Type: fmt.Sprintf("%T", service), Module: owners[name]})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+// Encoders lists the response encoders of a registry in negotiation order
+func Encoders(registry *encoder.Registry) []Encoder {
+	encoders := make([]Encoder, 0)
+	for _, registered := range registry.Registered() {
+		described := Encoder{ContentType: registered.ContentType, Type: fmt.Sprintf("%T", registered.Encoder)}
+		if shadow, ok := registered.Encoder.(*encoder.Shadow); ok {
+			stats := shadow.Stats()
+			described.Shadow = &stats
+		}
+		encoders = append(encoders, described)
+	}
+	return encoders
+}
+
+// Modules lists the registered modules by name
+func Modules() []Module {
+	modules := make([]Module, 0)
+	for _, m := range module.GetGlobalRegistry().GetAllModules() {
+		info := Module{
+			Ownership:   module.OwnershipOf(m),
+			Imports:     make([]string, 0),
+			Exports:     make([]string, 0),
+			Controllers: make([]string, 0),
+			Providers:   make([]string, 0),
+		}
+		if global, ok := m.(globalModule); ok {
+			info.Global = global.IsGlobal()
+		}
+		for _, imported := range m.GetImports() {
+			info.Imports = append(info.Imports, imported.GetModuleName())
+		}
+		if declarer, ok := m.(exporter); ok {
+			for _, exported := range declarer.GetExports() {
+				info.Exports = append(info.Exports, exportName(exported))
+			}
+		}
+		for _, controller := range m.GetControllers() {
+			info.Controllers = append(info.Controllers, container.ServiceName(controller))
+		}
+		for _, provider := range m.GetServices() {
+			info.Providers = append(info.Providers, container.ServiceName(provider))
+		}
+		modules = append(modules, info)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Module < modules[j].Module })
+	return modules
+}
+
+// exportName returns the name of an export: a provider name, a provider or
+// a re-exported module
+func exportName(exported interface{}) string {
+	switch value := exported.(type) {
+	case string:
+		return value
+	case module.Module:
+		return value.GetModuleName()
+	}
+	return container.ServiceName(exported)
+}
+
+// writeJSON writes a diagnostics response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	jsonData, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		http.Error(w, `{"error": "Failed to serialize response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
@@ -0,0 +1,153 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// Domain errors services return, or wrap with fmt.Errorf("...: %w", err),
+// without knowing about HTTP. The registry maps them to responses.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrInvalid      = errors.New("invalid")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+// ContentType is the media type of problem responses (RFC 9457)
+const ContentType = "application/problem+json"
+
+// Mapping describes the response of a domain error
+type Mapping struct {
+	// Status is the HTTP status code
+	Status int
+	// Type is a URI identifying the problem type; defaults to about:blank
+	Type string
+	// Title is a short summary of the problem type; defaults to the status text
+	Title string
+}
+
+// mapping is a registered error and its response
+type mapping struct {
+	target error
+	Mapping
+}
+
+// Registry maps domain errors to responses. A registry created with
+// NewRegistry extends the mappings registered with Register, so a server
+// can map errors without affecting others.
+type Registry struct {
+	parent   *Registry
+	mappings []mapping
+	mutex    sync.RWMutex
+}
+
+// defaultRegistry holds the mappings registered with Register
+var defaultRegistry = &Registry{mappings: []mapping{
+	{ErrNotFound, Mapping{Status: http.StatusNotFound}},
+	{ErrConflict, Mapping{Status: http.StatusConflict}},
+	{ErrInvalid, Mapping{Status: http.StatusUnprocessableEntity}},
+	{ErrUnauthorized, Mapping{Status: http.StatusUnauthorized}},
+	{ErrForbidden, Mapping{Status: http.StatusForbidden}},
+}}
+
+// registryKey is the context key of the registry of a server
+type registryKey struct{}
+
+// NewRegistry creates a registry extending the mappings registered with
+// Register; its own mappings are matched first
+func NewRegistry() *Registry {
+	return &Registry{parent: defaultRegistry}
+}
+
+// Register maps errors matching target, per errors.Is, to a response.
+// Registering a target again replaces its mapping; targets registered later
+// are matched first, so specific errors may wrap the built-in ones.
+func Register(target error, m Mapping) {
+	defaultRegistry.Register(target, m)
+}
+
+// Lookup returns the mapping of the most recently registered target err matches
+func Lookup(err error) (Mapping, bool) {
+	return defaultRegistry.Lookup(err)
+}
+
+// WithRegistry returns a context carrying the registry of a server
+func WithRegistry(ctx context.Context, reg *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, reg)
+}
+
+// RegistryFromContext returns the registry of the server handling a
+// request, or the registry of Register
+func RegistryFromContext(ctx context.Context) *Registry {
+	if reg, ok := ctx.Value(registryKey{}).(*Registry); ok {
+		return reg
+	}
+	return defaultRegistry
+}
+
+// Register maps errors matching target to a response in the registry, see
+// Register
+func (reg *Registry) Register(target error, m Mapping) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+
+	for i, entry := range reg.mappings {
+		if entry.target == target {
+			reg.mappings = append(reg.mappings[:i:i], reg.mappings[i+1:]...)
+			break
+		}
+	}
+	reg.mappings = append(reg.mappings, mapping{target: target, Mapping: m})
+}
+
+// Lookup returns the mapping of the most recently registered target err
+// matches, in the registry then in the mappings it extends
+func (reg *Registry) Lookup(err error) (Mapping, bool) {
+	if err == nil {
+		return Mapping{}, false
+	}
+
+	reg.mutex.RLock()
+	for i := len(reg.mappings) - 1; i >= 0; i-- {
+		if errors.Is(err, reg.mappings[i].target) {
+			m := reg.mappings[i].Mapping
+			reg.mutex.RUnlock()
+			return withDefaults(m), true
+		}
+	}
+	reg.mutex.RUnlock()
+
+	if reg.parent != nil {
+		return reg.parent.Lookup(err)
+	}
+	return Mapping{}, false
+}
+
+// withDefaults fills the type and title left empty by a mapping
+func withDefaults(m Mapping) Mapping {
+	if m.Type == "" {
+		m.Type = "about:blank"
+	}
+	if m.Title == "" {
+		m.Title = http.StatusText(m.Status)
+	}
+	return m
+}
+
+// Problem is the JSON body of a domain error response
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// NewProblem returns the body of err under its mapping. The error message is
+// the detail, so it must be safe to show to clients.
+func NewProblem(err error, m Mapping) Problem {
+	return Problem{Type: m.Type, Title: m.Title, Status: m.Status, Detail: err.Error()}
+}
@@ -0,0 +1,76 @@
+package encoder
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kevenmiano/nestgo/pkg/union"
+)
+
+// jsonEncoder writes values with encoding/json, tagging union values with
+// their discriminator. Unless the JSON style is compact, strings are
+// wrapped as {"message": ...} and string slices as {"data": [...], "count": n}.
+type jsonEncoder struct{}
+
+func (e jsonEncoder) Encode(value interface{}) ([]byte, error) {
+	return e.EncodeStyled(value, CurrentJSONStyle())
+}
+
+func (jsonEncoder) EncodeStyled(value interface{}, style JSONStyle) ([]byte, error) {
+	if style.Compact {
+		return union.Marshal(value)
+	}
+	switch v := value.(type) {
+	case []string:
+		return json.Marshal(map[string]interface{}{"data": v, "count": len(v)})
+	case string:
+		return json.Marshal(map[string]interface{}{"message": v})
+	}
+	return union.Marshal(value)
+}
+
+// xmlEncoder writes values with encoding/xml. Maps and other values it
+// cannot encode are unsupported.
+type xmlEncoder struct{}
+
+func (xmlEncoder) Encode(value interface{}) ([]byte, error) {
+	data, err := xml.Marshal(value)
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// textEncoder writes strings, byte slices, text marshalers, stringers and
+// scalars as plain text. Structs and collections are unsupported.
+type textEncoder struct{}
+
+func (textEncoder) Encode(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	case fmt.Stringer:
+		return []byte(v.String()), nil
+	}
+
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return []byte(fmt.Sprint(value)), nil
+	}
+	return nil, fmt.Errorf("%w: %T as text", ErrUnsupported, value)
+}
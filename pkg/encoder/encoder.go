@@ -0,0 +1,273 @@
+package encoder
+
+import (
+	"errors"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content types of the built-in encoders
+const (
+	JSON        = "application/json"
+	XML         = "application/xml"
+	MessagePack = "application/msgpack"
+	Text        = "text/plain; charset=utf-8"
+)
+
+// ErrUnsupported is returned by encoders that cannot encode a value, e.g.
+// XML for maps. The next content type accepted by the client is tried,
+// then JSON.
+var ErrUnsupported = errors.New("encoder: unsupported value")
+
+// Encoder serializes response bodies to a content type
+type Encoder interface {
+	Encode(value interface{}) ([]byte, error)
+}
+
+// Transcoder is implemented by encoders working from the JSON form of a
+// value, as written for the route with its time policy and schema version
+// applied. Such encoders honor json tags and union discriminators for free.
+type Transcoder interface {
+	Transcode(jsonData []byte) ([]byte, error)
+}
+
+// Func adapts a function to Encoder
+type Func func(value interface{}) ([]byte, error)
+
+// Encode calls the function
+func (f Func) Encode(value interface{}) ([]byte, error) {
+	return f(value)
+}
+
+// entry is a registered encoder with the media type it answers to
+type entry struct {
+	contentType string
+	mediaType   string
+	encoder     Encoder
+}
+
+// Registry holds the encoders of content types. A registry created with
+// NewRegistry extends the encoders registered with Register, so a server
+// can add or replace encoders without affecting others.
+type Registry struct {
+	parent *Registry
+	// entries is replaced, never modified, so it is read without the mutex
+	entries []entry
+	mutex   sync.RWMutex
+}
+
+// defaultRegistry holds the encoders registered with Register
+var defaultRegistry = &Registry{}
+
+func init() {
+	Register(JSON, jsonEncoder{})
+	Register(XML, xmlEncoder{})
+	Register("text/xml; charset=utf-8", xmlEncoder{})
+	Register(MessagePack, msgpackEncoder{})
+	Register("application/x-msgpack", msgpackEncoder{})
+	Register(Text, textEncoder{})
+}
+
+// NewRegistry creates a registry extending the encoders registered with
+// Register, including those registered after it was created
+func NewRegistry() *Registry {
+	return &Registry{parent: defaultRegistry}
+}
+
+// Default returns the registry of the encoders registered with Register
+func Default() *Registry {
+	return defaultRegistry
+}
+
+// Register sets the encoder of a content type, replacing any encoder
+// registered before. Parameters such as charset are written in the
+// Content-Type header but ignored when matching the Accept header.
+//
+//	encoder.Register("application/yaml", encoder.Func(yaml.Marshal))
+func Register(contentType string, e Encoder) {
+	defaultRegistry.Register(contentType, e)
+}
+
+// Lookup returns the encoder of a content type
+func Lookup(contentType string) (Encoder, bool) {
+	return defaultRegistry.Lookup(contentType)
+}
+
+// Registered returns the registered encoders in registration order
+func Registered() []Candidate {
+	return defaultRegistry.Registered()
+}
+
+// Negotiate returns the registered encoders acceptable to a client, see
+// Registry.Negotiate
+func Negotiate(accept string) []Candidate {
+	return defaultRegistry.Negotiate(accept)
+}
+
+// Register sets the encoder of a content type in the registry, replacing
+// any encoder registered before, see Register
+func (reg *Registry) Register(contentType string, e Encoder) {
+	mediaType := mediaTypeOf(contentType)
+
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	entries := append(make([]entry, 0, len(reg.entries)+1), reg.entries...)
+	for i := range entries {
+		if entries[i].mediaType == mediaType {
+			entries[i] = entry{contentType: contentType, mediaType: mediaType, encoder: e}
+			reg.entries = entries
+			return
+		}
+	}
+	reg.entries = append(entries, entry{contentType: contentType, mediaType: mediaType, encoder: e})
+}
+
+// encoders returns the encoders of the registry in registration order: the
+// encoders it extends first, replaced by its own for the same media type,
+// then its other encoders
+func (reg *Registry) encoders() []entry {
+	reg.mutex.RLock()
+	own := reg.entries
+	reg.mutex.RUnlock()
+	if reg.parent == nil {
+		return own
+	}
+
+	merged := append([]entry(nil), reg.parent.encoders()...)
+	for _, registered := range own {
+		replaced := false
+		for i := range merged {
+			if merged[i].mediaType == registered.mediaType {
+				merged[i], replaced = registered, true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, registered)
+		}
+	}
+	return merged
+}
+
+// Lookup returns the encoder of a content type
+func (reg *Registry) Lookup(contentType string) (Encoder, bool) {
+	mediaType := mediaTypeOf(contentType)
+	for _, registered := range reg.encoders() {
+		if registered.mediaType == mediaType {
+			return registered.encoder, true
+		}
+	}
+	return nil, false
+}
+
+// Registered returns the encoders of the registry in registration order
+func (reg *Registry) Registered() []Candidate {
+	encoders := reg.encoders()
+	registered := make([]Candidate, 0, len(encoders))
+	for _, entry := range encoders {
+		registered = append(registered, Candidate{ContentType: entry.contentType, Encoder: entry.encoder})
+	}
+	return registered
+}
+
+// Candidate is a content type acceptable to the client with its encoder
+type Candidate struct {
+	ContentType string
+	Encoder     Encoder
+}
+
+// Negotiate returns the encoders of the registry acceptable to a client,
+// best first, per the q values of its Accept header. An empty header
+// accepts JSON only; wildcards pick encoders in registration order, JSON
+// first.
+func (reg *Registry) Negotiate(accept string) []Candidate {
+	if strings.TrimSpace(accept) == "" {
+		accept = JSON
+	}
+
+	encoders := reg.encoders()
+	accepted, excluded := parseAccept(accept)
+	candidates := make([]Candidate, 0, 1)
+	seen := make(map[string]bool)
+	for _, mediaRange := range accepted {
+		for _, registered := range encoders {
+			if seen[registered.mediaType] || excluded[registered.mediaType] || !matches(mediaRange, registered.mediaType) {
+				continue
+			}
+			seen[registered.mediaType] = true
+			candidates = append(candidates, Candidate{ContentType: registered.contentType, Encoder: registered.encoder})
+		}
+	}
+	return candidates
+}
+
+// acceptedType is a media range of an Accept header
+type acceptedType struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses an Accept header into its media ranges with a
+// positive q value, highest first and most specific first among equals,
+// and the media types excluded with q=0
+func parseAccept(accept string) ([]acceptedType, map[string]bool) {
+	accepted := make([]acceptedType, 0)
+	excluded := make(map[string]bool)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			excluded[mediaType] = true
+			continue
+		}
+		accepted = append(accepted, acceptedType{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		if accepted[i].q != accepted[j].q {
+			return accepted[i].q > accepted[j].q
+		}
+		return specificity(accepted[i].mediaType) > specificity(accepted[j].mediaType)
+	})
+	return accepted, excluded
+}
+
+// specificity ranks */* below type/* below full media types
+func specificity(mediaType string) int {
+	switch {
+	case mediaType == "*/*":
+		return 0
+	case strings.HasSuffix(mediaType, "/*"):
+		return 1
+	}
+	return 2
+}
+
+// matches reports whether a registered media type is in an accepted range
+func matches(accepted acceptedType, mediaType string) bool {
+	if accepted.mediaType == "*/*" || accepted.mediaType == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(accepted.mediaType, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// mediaTypeOf returns the lower-cased media type of a content type
+func mediaTypeOf(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
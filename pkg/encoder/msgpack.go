@@ -0,0 +1,173 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// msgpackEncoder writes MessagePack transcoded from the JSON form of
+// values, keeping the order of object properties
+type msgpackEncoder struct{}
+
+func (e msgpackEncoder) Encode(value interface{}) ([]byte, error) {
+	jsonData, err := jsonEncoder{}.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return e.Transcode(jsonData)
+}
+
+func (msgpackEncoder) Transcode(jsonData []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.UseNumber()
+
+	var buffer bytes.Buffer
+	if err := transcodeValue(decoder, &buffer); err != nil {
+		return nil, fmt.Errorf("encoder: msgpack: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("encoder: msgpack: trailing data after JSON value")
+	}
+	return buffer.Bytes(), nil
+}
+
+// transcodeValue writes the next JSON value of decoder as MessagePack
+func transcodeValue(decoder *json.Decoder, buffer *bytes.Buffer) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := token.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if t {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case string:
+		writeString(buffer, t)
+	case json.Number:
+		writeNumber(buffer, t)
+	case json.Delim:
+		// Containers are written to a separate buffer as their length
+		// prefixes the elements
+		var elements bytes.Buffer
+		count := 0
+		for decoder.More() {
+			if t == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				writeString(&elements, key.(string))
+			}
+			if err := transcodeValue(decoder, &elements); err != nil {
+				return err
+			}
+			count++
+		}
+		if _, err := decoder.Token(); err != nil {
+			return err
+		}
+
+		if t == '{' {
+			writeHeader(buffer, count, 0x80, 0xde, 0xdf)
+		} else {
+			writeHeader(buffer, count, 0x90, 0xdc, 0xdd)
+		}
+		buffer.Write(elements.Bytes())
+	}
+	return nil
+}
+
+// writeHeader writes the length of a map or array, fixed up to 15 elements
+func writeHeader(buffer *bytes.Buffer, count int, fixed, code16, code32 byte) {
+	switch {
+	case count < 16:
+		buffer.WriteByte(fixed | byte(count))
+	case count <= math.MaxUint16:
+		buffer.WriteByte(code16)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(count)))
+	default:
+		buffer.WriteByte(code32)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(count)))
+	}
+}
+
+// writeString writes a str, fixed up to 31 bytes
+func writeString(buffer *bytes.Buffer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		buffer.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buffer.WriteByte(0xd9)
+		buffer.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buffer.WriteByte(0xda)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		buffer.WriteByte(0xdb)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+	buffer.WriteString(s)
+}
+
+// writeNumber writes an integer in its smallest form, or a float64
+func writeNumber(buffer *bytes.Buffer, number json.Number) {
+	if i, err := strconv.ParseInt(number.String(), 10, 64); err == nil {
+		writeInt(buffer, i)
+		return
+	}
+	if u, err := strconv.ParseUint(number.String(), 10, 64); err == nil {
+		buffer.WriteByte(0xcf)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, u))
+		return
+	}
+
+	f, _ := number.Float64()
+	buffer.WriteByte(0xcb)
+	buffer.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+// writeInt writes a signed integer as a fixint or the smallest int/uint
+func writeInt(buffer *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		buffer.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		buffer.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint8:
+		buffer.WriteByte(0xcc)
+		buffer.WriteByte(byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= 0 && i <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case i >= 0:
+		buffer.WriteByte(0xcf)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	case i >= math.MinInt8:
+		buffer.WriteByte(0xd0)
+		buffer.WriteByte(byte(i))
+	case i >= math.MinInt16:
+		buffer.WriteByte(0xd1)
+		buffer.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i >= math.MinInt32:
+		buffer.WriteByte(0xd2)
+		buffer.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	default:
+		buffer.WriteByte(0xd3)
+		buffer.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
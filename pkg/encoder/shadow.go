@@ -0,0 +1,211 @@
+package encoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"sort"
+	"sync/atomic"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// DefaultShadowLogLimit bounds the mismatches logged by a Shadow
+const DefaultShadowLogLimit = 100
+
+// maxDiffPaths bounds the differing paths listed in a mismatch log
+const maxDiffPaths = 10
+
+// ShadowOptions configures a Shadow
+type ShadowOptions struct {
+	// Candidate is the encoder being rolled out
+	Candidate Encoder
+	// Percent of responses also encoded with the candidate, from 0 to 100;
+	// zero compares every response
+	Percent float64
+	// Equal compares the primary and candidate outputs; defaults to
+	// comparing JSON documents semantically, ignoring property order and
+	// formatting, and bytes for other outputs
+	Equal func(primary, candidate []byte) bool
+	// LogLimit bounds the mismatches logged, DefaultShadowLogLimit when zero;
+	// every mismatch is counted
+	LogLimit int64
+}
+
+// ShadowStats counts the comparisons of a Shadow
+type ShadowStats struct {
+	Compared        int64 `json:"compared"`
+	Mismatched      int64 `json:"mismatched"`
+	CandidateErrors int64 `json:"candidateErrors"`
+}
+
+// Shadow is an Encoder answering with its primary encoder while encoding a
+// sample of the values with a candidate, logging and counting the outputs
+// that differ, to de-risk replacing an encoder:
+//
+//	current, _ := encoder.Lookup(encoder.JSON)
+//	shadow := encoder.NewShadow(current, encoder.ShadowOptions{Candidate: fastJSON, Percent: 10})
+//	application.WithEncoder(encoder.JSON, shadow)
+type Shadow struct {
+	primary Encoder
+	options ShadowOptions
+
+	compared        atomic.Int64
+	mismatched      atomic.Int64
+	candidateErrors atomic.Int64
+}
+
+// NewShadow creates a Shadow of primary
+func NewShadow(primary Encoder, options ShadowOptions) *Shadow {
+	if options.Percent <= 0 || options.Percent > 100 {
+		options.Percent = 100
+	}
+	if options.Equal == nil {
+		options.Equal = equalOutputs
+	}
+	if options.LogLimit <= 0 {
+		options.LogLimit = DefaultShadowLogLimit
+	}
+	return &Shadow{primary: primary, options: options}
+}
+
+// Encode returns the output of the primary encoder, comparing it with the
+// candidate for the sampled values. Candidate failures never reach clients.
+func (s *Shadow) Encode(value interface{}) ([]byte, error) {
+	return s.EncodeStyled(value, CurrentJSONStyle())
+}
+
+// EncodeStyled is Encode with the encoders in a JSON style
+func (s *Shadow) EncodeStyled(value interface{}, style JSONStyle) ([]byte, error) {
+	data, err := EncodeStyled(s.primary, value, style)
+	if err != nil || s.options.Candidate == nil || rand.Float64()*100 >= s.options.Percent {
+		return data, err
+	}
+
+	s.compare(value, data, style)
+	return data, nil
+}
+
+// compare encodes value with the candidate and records the outcome
+func (s *Shadow) compare(value interface{}, primary []byte, style JSONStyle) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			s.candidateErrors.Add(1)
+			logger.Error("Candidate encoder panicked", "type", fmt.Sprintf("%T", value), "error", recovered)
+		}
+	}()
+
+	s.compared.Add(1)
+	candidate, err := EncodeStyled(s.options.Candidate, value, style)
+	if err != nil {
+		if s.candidateErrors.Add(1) <= s.options.LogLimit {
+			logger.Warn("Candidate encoder failed", "type", fmt.Sprintf("%T", value), "error", err)
+		}
+		return
+	}
+	if s.options.Equal(primary, candidate) {
+		return
+	}
+
+	mismatched := s.mismatched.Add(1)
+	if mismatched <= s.options.LogLimit {
+		logger.Warn("Encoder outputs differ",
+			"type", fmt.Sprintf("%T", value),
+			"paths", diffPaths(primary, candidate),
+			"mismatched", mismatched,
+			"compared", s.compared.Load())
+	}
+}
+
+// Stats returns a snapshot of the comparison counters
+func (s *Shadow) Stats() ShadowStats {
+	return ShadowStats{
+		Compared:        s.compared.Load(),
+		Mismatched:      s.mismatched.Load(),
+		CandidateErrors: s.candidateErrors.Load(),
+	}
+}
+
+// equalOutputs compares JSON documents semantically, and other outputs
+// byte for byte
+func equalOutputs(primary, candidate []byte) bool {
+	if bytes.Equal(primary, candidate) {
+		return true
+	}
+	var primaryTree, candidateTree interface{}
+	if decodeJSON(primary, &primaryTree) != nil || decodeJSON(candidate, &candidateTree) != nil {
+		return false
+	}
+	return reflect.DeepEqual(primaryTree, candidateTree)
+}
+
+// decodeJSON decodes data keeping numbers exact
+func decodeJSON(data []byte, target *interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(target)
+}
+
+// diffPaths lists the JSON paths where the outputs differ, or "$" for
+// outputs that are not JSON
+func diffPaths(primary, candidate []byte) []string {
+	var primaryTree, candidateTree interface{}
+	if decodeJSON(primary, &primaryTree) != nil || decodeJSON(candidate, &candidateTree) != nil {
+		return []string{"$"}
+	}
+	paths := make([]string, 0)
+	collectDiffs("$", primaryTree, candidateTree, &paths)
+	return paths
+}
+
+// collectDiffs appends the paths where two JSON trees differ
+func collectDiffs(path string, a, b interface{}, paths *[]string) {
+	if len(*paths) >= maxDiffPaths {
+		return
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for key := range av {
+			keys = append(keys, key)
+		}
+		for key := range bv {
+			if _, ok := av[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, inA := av[key]
+			_, inB := bv[key]
+			if inA != inB {
+				if len(*paths) < maxDiffPaths {
+					*paths = append(*paths, path+"."+key)
+				}
+				continue
+			}
+			collectDiffs(path+"."+key, av[key], bv[key], paths)
+		}
+		return
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			break
+		}
+		for i := range av {
+			collectDiffs(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i], paths)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*paths = append(*paths, path)
+	}
+}
@@ -0,0 +1,106 @@
+package encoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// DefaultIndent is the indentation of pretty JSON responses
+const DefaultIndent = "  "
+
+// JSONStyle configures the layout of JSON responses. It can be loaded with
+// the application configuration, e.g. from JSON_PRETTY and JSON_COMPACT:
+//
+//	type AppConfig struct {
+//		JSON encoder.JSONStyle
+//	}
+//
+//	application.WithJSONStyle(cfg.JSON)
+type JSONStyle struct {
+	// Pretty indents responses for reading them in development
+	Pretty bool `default:"false"`
+	// Indent is the indentation of pretty responses, DefaultIndent when empty
+	Indent string
+	// Compact leaves out the envelopes of the default output: strings and
+	// string slices are written as JSON values instead of {"message": ...}
+	// and {"data": [...], "count": n}, handlers without a result answer 204
+	// No Content and nil results null
+	Compact bool `default:"false"`
+}
+
+var (
+	jsonStyle      = JSONStyle{Indent: DefaultIndent}
+	jsonStyleMutex sync.RWMutex
+)
+
+// jsonStyleKey is the context key of the JSON style of a server
+type jsonStyleKey struct{}
+
+// SetJSONStyle sets the layout of JSON responses of servers without their
+// own style
+func SetJSONStyle(style JSONStyle) {
+	jsonStyleMutex.Lock()
+	defer jsonStyleMutex.Unlock()
+	jsonStyle = style.withDefaults()
+}
+
+// CurrentJSONStyle returns the layout of JSON responses of servers without
+// their own style
+func CurrentJSONStyle() JSONStyle {
+	jsonStyleMutex.RLock()
+	defer jsonStyleMutex.RUnlock()
+	return jsonStyle
+}
+
+// WithJSONStyle returns a context carrying the JSON style of a server
+func WithJSONStyle(ctx context.Context, style JSONStyle) context.Context {
+	return context.WithValue(ctx, jsonStyleKey{}, style.withDefaults())
+}
+
+// JSONStyleFromContext returns the JSON style of the server handling a
+// request, or the current style
+func JSONStyleFromContext(ctx context.Context) JSONStyle {
+	if style, ok := ctx.Value(jsonStyleKey{}).(JSONStyle); ok {
+		return style
+	}
+	return CurrentJSONStyle()
+}
+
+// withDefaults fills the unset fields with the defaults
+func (s JSONStyle) withDefaults() JSONStyle {
+	if s.Indent == "" {
+		s.Indent = DefaultIndent
+	}
+	return s
+}
+
+// StyledEncoder is implemented by encoders whose output depends on the JSON
+// style, like the built-in JSON encoder. Encode uses the current style.
+type StyledEncoder interface {
+	Encoder
+	EncodeStyled(value interface{}, style JSONStyle) ([]byte, error)
+}
+
+// EncodeStyled serializes a value with an encoder in a JSON style, which
+// only styled encoders depend on
+func EncodeStyled(e Encoder, value interface{}, style JSONStyle) ([]byte, error) {
+	if styled, ok := e.(StyledEncoder); ok {
+		return styled.EncodeStyled(value, style)
+	}
+	return e.Encode(value)
+}
+
+// Format lays out an encoded JSON document in the style, returning it
+// unchanged when it is not pretty or not valid JSON
+func (s JSONStyle) Format(data []byte) []byte {
+	if !s.Pretty {
+		return data
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", s.Indent); err != nil {
+		return data
+	}
+	return indented.Bytes()
+}
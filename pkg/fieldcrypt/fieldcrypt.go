@@ -0,0 +1,188 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const (
+	// TagEncrypt marks string fields encrypted at rest
+	TagEncrypt = "encrypt"
+
+	// EnvKey is the environment variable read by EnvKeyProvider
+	EnvKey = "NESTGO_ENCRYPTION_KEY"
+
+	// ciphertextPrefix marks encrypted values so they are never encrypted twice
+	ciphertextPrefix = "enc:v1:"
+)
+
+// ErrInvalidCiphertext is returned when an encrypted value cannot be decoded
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// KeyProvider supplies the AES key (16, 24 or 32 bytes)
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// StaticKeyProvider returns a fixed key
+type StaticKeyProvider []byte
+
+// Key returns the static key
+func (skp StaticKeyProvider) Key() ([]byte, error) {
+	return skp, nil
+}
+
+// EnvKeyProvider reads a base64 encoded key from an environment variable
+type EnvKeyProvider struct {
+	// Variable defaults to EnvKey
+	Variable string
+}
+
+// Key decodes the key from the environment
+func (ekp EnvKeyProvider) Key() ([]byte, error) {
+	variable := ekp.Variable
+	if variable == "" {
+		variable = EnvKey
+	}
+
+	encoded := os.Getenv(variable)
+	if encoded == "" {
+		return nil, fmt.Errorf("encryption key variable %s is not set", variable)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key variable %s is not valid base64: %w", variable, err)
+	}
+	return key, nil
+}
+
+// Cipher encrypts and decrypts struct fields tagged with encrypt:"true"
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New creates a cipher using the key from the provider
+func New(provider KeyProvider) (*Cipher, error) {
+	key, err := provider.Key()
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts a plaintext value; already encrypted values are returned as is
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return ciphertextPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value produced by Encrypt; plaintext values are returned as is
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, ciphertextPrefix))
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(plaintext), nil
+}
+
+// EncryptFields encrypts in place every tagged string field of a struct pointer
+func (c *Cipher) EncryptFields(target interface{}) error {
+	return c.transform(target, c.Encrypt)
+}
+
+// DecryptFields decrypts in place every tagged string field of a struct pointer
+func (c *Cipher) DecryptFields(target interface{}) error {
+	return c.transform(target, c.Decrypt)
+}
+
+// IsEncrypted reports whether a value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+// transform applies fn to the tagged fields of a struct pointer
+func (c *Cipher) transform(target interface{}, fn func(string) (string, error)) error {
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("fieldcrypt: target must be a pointer to a struct, got %T", target)
+	}
+	return c.transformStruct(value.Elem(), fn)
+}
+
+// transformStruct walks a struct value, recursing into nested structs and pointers
+func (c *Cipher) transformStruct(structValue reflect.Value, fn func(string) (string, error)) error {
+	structType := structValue.Type()
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		fieldValue := structValue.Field(i)
+
+		if !fieldValue.CanSet() {
+			continue
+		}
+
+		if field.Tag.Get(TagEncrypt) == "true" {
+			if fieldValue.Kind() != reflect.String {
+				return fmt.Errorf("fieldcrypt: field %s.%s must be a string", structType.Name(), field.Name)
+			}
+
+			result, err := fn(fieldValue.String())
+			if err != nil {
+				return fmt.Errorf("fieldcrypt: field %s.%s: %w", structType.Name(), field.Name, err)
+			}
+			fieldValue.SetString(result)
+			continue
+		}
+
+		switch {
+		case fieldValue.Kind() == reflect.Struct:
+			if err := c.transformStruct(fieldValue, fn); err != nil {
+				return err
+			}
+		case fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() && fieldValue.Elem().Kind() == reflect.Struct:
+			if err := c.transformStruct(fieldValue.Elem(), fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
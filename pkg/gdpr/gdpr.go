@@ -0,0 +1,313 @@
+package gdpr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/container"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/service"
+)
+
+// DataHandler is implemented by providers owning personal data of a subject
+type DataHandler interface {
+	// DataCategory names the data owned by the handler (e.g. "orders")
+	DataCategory() string
+	// ExportSubjectData returns the subject data to include in the export bundle
+	ExportSubjectData(subjectID string) (interface{}, error)
+	// EraseSubjectData deletes or anonymizes the subject data
+	EraseSubjectData(subjectID string) error
+}
+
+// JobType is the kind of privacy job
+type JobType string
+
+const (
+	JobExport  JobType = "export"
+	JobErasure JobType = "erasure"
+)
+
+// JobStatus is the state of a privacy job or step
+type JobStatus string
+
+const (
+	StatusPending   JobStatus = "pending"
+	StatusRunning   JobStatus = "running"
+	StatusCompleted JobStatus = "completed"
+	StatusFailed    JobStatus = "failed"
+)
+
+// Step is the progress of one data handler within a job
+type Step struct {
+	Category string    `json:"category"`
+	Status   JobStatus `json:"status"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Job tracks an export or erasure of a subject across every data handler
+type Job struct {
+	ID          string                 `json:"id"`
+	Type        JobType                `json:"type"`
+	SubjectID   string                 `json:"subjectId"`
+	Status      JobStatus              `json:"status"`
+	Steps       []Step                 `json:"steps"`
+	Bundle      map[string]interface{} `json:"bundle,omitempty"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	CompletedAt *time.Time             `json:"completedAt,omitempty"`
+}
+
+// Progress returns the fraction of finished steps
+func (j *Job) Progress() float64 {
+	if len(j.Steps) == 0 {
+		return 1
+	}
+
+	finished := 0
+	for _, step := range j.Steps {
+		if step.Status == StatusCompleted || step.Status == StatusFailed {
+			finished++
+		}
+	}
+	return float64(finished) / float64(len(j.Steps))
+}
+
+// AuditRecord is written for every action taken on subject data
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	JobID     string    `json:"jobId"`
+	JobType   JobType   `json:"jobType"`
+	SubjectID string    `json:"subjectId"`
+	Category  string    `json:"category,omitempty"`
+	Outcome   JobStatus `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// AuditSink persists audit records
+type AuditSink interface {
+	Record(record AuditRecord)
+}
+
+// LogAuditSink writes audit records to the application log
+type LogAuditSink struct{}
+
+// Record logs the audit record
+func (LogAuditSink) Record(record AuditRecord) {
+	logger.Info("AUDIT: Privacy job event",
+		"jobId", record.JobID,
+		"jobType", record.JobType,
+		"subjectId", record.SubjectID,
+		"category", record.Category,
+		"outcome", record.Outcome,
+		"error", record.Error)
+}
+
+// PrivacyService orchestrates subject data exports and erasures.
+// Register it as a provider and inject it with `inject:"PrivacyService"`.
+type PrivacyService struct {
+	service.BaseService
+	handlers map[string]DataHandler
+	jobs     map[string]*Job
+	audit    AuditSink
+	mutex    sync.RWMutex
+}
+
+// NewPrivacyService creates a privacy service; a nil sink logs audit records
+func NewPrivacyService(audit AuditSink) *PrivacyService {
+	if audit == nil {
+		audit = LogAuditSink{}
+	}
+
+	return &PrivacyService{
+		handlers: make(map[string]DataHandler),
+		jobs:     make(map[string]*Job),
+		audit:    audit,
+	}
+}
+
+// RegisterHandler registers a data handler
+func (ps *PrivacyService) RegisterHandler(handler DataHandler) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+
+	ps.handlers[handler.DataCategory()] = handler
+	logger.Info("Privacy data handler registered", "category", handler.DataCategory())
+}
+
+// StartExport starts collecting the export bundle of a subject
+func (ps *PrivacyService) StartExport(subjectID string) (*Job, error) {
+	return ps.start(JobExport, subjectID)
+}
+
+// StartErasure starts erasing the data of a subject
+func (ps *PrivacyService) StartErasure(subjectID string) (*Job, error) {
+	return ps.start(JobErasure, subjectID)
+}
+
+// GetJob returns a snapshot of a job
+func (ps *PrivacyService) GetJob(id string) (*Job, bool) {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	job, exists := ps.jobs[id]
+	if !exists {
+		return nil, false
+	}
+	return job.snapshot(), true
+}
+
+// start creates a job and runs it in the background
+func (ps *PrivacyService) start(jobType JobType, subjectID string) (*Job, error) {
+	if subjectID == "" {
+		return nil, fmt.Errorf("subject ID is required")
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	ps.mutex.Lock()
+	categories := make([]string, 0, len(ps.handlers))
+	for category := range ps.handlers {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	job := &Job{
+		ID:        id,
+		Type:      jobType,
+		SubjectID: subjectID,
+		Status:    StatusPending,
+		Steps:     make([]Step, 0, len(categories)),
+		CreatedAt: clock.Now(),
+	}
+	if jobType == JobExport {
+		job.Bundle = make(map[string]interface{})
+	}
+	for _, category := range categories {
+		job.Steps = append(job.Steps, Step{Category: category, Status: StatusPending})
+	}
+	ps.jobs[id] = job
+	snapshot := job.snapshot()
+	ps.mutex.Unlock()
+
+	go ps.run(job)
+	return snapshot, nil
+}
+
+// run executes every step of a job sequentially
+func (ps *PrivacyService) run(job *Job) {
+	ps.updateJob(job, func() { job.Status = StatusRunning })
+
+	failed := false
+	for i := range job.Steps {
+		ps.mutex.RLock()
+		category := job.Steps[i].Category
+		handler := ps.handlers[category]
+		ps.mutex.RUnlock()
+
+		ps.updateJob(job, func() { job.Steps[i].Status = StatusRunning })
+
+		var data interface{}
+		var err error
+		if job.Type == JobExport {
+			data, err = handler.ExportSubjectData(job.SubjectID)
+		} else {
+			err = handler.EraseSubjectData(job.SubjectID)
+		}
+
+		record := AuditRecord{
+			Time:      clock.Now(),
+			JobID:     job.ID,
+			JobType:   job.Type,
+			SubjectID: job.SubjectID,
+			Category:  category,
+			Outcome:   StatusCompleted,
+		}
+
+		ps.updateJob(job, func() {
+			if err != nil {
+				job.Steps[i].Status = StatusFailed
+				job.Steps[i].Error = err.Error()
+				record.Outcome = StatusFailed
+				record.Error = err.Error()
+				failed = true
+				return
+			}
+			job.Steps[i].Status = StatusCompleted
+			if job.Bundle != nil {
+				job.Bundle[category] = data
+			}
+		})
+		ps.audit.Record(record)
+	}
+
+	ps.updateJob(job, func() {
+		now := clock.Now()
+		job.CompletedAt = &now
+		job.Status = StatusCompleted
+		if failed {
+			job.Status = StatusFailed
+		}
+	})
+
+	ps.audit.Record(AuditRecord{
+		Time:      clock.Now(),
+		JobID:     job.ID,
+		JobType:   job.Type,
+		SubjectID: job.SubjectID,
+		Outcome:   job.Status,
+	})
+}
+
+// updateJob mutates a job under the service lock
+func (ps *PrivacyService) updateJob(job *Job, update func()) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	update()
+}
+
+// snapshot returns a copy of the job safe to hand out
+func (j *Job) snapshot() *Job {
+	copied := *j
+	copied.Steps = append([]Step{}, j.Steps...)
+	if j.Bundle != nil {
+		copied.Bundle = make(map[string]interface{}, len(j.Bundle))
+		for category, data := range j.Bundle {
+			copied.Bundle[category] = data
+		}
+	}
+	return &copied
+}
+
+// Discover registers every DataHandler provider of the container with
+// every PrivacyService provider of the container
+func Discover(c *container.Container) {
+	services := c.GetAllServices()
+
+	privacyServices := make([]*PrivacyService, 0)
+	handlers := make([]DataHandler, 0)
+	for _, provider := range services {
+		if privacyService, ok := provider.(*PrivacyService); ok {
+			privacyServices = append(privacyServices, privacyService)
+		}
+		if handler, ok := provider.(DataHandler); ok {
+			handlers = append(handlers, handler)
+		}
+	}
+
+	for _, privacyService := range privacyServices {
+		for _, handler := range handlers {
+			privacyService.RegisterHandler(handler)
+		}
+	}
+}
+
+// newJobID returns a random job identifier
+func newJobID() (string, error) {
+	return ids.Hex(8)
+}
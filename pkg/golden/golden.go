@@ -0,0 +1,203 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Placeholder replaces scrubbed values
+const Placeholder = "<scrubbed>"
+
+// Dir is where golden files are kept, relative to the package under test
+var Dir = filepath.Join("testdata", "golden")
+
+// update rewrites golden files instead of comparing them:
+// go test ./... -update-golden
+var update = flag.Bool("update-golden", false, "rewrite golden response files")
+
+// volatileHeaders change on every response and are left out of snapshots
+var volatileHeaders = []string{"Date"}
+
+// Snapshot is the comparable form of a response
+type Snapshot struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Scrubber masks volatile parts of a snapshot, such as IDs or timestamps
+type Scrubber func(*Snapshot)
+
+// ScrubFields replaces the values of JSON object fields with these names,
+// at any depth, with Placeholder
+func ScrubFields(names ...string) Scrubber {
+	return func(s *Snapshot) {
+		var body interface{}
+		if err := json.Unmarshal(s.Body, &body); err != nil {
+			return
+		}
+		var scrubbed bytes.Buffer
+		encoder := json.NewEncoder(&scrubbed)
+		encoder.SetEscapeHTML(false)
+		if encoder.Encode(scrubFields(body, names)) == nil {
+			s.Body = bytes.TrimRight(scrubbed.Bytes(), "\n")
+		}
+	}
+}
+
+// scrubFields walks a decoded JSON value replacing the named fields
+func scrubFields(value interface{}, names []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if containsName(names, key) {
+				v[key] = Placeholder
+				continue
+			}
+			v[key] = scrubFields(field, names)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrubFields(item, names)
+		}
+	}
+	return value
+}
+
+// ScrubHeaders replaces the values of these response headers with Placeholder
+func ScrubHeaders(names ...string) Scrubber {
+	return func(s *Snapshot) {
+		for _, name := range names {
+			if s.Header.Get(name) != "" {
+				s.Header.Set(name, Placeholder)
+			}
+		}
+	}
+}
+
+// ScrubPattern replaces every match of pattern in the body with replacement,
+// e.g. ScrubPattern(uuidPattern, "<uuid>")
+func ScrubPattern(pattern *regexp.Regexp, replacement string) Scrubber {
+	return func(s *Snapshot) {
+		s.Body = pattern.ReplaceAll(s.Body, []byte(replacement))
+	}
+}
+
+// FromRecorder captures the response written to a recorder
+func FromRecorder(rec *httptest.ResponseRecorder) *Snapshot {
+	return &Snapshot{
+		Status: rec.Code,
+		Header: rec.Header().Clone(),
+		Body:   rec.Body.Bytes(),
+	}
+}
+
+// Bytes serializes the snapshot: the status, the sorted headers and the
+// body, indented when it is JSON
+func (s *Snapshot) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("HTTP " + strconv.Itoa(s.Status) + " " + http.StatusText(s.Status) + "\n")
+
+	names := make([]string, 0, len(s.Header))
+	for name := range s.Header {
+		if !containsName(volatileHeaders, http.CanonicalHeaderKey(name)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range s.Header[name] {
+			buf.WriteString(name + ": " + value + "\n")
+		}
+	}
+	buf.WriteString("\n")
+
+	var indented bytes.Buffer
+	if json.Indent(&indented, s.Body, "", "  ") == nil {
+		buf.Write(indented.Bytes())
+	} else {
+		buf.Write(s.Body)
+	}
+	if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// Assert compares a scrubbed snapshot to the golden file name, writing the
+// file instead when it does not exist or -update-golden is set
+func Assert(t testing.TB, name string, snapshot *Snapshot, scrubbers ...Scrubber) {
+	t.Helper()
+
+	for _, scrub := range scrubbers {
+		scrub(snapshot)
+	}
+	got := snapshot.Bytes()
+	path := filepath.Join(Dir, name+".golden")
+
+	want, err := os.ReadFile(path)
+	if *update || os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		t.Logf("golden: wrote %s", path)
+		return
+	}
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("golden: response differs from %s (run with -update-golden to accept)\n%s", path, Diff(string(want), string(got)))
+	}
+}
+
+// AssertResponse is Assert for a response written to a recorder
+func AssertResponse(t testing.TB, name string, rec *httptest.ResponseRecorder, scrubbers ...Scrubber) {
+	t.Helper()
+	Assert(t, name, FromRecorder(rec), scrubbers...)
+}
+
+// Diff describes the first line that differs between want and got, or
+// returns "" when they are equal
+func Diff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
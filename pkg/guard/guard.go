@@ -0,0 +1,180 @@
+package guard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/domain"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// TagGuards lists the guards protecting a controller (on its BaseController
+// field) or a route field: `guards:"AuthGuard,RolesGuard"`. Names refer to
+// guards registered by name or to providers in the DI container.
+const TagGuards = "guards"
+
+// TagName names a route for handlers, guards and interceptors telling
+// routes apart: `route:"GET /:id" name:"users.get"`. Routes default to
+// Controller.Handler.
+const TagName = "name"
+
+// Guard decides whether a request may reach the route handler, like a
+// NestJS guard. Returning false rejects the request with 403 Forbidden;
+// errors implementing StatusCode() int use that status instead, and
+// registered domain errors their problem response.
+type Guard interface {
+	CanActivate(r *http.Request) (bool, error)
+}
+
+// Activator is a Guard deriving request values for the handler, such as
+// the authenticated user. Middleware calls Activate instead of CanActivate
+// and passes the returned request on.
+type Activator interface {
+	Guard
+	Activate(r *http.Request) (*http.Request, bool, error)
+}
+
+// GuardFunc adapts a function to the Guard interface
+type GuardFunc func(r *http.Request) (bool, error)
+
+// CanActivate calls f(r)
+func (f GuardFunc) CanActivate(r *http.Request) (bool, error) {
+	return f(r)
+}
+
+// Route describes the route handling a request. Every controller route
+// carries it, see RouteFromRequest.
+type Route struct {
+	// Name is the name tag of the route, Controller.Handler by default
+	Name       string
+	Module     string
+	Controller string
+	Handler    string
+	Method     string
+	// Path is the pattern of the route, e.g. /users/:id
+	Path string
+	// Tag is the struct tag of the route field, for guards reading route
+	// metadata such as `roles:"admin"`
+	Tag reflect.StructTag
+}
+
+// routeKey is the context key of the current route
+type routeKey struct{}
+
+// routeSlotKey is the context key of the slot filled by CaptureRoute
+type routeSlotKey struct{}
+
+// routeSlot receives the route handling a captured request
+type routeSlot struct {
+	route Route
+	ok    bool
+}
+
+// WithRoute returns a request carrying the route being executed
+func WithRoute(r *http.Request, route Route) *http.Request {
+	if slot, ok := r.Context().Value(routeSlotKey{}).(*routeSlot); ok {
+		slot.route, slot.ok = route, true
+	}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, route))
+}
+
+// CaptureRoute returns a request recording the route that ends up handling
+// it, for middlewares running before routing such as metrics. The returned
+// function reports the route once the request was served, false when no
+// route matched.
+func CaptureRoute(r *http.Request) (*http.Request, func() (Route, bool)) {
+	slot := &routeSlot{}
+	r = r.WithContext(context.WithValue(r.Context(), routeSlotKey{}, slot))
+	return r, func() (Route, bool) {
+		return slot.route, slot.ok
+	}
+}
+
+// RouteFromRequest returns the route handling the request
+func RouteFromRequest(r *http.Request) (Route, bool) {
+	return RouteFromContext(r.Context())
+}
+
+// RouteFromContext returns the route handling the request of ctx
+func RouteFromContext(ctx context.Context) (Route, bool) {
+	route, ok := ctx.Value(routeKey{}).(Route)
+	return route, ok
+}
+
+// ParseNames splits a guards tag into guard names
+func ParseNames(tag string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(tag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Middleware returns an HTTP middleware evaluating the guards in order,
+// rejecting the request at the first guard that does not allow it
+func Middleware(route Route, guards ...Guard) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = WithRoute(r, route)
+
+			for _, g := range guards {
+				var allowed bool
+				var err error
+				if activator, ok := g.(Activator); ok {
+					var activated *http.Request
+					activated, allowed, err = activator.Activate(r)
+					if activated != nil {
+						r = activated
+					}
+				} else {
+					allowed, err = g.CanActivate(r)
+				}
+				if err != nil {
+					writeError(w, r, route, err)
+					return
+				}
+				if !allowed {
+					logger.Warn("Request rejected by guard", "guard", reflect.TypeOf(g).String(), "controller", route.Controller, "handler", route.Handler, "path", r.URL.Path)
+					writeJSON(w, http.StatusForbidden, "Forbidden resource")
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeError writes the response of a guard returning an error
+func writeError(w http.ResponseWriter, r *http.Request, route Route, err error) {
+	var coder interface{ StatusCode() int }
+	if errors.As(err, &coder) {
+		writeJSON(w, coder.StatusCode(), err.Error())
+		return
+	}
+	if mapping, ok := domain.RegistryFromContext(r.Context()).Lookup(err); ok && mapping.Status < http.StatusInternalServerError {
+		jsonData, _ := json.Marshal(domain.NewProblem(err, mapping))
+		w.Header().Set("Content-Type", domain.ContentType)
+		w.WriteHeader(mapping.Status)
+		w.Write(jsonData)
+		return
+	}
+
+	logger.Error("Guard failed", "controller", route.Controller, "handler", route.Handler, "error", err)
+	writeJSON(w, http.StatusInternalServerError, "Internal server error")
+}
+
+// writeJSON writes a JSON error response
+func writeJSON(w http.ResponseWriter, status int, message string) {
+	jsonData, _ := json.Marshal(map[string]string{"error": message})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
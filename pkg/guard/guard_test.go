@@ -0,0 +1,128 @@
+package guard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// userKey is the context key of the user set by userActivator
+type userKey struct{}
+
+// userActivator allows requests with a user header, passing the user on
+type userActivator struct{}
+
+func (userActivator) CanActivate(r *http.Request) (bool, error) {
+	return r.Header.Get("X-User") != "", nil
+}
+
+func (userActivator) Activate(r *http.Request) (*http.Request, bool, error) {
+	user := r.Header.Get("X-User")
+	if user == "" {
+		return nil, false, nil
+	}
+	return r.WithContext(context.WithValue(r.Context(), userKey{}, user)), true, nil
+}
+
+// statusError is a guard error with its own status
+type statusError struct{}
+
+func (statusError) Error() string   { return "Too many attempts" }
+func (statusError) StatusCode() int { return http.StatusTooManyRequests }
+
+func TestMiddleware(t *testing.T) {
+	allow := GuardFunc(func(r *http.Request) (bool, error) { return true, nil })
+	deny := GuardFunc(func(r *http.Request) (bool, error) { return false, nil })
+	fail := GuardFunc(func(r *http.Request) (bool, error) { return false, errors.New("store unavailable") })
+	limited := GuardFunc(func(r *http.Request) (bool, error) { return false, statusError{} })
+	requireUser := GuardFunc(func(r *http.Request) (bool, error) {
+		return r.Context().Value(userKey{}) != nil, nil
+	})
+	route := Route{Name: "users.get", Controller: "UserController", Handler: "Get", Method: http.MethodGet, Path: "/users/:id"}
+
+	tests := []struct {
+		name     string
+		guards   []Guard
+		user     string
+		want     int
+		wantUser string
+	}{
+		{name: "no guards", want: http.StatusOK},
+		{name: "allowed", guards: []Guard{allow, allow}, want: http.StatusOK},
+		{name: "denied", guards: []Guard{allow, deny}, want: http.StatusForbidden},
+		{name: "first rejection wins", guards: []Guard{deny, limited}, want: http.StatusForbidden},
+		{name: "status error", guards: []Guard{limited}, want: http.StatusTooManyRequests},
+		{name: "failing guard", guards: []Guard{fail}, want: http.StatusInternalServerError},
+		{name: "activator passes values on", guards: []Guard{userActivator{}, requireUser}, user: "ana", want: http.StatusOK, wantUser: "ana"},
+		{name: "activator rejects", guards: []Guard{userActivator{}, requireUser}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var user interface{}
+			var seen Route
+			handler := Middleware(route, tt.guards...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user = r.Context().Value(userKey{})
+				seen, _ = RouteFromRequest(r)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+			if tt.user != "" {
+				r.Header.Set("X-User", tt.user)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			if tt.wantUser != "" && user != tt.wantUser {
+				t.Errorf("user = %v, want %s", user, tt.wantUser)
+			}
+			if !reflect.DeepEqual(seen, route) {
+				t.Errorf("route = %+v, want %+v", seen, route)
+			}
+		})
+	}
+}
+
+func TestCaptureRoute(t *testing.T) {
+	route := Route{Name: "users.list", Controller: "UserController", Handler: "List"}
+	tests := []struct {
+		name    string
+		handler http.Handler
+		want    bool
+	}{
+		{"routed request", Middleware(route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})), true},
+		{"unmatched request", http.NotFoundHandler(), false},
+	}
+	for _, tt := range tests {
+		r, captured := CaptureRoute(httptest.NewRequest(http.MethodGet, "/users", nil))
+		tt.handler.ServeHTTP(httptest.NewRecorder(), r)
+		got, ok := captured()
+		if ok != tt.want || ok && got.Name != route.Name {
+			t.Errorf("%s: captured %+v, %v, want %v", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestParseNames(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{"", []string{}},
+		{"AuthGuard", []string{"AuthGuard"}},
+		{" AuthGuard , RolesGuard ,", []string{"AuthGuard", "RolesGuard"}},
+	}
+	for _, tt := range tests {
+		if got := ParseNames(tt.tag); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseNames(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
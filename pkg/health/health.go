@@ -0,0 +1,312 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/appinfo"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/container"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// Status of an indicator or of the application
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// ProviderName is the name the Checker is injectable under
+const ProviderName = "HealthChecker"
+
+const (
+	// DefaultPath serves the health report
+	DefaultPath = "/health"
+	// DefaultLivePath serves the liveness probe
+	DefaultLivePath = "/health/live"
+	// DefaultReadyPath serves the readiness probe
+	DefaultReadyPath = "/health/ready"
+	// DefaultTimeout bounds each indicator check
+	DefaultTimeout = 5 * time.Second
+)
+
+// Details describes the state of a checked dependency, e.g. its pool usage
+type Details map[string]interface{}
+
+// Indicator checks a dependency of the application, such as a database or
+// a message broker. Check returns an error when the dependency is down.
+type Indicator interface {
+	Name() string
+	Check(ctx context.Context) (Details, error)
+}
+
+// pingIndicator adapts a ping function to an Indicator
+type pingIndicator struct {
+	name string
+	ping func(ctx context.Context) error
+}
+
+// Ping returns an indicator calling ping, for clients exposing one, e.g.
+// health.Ping("redis", func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+func Ping(name string, ping func(ctx context.Context) error) Indicator {
+	return &pingIndicator{name: name, ping: ping}
+}
+
+// Name returns the name of the indicator
+func (p *pingIndicator) Name() string {
+	return p.name
+}
+
+// Check calls the ping function
+func (p *pingIndicator) Check(ctx context.Context) (Details, error) {
+	return nil, p.ping(ctx)
+}
+
+var (
+	registered      = make([]Indicator, 0)
+	registeredMutex sync.RWMutex
+)
+
+// Register adds indicators checked by every Checker, so integration modules
+// report their health once they are enabled
+func Register(indicators ...Indicator) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	registered = append(registered, indicators...)
+}
+
+// Registered returns the indicators added with Register
+func Registered() []Indicator {
+	registeredMutex.RLock()
+	defer registeredMutex.RUnlock()
+	return append([]Indicator(nil), registered...)
+}
+
+// Options configures a Checker
+type Options struct {
+	// Path serves the health report, DefaultPath when empty
+	Path string
+	// LivePath answers whether the process serves requests, without
+	// checking the indicators, DefaultLivePath when empty
+	LivePath string
+	// ReadyPath answers whether the application should receive traffic:
+	// every indicator is up and it is not shutting down. DefaultReadyPath
+	// when empty.
+	ReadyPath string
+	// Timeout bounds each indicator check, DefaultTimeout when zero
+	Timeout time.Duration
+	// Indicators are checked besides the registered ones and the providers
+	// implementing Indicator
+	Indicators []Indicator
+}
+
+// Result is the outcome of one indicator check
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Details   Details `json:"details,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the health of the application, down when any indicator is
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+	// Draining is set by readiness reports once the application is
+	// shutting down
+	Draining bool `json:"draining,omitempty"`
+	// Info identifies the instance reporting, with its uptime
+	Info *appinfo.Snapshot `json:"info,omitempty"`
+}
+
+// Checker runs the health indicators of the application
+type Checker struct {
+	options    Options
+	mutex      sync.RWMutex
+	indicators []Indicator
+	info       *appinfo.AppInfo
+	draining   atomic.Bool
+}
+
+// NewChecker creates a health checker
+func NewChecker(opts Options) *Checker {
+	if opts.Path == "" {
+		opts.Path = DefaultPath
+	}
+	if opts.LivePath == "" {
+		opts.LivePath = DefaultLivePath
+	}
+	if opts.ReadyPath == "" {
+		opts.ReadyPath = DefaultReadyPath
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	return &Checker{options: opts, indicators: append([]Indicator(nil), opts.Indicators...)}
+}
+
+// Path returns the path serving the health report
+func (c *Checker) Path() string {
+	return c.options.Path
+}
+
+// LivePath returns the path serving the liveness probe
+func (c *Checker) LivePath() string {
+	return c.options.LivePath
+}
+
+// ReadyPath returns the path serving the readiness probe
+func (c *Checker) ReadyPath() string {
+	return c.options.ReadyPath
+}
+
+// Drain marks the application as shutting down, so readiness reports are
+// down and load balancers stop sending traffic while in-flight requests
+// finish
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+// Add adds indicators to the checker
+func (c *Checker) Add(indicators ...Indicator) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.indicators = append(c.indicators, indicators...)
+}
+
+// SetInfo includes the info of the instance in the reports
+func (c *Checker) SetInfo(info *appinfo.AppInfo) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.info = info
+}
+
+// Discover adds every provider of the container implementing Indicator
+func (c *Checker) Discover(ct *container.Container) {
+	for name, provider := range ct.GetAllServices() {
+		if indicator, ok := provider.(Indicator); ok {
+			c.Add(indicator)
+			logger.Info("Health indicator discovered", "provider", name, "name", indicator.Name())
+		}
+	}
+}
+
+// Indicators returns the registered indicators followed by the ones of the checker
+func (c *Checker) Indicators() []Indicator {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return append(Registered(), c.indicators...)
+}
+
+// Check runs every indicator concurrently, each bounded by the timeout
+func (c *Checker) Check(ctx context.Context) Report {
+	indicators := c.Indicators()
+	results := make([]Result, len(indicators))
+
+	var wg sync.WaitGroup
+	for i, indicator := range indicators {
+		wg.Add(1)
+		go func(i int, indicator Indicator) {
+			defer wg.Done()
+			results[i] = c.check(ctx, indicator)
+		}(i, indicator)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(indicators))}
+	for i, indicator := range indicators {
+		if results[i].Status == StatusDown {
+			report.Status = StatusDown
+		}
+		report.Checks[indicator.Name()] = results[i]
+	}
+
+	report.Info = c.snapshot()
+	return report
+}
+
+// Live reports the application up without checking the indicators: a
+// dependency being down must not restart the process
+func (c *Checker) Live() Report {
+	return Report{Status: StatusUp, Checks: make(map[string]Result), Info: c.snapshot()}
+}
+
+// Ready checks the indicators like Check, reporting down once the
+// application is draining
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := c.Check(ctx)
+	if c.draining.Load() {
+		report.Status = StatusDown
+		report.Draining = true
+	}
+	return report
+}
+
+// snapshot returns the info of the instance, nil when unset
+func (c *Checker) snapshot() *appinfo.Snapshot {
+	c.mutex.RLock()
+	info := c.info
+	c.mutex.RUnlock()
+	if info == nil {
+		return nil
+	}
+	snapshot := info.Snapshot()
+	return &snapshot
+}
+
+// check runs one indicator, measuring its latency
+func (c *Checker) check(ctx context.Context, indicator Indicator) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	start := clock.Now()
+	details, err := indicator.Check(ctx)
+	latency := clock.Now().Sub(start)
+
+	result := Result{
+		Status:    StatusUp,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		logger.Warn("Health check failed", "indicator", indicator.Name(), "latency", latency, "error", err)
+	}
+	return result
+}
+
+// ServeHTTP answers the health report, with 503 Service Unavailable when
+// any indicator is down
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Check(r.Context()))
+}
+
+// ServeLive answers the liveness probe
+func (c *Checker) ServeLive(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Live())
+}
+
+// ServeReady answers the readiness probe, with 503 Service Unavailable
+// when any indicator is down or the application is draining
+func (c *Checker) ServeReady(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, c.Ready(r.Context()))
+}
+
+// writeReport writes a report, with 503 Service Unavailable when it is down
+func writeReport(w http.ResponseWriter, report Report) {
+	jsonData, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to serialize response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status != StatusUp {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(jsonData)
+}
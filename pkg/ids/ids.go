@@ -0,0 +1,114 @@
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ProviderName is the name the application ID generator is injected under,
+// e.g. IDs ids.Generator `inject:"IDGenerator"`
+const ProviderName = "IDGenerator"
+
+// Generator produces the bytes behind identifiers and tokens, such as job
+// IDs, UUIDs, refresh tokens and recovery codes
+type Generator interface {
+	Bytes(size int) ([]byte, error)
+}
+
+// randomGenerator reads from crypto/rand
+type randomGenerator struct{}
+
+// Bytes returns size cryptographically random bytes
+func (randomGenerator) Bytes(size int) ([]byte, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("ids: failed to read random bytes: %w", err)
+	}
+	return raw, nil
+}
+
+// Random returns the generator backed by crypto/rand
+func Random() Generator {
+	return randomGenerator{}
+}
+
+// Sequence is a predictable Generator for tests. Every call returns the next
+// counter value, big-endian, in the last bytes of a zeroed buffer, so the
+// first 8-byte hex ID is "0000000000000001".
+type Sequence struct {
+	mu   sync.Mutex
+	next uint64
+}
+
+// NewSequence creates a sequence starting at 1
+func NewSequence() *Sequence {
+	return &Sequence{next: 1}
+}
+
+// Bytes returns the next value of the sequence
+func (s *Sequence) Bytes(size int) ([]byte, error) {
+	s.mu.Lock()
+	value := s.next
+	s.next++
+	s.mu.Unlock()
+
+	raw := make([]byte, size)
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], value)
+	if size >= len(counter) {
+		copy(raw[size-len(counter):], counter[:])
+	} else {
+		copy(raw, counter[len(counter)-size:])
+	}
+	return raw, nil
+}
+
+// Reset restarts the sequence at 1
+func (s *Sequence) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next = 1
+}
+
+// holder wraps a Generator so atomic.Value always stores the same type
+type holder struct {
+	generator Generator
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(holder{generator: Random()})
+}
+
+// Default returns the generator used by framework components
+func Default() Generator {
+	return current.Load().(holder).generator
+}
+
+// SetDefault replaces the generator used by framework components; nil
+// restores crypto/rand
+func SetDefault(g Generator) {
+	if g == nil {
+		g = Random()
+	}
+	current.Store(holder{generator: g})
+}
+
+// Bytes returns size bytes from the default generator
+func Bytes(size int) ([]byte, error) {
+	return Default().Bytes(size)
+}
+
+// Hex returns size bytes from the default generator, hex encoded
+func Hex(size int) (string, error) {
+	raw, err := Bytes(size)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
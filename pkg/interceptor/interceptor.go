@@ -0,0 +1,54 @@
+package interceptor
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TagInterceptors lists the interceptors wrapping a controller (on its
+// BaseController field) or a route field: `interceptors:"TimingInterceptor"`.
+// Names refer to interceptors registered by name or to DI providers.
+const TagInterceptors = "interceptors"
+
+// CallHandler invokes the next interceptor or the route handler and returns
+// the value the handler produced
+type CallHandler func() (interface{}, error)
+
+// Interceptor wraps the execution of a route handler, like a NestJS
+// interceptor. Code before next() runs before the handler, code after it
+// runs once the handler returned and may replace the returned value or error
+// before the response is written.
+type Interceptor interface {
+	Intercept(r *http.Request, next CallHandler) (interface{}, error)
+}
+
+// InterceptorFunc adapts a function to the Interceptor interface
+type InterceptorFunc func(r *http.Request, next CallHandler) (interface{}, error)
+
+// Intercept calls f(r, next)
+func (f InterceptorFunc) Intercept(r *http.Request, next CallHandler) (interface{}, error) {
+	return f(r, next)
+}
+
+// Chain wraps handler with the interceptors, the first one being the outermost
+func Chain(r *http.Request, handler CallHandler, interceptors []Interceptor) CallHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		current := interceptors[i]
+		next := handler
+		handler = func() (interface{}, error) {
+			return current.Intercept(r, next)
+		}
+	}
+	return handler
+}
+
+// ParseNames splits an interceptors tag into interceptor names
+func ParseNames(tag string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(tag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
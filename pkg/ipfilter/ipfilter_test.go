@@ -0,0 +1,104 @@
+package ipfilter
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      Rules
+		ip         string
+		want       bool
+		wantReason string
+	}{
+		{"no rules allow everyone", Rules{}, "203.0.113.9", true, ""},
+		{"allowed network", ParseRules("10.0.0.0/8, 192.168.1.1", ""), "10.1.2.3", true, ""},
+		{"allowed single IP", ParseRules("10.0.0.0/8, 192.168.1.1", ""), "192.168.1.1", true, ""},
+		{"outside allow list", ParseRules("10.0.0.0/8", ""), "192.168.1.2", false, "not in allow list"},
+		{"denied network", ParseRules("", "203.0.113.0/24"), "203.0.113.9", false, "denied by 203.0.113.0/24"},
+		{"outside deny list", ParseRules("", "203.0.113.0/24"), "198.51.100.1", true, ""},
+		{"deny wins over allow", ParseRules("10.0.0.0/8", "10.0.0.66"), "10.0.0.66", false, "denied by 10.0.0.66/32"},
+		{"IPv6 allowed", ParseRules("2001:db8::/32", ""), "2001:db8::1", true, ""},
+		{"IPv4 outside IPv6 allow list", ParseRules("2001:db8::/32", ""), "10.0.0.1", false, "not in allow list"},
+		{"invalid IP", Rules{}, "not-an-ip", false, "invalid client IP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := New("test", tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed, reason := filter.Check(net.ParseIP(tt.ip))
+			if allowed != tt.want || reason != tt.wantReason {
+				t.Errorf("Check(%s) = %v, %q, want %v, %q", tt.ip, allowed, reason, tt.want, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules Rules
+	}{
+		{"invalid allow entry", ParseRules("10.0.0.0/8, nope", "")},
+		{"invalid deny entry", ParseRules("", "10.0.0.0/99")},
+	}
+	for _, tt := range tests {
+		if _, err := New("test", tt.rules); err == nil {
+			t.Errorf("%s: New() succeeded, want error", tt.name)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	filter, err := New("admin", ParseRules("198.51.100.0/24", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := realip.NewResolver("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		resolve   bool
+		want      int
+	}{
+		{"allowed peer", "198.51.100.7:1234", "", false, http.StatusNoContent},
+		{"blocked peer", "203.0.113.9:1234", "", false, http.StatusForbidden},
+		{"headers ignored without resolver", "203.0.113.9:1234", "198.51.100.7", false, http.StatusForbidden},
+		{"client behind trusted proxy", "10.0.0.1:1234", "198.51.100.7", true, http.StatusNoContent},
+		{"spoofed header from untrusted peer", "203.0.113.9:1234", "198.51.100.7", true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handler http.Handler = filter.Middleware(ok)
+			if tt.resolve {
+				handler = realip.Middleware(resolver)(handler)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set(realip.HeaderForwardedFor, tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER identifier octets used by the LDAP protocol
+const (
+	classApplication = 0x40
+	classContext     = 0x80
+	constructed      = 0x20
+
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x30
+	tagSet         = 0x31
+)
+
+// maxPacketSize bounds the messages read from a server
+const maxPacketSize = 16 << 20
+
+// packet is a BER element, either primitive with a value or constructed
+// with children. Only the low tag numbers LDAP uses are supported.
+type packet struct {
+	tag      byte
+	value    []byte
+	children []*packet
+}
+
+// newSequence returns a constructed packet
+func newSequence(tag byte, children ...*packet) *packet {
+	return &packet{tag: tag | constructed, children: children}
+}
+
+// newString returns an octet string packet
+func newString(tag byte, value string) *packet {
+	return &packet{tag: tag, value: []byte(value)}
+}
+
+// newInteger returns an integer or enumerated packet in minimal two's
+// complement form
+func newInteger(tag byte, value int64) *packet {
+	encoded := []byte{byte(value)}
+	for value > 127 || value < -128 {
+		value >>= 8
+		encoded = append([]byte{byte(value)}, encoded...)
+	}
+	return &packet{tag: tag, value: encoded}
+}
+
+// newBoolean returns a boolean packet
+func newBoolean(value bool) *packet {
+	if value {
+		return &packet{tag: tagBoolean, value: []byte{0xff}}
+	}
+	return &packet{tag: tagBoolean, value: []byte{0}}
+}
+
+// encode returns the BER encoding of p
+func (p *packet) encode() []byte {
+	contents := p.value
+	if p.tag&constructed != 0 {
+		contents = nil
+		for _, child := range p.children {
+			contents = append(contents, child.encode()...)
+		}
+	}
+
+	encoded := []byte{p.tag}
+	if length := len(contents); length < 128 {
+		encoded = append(encoded, byte(length))
+	} else {
+		var octets []byte
+		for ; length > 0; length >>= 8 {
+			octets = append([]byte{byte(length)}, octets...)
+		}
+		encoded = append(encoded, 0x80|byte(len(octets)))
+		encoded = append(encoded, octets...)
+	}
+	return append(encoded, contents...)
+}
+
+// integer returns the value of an integer or enumerated packet
+func (p *packet) integer() (int64, error) {
+	if p.tag&constructed != 0 || len(p.value) == 0 || len(p.value) > 8 {
+		return 0, errors.New("ldap: malformed integer")
+	}
+	value := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		value = value<<8 | int64(b)
+	}
+	return value, nil
+}
+
+// child returns the i-th child of a constructed packet, or an error
+func (p *packet) child(i int) (*packet, error) {
+	if i >= len(p.children) {
+		return nil, fmt.Errorf("ldap: malformed message, element %#x has %d children", p.tag, len(p.children))
+	}
+	return p.children[i], nil
+}
+
+// readPacket reads a BER element from a stream
+func readPacket(r *bufio.Reader) (*packet, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+	contents := make([]byte, length)
+	if _, err := io.ReadFull(r, contents); err != nil {
+		return nil, err
+	}
+	return decode(tag, contents)
+}
+
+// readLength reads a definite BER length
+func readLength(r io.ByteReader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+	octets := int(first &^ 0x80)
+	if octets == 0 || octets > 4 {
+		return 0, errors.New("ldap: unsupported length encoding")
+	}
+	length := 0
+	for i := 0; i < octets; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	if length > maxPacketSize {
+		return 0, fmt.Errorf("ldap: message of %d bytes exceeds the limit", length)
+	}
+	return length, nil
+}
+
+// decode builds a packet from its tag and contents, parsing the children
+// of constructed ones
+func decode(tag byte, contents []byte) (*packet, error) {
+	if tag&0x1f == 0x1f {
+		return nil, errors.New("ldap: unsupported high tag number")
+	}
+	p := &packet{tag: tag}
+	if tag&constructed == 0 {
+		p.value = contents
+		return p, nil
+	}
+
+	r := &byteReader{data: contents}
+	for r.offset < len(contents) {
+		childTag, _ := r.ReadByte()
+		length, err := readLength(r)
+		if err != nil {
+			return nil, err
+		}
+		if length > len(contents)-r.offset {
+			return nil, io.ErrUnexpectedEOF
+		}
+		child, err := decode(childTag, contents[r.offset:r.offset+length])
+		if err != nil {
+			return nil, err
+		}
+		r.offset += length
+		p.children = append(p.children, child)
+	}
+	return p, nil
+}
+
+// byteReader reads the contents of a constructed packet
+type byteReader struct {
+	data   []byte
+	offset int
+}
+
+// ReadByte implements io.ByteReader
+func (r *byteReader) ReadByte() (byte, error) {
+	if r.offset >= len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.data[r.offset]
+	r.offset++
+	return b, nil
+}
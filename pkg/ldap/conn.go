@@ -0,0 +1,376 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds dialing and each operation
+const DefaultTimeout = 10 * time.Second
+
+// Result codes checked by callers, see RFC 4511
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultInvalidCredentials = 49
+)
+
+// Protocol operations
+const (
+	opBindRequest           = classApplication | constructed | 0
+	opBindResponse          = classApplication | constructed | 1
+	opUnbindRequest         = classApplication | 2
+	opSearchRequest         = classApplication | constructed | 3
+	opSearchResultEntry     = classApplication | constructed | 4
+	opSearchResultDone      = classApplication | constructed | 5
+	opSearchResultReference = classApplication | constructed | 19
+	opExtendedRequest       = classApplication | constructed | 23
+	opExtendedResponse      = classApplication | constructed | 24
+
+	oidStartTLS = "1.3.6.1.4.1.1466.20037"
+)
+
+// Scope of a search
+type Scope int
+
+// Search scopes
+const (
+	ScopeBaseObject Scope = iota
+	ScopeSingleLevel
+	ScopeWholeSubtree
+)
+
+// ErrEmptyPassword rejects simple binds with a DN and no password, which
+// servers treat as unauthenticated binds and accept whatever the DN
+var ErrEmptyPassword = errors.New("ldap: empty password")
+
+// Error is a result other than success answered by the server. The
+// connection remains usable.
+type Error struct {
+	Code    int
+	Message string
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// IsResult reports whether err is an Error with the result code
+func IsResult(err error, code int) bool {
+	var result *Error
+	return errors.As(err, &result) && result.Code == code
+}
+
+// Options configures the connections to an LDAP server
+type Options struct {
+	// URL locates the server, "ldap://host:389" or "ldaps://host:636"
+	URL string
+	// TLSConfig secures ldaps and StartTLS connections; the server name
+	// defaults to the URL host
+	TLSConfig *tls.Config
+	// StartTLS upgrades ldap connections to TLS before any bind. Without it
+	// or ldaps, passwords cross the network in clear text.
+	StartTLS bool
+	// Timeout bounds dialing and each operation, DefaultTimeout when zero
+	Timeout time.Duration
+	// MaxIdle bounds the idle connections kept by a Pool, DefaultMaxIdle
+	// when zero
+	MaxIdle int
+	// MaxOpen bounds the connections opened by a Pool; zero is unlimited
+	MaxOpen int
+}
+
+// Conn is a connection to an LDAP server. It runs one operation at a time
+// and is not safe for concurrent use.
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	nextID  int64
+}
+
+// SearchRequest describes a search
+type SearchRequest struct {
+	BaseDN string
+	Scope  Scope
+	// Filter is a string filter as defined by RFC 4515, e.g. "(uid=ana)".
+	// Values from users must be escaped with EscapeFilter.
+	Filter string
+	// Attributes to return, all user attributes when empty
+	Attributes []string
+	// SizeLimit bounds the returned entries; zero is the server limit
+	SizeLimit int
+}
+
+// Entry is a search result
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get returns the values of an attribute, whose name is case insensitive
+func (e *Entry) Get(name string) []string {
+	for attribute, values := range e.Attributes {
+		if strings.EqualFold(attribute, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Dial connects to the server of the options, upgrading the connection when
+// StartTLS is set
+func Dial(opts Options) (*Conn, error) {
+	location, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid URL: %w", err)
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	config := &tls.Config{}
+	if opts.TLSConfig != nil {
+		config = opts.TLSConfig.Clone()
+	}
+	if config.ServerName == "" {
+		config.ServerName = location.Hostname()
+	}
+
+	address := location.Host
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	var conn net.Conn
+	switch location.Scheme {
+	case "ldap":
+		if location.Port() == "" {
+			address = net.JoinHostPort(location.Hostname(), "389")
+		}
+		conn, err = dialer.Dial("tcp", address)
+	case "ldaps":
+		if location.Port() == "" {
+			address = net.JoinHostPort(location.Hostname(), "636")
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, config)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported URL scheme %q", location.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConn(conn, opts.Timeout)
+	if opts.StartTLS && location.Scheme == "ldap" {
+		if err := c.startTLS(config); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// newConn wraps an established connection
+func newConn(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+}
+
+// startTLS runs the StartTLS extended operation and upgrades the connection
+func (c *Conn) startTLS(config *tls.Config) error {
+	response, err := c.request(newSequence(opExtendedRequest, newString(classContext|0, oidStartTLS)), opExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := result(response); err != nil {
+		return err
+	}
+
+	secured := tls.Client(c.conn, config)
+	secured.SetDeadline(time.Now().Add(c.timeout))
+	if err := secured.Handshake(); err != nil {
+		return err
+	}
+	c.conn, c.reader = secured, bufio.NewReader(secured)
+	return nil
+}
+
+// Bind authenticates the connection with a simple bind. An empty DN and
+// password bind anonymously.
+func (c *Conn) Bind(dn, password string) error {
+	if dn != "" && password == "" {
+		return ErrEmptyPassword
+	}
+	response, err := c.request(newSequence(opBindRequest,
+		newInteger(tagInteger, 3),
+		newString(tagOctetString, dn),
+		newString(classContext|0, password),
+	), opBindResponse)
+	if err != nil {
+		return err
+	}
+	return result(response)
+}
+
+// Search returns the entries matching a request
+func (c *Conn) Search(req SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attributes := newSequence(tagSequence)
+	for _, attribute := range req.Attributes {
+		attributes.children = append(attributes.children, newString(tagOctetString, attribute))
+	}
+	id, err := c.send(newSequence(opSearchRequest,
+		newString(tagOctetString, req.BaseDN),
+		newInteger(tagEnumerated, int64(req.Scope)),
+		newInteger(tagEnumerated, 0), // never dereference aliases
+		newInteger(tagInteger, int64(req.SizeLimit)),
+		newInteger(tagInteger, int64(c.timeout/time.Second)),
+		newBoolean(false),
+		filter,
+		attributes,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for {
+		response, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+		switch response.tag {
+		case opSearchResultEntry:
+			entry, err := parseEntry(response)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		case opSearchResultReference:
+			// Referrals to other servers are not followed
+		case opSearchResultDone:
+			return entries, result(response)
+		default:
+			return nil, fmt.Errorf("ldap: unexpected response %#x to a search", response.tag)
+		}
+	}
+}
+
+// Close unbinds and closes the connection
+func (c *Conn) Close() error {
+	c.send(&packet{tag: opUnbindRequest})
+	return c.conn.Close()
+}
+
+// request sends an operation and returns its single response, checking its
+// type
+func (c *Conn) request(op *packet, expected byte) (*packet, error) {
+	id, err := c.send(op)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.receive(id)
+	if err != nil {
+		return nil, err
+	}
+	if response.tag != expected {
+		return nil, fmt.Errorf("ldap: unexpected response %#x, expected %#x", response.tag, expected)
+	}
+	return response, nil
+}
+
+// send writes an operation in a new message, returning its ID
+func (c *Conn) send(op *packet) (int64, error) {
+	c.nextID++
+	message := newSequence(tagSequence, newInteger(tagInteger, c.nextID), op)
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(message.encode()); err != nil {
+		return 0, err
+	}
+	return c.nextID, nil
+}
+
+// receive reads the next response to the message id
+func (c *Conn) receive(id int64) (*packet, error) {
+	for {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+		message, err := readPacket(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if message.tag != tagSequence || len(message.children) < 2 {
+			return nil, errors.New("ldap: malformed message")
+		}
+		messageID, err := message.children[0].integer()
+		if err != nil {
+			return nil, err
+		}
+		// Unsolicited notifications, such as a notice of disconnection,
+		// have the ID zero and end the connection
+		if messageID == 0 {
+			return nil, fmt.Errorf("ldap: unsolicited server notice: %v", result(message.children[1]))
+		}
+		if messageID == id {
+			return message.children[1], nil
+		}
+	}
+}
+
+// result returns the Error of an LDAPResult other than success
+func result(response *packet) error {
+	codePacket, err := response.child(0)
+	if err != nil {
+		return err
+	}
+	code, err := codePacket.integer()
+	if err != nil {
+		return err
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	message := ""
+	if diagnostic, err := response.child(2); err == nil {
+		message = string(diagnostic.value)
+	}
+	return &Error{Code: int(code), Message: message}
+}
+
+// parseEntry reads a search result entry
+func parseEntry(response *packet) (*Entry, error) {
+	dn, err := response.child(0)
+	if err != nil {
+		return nil, err
+	}
+	list, err := response.child(1)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &Entry{DN: string(dn.value), Attributes: make(map[string][]string, len(list.children))}
+	for _, attribute := range list.children {
+		name, err := attribute.child(0)
+		if err != nil {
+			return nil, err
+		}
+		set, err := attribute.child(1)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, len(set.children))
+		for _, value := range set.children {
+			values = append(values, string(value.value))
+		}
+		entry.Attributes[string(name.value)] = values
+	}
+	return entry, nil
+}
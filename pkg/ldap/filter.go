@@ -0,0 +1,178 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Filter choices of a search request
+const (
+	filterAnd            = classContext | constructed | 0
+	filterOr             = classContext | constructed | 1
+	filterNot            = classContext | constructed | 2
+	filterEquality       = classContext | constructed | 3
+	filterSubstrings     = classContext | constructed | 4
+	filterGreaterOrEqual = classContext | constructed | 5
+	filterLessOrEqual    = classContext | constructed | 6
+	filterPresent        = classContext | 7
+	filterApprox         = classContext | constructed | 8
+)
+
+// EscapeFilter escapes a value for use in a search filter, e.g. a username
+// typed by a user
+func EscapeFilter(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter encodes a string filter as defined by RFC 4515, e.g.
+// "(&(objectClass=person)(uid=ana))". Extensible matches are not supported.
+func compileFilter(filter string) (*packet, error) {
+	compiled, rest, err := parseFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid filter %q: %w", filter, err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("ldap: invalid filter %q: unexpected %q", filter, rest)
+	}
+	return compiled, nil
+}
+
+// parseFilter parses the parenthesized filter at the start of s, returning
+// the rest of s
+func parseFilter(s string) (*packet, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, s, fmt.Errorf("expected ( at %q", s)
+	}
+	s = s[1:]
+
+	var compiled *packet
+	switch {
+	case strings.HasPrefix(s, "&"), strings.HasPrefix(s, "|"):
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		compiled = newSequence(tag)
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			var child *packet
+			var err error
+			if child, s, err = parseFilter(s); err != nil {
+				return nil, s, err
+			}
+			compiled.children = append(compiled.children, child)
+		}
+		if len(compiled.children) == 0 {
+			return nil, s, fmt.Errorf("empty filter list")
+		}
+	case strings.HasPrefix(s, "!"):
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, rest, err
+		}
+		compiled, s = newSequence(filterNot, child), rest
+	default:
+		end := strings.IndexByte(s, ')')
+		if end < 0 {
+			return nil, s, fmt.Errorf("missing )")
+		}
+		item, err := parseItem(s[:end])
+		if err != nil {
+			return nil, s, err
+		}
+		compiled, s = item, s[end:]
+	}
+
+	if !strings.HasPrefix(s, ")") {
+		return nil, s, fmt.Errorf("missing )")
+	}
+	return compiled, s[1:], nil
+}
+
+// parseItem parses a simple, presence or substrings filter item
+func parseItem(item string) (*packet, error) {
+	equals := strings.IndexByte(item, '=')
+	if equals <= 0 {
+		return nil, fmt.Errorf("invalid item %q", item)
+	}
+	attribute, raw := item[:equals], item[equals+1:]
+	tag := byte(filterEquality)
+	switch attribute[len(attribute)-1] {
+	case '~':
+		tag, attribute = filterApprox, attribute[:len(attribute)-1]
+	case '>':
+		tag, attribute = filterGreaterOrEqual, attribute[:len(attribute)-1]
+	case '<':
+		tag, attribute = filterLessOrEqual, attribute[:len(attribute)-1]
+	}
+	if attribute == "" || strings.ContainsAny(attribute, " ()*\\") {
+		return nil, fmt.Errorf("invalid attribute %q", attribute)
+	}
+
+	if tag == filterEquality && raw == "*" {
+		return newString(filterPresent, attribute), nil
+	}
+	if tag != filterEquality || !strings.Contains(raw, "*") {
+		value, err := unescapeFilter(raw)
+		if err != nil {
+			return nil, err
+		}
+		return newSequence(tag, newString(tagOctetString, attribute), newString(tagOctetString, value)), nil
+	}
+
+	// Substrings: an optional initial part, any parts and an optional final part
+	parts := strings.Split(raw, "*")
+	substrings := newSequence(tagSequence)
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		value, err := unescapeFilter(part)
+		if err != nil {
+			return nil, err
+		}
+		choice := byte(classContext | 1)
+		switch i {
+		case 0:
+			choice = classContext | 0
+		case len(parts) - 1:
+			choice = classContext | 2
+		}
+		substrings.children = append(substrings.children, newString(choice, value))
+	}
+	return newSequence(filterSubstrings, newString(tagOctetString, attribute), substrings), nil
+}
+
+// unescapeFilter decodes the \XX escapes of a filter value
+func unescapeFilter(raw string) (string, error) {
+	if strings.ContainsAny(raw, "()") {
+		return "", fmt.Errorf("unescaped parenthesis in %q", raw)
+	}
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			b.WriteByte(raw[i])
+			continue
+		}
+		if i+3 > len(raw) {
+			return "", fmt.Errorf("truncated escape in %q", raw)
+		}
+		decoded, err := hex.DecodeString(raw[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", raw)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
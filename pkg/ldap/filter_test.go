@@ -0,0 +1,78 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    string
+		wantErr bool
+	}{
+		{filter: "(uid=ana)", want: "a30a04037569640403616e61"},
+		{filter: "(objectClass=*)", want: "870b6f626a656374436c617373"},
+		{filter: "(&(a=1)(!(b=2)))", want: "a012a306040161040131a208a306040162040132"},
+		{filter: "(|(a=1)(b=2))", want: "a110a306040161040131a306040162040132"},
+		{filter: "(cn=a*b*c)", want: "a40f0402636e3009800161810162820163"},
+		{filter: "(cn=*b*)", want: "a4090402636e3003810162"},
+		{filter: "(uid=a\\2ab)", want: "a30a04037569640403612a62"},
+		{filter: "(age>=3)", want: "a5080403616765040133"},
+		{filter: "(cn~=ana)", want: "a8090402636e0403616e61"},
+		{filter: "uid=ana", wantErr: true},
+		{filter: "(uid=ana", wantErr: true},
+		{filter: "(uid=ana))", wantErr: true},
+		{filter: "(&)", wantErr: true},
+		{filter: "(=ana)", wantErr: true},
+		{filter: "(uid=a\\zz)", wantErr: true},
+		{filter: "(uid=a\\2)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			compiled, err := compileFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compileFilter() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := hex.EncodeToString(compiled.encode()); got != tt.want {
+					t.Errorf("compileFilter() = %s, want %s", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEscapeFilter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "ana", want: "ana"},
+		{value: "*", want: `\2a`},
+		{value: "a)(uid=*", want: `a\29\28uid=\2a`},
+		{value: "back\\slash\x00", want: `back\5cslash\00`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := EscapeFilter(tt.value); got != tt.want {
+				t.Errorf("EscapeFilter(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+			if _, err := compileFilter("(uid=" + EscapeFilter(tt.value) + ")"); err != nil {
+				t.Errorf("escaped value does not compile: %v", err)
+			}
+		})
+	}
+}
+
+func TestPacketInteger(t *testing.T) {
+	for _, value := range []int64{0, 1, 127, 128, 255, 256, 32768, -1, -128, -129, 1 << 40} {
+		decoded, err := decode(tagInteger, newInteger(tagInteger, value).value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := decoded.integer(); err != nil || got != value {
+			t.Errorf("integer round trip of %d = %d, %v", value, got, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package ldap
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// DefaultMaxIdle is the number of idle connections kept by a pool
+const DefaultMaxIdle = 2
+
+// ErrPoolClosed is returned by the operations of a closed pool
+var ErrPoolClosed = errors.New("ldap: pool closed")
+
+// Pool reuses the connections to an LDAP server across operations
+type Pool struct {
+	options Options
+	slots   chan struct{}
+
+	mu     sync.Mutex
+	idle   []*Conn
+	closed bool
+}
+
+// NewPool creates a pool dialing connections with the options
+func NewPool(opts Options) *Pool {
+	if opts.MaxIdle <= 0 {
+		opts.MaxIdle = DefaultMaxIdle
+	}
+	p := &Pool{options: opts}
+	if opts.MaxOpen > 0 {
+		p.slots = make(chan struct{}, opts.MaxOpen)
+	}
+	return p
+}
+
+// Do runs fn with a connection of the pool, waiting for one while MaxOpen
+// connections are in use. The context bounds the wait, operations are
+// bounded by the Timeout option. The connection returns to the pool unless
+// fn fails with an error other than an Error result, which may have left
+// it unusable. A failing idle connection, e.g. closed by the server, is
+// replaced and fn run again once.
+func (p *Pool) Do(ctx context.Context, fn func(c *Conn) error) error {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+			defer func() { <-p.slots }()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	conn, reused, err := p.get()
+	if err != nil {
+		return err
+	}
+	err = fn(conn)
+	if err != nil && reused && !isResult(err) {
+		conn.Close()
+		if conn, err = Dial(p.options); err != nil {
+			return err
+		}
+		err = fn(conn)
+	}
+
+	if err != nil && !isResult(err) {
+		conn.Close()
+		return err
+	}
+	p.put(conn)
+	return err
+}
+
+// Close closes the idle connections; connections in use are closed when
+// returned
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle, p.closed = nil, true
+	p.mu.Unlock()
+
+	for _, conn := range idle {
+		conn.Close()
+	}
+	return nil
+}
+
+// get returns an idle connection, reporting it was reused, or dials one
+func (p *Pool) get() (*Conn, bool, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, false, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		conn := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return conn, true, nil
+	}
+	p.mu.Unlock()
+
+	conn, err := Dial(p.options)
+	return conn, false, err
+}
+
+// put returns a healthy connection to the pool
+func (p *Pool) put(conn *Conn) {
+	p.mu.Lock()
+	if !p.closed && len(p.idle) < p.options.MaxIdle {
+		p.idle = append(p.idle, conn)
+		conn = nil
+	}
+	p.mu.Unlock()
+
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// isResult reports whether err is a result of the server, after which the
+// connection remains usable
+func isResult(err error) bool {
+	var result *Error
+	return errors.As(err, &result)
+}
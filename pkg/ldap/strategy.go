@@ -0,0 +1,224 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+const (
+	// DefaultUserFilter finds the entry of a username
+	DefaultUserFilter = "(uid={username})"
+	// DefaultGroupAttribute lists the groups of a user entry
+	DefaultGroupAttribute = "memberOf"
+	// DefaultGroupFilter finds the groups of a user entry under GroupBaseDN
+	DefaultGroupFilter = "(member={dn})"
+)
+
+// StrategyOptions configures a Strategy
+type StrategyOptions struct {
+	// Server configures the connections, pooled by the strategy
+	Server Options
+	// BindDN and BindPassword authenticate the searches of users and
+	// groups; anonymous when empty
+	BindDN       string
+	BindPassword string
+	// BaseDN is searched for users with UserFilter, DefaultUserFilter when
+	// empty, where {username} is replaced by the escaped username
+	BaseDN     string
+	UserFilter string
+	// GroupAttribute lists the group DNs of a user entry,
+	// DefaultGroupAttribute when empty
+	GroupAttribute string
+	// GroupBaseDN, when set, is searched for the groups of a user with
+	// GroupFilter, DefaultGroupFilter when empty, instead of reading
+	// GroupAttribute, for servers without memberOf. {dn} and {username} are
+	// replaced by the escaped DN and username of the user.
+	GroupBaseDN string
+	GroupFilter string
+	// GroupRoles maps group DNs, compared case insensitively, to principal
+	// roles. Groups without a role are ignored.
+	GroupRoles map[string]string
+	// Attributes of the user entry copied to the principal claims
+	Attributes []string
+}
+
+// Strategy authenticates the HTTP basic credentials of requests against an
+// LDAP directory: it searches the entry of the username with the service
+// account, then binds as that entry with the password. It implements
+// auth.Strategy:
+//
+//	auth.NewAuthGuard(ldap.NewStrategy(ldap.StrategyOptions{
+//		Server: ldap.Options{URL: "ldaps://ldap.example.com"},
+//		BindDN: "cn=app,ou=services,dc=example,dc=com", BindPassword: secret,
+//		BaseDN: "ou=people,dc=example,dc=com",
+//		GroupRoles: map[string]string{"cn=admins,ou=groups,dc=example,dc=com": "admin"},
+//	}))
+type Strategy struct {
+	options StrategyOptions
+	groups  map[string]string
+	pool    *Pool
+}
+
+// NewStrategy creates an LDAP strategy
+func NewStrategy(opts StrategyOptions) *Strategy {
+	if opts.UserFilter == "" {
+		opts.UserFilter = DefaultUserFilter
+	}
+	if opts.GroupAttribute == "" {
+		opts.GroupAttribute = DefaultGroupAttribute
+	}
+	if opts.GroupFilter == "" {
+		opts.GroupFilter = DefaultGroupFilter
+	}
+	if location, err := url.Parse(opts.Server.URL); err == nil && location.Scheme == "ldap" && !opts.Server.StartTLS {
+		logger.Warn("LDAP passwords are sent in clear text, use ldaps or StartTLS", "url", opts.Server.URL)
+	}
+
+	groups := make(map[string]string, len(opts.GroupRoles))
+	for dn, role := range opts.GroupRoles {
+		groups[normalizeDN(dn)] = role
+	}
+	return &Strategy{options: opts, groups: groups, pool: NewPool(opts.Server)}
+}
+
+// Name returns "ldap"
+func (s *Strategy) Name() string {
+	return "ldap"
+}
+
+// Authenticate verifies the basic credentials of the request
+func (s *Strategy) Authenticate(r *http.Request) (*auth.Principal, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		if scheme, _, _ := strings.Cut(r.Header.Get("Authorization"), " "); strings.EqualFold(scheme, "Basic") {
+			return nil, auth.ErrInvalidCredentials
+		}
+		return nil, auth.ErrNoCredentials
+	}
+	return s.Verify(r.Context(), username, password)
+}
+
+// Verify returns the principal of a username and password, e.g. from a
+// login handler, or an error wrapping auth.ErrInvalidCredentials
+func (s *Strategy) Verify(ctx context.Context, username, password string) (*auth.Principal, error) {
+	if username == "" || password == "" {
+		return nil, auth.ErrInvalidCredentials
+	}
+
+	// Rejections are not connection failures, they leave it to the pool
+	var principal *auth.Principal
+	var rejected error
+	err := s.pool.Do(ctx, func(conn *Conn) error {
+		if err := conn.Bind(s.options.BindDN, s.options.BindPassword); err != nil {
+			return fmt.Errorf("ldap: service bind: %w", err)
+		}
+		entries, err := conn.Search(SearchRequest{
+			BaseDN:     s.options.BaseDN,
+			Scope:      ScopeWholeSubtree,
+			Filter:     strings.ReplaceAll(s.options.UserFilter, "{username}", EscapeFilter(username)),
+			Attributes: append([]string{s.options.GroupAttribute}, s.options.Attributes...),
+			SizeLimit:  2,
+		})
+		// Several entries for a username is a misconfigured filter, never
+		// resolved by picking one
+		if IsResult(err, ResultSizeLimitExceeded) || err == nil && len(entries) != 1 {
+			rejected = fmt.Errorf("%w: %d entries for the username", auth.ErrInvalidCredentials, len(entries))
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		entry := entries[0]
+
+		groups := entry.Get(s.options.GroupAttribute)
+		if s.options.GroupBaseDN != "" {
+			if groups, err = s.searchGroups(conn, entry.DN, username); err != nil {
+				return err
+			}
+		}
+
+		// The bind as the user verifies the password, last as it leaves the
+		// connection bound to the user
+		if err := conn.Bind(entry.DN, password); err != nil {
+			if IsResult(err, ResultInvalidCredentials) {
+				rejected = fmt.Errorf("%w: %v", auth.ErrInvalidCredentials, err)
+				return nil
+			}
+			return err
+		}
+		principal = s.principal(username, entry, groups)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if rejected != nil {
+		return nil, rejected
+	}
+	return principal, nil
+}
+
+// searchGroups returns the DNs of the groups of a user entry
+func (s *Strategy) searchGroups(conn *Conn, dn, username string) ([]string, error) {
+	filter := strings.NewReplacer("{dn}", EscapeFilter(dn), "{username}", EscapeFilter(username)).Replace(s.options.GroupFilter)
+	entries, err := conn.Search(SearchRequest{
+		BaseDN:     s.options.GroupBaseDN,
+		Scope:      ScopeWholeSubtree,
+		Filter:     filter,
+		Attributes: []string{"1.1"}, // no attributes, only DNs
+	})
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+// principal maps a user entry and its groups to a principal
+func (s *Strategy) principal(username string, entry *Entry, groups []string) *auth.Principal {
+	principal := &auth.Principal{
+		Subject: username,
+		Claims:  map[string]interface{}{"dn": entry.DN},
+	}
+	for _, group := range groups {
+		role, found := s.groups[normalizeDN(group)]
+		if found && !slices.Contains(principal.Roles, role) {
+			principal.Roles = append(principal.Roles, role)
+		}
+	}
+	for _, attribute := range s.options.Attributes {
+		switch values := entry.Get(attribute); len(values) {
+		case 0:
+		case 1:
+			principal.Claims[attribute] = values[0]
+		default:
+			principal.Claims[attribute] = values
+		}
+	}
+	return principal
+}
+
+// Close closes the pooled connections
+func (s *Strategy) Close() error {
+	return s.pool.Close()
+}
+
+// normalizeDN lowercases a DN and removes the spaces around its RDNs, so
+// equivalent spellings compare equal
+func normalizeDN(dn string) string {
+	rdns := strings.Split(dn, ",")
+	for i, rdn := range rdns {
+		rdns[i] = strings.TrimSpace(rdn)
+	}
+	return strings.ToLower(strings.Join(rdns, ","))
+}
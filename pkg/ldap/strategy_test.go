@@ -0,0 +1,281 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+)
+
+// directory is an in-memory LDAP server answering binds and searches
+type directory struct {
+	listener  net.Listener
+	entries   []*Entry
+	passwords map[string]string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+// newDirectory starts a directory serving the entries
+func newDirectory(t *testing.T, passwords map[string]string, entries ...*Entry) *directory {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &directory{listener: listener, entries: entries, passwords: passwords}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			d.mu.Lock()
+			d.conns = append(d.conns, conn)
+			d.mu.Unlock()
+			go d.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		listener.Close()
+		d.dropConnections()
+	})
+	return d
+}
+
+// url returns the URL of the directory
+func (d *directory) url() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+// accepted returns the number of connections accepted
+func (d *directory) accepted() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.conns)
+}
+
+// dropConnections closes the connections, as servers do with idle ones
+func (d *directory) dropConnections() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+}
+
+// serve answers the messages of a connection
+func (d *directory) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		message, err := readPacket(reader)
+		if err != nil || len(message.children) < 2 {
+			return
+		}
+		id, op := message.children[0], message.children[1]
+		reply := func(response *packet) {
+			conn.Write(newSequence(tagSequence, id, response).encode())
+		}
+		done := func(tag byte, code int64) *packet {
+			return newSequence(tag, newInteger(tagEnumerated, code), newString(tagOctetString, ""), newString(tagOctetString, ""))
+		}
+
+		switch op.tag {
+		case opBindRequest:
+			dn, password := string(op.children[1].value), string(op.children[2].value)
+			code := int64(ResultInvalidCredentials)
+			if expected, found := d.passwords[dn]; dn == "" && password == "" || found && expected == password {
+				code = ResultSuccess
+			}
+			reply(done(opBindResponse, code))
+		case opSearchRequest:
+			base := strings.ToLower(string(op.children[0].value))
+			limit, _ := op.children[3].integer()
+			code := int64(ResultSuccess)
+			sent := int64(0)
+			for _, entry := range d.entries {
+				if !strings.HasSuffix(strings.ToLower(entry.DN), base) || !matches(op.children[6], entry) {
+					continue
+				}
+				if limit > 0 && sent == limit {
+					code = ResultSizeLimitExceeded
+					break
+				}
+				attributes := newSequence(tagSequence)
+				for name, values := range entry.Attributes {
+					set := newSequence(tagSet)
+					for _, value := range values {
+						set.children = append(set.children, newString(tagOctetString, value))
+					}
+					attributes.children = append(attributes.children, newSequence(tagSequence, newString(tagOctetString, name), set))
+				}
+				reply(newSequence(opSearchResultEntry, newString(tagOctetString, entry.DN), attributes))
+				sent++
+			}
+			reply(done(opSearchResultDone, code))
+		case opUnbindRequest:
+			return
+		}
+	}
+}
+
+// matches evaluates the equality, presence and boolean filters of a search
+func matches(filter *packet, entry *Entry) bool {
+	switch filter.tag {
+	case filterAnd, filterOr:
+		for _, child := range filter.children {
+			if matches(child, entry) != (filter.tag == filterAnd) {
+				return filter.tag == filterOr
+			}
+		}
+		return filter.tag == filterAnd
+	case filterNot:
+		return !matches(filter.children[0], entry)
+	case filterPresent:
+		return len(entry.Get(string(filter.value))) > 0
+	case filterEquality:
+		for _, value := range entry.Get(string(filter.children[0].value)) {
+			if strings.EqualFold(value, string(filter.children[1].value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestStrategy(t *testing.T) {
+	const service = "cn=app,dc=example,dc=com"
+	dir := newDirectory(t,
+		map[string]string{
+			service:                               "service",
+			"uid=ana,ou=people,dc=example,dc=com": "secret",
+			"uid=bob,ou=people,dc=example,dc=com": "hunter2",
+			"uid=dup,ou=people,dc=example,dc=com": "secret",
+		},
+		&Entry{DN: "uid=ana,ou=people,dc=example,dc=com", Attributes: map[string][]string{
+			"uid":      {"ana"},
+			"mail":     {"ana@example.com"},
+			"memberOf": {"CN=Admins, OU=Groups, DC=example, DC=com", "cn=staff,ou=groups,dc=example,dc=com"},
+		}},
+		&Entry{DN: "uid=bob,ou=people,dc=example,dc=com", Attributes: map[string][]string{"uid": {"bob"}}},
+		&Entry{DN: "uid=dup,ou=people,dc=example,dc=com", Attributes: map[string][]string{"uid": {"dup"}}},
+		&Entry{DN: "uid=dup,ou=contractors,ou=people,dc=example,dc=com", Attributes: map[string][]string{"uid": {"dup"}}},
+		&Entry{DN: "cn=admins,ou=groups,dc=example,dc=com", Attributes: map[string][]string{"member": {"uid=bob,ou=people,dc=example,dc=com"}}},
+	)
+	options := StrategyOptions{
+		Server:       Options{URL: dir.url()},
+		BindDN:       service,
+		BindPassword: "service",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		GroupRoles:   map[string]string{"cn=admins,ou=groups,dc=example,dc=com": "admin"},
+		Attributes:   []string{"mail"},
+	}
+	withGroupSearch := options
+	withGroupSearch.GroupBaseDN = "ou=groups,dc=example,dc=com"
+	wrongService := options
+	wrongService.BindPassword = "wrong"
+
+	tests := []struct {
+		name      string
+		options   StrategyOptions
+		username  string
+		password  string
+		noHeader  bool
+		wantErr   error
+		wantRoles []string
+		wantMail  interface{}
+	}{
+		{name: "valid credentials", options: options, username: "ana", password: "secret", wantRoles: []string{"admin"}, wantMail: "ana@example.com"},
+		{name: "user without groups", options: options, username: "bob", password: "hunter2"},
+		{name: "groups searched", options: withGroupSearch, username: "bob", password: "hunter2", wantRoles: []string{"admin"}},
+		{name: "wrong password", options: options, username: "ana", password: "wrong", wantErr: auth.ErrInvalidCredentials},
+		{name: "empty password", options: options, username: "ana", password: "", wantErr: auth.ErrInvalidCredentials},
+		{name: "unknown user", options: options, username: "eve", password: "secret", wantErr: auth.ErrInvalidCredentials},
+		{name: "ambiguous username", options: options, username: "dup", password: "secret", wantErr: auth.ErrInvalidCredentials},
+		{name: "filter injection", options: options, username: "*", password: "secret", wantErr: auth.ErrInvalidCredentials},
+		{name: "no credentials", options: options, noHeader: true, wantErr: auth.ErrNoCredentials},
+		{name: "service account rejected", options: wrongService, username: "ana", password: "secret", wantErr: errors.New("ldap: service bind")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := NewStrategy(tt.options)
+			defer strategy.Close()
+
+			r := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if !tt.noHeader {
+				r.SetBasicAuth(tt.username, tt.password)
+			}
+			principal, err := strategy.Authenticate(r)
+			if tt.wantErr != nil {
+				if err == nil || !errors.Is(err, tt.wantErr) && !strings.HasPrefix(err.Error(), tt.wantErr.Error()) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				if errors.Is(tt.wantErr, auth.ErrInvalidCredentials) != errors.Is(err, auth.ErrInvalidCredentials) {
+					t.Fatalf("error = %v, invalid credentials %v", err, errors.Is(tt.wantErr, auth.ErrInvalidCredentials))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if principal.Subject != tt.username || len(principal.Roles) != len(tt.wantRoles) {
+				t.Fatalf("principal = %s with roles %v, want %s with %v", principal.Subject, principal.Roles, tt.username, tt.wantRoles)
+			}
+			for _, role := range tt.wantRoles {
+				if !principal.HasRole(role) {
+					t.Errorf("principal lacks role %q", role)
+				}
+			}
+			if principal.Claims["mail"] != tt.wantMail {
+				t.Errorf("mail claim = %v, want %v", principal.Claims["mail"], tt.wantMail)
+			}
+		})
+	}
+}
+
+func TestStrategyPoolsConnections(t *testing.T) {
+	dir := newDirectory(t,
+		map[string]string{"uid=ana,dc=example,dc=com": "secret"},
+		&Entry{DN: "uid=ana,dc=example,dc=com", Attributes: map[string][]string{"uid": {"ana"}}},
+	)
+	strategy := NewStrategy(StrategyOptions{Server: Options{URL: dir.url(), MaxOpen: 1}, BaseDN: "dc=example,dc=com"})
+	defer strategy.Close()
+	verify := func(password string) error {
+		_, err := strategy.Verify(context.Background(), "ana", password)
+		return err
+	}
+
+	steps := []struct {
+		name         string
+		password     string
+		drop         bool
+		wantErr      bool
+		wantAccepted int
+	}{
+		{name: "first login dials", password: "secret", wantAccepted: 1},
+		{name: "rejected login keeps the connection", password: "wrong", wantErr: true, wantAccepted: 1},
+		{name: "next login reuses it", password: "secret", wantAccepted: 1},
+		{name: "dropped connection is replaced", password: "secret", drop: true, wantAccepted: 2},
+	}
+	for _, step := range steps {
+		if step.drop {
+			dir.dropConnections()
+		}
+		if err := verify(step.password); (err != nil) != step.wantErr {
+			t.Fatalf("%s: error = %v, want error %v", step.name, err, step.wantErr)
+		}
+		if accepted := dir.accepted(); accepted != step.wantAccepted {
+			t.Errorf("%s: %d connections, want %d", step.name, accepted, step.wantAccepted)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package limits
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/upload"
+)
+
+// TagLimits configures the limits of a route, e.g.
+// `limits:"body=1MB,read=5s,deadline=2s"`; body=0, read=0 or deadline=0
+// lift a default limit
+const TagLimits = "limits"
+
+var (
+	// ErrBodyTooLarge is returned when reading a body over the size limit
+	ErrBodyTooLarge = controller.NewHTTPError(http.StatusRequestEntityTooLarge, "Request body too large")
+	// ErrRequestTimeout is returned when reading a body past the read
+	// timeout, and answered to requests past their handler deadline
+	ErrRequestTimeout = controller.NewHTTPError(http.StatusRequestTimeout, "Request timeout")
+)
+
+// Options configures the limits of a route; zero leaves a limit unset
+type Options struct {
+	// MaxBodySize bounds the request body in bytes
+	MaxBodySize int64
+	// ReadTimeout bounds the time spent reading the request body
+	ReadTimeout time.Duration
+	// Deadline bounds the handling of the request. The request context
+	// carries it, so queries and calls made with it are cancelled.
+	Deadline time.Duration
+}
+
+// IsZero reports whether no limit is set
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+var (
+	defaults      = Options{}
+	defaultsMutex sync.RWMutex
+)
+
+// SetDefaults sets the limits of routes without a limits tag, and the base
+// of limits tags
+func SetDefaults(options Options) {
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	defaults = options
+}
+
+// Defaults returns the limits of routes without a limits tag
+func Defaults() Options {
+	defaultsMutex.RLock()
+	defer defaultsMutex.RUnlock()
+	return defaults
+}
+
+// ParseTag parses a limits tag over base: comma separated body=, read= and
+// deadline= settings
+func ParseTag(tag string, base Options) (Options, error) {
+	options := base
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Options{}, fmt.Errorf("invalid limits setting %q", part)
+		}
+
+		switch key {
+		case "body":
+			if value == "0" {
+				options.MaxBodySize = 0
+				continue
+			}
+			size, err := upload.ParseSize(value)
+			if err != nil {
+				return Options{}, err
+			}
+			options.MaxBodySize = size
+		case "read", "deadline":
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return Options{}, fmt.Errorf("invalid limits duration %q", part)
+			}
+			if key == "read" {
+				options.ReadTimeout = duration
+			} else {
+				options.Deadline = duration
+			}
+		default:
+			return Options{}, fmt.Errorf("unknown limits setting %q", part)
+		}
+	}
+	return options, nil
+}
+
+// writeGrace is the time left past a handler deadline to write the timeout
+// response
+const writeGrace = 5 * time.Second
+
+// Middleware returns an HTTP middleware enforcing the limits. Bodies
+// declared over the size limit are answered 413 Request Entity Too Large
+// before reaching the handler; bodies found over it or read past the read
+// timeout fail with ErrBodyTooLarge or ErrRequestTimeout. Requests not
+// answered by their deadline are answered 408 Request Timeout and what the
+// handler writes afterwards is discarded. The read timeout and deadline
+// replace the connection deadlines of the HTTP server, so routes may wait
+// longer than its timeouts.
+func Middleware(options Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if options.MaxBodySize > 0 && r.ContentLength > options.MaxBodySize {
+				writeError(w, ErrBodyTooLarge)
+				return
+			}
+
+			if r.Body != nil && r.Body != http.NoBody && (options.MaxBodySize > 0 || options.ReadTimeout > 0) {
+				body := &limitedBody{ReadCloser: r.Body}
+				if options.MaxBodySize > 0 {
+					body.ReadCloser = http.MaxBytesReader(w, r.Body, options.MaxBodySize)
+				}
+				if options.ReadTimeout > 0 {
+					body.deadline = time.Now().Add(options.ReadTimeout)
+					err := http.NewResponseController(w).SetReadDeadline(body.deadline)
+					if err != nil && !errors.Is(err, http.ErrNotSupported) {
+						logger.Warn("Failed to set read deadline", "path", r.URL.Path, "error", err)
+					}
+				}
+				r.Body = body
+			}
+
+			if options.Deadline <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), options.Deadline)
+			defer cancel()
+
+			deadline := time.Now().Add(options.Deadline)
+			err := http.NewResponseController(w).SetWriteDeadline(deadline.Add(writeGrace))
+			if err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logger.Warn("Failed to set write deadline", "path", r.URL.Path, "error", err)
+			}
+
+			dw := &deadlineWriter{ResponseWriter: w, header: w.Header().Clone(), deadline: deadline}
+			fired := make(chan struct{})
+			timer := time.AfterFunc(options.Deadline, func() {
+				defer close(fired)
+				dw.timeout()
+			})
+
+			next.ServeHTTP(dw, r.WithContext(ctx))
+
+			// The timeout response must be written before the writer is
+			// released to net/http
+			if !timer.Stop() {
+				<-fired
+			}
+		})
+	}
+}
+
+// limitedBody reports the errors of a limited request body as HTTP errors
+type limitedBody struct {
+	io.ReadCloser
+	deadline time.Time
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		return 0, ErrRequestTimeout
+	}
+	n, err := b.ReadCloser.Read(p)
+	if err == nil || err == io.EOF {
+		return n, err
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return n, ErrBodyTooLarge
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return n, ErrRequestTimeout
+	}
+	return n, err
+}
+
+// deadlineWriter answers 408 Request Timeout once the deadline of a request
+// passed before its handler wrote a response, discarding later writes. The
+// handler sets headers on a copy, applied when it writes, so the timeout
+// never races with it. The underlying writer is not exposed: flushes,
+// hijacks and connection deadlines go through the writer, under its mutex
+// where they could race with the timeout.
+type deadlineWriter struct {
+	http.ResponseWriter
+	header   http.Header
+	deadline time.Time
+	mutex    sync.Mutex
+	written  bool
+	timedOut bool
+}
+
+// Header returns the headers written with the response of the handler
+func (dw *deadlineWriter) Header() http.Header {
+	return dw.header
+}
+
+// writeHeader applies the headers of the handler and writes the status
+// line, with the mutex held
+func (dw *deadlineWriter) writeHeader(statusCode int) {
+	header := dw.ResponseWriter.Header()
+	for name := range header {
+		if _, ok := dw.header[name]; !ok {
+			header.Del(name)
+		}
+	}
+	for name, values := range dw.header {
+		header[name] = values
+	}
+	dw.written = true
+	dw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// timeout answers the request unless the handler already did
+func (dw *deadlineWriter) timeout() {
+	dw.mutex.Lock()
+	defer dw.mutex.Unlock()
+	dw.expire()
+}
+
+// expire answers 408 Request Timeout unless the handler already wrote, with
+// the mutex held. Writes past the deadline expire the request themselves,
+// e.g. the error of a handler cancelled by its context.
+func (dw *deadlineWriter) expire() {
+	if dw.written || dw.timedOut {
+		return
+	}
+	dw.timedOut = true
+	writeError(dw.ResponseWriter, ErrRequestTimeout)
+	if flusher, ok := dw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (dw *deadlineWriter) WriteHeader(statusCode int) {
+	dw.mutex.Lock()
+	defer dw.mutex.Unlock()
+	if !dw.written && !time.Now().Before(dw.deadline) {
+		dw.expire()
+	}
+	if dw.timedOut || dw.written {
+		return
+	}
+	dw.writeHeader(statusCode)
+}
+
+func (dw *deadlineWriter) Write(data []byte) (int, error) {
+	dw.mutex.Lock()
+	defer dw.mutex.Unlock()
+	if !dw.written && !time.Now().Before(dw.deadline) {
+		dw.expire()
+	}
+	if dw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !dw.written {
+		dw.writeHeader(http.StatusOK)
+	}
+	return dw.ResponseWriter.Write(data)
+}
+
+// Flush sends buffered data to the client, e.g. while streaming
+func (dw *deadlineWriter) Flush() {
+	dw.mutex.Lock()
+	defer dw.mutex.Unlock()
+	if dw.timedOut {
+		return
+	}
+	if flusher, ok := dw.ResponseWriter.(http.Flusher); ok {
+		if !dw.written {
+			dw.writeHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection, e.g. for WebSockets,
+// unless the request timed out
+func (dw *deadlineWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	dw.mutex.Lock()
+	defer dw.mutex.Unlock()
+	if dw.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := http.NewResponseController(dw.ResponseWriter).Hijack()
+	if err == nil {
+		// The connection is the handler's, the timeout has nothing to answer
+		dw.written = true
+	}
+	return conn, rw, err
+}
+
+// SetReadDeadline sets the read deadline of the connection
+func (dw *deadlineWriter) SetReadDeadline(deadline time.Time) error {
+	return http.NewResponseController(dw.ResponseWriter).SetReadDeadline(deadline)
+}
+
+// SetWriteDeadline sets the write deadline of the connection
+func (dw *deadlineWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(dw.ResponseWriter).SetWriteDeadline(deadline)
+}
+
+// writeError writes the JSON body of an HTTP error
+func writeError(w http.ResponseWriter, err *controller.HTTPError) {
+	jsonData, _ := json.Marshal(map[string]string{"error": err.Message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.StatusCode())
+	w.Write(jsonData)
+}
@@ -0,0 +1,149 @@
+package limits
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTag(t *testing.T) {
+	base := Options{MaxBodySize: 1 << 20, ReadTimeout: time.Second, Deadline: 2 * time.Second}
+	tests := []struct {
+		name    string
+		tag     string
+		want    Options
+		wantErr bool
+	}{
+		{name: "empty keeps the base", tag: "", want: base},
+		{name: "body", tag: "body=2KB", want: Options{MaxBodySize: 2 << 10, ReadTimeout: time.Second, Deadline: 2 * time.Second}},
+		{name: "durations", tag: "read=5s, deadline=30s", want: Options{MaxBodySize: 1 << 20, ReadTimeout: 5 * time.Second, Deadline: 30 * time.Second}},
+		{name: "zero lifts a limit", tag: "body=0,deadline=0", want: Options{ReadTimeout: time.Second}},
+		{name: "missing value", tag: "body", wantErr: true},
+		{name: "negative duration", tag: "read=-1s", wantErr: true},
+		{name: "unknown setting", tag: "size=1MB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTag(tt.tag, base)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTag(%q) error = %v, want error %v", tt.tag, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseTag(%q) = %+v, want %+v", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    Options
+		body       string
+		chunked    bool
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:    "answered in time",
+			options: Options{Deadline: time.Second},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Handler", "yes")
+				w.Write([]byte("ok"))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+		{
+			name:    "past the deadline",
+			options: Options{Deadline: 20 * time.Millisecond},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				time.Sleep(10 * time.Millisecond)
+				if _, err := w.Write([]byte("late")); err != http.ErrHandlerTimeout {
+					t.Errorf("late Write error = %v, want %v", err, http.ErrHandlerTimeout)
+				}
+			},
+			wantStatus: http.StatusRequestTimeout,
+			wantBody:   "Request timeout",
+		},
+		{
+			name:    "flush past the deadline",
+			options: Options{Deadline: 20 * time.Millisecond},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+				http.NewResponseController(w).Flush()
+			},
+			wantStatus: http.StatusRequestTimeout,
+			wantBody:   "Request timeout",
+		},
+		{
+			name:       "declared body too large",
+			options:    Options{MaxBodySize: 4},
+			body:       "too large",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantBody:   "Request body too large",
+		},
+		{
+			name:    "body read past the size limit",
+			options: Options{MaxBodySize: 4},
+			body:    "too large",
+			chunked: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if _, err := io.ReadAll(r.Body); err != ErrBodyTooLarge {
+					t.Errorf("ReadAll error = %v, want %v", err, ErrBodyTooLarge)
+				}
+				w.WriteHeader(http.StatusNoContent)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Middleware(tt.options)(tt.handler)
+			request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.chunked {
+				request.ContentLength = -1
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if !strings.Contains(recorder.Body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", recorder.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestMiddlewareHijackAfterTimeout(t *testing.T) {
+	done := make(chan error, 1)
+	handler := Middleware(Options{Deadline: 10 * time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, _, err := http.NewResponseController(w).Hijack()
+		done <- err
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("status = %d, want %d", response.StatusCode, http.StatusRequestTimeout)
+	}
+	if err := <-done; err != http.ErrHandlerTimeout {
+		t.Errorf("Hijack error = %v, want %v", err, http.ErrHandlerTimeout)
+	}
+}
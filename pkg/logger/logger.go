@@ -3,6 +3,8 @@ package logger
 import (
 	"log/slog"
 	"os"
+
+	"github.com/kevenmiano/nestgo/pkg/pii"
 )
 
 var Logger *slog.Logger
@@ -10,7 +12,8 @@ var Logger *slog.Logger
 func init() {
 	// Configure JSON logger
 	opts := &slog.HandlerOptions{
-		Level: slog.LevelDebug,
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redactAttr,
 	}
 
 	handler := slog.NewJSONHandler(os.Stdout, opts)
@@ -36,3 +39,16 @@ func Debug(msg string, args ...any) {
 func Warn(msg string, args ...any) {
 	Logger.Warn(msg, args...)
 }
+
+// With adds attributes to every later log entry, e.g. the instance ID
+func With(args ...any) {
+	Logger = Logger.With(args...)
+}
+
+// redactAttr masks fields tagged with pii before they are written to the log
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindAny {
+		attr.Value = slog.AnyValue(pii.Redact(attr.Value.Any()))
+	}
+	return attr
+}
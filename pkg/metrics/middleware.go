@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/guard"
+)
+
+// DefaultPath serves the metrics
+const DefaultPath = "/metrics"
+
+// Options configures the metrics of an application
+type Options struct {
+	// Path serves the metrics, DefaultPath when empty
+	Path string
+	// Registry holds the metrics, a new one when nil. It is injectable under
+	// ProviderName for services recording their own metrics.
+	Registry *Registry
+	// Buckets bound the request duration histogram, DefaultBuckets when empty
+	Buckets []float64
+}
+
+// Names of the HTTP metrics recorded by Middleware
+const (
+	RequestsTotal    = "http_requests_total"
+	RequestDuration  = "http_request_duration_seconds"
+	RequestsInFlight = "http_requests_in_flight"
+	ResponseSize     = "http_response_size_bytes"
+)
+
+// RouteUnmatched labels the requests no route matched, keeping unknown
+// paths out of the label values
+const RouteUnmatched = "unmatched"
+
+// methodOther labels requests with a non-standard method
+const methodOther = "OTHER"
+
+// SizeBuckets are the upper bounds, in bytes, of response size histograms
+var SizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+// standardMethods are kept as method labels
+var standardMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodConnect: true,
+	http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// Middleware returns an HTTP middleware recording the requests served, by
+// method, route template (e.g. /users/:id) and status: their count,
+// duration and response size, and the requests in flight. buckets bound the
+// duration histogram, DefaultBuckets when empty.
+func Middleware(registry *Registry, buckets []float64) func(http.Handler) http.Handler {
+	requests := registry.Counter(RequestsTotal, "Requests served.", "method", "route", "status")
+	durations := registry.Histogram(RequestDuration, "Time to serve requests, in seconds.", buckets, "method", "route", "status")
+	sizes := registry.Histogram(ResponseSize, "Size of response bodies, in bytes.", SizeBuckets, "method", "route", "status")
+	inFlight := registry.Gauge(RequestsInFlight, "Requests being served.")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			r, matched := guard.CaptureRoute(r)
+			sw := &statusWriter{ResponseWriter: w}
+			next.ServeHTTP(sw, r)
+
+			method := r.Method
+			if !standardMethods[method] {
+				method = methodOther
+			}
+			route := RouteUnmatched
+			if matchedRoute, ok := matched(); ok {
+				route = matchedRoute.Path
+			}
+			status := strconv.Itoa(sw.Status())
+
+			requests.Inc(method, route, status)
+			durations.Observe(time.Since(start).Seconds(), method, route, status)
+			sizes.Observe(float64(sw.size), method, route, status)
+		})
+	}
+}
+
+// statusWriter records the status and body size of a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+// Status returns the status written, 200 when the handler wrote none
+func (sw *statusWriter) Status() int {
+	if sw.status == 0 {
+		return http.StatusOK
+	}
+	return sw.status
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	// Informational responses precede the final status
+	if sw.status == 0 && (statusCode >= 200 || statusCode == http.StatusSwitchingProtocols) {
+		sw.status = statusCode
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sw *statusWriter) Write(data []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	n, err := sw.ResponseWriter.Write(data)
+	sw.size += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, e.g. while streaming
+func (sw *statusWriter) Flush() {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Hijack lets the handler take over the connection, e.g. for WebSockets
+func (sw *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(sw.ResponseWriter).Hijack()
+	if err == nil && sw.status == 0 {
+		sw.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,331 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// ProviderName is the name the Registry is injectable under, e.g.
+// `inject:"MetricsRegistry"` on a *metrics.Registry field
+const ProviderName = "MetricsRegistry"
+
+// ContentType is the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds, in seconds, of duration histograms
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Kinds of metrics
+const (
+	kindCounter   = "counter"
+	kindGauge     = "gauge"
+	kindHistogram = "histogram"
+)
+
+// labelSeparator joins label values into series keys; it cannot appear in
+// valid UTF-8 label values
+const labelSeparator = "\xff"
+
+// Registry holds metrics and writes them in the Prometheus text format.
+// Metrics are registered once by name; registering a name again returns
+// the same metric.
+type Registry struct {
+	mutex    sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+// family is a metric with its series, one per set of label values
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+	mutex   sync.Mutex
+	series  map[string]*series
+}
+
+// series holds the value of one set of label values
+type series struct {
+	values []string
+	// value is the value of counters and gauges
+	value float64
+	// counts are the observations of histograms per bucket, not cumulated
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// register returns the family of a name, creating it when missing. A name
+// registered with another kind or other labels keeps its first definition.
+func (r *Registry) register(name, help, kind string, labels []string, buckets []float64) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || strings.Join(f.labels, ",") != strings.Join(labels, ",") {
+			logger.Warn("Metric already registered with another definition", "name", name, "kind", f.kind, "labels", f.labels)
+		}
+		return f
+	}
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  append([]string(nil), labels...),
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+	return f
+}
+
+// with returns the series of label values, with the family mutex held.
+// It returns nil when the number of values does not match the labels.
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		logger.Warn("Metric label values do not match its labels", "name", f.name, "labels", f.labels, "values", values)
+		return nil
+	}
+	key := strings.Join(values, labelSeparator)
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		if f.kind == kindHistogram {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// add adds delta to the series of label values
+func (f *family) add(delta float64, values []string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if s := f.with(values); s != nil {
+		s.value += delta
+	}
+}
+
+// Counter is a value that only goes up, e.g. requests served
+type Counter struct {
+	family *family
+}
+
+// Counter registers a counter with the names of its labels
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{family: r.register(name, help, kindCounter, labels, nil)}
+}
+
+// Inc adds one to the series of the label values
+func (c *Counter) Inc(values ...string) {
+	c.family.add(1, values)
+}
+
+// Add adds a non-negative delta to the series of the label values
+func (c *Counter) Add(delta float64, values ...string) {
+	if delta < 0 {
+		logger.Warn("Counter cannot decrease", "name", c.family.name, "delta", delta)
+		return
+	}
+	c.family.add(delta, values)
+}
+
+// Gauge is a value that goes up and down, e.g. requests in flight
+type Gauge struct {
+	family *family
+}
+
+// Gauge registers a gauge with the names of its labels
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{family: r.register(name, help, kindGauge, labels, nil)}
+}
+
+// Set sets the series of the label values
+func (g *Gauge) Set(value float64, values ...string) {
+	g.family.mutex.Lock()
+	defer g.family.mutex.Unlock()
+	if s := g.family.with(values); s != nil {
+		s.value = value
+	}
+}
+
+// Add adds delta, possibly negative, to the series of the label values
+func (g *Gauge) Add(delta float64, values ...string) {
+	g.family.add(delta, values)
+}
+
+// Inc adds one to the series of the label values
+func (g *Gauge) Inc(values ...string) {
+	g.family.add(1, values)
+}
+
+// Dec subtracts one from the series of the label values
+func (g *Gauge) Dec(values ...string) {
+	g.family.add(-1, values)
+}
+
+// Histogram counts observations in buckets, e.g. request durations
+type Histogram struct {
+	family *family
+}
+
+// Histogram registers a histogram with the upper bounds of its buckets,
+// DefaultBuckets when empty, and the names of its labels
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Histogram{family: r.register(name, help, kindHistogram, labels, buckets)}
+}
+
+// Observe records a value in the series of the label values
+func (h *Histogram) Observe(value float64, values ...string) {
+	h.family.mutex.Lock()
+	defer h.family.mutex.Unlock()
+	s := h.family.with(values)
+	if s == nil {
+		return
+	}
+	if i := sort.SearchFloat64s(h.family.buckets, value); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.sum += value
+	s.count++
+}
+
+// WriteTo writes every metric in the Prometheus text format, sorted by
+// name and label values
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mutex.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mutex.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counter)
+	for _, f := range families {
+		f.write(buffered)
+	}
+	err := buffered.Flush()
+	return counter.n, err
+}
+
+// write writes the help, type and series of a family
+func (f *family) write(w *bufio.Writer) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.help != "" {
+		fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != kindHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, labelSet(f.labels, s.values, ""), formatFloat(s.value))
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, labelSet(f.labels, s.values, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, labelSet(f.labels, s.values, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, labelSet(f.labels, s.values, ""), s.count)
+	}
+}
+
+// ServeHTTP writes the metrics for Prometheus to scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if _, err := r.WriteTo(w); err != nil {
+		logger.Warn("Failed to write metrics", "error", err)
+	}
+}
+
+// labelSet formats the labels of a series as {name="value",...}, with the
+// le label of histogram buckets when set
+func labelSet(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelReplacer escapes label values
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value
+func escapeLabel(value string) string {
+	return labelReplacer.Replace(value)
+}
+
+// helpReplacer escapes help texts
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// escapeHelp escapes a help text
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
+
+// formatFloat formats a sample value
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written for WriteTo
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(data []byte) (int, error) {
+	n, err := cw.w.Write(data)
+	cw.n += int64(n)
+	return n, err
+}
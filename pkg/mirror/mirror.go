@@ -0,0 +1,233 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+// HeaderMirrored marks the requests sent to the shadow upstream, so it can
+// tell them apart, e.g. to skip side effects such as sending emails
+const HeaderMirrored = "X-Mirrored-Request"
+
+// DefaultMaxBodySize bounds the bodies buffered for mirroring
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultTimeout bounds a mirrored request
+const DefaultTimeout = 5 * time.Second
+
+// DefaultMaxInFlight bounds the mirrored requests in flight
+const DefaultMaxInFlight = 64
+
+// maxDrainSize bounds the shadow response read before closing it, so the
+// connection can be reused
+const maxDrainSize = 64 << 10
+
+// hopHeaders are connection specific and not forwarded
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// Options configures traffic mirroring
+type Options struct {
+	// Target is the base URL of the shadow upstream, e.g.
+	// "http://api-next.internal:8080"; request paths are appended to it
+	Target string
+	// Percent of requests mirrored, from 0 to 100
+	Percent float64
+	// Filter selects the requests that can be mirrored, e.g. to leave out
+	// payment routes; every request when nil
+	Filter func(r *http.Request) bool
+	// MaxBodySize bounds the bodies buffered for mirroring,
+	// DefaultMaxBodySize when zero. Requests with larger bodies are served
+	// without being mirrored.
+	MaxBodySize int64
+	// Timeout bounds a mirrored request, DefaultTimeout when zero
+	Timeout time.Duration
+	// MaxInFlight bounds the mirrored requests in flight,
+	// DefaultMaxInFlight when zero; requests over it are not mirrored so a
+	// slow shadow never holds resources of the primary
+	MaxInFlight int
+	// Client sends the mirrored requests; defaults to a client that does
+	// not follow redirects
+	Client *http.Client
+}
+
+// Stats counts the requests considered for mirroring
+type Stats struct {
+	Mirrored int64 `json:"mirrored"`
+	// Dropped were sampled but not mirrored, over MaxInFlight or
+	// MaxBodySize
+	Dropped int64 `json:"dropped"`
+	// Failed could not reach the shadow upstream
+	Failed int64 `json:"failed"`
+}
+
+// Mirror copies a sample of the requests to a shadow upstream, fire and
+// forget: shadow responses are discarded and never delay or change the
+// response of the primary
+type Mirror struct {
+	target  *url.URL
+	options Options
+	slots   chan struct{}
+
+	mirrored atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+}
+
+// New creates a mirror of the requests to opts.Target
+func New(opts Options) (*Mirror, error) {
+	target, err := url.Parse(opts.Target)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("mirror: invalid target %q: expected an http or https URL", opts.Target)
+	}
+	if opts.Percent < 0 || opts.Percent > 100 {
+		return nil, fmt.Errorf("mirror: percent must be between 0 and 100, got %v", opts.Percent)
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxInFlight <= 0 {
+		opts.MaxInFlight = DefaultMaxInFlight
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return &Mirror{
+		target:  target,
+		options: opts,
+		slots:   make(chan struct{}, opts.MaxInFlight),
+	}, nil
+}
+
+// Middleware returns an HTTP middleware mirroring the sampled requests.
+// Their bodies are buffered and replayed to the handler.
+func (m *Mirror) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rand.Float64()*100 >= m.options.Percent || (m.options.Filter != nil && !m.options.Filter(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, ok := m.bufferBody(r)
+		if !ok {
+			m.dropped.Add(1)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case m.slots <- struct{}{}:
+			shadow := m.shadowRequest(r, body)
+			go m.send(shadow)
+		default:
+			m.dropped.Add(1)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bufferBody reads the body of a request up to MaxBodySize, replacing it
+// with a reader that replays it. It reports false for bodies that are too
+// large or unreadable, which the handler still reads in full.
+func (m *Mirror) bufferBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+
+	original := r.Body
+	body, err := io.ReadAll(io.LimitReader(original, m.options.MaxBodySize+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), original), original}
+
+	if err != nil || int64(len(body)) > m.options.MaxBodySize {
+		return nil, false
+	}
+	return body, true
+}
+
+// shadowRequest copies a request for the shadow upstream. Headers are
+// copied before the handler runs, as middlewares may change them.
+func (m *Mirror) shadowRequest(r *http.Request, body []byte) *http.Request {
+	target := *m.target
+	target.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+	target.RawPath = ""
+	target.RawQuery = r.URL.RawQuery
+
+	header := r.Header.Clone()
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	header.Set(HeaderMirrored, "true")
+	header.Set("X-Forwarded-For", realip.ClientIP(r))
+	header.Set("X-Forwarded-Host", r.Host)
+
+	shadow := &http.Request{
+		Method:        r.Method,
+		URL:           &target,
+		Header:        header,
+		Host:          target.Host,
+		ContentLength: int64(len(body)),
+	}
+	if len(body) > 0 {
+		shadow.Body = io.NopCloser(bytes.NewReader(body))
+		shadow.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	return shadow
+}
+
+// send delivers a mirrored request, detached from the original request so
+// it outlives the response of the primary
+func (m *Mirror) send(shadow *http.Request) {
+	defer func() { <-m.slots }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.options.Timeout)
+	defer cancel()
+
+	resp, err := m.options.Client.Do(shadow.WithContext(ctx))
+	if err != nil {
+		m.failed.Add(1)
+		logger.Debug("Mirrored request failed", "method", shadow.Method, "url", shadow.URL.String(), "error", err)
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainSize))
+	resp.Body.Close()
+
+	m.mirrored.Add(1)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		logger.Debug("Mirrored request answered with an error", "method", shadow.Method, "path", shadow.URL.Path, "status", resp.StatusCode)
+	}
+}
+
+// Stats returns a snapshot of the mirroring counters
+func (m *Mirror) Stats() Stats {
+	return Stats{
+		Mirrored: m.mirrored.Load(),
+		Dropped:  m.dropped.Load(),
+		Failed:   m.failed.Load(),
+	}
+}
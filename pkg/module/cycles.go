@@ -0,0 +1,107 @@
+package module
+
+import (
+	"sort"
+	"strings"
+)
+
+// ForwardReference is an import closing an intended import cycle, see ForwardRef
+type ForwardReference struct {
+	resolve func() interface{}
+}
+
+// ForwardRef marks an import as closing an import cycle, like NestJS
+// forwardRef. Modules importing each other fail at bootstrap unless one of
+// the imports of the cycle is a forward reference:
+//
+//	Imports: []interface{}{module.ForwardRef(func() interface{} { return &UsersModule{} })}
+//
+// Providers of both modules can then inject each other's exports, lazily
+// with container.Lazy when their constructors depend on each other.
+func ForwardRef(resolve func() interface{}) *ForwardReference {
+	return &ForwardReference{resolve: resolve}
+}
+
+// ImportCycleError reports modules importing themselves through their imports
+type ImportCycleError struct {
+	// Path lists the modules of the cycle, starting and ending with the same one
+	Path []string
+}
+
+func (e *ImportCycleError) Error() string {
+	return "module import cycle: " + strings.Join(e.Path, " -> ") + "; import one of the modules with module.ForwardRef if the cycle is intended"
+}
+
+// CheckImportCycles returns an ImportCycleError for the first import cycle
+// of the modules without a forward reference, in module name order
+func CheckImportCycles(modules map[string]Module) error {
+	names := make([]string, 0, len(modules))
+	for name := range modules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(modules))
+	path := make([]string, 0)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		m, exists := modules[name]
+		if !exists || state[name] == done {
+			return nil
+		}
+		if state[name] == visiting {
+			start := 0
+			for i, entry := range path {
+				if entry == name {
+					start = i
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), name)
+			return &ImportCycleError{Path: cycle}
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, imported := range strictImports(m) {
+			if err := visit(imported.GetModuleName()); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// strictImports returns the imports of a module that are not forward
+// references, skipping invalid entries
+func strictImports(m Module) []Module {
+	cmw, ok := m.(*ConfiguredModuleWrapper)
+	if !ok {
+		return m.GetImports()
+	}
+
+	imports := make([]Module, 0, len(cmw.config.Imports))
+	for _, entry := range cmw.config.Imports {
+		if _, forward := entry.(*ForwardReference); forward {
+			continue
+		}
+		if imported, err := Resolve(entry); err == nil {
+			imports = append(imports, imported)
+		}
+	}
+	return imports
+}
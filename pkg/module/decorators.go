@@ -1,6 +1,7 @@
 package module
 
 import (
+	"fmt"
 	"reflect"
 )
 
@@ -8,8 +9,43 @@ import (
 type ModuleConfig struct {
 	Controllers []interface{}
 	Providers   []interface{}
-	Imports     []interface{}
-	Exports     []interface{}
+	// Imports are the modules whose exported providers this module can inject.
+	// Imports closing an import cycle must be wrapped with ForwardRef.
+	Imports []interface{}
+	// Exports are the providers other modules can inject when importing this
+	// one: provider instances, provider names or imported modules, whose
+	// exports are re-exported
+	Exports []interface{}
+	// Global makes the exports injectable in every module without importing it
+	Global bool
+	// Owner is the team accountable for the module's routes, shown in the
+	// route table, error logs and query events
+	Owner string
+	// Tags label the module's routes for reporting, e.g. "billing", "tier-1"
+	Tags []string
+	// Middlewares run for every route of the module's controllers, after the
+	// global middlewares. Entries may be a server.Middleware, a net/http
+	// middleware or the name of a registered middleware.
+	Middlewares []interface{}
+	// Prefix is prepended to the base path of the module's controllers,
+	// e.g. "/api/v1"
+	Prefix string
+	// Guards run for every route of the module's controllers, after the
+	// global guards and before controller and route guards. Entries may be
+	// a guard.Guard or the name of a registered guard.
+	Guards []interface{}
+	// Interceptors wrap every route of the module's controllers, inside the
+	// global interceptors and outside controller and route interceptors.
+	// Entries may be an interceptor.Interceptor or the name of a registered
+	// interceptor.
+	Interceptors []interface{}
+	// Throttle limits each route of the module's controllers without a
+	// throttle tag, e.g. "100/1m"
+	Throttle string
+	// Server names the server hosting the module's routes, declared with
+	// application.WithServer, e.g. "admin" on its own port; the main server
+	// when empty
+	Server string
 }
 
 // Module decorator function that registers a module (like NestJS @Module)
@@ -64,13 +100,57 @@ func (cmw *ConfiguredModuleWrapper) GetServices() []interface{} {
 	return cmw.config.Providers
 }
 
-// GetImports returns imported modules
+// GetMiddlewares returns the middlewares declared in the module configuration
+func (cmw *ConfiguredModuleWrapper) GetMiddlewares() []interface{} {
+	return cmw.config.Middlewares
+}
+
+// GetImports returns imported modules, skipping entries that are not modules
 func (cmw *ConfiguredModuleWrapper) GetImports() []Module {
+	imports, _ := cmw.ResolveImports()
+	return imports
+}
+
+// ResolveImports returns imported modules. Entries may be modules or the
+// module structs passed to New.
+func (cmw *ConfiguredModuleWrapper) ResolveImports() ([]Module, error) {
 	imports := make([]Module, 0, len(cmw.config.Imports))
 	for _, imp := range cmw.config.Imports {
-		if module, ok := imp.(Module); ok {
-			imports = append(imports, module)
+		module, err := Resolve(imp)
+		if err != nil {
+			return imports, fmt.Errorf("module %s: invalid import: %w", cmw.name, err)
 		}
+		imports = append(imports, module)
 	}
-	return imports
+	return imports, nil
+}
+
+// GetExports returns the exports declared in the module configuration
+func (cmw *ConfiguredModuleWrapper) GetExports() []interface{} {
+	return cmw.config.Exports
+}
+
+// IsGlobal reports whether the module exports are visible to every module
+func (cmw *ConfiguredModuleWrapper) IsGlobal() bool {
+	return cmw.config.Global
+}
+
+// Resolve returns the registered module for a Module, a module struct
+// passed to New or a forward reference to one
+func Resolve(entry interface{}) (Module, error) {
+	if module, ok := entry.(Module); ok {
+		return module, nil
+	}
+	if ref, ok := entry.(*ForwardReference); ok {
+		return Resolve(ref.resolve())
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("nil module")
+	}
+
+	moduleType := reflect.TypeOf(entry)
+	if moduleType.Kind() == reflect.Ptr {
+		moduleType = moduleType.Elem()
+	}
+	return GetGlobalRegistry().GetModule(moduleType.Name())
 }
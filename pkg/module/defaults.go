@@ -0,0 +1,37 @@
+package module
+
+// RouteDefaults are shared by every route of a module's controllers, so a
+// group of controllers declares its prefix, guards, interceptors, throttle
+// and server once instead of on each controller
+type RouteDefaults struct {
+	Prefix       string
+	Guards       []interface{}
+	Interceptors []interface{}
+	Throttle     string
+	Server       string
+}
+
+// routeDefaultsProvider is implemented by modules declaring route defaults
+type routeDefaultsProvider interface {
+	GetRouteDefaults() RouteDefaults
+}
+
+// GetRouteDefaults returns the route defaults declared in the module configuration
+func (cmw *ConfiguredModuleWrapper) GetRouteDefaults() RouteDefaults {
+	return RouteDefaults{
+		Prefix:       cmw.config.Prefix,
+		Guards:       cmw.config.Guards,
+		Interceptors: cmw.config.Interceptors,
+		Throttle:     cmw.config.Throttle,
+		Server:       cmw.config.Server,
+	}
+}
+
+// RouteDefaultsOf returns the route defaults of a module; modules without
+// configuration have none
+func RouteDefaultsOf(m Module) RouteDefaults {
+	if provider, ok := m.(routeDefaultsProvider); ok {
+		return provider.GetRouteDefaults()
+	}
+	return RouteDefaults{}
+}
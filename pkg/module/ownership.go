@@ -0,0 +1,48 @@
+package module
+
+import "context"
+
+// Ownership attributes a module's routes to a team for the route table,
+// error logs and query events
+type Ownership struct {
+	Module string   `json:"module"`
+	Owner  string   `json:"owner,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// ownershipProvider is implemented by modules declaring ownership metadata
+type ownershipProvider interface {
+	GetOwnership() Ownership
+}
+
+// GetOwnership returns the owner and tags declared in the module configuration
+func (cmw *ConfiguredModuleWrapper) GetOwnership() Ownership {
+	return Ownership{
+		Module: cmw.name,
+		Owner:  cmw.config.Owner,
+		Tags:   cmw.config.Tags,
+	}
+}
+
+// OwnershipOf returns the ownership of a module; modules without metadata
+// only carry their name
+func OwnershipOf(m Module) Ownership {
+	if provider, ok := m.(ownershipProvider); ok {
+		return provider.GetOwnership()
+	}
+	return Ownership{Module: m.GetModuleName()}
+}
+
+type ownershipKey struct{}
+
+// WithOwnership returns a copy of ctx carrying the ownership of the route
+// being served
+func WithOwnership(ctx context.Context, ownership Ownership) context.Context {
+	return context.WithValue(ctx, ownershipKey{}, ownership)
+}
+
+// OwnershipFromContext returns the ownership of the route serving ctx
+func OwnershipFromContext(ctx context.Context) (Ownership, bool) {
+	ownership, ok := ctx.Value(ownershipKey{}).(Ownership)
+	return ownership, ok
+}
@@ -0,0 +1,103 @@
+package openapi
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server is a base URL serving the API, e.g. https://api.example.com
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lowercase HTTP method
+type PathItem map[string]*Operation
+
+// Operation describes a route
+type Operation struct {
+	Tags        []string            `json:"tags,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter of an operation
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Style is deepObject for name[key] parameters bound to maps
+	Style   string  `json:"style,omitempty"`
+	Explode bool    `json:"explode,omitempty"`
+	Schema  *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of an operation by media type
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the schemas referenced by the operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Schema is a JSON schema, as supported by OpenAPI
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	WriteOnly            bool               `json:"writeOnly,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Discriminator        *Discriminator     `json:"discriminator,omitempty"`
+}
+
+// Discriminator selects the schema of a union value by a property
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
@@ -0,0 +1,345 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+	"github.com/kevenmiano/nestgo/pkg/server"
+	"github.com/kevenmiano/nestgo/pkg/upload"
+	"github.com/kevenmiano/nestgo/pkg/validation"
+)
+
+const (
+	// DefaultPath serves the OpenAPI document
+	DefaultPath = "/openapi.json"
+	// DefaultTitle is the title of APIs without one
+	DefaultTitle = "NestGo API"
+	// DefaultVersion is the version of APIs without one
+	DefaultVersion = "1.0.0"
+)
+
+// TagOpenAPI set to "-" leaves a route out of the document:
+// `route:"GET /internal" openapi:"-"`
+const TagOpenAPI = "openapi"
+
+// errorType is the type of error results
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Options configures the OpenAPI document
+type Options struct {
+	// Path serves the document, DefaultPath when empty
+	Path string
+	// Title of the API, DefaultTitle when empty
+	Title string
+	// Version of the API, DefaultVersion when empty
+	Version     string
+	Description string
+	// Servers lists the base URLs of the API
+	Servers []Server
+	// Docs serves an interactive documentation page of the document,
+	// UISwagger or UIRedoc; empty serves none, e.g. in production
+	Docs UI
+	// DocsPath serves the documentation page, DefaultDocsPath when empty
+	DocsPath string
+}
+
+// Spec documents the controller routes of a server
+type Spec struct {
+	options  Options
+	server   *server.Server
+	once     sync.Once
+	document *Document
+}
+
+// New creates the OpenAPI document of the routes registered on s. It is
+// built on first use, once the modules registered their controllers.
+func New(opts Options, s *server.Server) *Spec {
+	return &Spec{options: opts.withDefaults(), server: s}
+}
+
+// withDefaults fills the unset options
+func (o Options) withDefaults() Options {
+	if o.Path == "" {
+		o.Path = DefaultPath
+	}
+	if o.Title == "" {
+		o.Title = DefaultTitle
+	}
+	if o.Version == "" {
+		o.Version = DefaultVersion
+	}
+	if o.DocsPath == "" {
+		o.DocsPath = DefaultDocsPath
+	}
+	return o
+}
+
+// Path returns the path serving the document
+func (sp *Spec) Path() string {
+	return sp.options.Path
+}
+
+// Document returns the OpenAPI document of the controller routes
+func (sp *Spec) Document() *Document {
+	sp.once.Do(func() {
+		sp.document = build(sp.options, sp.server.Routes(), conventions{
+			timePolicy: sp.server.TimePolicy(),
+			jsonStyle:  sp.server.JSONStyle(),
+			uploads:    sp.server.UploadDefaults(),
+		})
+	})
+	return sp.document
+}
+
+// ServeHTTP writes the document as JSON
+func (sp *Spec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(sp.Document())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(sp.server.JSONStyle().Format(data))
+}
+
+// Build returns the OpenAPI document of routes. Only controller routes of
+// the main server are documented: their handler signature gives the
+// parameters, body and responses, and their tags the summary and
+// constraints. Routes tagged openapi:"-" are left out. Timestamps, JSON
+// envelopes and upload fields follow the package defaults, such as
+// codec.CurrentTimePolicy, rather than the settings of a server.
+func Build(opts Options, routes []server.RouteInfo) *Document {
+	return build(opts, routes, conventions{
+		timePolicy: codec.CurrentTimePolicy(),
+		jsonStyle:  encoder.CurrentJSONStyle(),
+		uploads:    upload.Defaults(),
+	})
+}
+
+// build returns the OpenAPI document of routes served with conventions
+func build(opts Options, routes []server.RouteInfo, conv conventions) *Document {
+	opts = opts.withDefaults()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: opts.Title, Version: opts.Version, Description: opts.Description},
+		Servers: opts.Servers,
+		Paths:   make(map[string]PathItem),
+	}
+	s := newSchemas(conv)
+
+	for _, route := range routes {
+		if route.Signature == nil || route.Server != "" || route.Tag.Get(TagOpenAPI) == "-" {
+			continue
+		}
+		parsed, err := server.ParsePath(route.Path)
+		if err != nil {
+			continue
+		}
+
+		path := parsed.Format(func(part server.PathPart) string {
+			return "{" + part.Param + "}"
+		})
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = operation(s, route, parsed)
+	}
+
+	doc.Components.Schemas = s.components
+	return doc
+}
+
+// operation describes a controller route
+func operation(s *schemas, route server.RouteInfo, parsed server.RoutePath) *Operation {
+	op := &Operation{
+		Summary:     route.Tag.Get(controller.TagDesc),
+		OperationID: route.Controller + "." + route.Handler,
+		Responses:   make(map[string]Response),
+	}
+	if tag := strings.TrimSuffix(route.Controller, "Controller"); tag != "" {
+		op.Tags = []string{tag}
+	}
+
+	constraints := make(map[string]string)
+	for _, part := range parsed.Parts {
+		if part.Param != "" {
+			constraints[part.Param] = part.Constraint
+		}
+	}
+
+	validated := false
+	for _, arg := range route.Arguments {
+		switch arg.Source {
+		case pipe.SourcePath:
+			schema := s.of(arg.Type, "")
+			if constraint := constraints[arg.Name]; constraint != "" {
+				schema.Pattern = "^(?:" + constraint + ")$"
+			}
+			op.Parameters = append(op.Parameters, Parameter{Name: arg.Name, In: "path", Required: true, Schema: schema})
+		case pipe.SourceQuery:
+			op.Parameters = append(op.Parameters, queryParameters(s, arg.Type)...)
+			validated = validated || validation.HasRules(arg.Type)
+		case pipe.SourceBody:
+			op.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/json": {Schema: s.of(arg.Type, "")},
+			}}
+			validated = validated || validation.HasRules(arg.Type)
+		case server.SourceUpload:
+			op.RequestBody = uploadBody(s, arg.Type, route.Tag)
+		}
+	}
+
+	status, response := success(s, route)
+	op.Responses[status] = response
+	if len(route.Arguments) > 0 {
+		op.Responses[strconv.Itoa(http.StatusBadRequest)] = s.errorResponse("Invalid request")
+	}
+	if validated {
+		op.Responses[strconv.Itoa(http.StatusUnprocessableEntity)] = Response{
+			Description: "Validation failed",
+			Content:     map[string]MediaType{"application/json": {Schema: s.validationError()}},
+		}
+	}
+	if returnsError(route.Signature) {
+		op.Responses["default"] = s.errorResponse("Error")
+	}
+	return op
+}
+
+// queryParameters describes the query tagged fields of a query argument
+func queryParameters(s *schemas, t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	parameters := make([]Parameter, 0)
+	for _, field := range reflect.VisibleFields(t) {
+		name, ok := field.Tag.Lookup(controller.TagQuery)
+		if !ok || name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := constrain(s.of(field.Type, ""), field.Type, field.Tag)
+		parameter := Parameter{
+			Name:        name,
+			In:          "query",
+			Description: schema.Description,
+			Required:    isRequired(field.Tag),
+			Schema:      schema,
+		}
+		if field.Type.Kind() == reflect.Map {
+			parameter.Style, parameter.Explode = "deepObject", true
+		}
+		parameters = append(parameters, parameter)
+		schema.Description = ""
+	}
+	return parameters
+}
+
+// uploadBody describes the multipart form of an upload route
+func uploadBody(s *schemas, t reflect.Type, tag reflect.StructTag) *RequestBody {
+	field := upload.DefaultField
+	if options, err := upload.ParseTag(tag.Get(upload.TagUpload), s.uploads); err == nil && options.Field != "" {
+		field = options.Field
+	}
+
+	file := &Schema{Type: "string", Format: "binary"}
+	property := file
+	if t.Kind() == reflect.Slice {
+		property = &Schema{Type: "array", Items: file}
+	}
+	return &RequestBody{Required: true, Content: map[string]MediaType{
+		"multipart/form-data": {Schema: &Schema{Type: "object", Properties: map[string]*Schema{field: property}}},
+	}}
+}
+
+// success describes the response of a route returning without error, as
+// the server writes it
+func success(s *schemas, route server.RouteInfo) (string, Response) {
+	status := http.StatusOK
+	if route.Method == http.MethodPost {
+		status = http.StatusCreated
+	}
+	compact := s.jsonStyle.Compact
+	body := func(schema *Schema) Response {
+		return Response{Description: http.StatusText(status), Content: map[string]MediaType{"application/json": {Schema: schema}}}
+	}
+
+	value, ok := valueType(route.Signature)
+	switch {
+	case !ok && compact:
+		return strconv.Itoa(http.StatusNoContent), Response{Description: http.StatusText(http.StatusNoContent)}
+	case !ok:
+		return strconv.Itoa(status), body(message())
+	case value == reflect.TypeOf(controller.Response{}) || value == reflect.TypeOf(&controller.Response{}):
+		// The handler chooses the status and body
+		return strconv.Itoa(status), Response{Description: http.StatusText(status)}
+	case value.Kind() == reflect.String && !compact:
+		return strconv.Itoa(status), body(message())
+	case value.Kind() == reflect.Slice && value.Elem().Kind() == reflect.String && !compact:
+		return strconv.Itoa(status), body(&Schema{Type: "object", Properties: map[string]*Schema{
+			"data":  {Type: "array", Items: &Schema{Type: "string"}},
+			"count": {Type: "integer"},
+		}})
+	}
+	return strconv.Itoa(status), body(s.of(value, ""))
+}
+
+// valueType returns the type of the value returned by a handler, false for
+// handlers returning nothing or only an error
+func valueType(signature reflect.Type) (reflect.Type, bool) {
+	if signature.NumOut() == 0 || (signature.NumOut() == 1 && returnsError(signature)) {
+		return nil, false
+	}
+	return signature.Out(0), true
+}
+
+// returnsError reports whether a handler returns an error
+func returnsError(signature reflect.Type) bool {
+	return signature.NumOut() > 0 && signature.Out(signature.NumOut()-1).Implements(errorType)
+}
+
+// message is the schema of {"message": ...} responses
+func message() *Schema {
+	return &Schema{Type: "object", Properties: map[string]*Schema{"message": {Type: "string"}}}
+}
+
+// errorResponse describes an {"error": ...} response
+func (s *schemas) errorResponse(description string) Response {
+	if _, ok := s.components["Error"]; !ok {
+		s.components["Error"] = &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{"error": {Type: "string"}},
+			Required:   []string{"error"},
+		}
+	}
+	return Response{Description: description, Content: map[string]MediaType{
+		"application/json": {Schema: &Schema{Ref: refPrefix + "Error"}},
+	}}
+}
+
+// validationError returns a reference to the schema of validation failures
+func (s *schemas) validationError() *Schema {
+	if _, ok := s.components["ValidationError"]; !ok {
+		s.components["ValidationError"] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"error":  {Type: "string"},
+				"fields": {Type: "array", Items: s.of(reflect.TypeOf(validation.FieldError{}), "")},
+			},
+			Required: []string{"error", "fields"},
+		}
+	}
+	return &Schema{Ref: refPrefix + "ValidationError"}
+}
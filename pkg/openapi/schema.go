@@ -0,0 +1,281 @@
+package openapi
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/serialize"
+	"github.com/kevenmiano/nestgo/pkg/union"
+	"github.com/kevenmiano/nestgo/pkg/upload"
+)
+
+// refPrefix prefixes references to component schemas
+const refPrefix = "#/components/schemas/"
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// schemas builds the schemas of Go types, declaring named structs as
+// components referenced by the operations
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	conventions
+}
+
+// conventions are the settings of the server shaping its requests and
+// responses
+type conventions struct {
+	timePolicy codec.TimePolicy
+	jsonStyle  encoder.JSONStyle
+	uploads    upload.Options
+}
+
+// newSchemas creates an empty schema builder
+func newSchemas(conv conventions) *schemas {
+	return &schemas{components: make(map[string]*Schema), names: make(map[reflect.Type]string), conventions: conv}
+}
+
+// of returns the schema of a type. discriminator names the property
+// selecting the variant of union values, union.DefaultDiscriminator when
+// empty.
+func (s *schemas) of(t reflect.Type, discriminator string) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		policy := s.timePolicy
+		if policy.Output == codec.FormatEpoch || policy.Output == codec.FormatEpochMillis {
+			return &Schema{Type: "integer", Format: "int64"}
+		}
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	if _, ok := codec.Lookup(t); ok || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes byte slices as base64 strings
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.of(t.Elem(), discriminator)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem(), discriminator)}
+	case reflect.Interface:
+		return s.union(t, discriminator)
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: refPrefix + s.component(t)}
+	}
+	return &Schema{}
+}
+
+// component declares a named struct as a component schema and returns its
+// name, suffixed when another type already uses it
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	base := componentName(t)
+	name := base
+	for i := 2; s.components[name] != nil; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	s.names[t] = name
+	// Declare the component before describing it so recursive types end
+	s.components[name] = &Schema{}
+	*s.components[name] = *s.object(t)
+	return name
+}
+
+// componentName returns a component name of a struct type, keeping the
+// characters allowed by OpenAPI, e.g. Page_main.User_ for Page[main.User]
+func componentName(t reflect.Type) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, t.Name())
+}
+
+// object returns the schema of a struct, with the constraints of the
+// validate, required, default and desc tags of its fields
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, field := range codec.Fields(t) {
+		fieldType := t.FieldByIndex(field.Index).Type
+		property := s.of(fieldType, field.Tag.Get(union.TagDiscriminator))
+		property = constrain(property, fieldType, field.Tag)
+		// Excluded fields are bound from requests but never written
+		if property.Ref == "" && serialize.Excluded(field.Tag) {
+			property.WriteOnly = true
+		}
+		schema.Properties[field.Name] = property
+		if isRequired(field.Tag) {
+			schema.Required = append(schema.Required, field.Name)
+		}
+	}
+	return schema
+}
+
+// union returns the schema of an interface type: one of the variants of a
+// union, any value otherwise
+func (s *schemas) union(t reflect.Type, discriminator string) *Schema {
+	variants := union.Variants(t)
+	if len(variants) == 0 {
+		return &Schema{}
+	}
+	if discriminator == "" {
+		discriminator = union.DefaultDiscriminator
+	}
+
+	values := make([]string, 0, len(variants))
+	for value := range variants {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	schema := &Schema{Discriminator: &Discriminator{PropertyName: discriminator, Mapping: make(map[string]string)}}
+	for _, value := range values {
+		variant := s.of(variants[value], "")
+		schema.OneOf = append(schema.OneOf, variant)
+		if variant.Ref != "" {
+			schema.Discriminator.Mapping[value] = variant.Ref
+		}
+	}
+	return schema
+}
+
+// constrain applies the desc, default and validate tags of a field or
+// query parameter to its schema. References cannot carry constraints in
+// OpenAPI 3.0, so they are returned unchanged.
+func constrain(schema *Schema, t reflect.Type, tag reflect.StructTag) *Schema {
+	if schema.Ref != "" {
+		return schema
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema.Description = tag.Get(controller.TagDesc)
+	if def, ok := tag.Lookup(controller.TagDefault); ok {
+		schema.Default = literal(schema.Type, def)
+	}
+
+	for _, rule := range strings.Split(tag.Get(controller.TagValidate), ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "uuid":
+			schema.Format = "uuid"
+		case "oneof":
+			for _, option := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, literal(schema.Type, option))
+			}
+		case "min", "max", "len":
+			bound(schema, name, param)
+		}
+	}
+	return schema
+}
+
+// bound applies a min, max or len rule to string lengths, array sizes or
+// numbers, like the validation package checks them
+func bound(schema *Schema, rule, param string) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	size := int(limit)
+
+	switch schema.Type {
+	case "string":
+		if rule != "max" {
+			schema.MinLength = &size
+		}
+		if rule != "min" {
+			schema.MaxLength = &size
+		}
+	case "array":
+		if rule != "max" {
+			schema.MinItems = &size
+		}
+		if rule != "min" {
+			schema.MaxItems = &size
+		}
+	case "integer", "number":
+		if rule != "max" {
+			schema.Minimum = &limit
+		}
+		if rule != "min" {
+			schema.Maximum = &limit
+		}
+	}
+}
+
+// literal converts a tag value to a JSON value of a schema type, keeping
+// strings that do not parse
+func literal(schemaType, text string) interface{} {
+	switch schemaType {
+	case "integer":
+		if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return value
+		}
+	case "number":
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value
+		}
+	case "boolean":
+		if value, err := strconv.ParseBool(text); err == nil {
+			return value
+		}
+	}
+	return text
+}
+
+// isRequired reports whether a field must be present, like the schema and
+// validation packages decide it
+func isRequired(tag reflect.StructTag) bool {
+	if tag.Get(controller.TagRequired) == controller.TagValueTrue {
+		return true
+	}
+	for _, rule := range strings.Split(tag.Get(controller.TagValidate), ",") {
+		if strings.TrimSpace(rule) == "required" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+)
+
+// UI is an interactive documentation page rendering the document
+type UI string
+
+// Documentation pages, loaded from a CDN by the browser
+const (
+	UISwagger UI = "swagger"
+	UIRedoc   UI = "redoc"
+)
+
+// DefaultDocsPath serves the documentation page
+const DefaultDocsPath = "/docs"
+
+// pages are the templates of the documentation pages, given the title and
+// the path of the document
+var pages = map[UI]*template.Template{
+	UISwagger: template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: {{.Path}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`)),
+	UIRedoc: template.Must(template.New("redoc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>{{.Title}}</title>
+</head>
+<body>
+  <redoc spec-url="{{.Path}}"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`)),
+}
+
+// Known reports whether u is one of the documentation pages
+func (u UI) Known() bool {
+	_, ok := pages[u]
+	return ok
+}
+
+// DocsPath returns the path serving the documentation page, empty when
+// the options enable none
+func (sp *Spec) DocsPath() string {
+	if sp.options.Docs == "" {
+		return ""
+	}
+	return sp.options.DocsPath
+}
+
+// ServeDocs writes the documentation page of the document
+func (sp *Spec) ServeDocs(w http.ResponseWriter, r *http.Request) {
+	var page bytes.Buffer
+	err := pages[sp.options.Docs].Execute(&page, struct{ Title, Path string }{sp.options.Title, sp.options.Path})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
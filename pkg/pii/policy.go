@@ -0,0 +1,111 @@
+package pii
+
+import (
+	"strings"
+	"sync"
+)
+
+// TagPII classifies a field as personal data, e.g. `pii:"email"`
+const TagPII = "pii"
+
+// Classifications with built-in masking formats
+const (
+	ClassEmail = "email"
+	ClassPhone = "phone"
+	ClassName  = "name"
+	ClassCard  = "card"
+	ClassToken = "token"
+)
+
+// RedactedValue replaces values whose classification has no masker
+const RedactedValue = "[REDACTED]"
+
+// Masker masks a value of a given classification
+type Masker func(value string) string
+
+// Policy maps data classifications to masking formats
+type Policy struct {
+	maskers map[string]Masker
+	mutex   sync.RWMutex
+}
+
+// NewPolicy creates a policy with the built-in masking formats
+func NewPolicy() *Policy {
+	return &Policy{
+		maskers: map[string]Masker{
+			ClassEmail: MaskEmail,
+			ClassPhone: MaskKeepLast(4),
+			ClassName:  MaskKeepFirst(1),
+			ClassCard:  MaskKeepLast(4),
+			ClassToken: MaskFull,
+		},
+	}
+}
+
+// SetMasker registers the masking format of a classification
+func (p *Policy) SetMasker(class string, masker Masker) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.maskers[class] = masker
+}
+
+// Mask masks a value according to its classification
+func (p *Policy) Mask(class, value string) string {
+	if value == "" {
+		return value
+	}
+
+	p.mutex.RLock()
+	masker, exists := p.maskers[class]
+	p.mutex.RUnlock()
+
+	if !exists {
+		return RedactedValue
+	}
+	return masker(value)
+}
+
+// defaultPolicy is the central policy used by Redact and the logger
+var defaultPolicy = NewPolicy()
+
+// DefaultPolicy returns the central masking policy
+func DefaultPolicy() *Policy {
+	return defaultPolicy
+}
+
+// MaskFull replaces the whole value
+func MaskFull(value string) string {
+	return RedactedValue
+}
+
+// MaskEmail keeps the first character of the local part and the domain
+func MaskEmail(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return RedactedValue
+	}
+	return value[:1] + "***" + value[at:]
+}
+
+// MaskKeepLast keeps the last n characters of a value
+func MaskKeepLast(n int) Masker {
+	return func(value string) string {
+		runes := []rune(value)
+		if len(runes) <= n {
+			return strings.Repeat("*", len(runes))
+		}
+		return strings.Repeat("*", len(runes)-n) + string(runes[len(runes)-n:])
+	}
+}
+
+// MaskKeepFirst keeps the first n characters of a value
+func MaskKeepFirst(n int) Masker {
+	return func(value string) string {
+		runes := []rune(value)
+		if len(runes) <= n {
+			return strings.Repeat("*", len(runes))
+		}
+		return string(runes[:n]) + "***"
+	}
+}
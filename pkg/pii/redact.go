@@ -0,0 +1,285 @@
+package pii
+
+import (
+	"reflect"
+	"sync"
+)
+
+// MaxDepth bounds the nesting Redact descends into. Values nested deeper
+// are replaced by their zero value rather than written unredacted.
+const MaxDepth = 32
+
+// typeCache remembers what the values of a type may hold, as a typeInfo
+var typeCache sync.Map
+
+// typeInfo describes the classified data the values of a type may hold
+type typeInfo struct {
+	// tagged is true when the type reaches a field tagged with pii
+	tagged bool
+	// dynamic is true when the type reaches an interface, whose dynamic
+	// values are inspected when redacting
+	dynamic bool
+}
+
+// redactable reports whether values of the type must be walked
+func (ti typeInfo) redactable() bool {
+	return ti.tagged || ti.dynamic
+}
+
+// visitKey identifies a pointer, map or slice already redacted, so cyclic
+// values are copied once
+type visitKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// redaction holds the state of a Redact call
+type redaction struct {
+	policy *Policy
+	// visited maps the references already redacted to their copies
+	visited map[visitKey]reflect.Value
+}
+
+// Redact returns a copy of v with every classified field masked by the
+// default policy. Values without classified fields are returned unchanged.
+func Redact(v interface{}) interface{} {
+	return DefaultPolicy().Redact(v)
+}
+
+// Redact returns a copy of v with every classified field masked: tagged
+// strings, also behind pointers and in slices, arrays and map values, are
+// masked and tagged values of other kinds are zeroed. Cyclic
+// values are copied with their cycles, and values nested deeper than
+// MaxDepth are dropped.
+func (p *Policy) Redact(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(v)
+	if !inspect(value.Type()).redactable() {
+		return v
+	}
+	rd := &redaction{policy: p, visited: make(map[visitKey]reflect.Value)}
+	return rd.redactValue(value, 0).Interface()
+}
+
+// redactValue returns a masked copy of a value nested depth levels deep
+func (rd *redaction) redactValue(value reflect.Value, depth int) reflect.Value {
+	if !inspect(value.Type()).redactable() {
+		return value
+	}
+	if depth > MaxDepth {
+		return reflect.Zero(value.Type())
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value
+		}
+		key := visitKey{typ: value.Type(), ptr: value.Pointer()}
+		if copied, ok := rd.visited[key]; ok {
+			return copied
+		}
+		copied := reflect.New(value.Type().Elem())
+		rd.visited[key] = copied
+		copied.Elem().Set(rd.redactValue(value.Elem(), depth+1))
+		return copied
+
+	case reflect.Interface:
+		// Only dynamic values that may hold classified data are walked
+		if value.IsNil() || !inspect(value.Elem().Type()).redactable() {
+			return value
+		}
+		return rd.redactValue(value.Elem(), depth+1)
+
+	case reflect.Struct:
+		copied := reflect.New(value.Type()).Elem()
+		copied.Set(value)
+
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			fieldValue := copied.Field(i)
+			if !fieldValue.CanSet() {
+				continue
+			}
+
+			if class := field.Tag.Get(TagPII); class != "" {
+				fieldValue.Set(rd.maskValue(class, fieldValue, depth+1))
+				continue
+			}
+
+			redacted := rd.redactValue(fieldValue, depth+1)
+			if redacted.Type().AssignableTo(fieldValue.Type()) {
+				fieldValue.Set(redacted)
+			}
+		}
+		return copied
+
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		key := visitKey{typ: value.Type(), ptr: value.Pointer(), len: value.Len()}
+		if copied, ok := rd.visited[key]; ok {
+			return copied
+		}
+		copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		rd.visited[key] = copied
+		rd.redactElems(copied, value, depth)
+		return copied
+
+	case reflect.Array:
+		copied := reflect.New(value.Type()).Elem()
+		rd.redactElems(copied, value, depth)
+		return copied
+
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		key := visitKey{typ: value.Type(), ptr: value.Pointer()}
+		if copied, ok := rd.visited[key]; ok {
+			return copied
+		}
+		copied := reflect.MakeMapWithSize(value.Type(), value.Len())
+		rd.visited[key] = copied
+		iter := value.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), rd.redactValue(iter.Value(), depth+1))
+		}
+		return copied
+	}
+
+	return value
+}
+
+// maskValue returns a copy of the value of a tagged field with its strings
+// masked, through pointers, slices, arrays, map values and interfaces.
+// Values of other kinds, e.g. numbers or structs, are replaced by their zero
+// value: a classification tells how to mask text only.
+func (rd *redaction) maskValue(class string, value reflect.Value, depth int) reflect.Value {
+	if depth > MaxDepth {
+		return reflect.Zero(value.Type())
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		masked := reflect.New(value.Type()).Elem()
+		masked.SetString(rd.policy.Mask(class, value.String()))
+		return masked
+
+	case reflect.Ptr:
+		if value.IsNil() || !holdsText(value.Type()) {
+			return reflect.Zero(value.Type())
+		}
+		masked := reflect.New(value.Type().Elem())
+		masked.Elem().Set(rd.maskValue(class, value.Elem(), depth+1))
+		return masked
+
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		masked := reflect.New(value.Type()).Elem()
+		masked.Set(rd.maskValue(class, value.Elem(), depth+1))
+		return masked
+
+	case reflect.Slice:
+		if value.IsNil() || !holdsText(value.Type()) {
+			return reflect.Zero(value.Type())
+		}
+		masked := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			masked.Index(i).Set(rd.maskValue(class, value.Index(i), depth+1))
+		}
+		return masked
+
+	case reflect.Array:
+		masked := reflect.New(value.Type()).Elem()
+		if holdsText(value.Type()) {
+			for i := 0; i < value.Len(); i++ {
+				masked.Index(i).Set(rd.maskValue(class, value.Index(i), depth+1))
+			}
+		}
+		return masked
+
+	case reflect.Map:
+		if value.IsNil() || !holdsText(value.Type()) {
+			return reflect.Zero(value.Type())
+		}
+		masked := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			masked.SetMapIndex(iter.Key(), rd.maskValue(class, iter.Value(), depth+1))
+		}
+		return masked
+	}
+
+	return reflect.Zero(value.Type())
+}
+
+// holdsText reports whether values of the type may hold strings to mask,
+// as the type itself or the elements it references
+func holdsText(t reflect.Type) bool {
+	for depth := 0; depth <= MaxDepth; depth++ {
+		switch t.Kind() {
+		case reflect.String, reflect.Interface:
+			return true
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// redactElems sets the elements of a slice or array copy to the masked
+// elements of value
+func (rd *redaction) redactElems(copied, value reflect.Value, depth int) {
+	for i := 0; i < value.Len(); i++ {
+		copied.Index(i).Set(rd.redactValue(value.Index(i), depth+1))
+	}
+}
+
+// inspect returns what the values of a type may hold, cached per type
+func inspect(t reflect.Type) typeInfo {
+	if cached, ok := typeCache.Load(t); ok {
+		return cached.(typeInfo)
+	}
+
+	var info typeInfo
+	walkType(t, make(map[reflect.Type]bool), &info)
+	typeCache.Store(t, info)
+	return info
+}
+
+// walkType visits the types reachable from t once, recording the tagged
+// fields and interfaces found
+func walkType(t reflect.Type, visited map[reflect.Type]bool, info *typeInfo) {
+	if visited[t] || (info.tagged && info.dynamic) {
+		return
+	}
+	visited[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		walkType(t.Elem(), visited, info)
+	case reflect.Interface:
+		info.dynamic = true
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get(TagPII) != "" {
+				info.tagged = true
+			}
+			walkType(field.Type, visited, info)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package pii
+
+import (
+	"reflect"
+	"testing"
+)
+
+type contact struct {
+	Name    string            `pii:"name"`
+	Email   *string           `pii:"email"`
+	Phones  []string          `pii:"phone"`
+	Backup  [2]string         `pii:"phone"`
+	Cards   map[string]string `pii:"card"`
+	Extra   interface{}       `pii:"token"`
+	Age     int               `pii:"name"`
+	Country string
+}
+
+type node struct {
+	Secret string `pii:"token"`
+	Next   *node
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestRedact(t *testing.T) {
+	cyclic := &node{Secret: "abc"}
+	cyclic.Next = cyclic
+
+	tests := []struct {
+		name  string
+		value interface{}
+		check func(t *testing.T, redacted interface{})
+	}{
+		{
+			name: "tagged kinds",
+			value: contact{
+				Name:    "Maria",
+				Email:   stringPtr("maria@example.com"),
+				Phones:  []string{"11987654321", "1133334444"},
+				Backup:  [2]string{"21999990000"},
+				Cards:   map[string]string{"main": "4111111111111111"},
+				Extra:   "token-value",
+				Age:     42,
+				Country: "BR",
+			},
+			check: func(t *testing.T, redacted interface{}) {
+				got := redacted.(contact)
+				want := contact{
+					Name:    "M***",
+					Email:   stringPtr("m***@example.com"),
+					Phones:  []string{"*******4321", "******4444"},
+					Backup:  [2]string{"*******0000", ""},
+					Cards:   map[string]string{"main": "************1111"},
+					Extra:   RedactedValue,
+					Country: "BR",
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Redact() = %+v, want %+v", got, want)
+				}
+			},
+		},
+		{
+			name:  "pointer to a struct",
+			value: &contact{Email: stringPtr("maria@example.com"), Phones: []string{"11987654321"}},
+			check: func(t *testing.T, redacted interface{}) {
+				got := redacted.(*contact)
+				if *got.Email != "m***@example.com" || got.Phones[0] != "*******4321" {
+					t.Errorf("Redact() = %+v, want masked values", got)
+				}
+			},
+		},
+		{
+			name:  "nil tagged values",
+			value: contact{},
+			check: func(t *testing.T, redacted interface{}) {
+				if got := redacted.(contact); !reflect.DeepEqual(got, contact{}) {
+					t.Errorf("Redact() = %+v, want zero value", got)
+				}
+			},
+		},
+		{
+			name:  "cycles",
+			value: cyclic,
+			check: func(t *testing.T, redacted interface{}) {
+				got := redacted.(*node)
+				if got.Secret != RedactedValue || got.Next != got {
+					t.Errorf("Redact() = %+v, want a masked cycle", got)
+				}
+			},
+		},
+		{
+			name:  "untagged values",
+			value: map[string]int{"a": 1},
+			check: func(t *testing.T, redacted interface{}) {
+				if !reflect.DeepEqual(redacted, map[string]int{"a": 1}) {
+					t.Errorf("Redact() = %v, want it unchanged", redacted)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, Redact(tt.value))
+		})
+	}
+}
+
+func TestRedactLeavesOriginal(t *testing.T) {
+	email := "maria@example.com"
+	phones := []string{"11987654321"}
+	original := &contact{Email: &email, Phones: phones}
+
+	Redact(original)
+
+	if email != "maria@example.com" || phones[0] != "11987654321" {
+		t.Errorf("Redact() modified the original: %q %q", email, phones[0])
+	}
+}
@@ -0,0 +1,70 @@
+package pipe
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/validation"
+)
+
+// uuidPattern matches canonical UUID strings of any version
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ParseIntPipe converts string values to int
+type ParseIntPipe struct{}
+
+// Transform parses a numeric string, passing other values through
+func (ParseIntPipe) Transform(value interface{}, metadata ArgumentMetadata) (interface{}, error) {
+	raw, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	parsed, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, Errorf("Validation failed (numeric string is expected for %s)", metadata.Name)
+	}
+	return parsed, nil
+}
+
+// ParseUUIDPipe requires string values to be UUIDs
+type ParseUUIDPipe struct{}
+
+// Transform validates a UUID string, passing other values through
+func (ParseUUIDPipe) Transform(value interface{}, metadata ArgumentMetadata) (interface{}, error) {
+	raw, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+
+	if !uuidPattern.MatchString(raw) {
+		return nil, Errorf("Validation failed (uuid is expected for %s)", metadata.Name)
+	}
+	return strings.ToLower(raw), nil
+}
+
+// ValidationPipe validates body structs against their validate tags,
+// rejecting invalid ones with 422 Unprocessable Entity
+type ValidationPipe struct{}
+
+// Transform validates struct bodies, passing other values through
+func (ValidationPipe) Transform(value interface{}, metadata ArgumentMetadata) (interface{}, error) {
+	if metadata.Source != SourceBody {
+		return value, nil
+	}
+
+	if err := validation.Validate(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Builtins returns the built-in pipes by name
+func Builtins() map[string]Pipe {
+	return map[string]Pipe{
+		"ParseIntPipe":   ParseIntPipe{},
+		"ParseUUIDPipe":  ParseUUIDPipe{},
+		"ValidationPipe": ValidationPipe{},
+	}
+}
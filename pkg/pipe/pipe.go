@@ -0,0 +1,104 @@
+package pipe
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// TagPipes lists the pipes applied to the arguments of a route field.
+// Bare names apply to every argument; name=Pipe applies to one path or
+// query parameter, or to the body with body=Pipe:
+// `pipes:"ValidationPipe,id=ParseUUIDPipe"`
+const TagPipes = "pipes"
+
+// Source is where an argument value comes from
+type Source string
+
+const (
+	SourcePath  Source = "path"
+	SourceQuery Source = "query"
+	SourceBody  Source = "body"
+)
+
+// BodyParam is the name used to target the body in a pipes tag
+const BodyParam = "body"
+
+// ArgumentMetadata describes the handler argument a value is bound to
+type ArgumentMetadata struct {
+	Source Source
+	// Name is the path or query parameter name, or "body"
+	Name string
+	// Type is the type of the handler argument
+	Type reflect.Type
+}
+
+// Pipe transforms or validates a bound value before it reaches the handler,
+// like a NestJS pipe. Path and query values arrive as strings; the body
+// arrives decoded into the argument type.
+type Pipe interface {
+	Transform(value interface{}, metadata ArgumentMetadata) (interface{}, error)
+}
+
+// PipeFunc adapts a function to the Pipe interface
+type PipeFunc func(value interface{}, metadata ArgumentMetadata) (interface{}, error)
+
+// Transform calls f(value, metadata)
+func (f PipeFunc) Transform(value interface{}, metadata ArgumentMetadata) (interface{}, error) {
+	return f(value, metadata)
+}
+
+// Error is a pipe failure answered with 400 Bad Request
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// StatusCode returns the HTTP status code of the error
+func (e *Error) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// Errorf creates a pipe error with a formatted message
+func Errorf(format string, args ...interface{}) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...)}
+}
+
+// Apply runs the pipes in order over a value
+func Apply(value interface{}, metadata ArgumentMetadata, pipes []Pipe) (interface{}, error) {
+	for _, p := range pipes {
+		transformed, err := p.Transform(value, metadata)
+		if err != nil {
+			return nil, err
+		}
+		value = transformed
+	}
+	return value, nil
+}
+
+// ParseTag splits a pipes tag into pipes for every argument and pipes
+// targeting a single parameter
+func ParseTag(tag string) ([]string, map[string][]string) {
+	all := make([]string, 0)
+	byParam := make(map[string][]string)
+
+	for _, entry := range strings.Split(tag, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if param, name, found := strings.Cut(entry, "="); found {
+			param, name = strings.TrimSpace(param), strings.TrimSpace(name)
+			byParam[param] = append(byParam[param], name)
+			continue
+		}
+		all = append(all, entry)
+	}
+
+	return all, byParam
+}
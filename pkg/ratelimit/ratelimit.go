@@ -0,0 +1,259 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+	"github.com/kevenmiano/nestgo/pkg/tenant"
+)
+
+// Headers exposing the most restrictive limit of a request
+const (
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// DefaultPlan is the plan of keys without one
+const DefaultPlan = "default"
+
+// Limit allows Requests per Window. Short windows act as rate limits and
+// long ones, e.g. 24h, as quotas.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Window)
+}
+
+// Plans maps plan names to their limits; a request must be within every
+// limit of its plan. Plans can be loaded by the config package from a value
+// like "free=60/1m+1000/24h,pro=600/1m".
+type Plans map[string][]Limit
+
+// UnmarshalText parses plans from their text form
+func (p *Plans) UnmarshalText(text []byte) error {
+	parsed, err := ParsePlans(string(text))
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// ParsePlans parses plans written as name=limit+limit,... where a limit is
+// requests/window, e.g. "free=60/1m+1000/24h,pro=600/1m"
+func ParsePlans(spec string) (Plans, error) {
+	plans := make(Plans)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, limits, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid plan %q: expected name=requests/window", entry)
+		}
+
+		for _, raw := range strings.Split(limits, "+") {
+			limit, err := ParseLimit(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, fmt.Errorf("invalid plan %s: %w", name, err)
+			}
+			plans[name] = append(plans[name], limit)
+		}
+	}
+	return plans, nil
+}
+
+// ParseLimit parses a requests/window limit, e.g. "10/1m"
+func ParseLimit(raw string) (Limit, error) {
+	count, window, found := strings.Cut(raw, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected requests/window", raw)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || requests < 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: requests must be a non-negative number", raw)
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || duration <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: invalid window", raw)
+	}
+	return Limit{Requests: requests, Window: duration}, nil
+}
+
+// Options configures a Limiter
+type Options struct {
+	// Plans holds the limits of each plan. Keys without a plan use the
+	// DefaultPlan entry; without one they are not limited.
+	Plans Plans
+	// Key returns the key requests are counted by. Defaults to the tenant
+	// resolved by the tenant middleware, then the client IP.
+	Key func(r *http.Request) string
+	// Plan returns the plan of a key, e.g. from a billing provider.
+	// Defaults to DefaultPlan for every key.
+	Plan func(r *http.Request, key string) string
+}
+
+// Result is the state of the most restrictive limit after a request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is when the window of the limit restarts
+	Reset time.Time
+}
+
+// window counts the requests of a key in the current window of a limit
+type window struct {
+	start time.Time
+	count int
+}
+
+// Limiter counts requests per key in fixed windows, in memory
+type Limiter struct {
+	options   Options
+	mutex     sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+// New creates a limiter
+func New(opts Options) *Limiter {
+	if opts.Key == nil {
+		opts.Key = DefaultKey
+	}
+	if opts.Plan == nil {
+		opts.Plan = func(*http.Request, string) string { return DefaultPlan }
+	}
+	return &Limiter{options: opts, windows: make(map[string]*window)}
+}
+
+// DefaultKey counts requests by tenant, falling back to the client IP
+func DefaultKey(r *http.Request) string {
+	if id, ok := tenant.FromRequest(r); ok {
+		return "tenant:" + id
+	}
+	return "ip:" + realip.ClientIP(r)
+}
+
+// Allow counts a request of key against the limits of a plan. A rejected
+// request is not counted.
+func (l *Limiter) Allow(key, plan string) Result {
+	limits, ok := l.options.Plans[plan]
+	if !ok {
+		limits = l.options.Plans[DefaultPlan]
+	}
+	if len(limits) == 0 {
+		return Result{Allowed: true, Limit: -1, Remaining: -1}
+	}
+
+	now := clock.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.sweep(now)
+
+	windows := make([]*window, len(limits))
+	result := Result{Allowed: true}
+	var retryAt time.Time
+	for i, limit := range limits {
+		id := key + "|" + limit.String()
+		w, exists := l.windows[id]
+		if !exists || now.Sub(w.start) >= limit.Window {
+			w = &window{start: now.Truncate(limit.Window)}
+			l.windows[id] = w
+		}
+		windows[i] = w
+
+		remaining := limit.Requests - w.count
+		reset := w.start.Add(limit.Window)
+		if remaining <= 0 {
+			result.Allowed = false
+			if reset.After(retryAt) {
+				retryAt = reset
+			}
+		}
+		if i == 0 || remaining < result.Remaining {
+			result.Limit = limit.Requests
+			result.Remaining = remaining
+			result.Reset = reset
+		}
+	}
+
+	if !result.Allowed {
+		// Every exhausted window must restart before a retry succeeds
+		result.Reset = retryAt
+		return result
+	}
+	for _, w := range windows {
+		w.count++
+	}
+	result.Remaining--
+	return result
+}
+
+// sweep drops expired windows, at most once per minute
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	longest := time.Duration(0)
+	for _, limits := range l.options.Plans {
+		for _, limit := range limits {
+			if limit.Window > longest {
+				longest = limit.Window
+			}
+		}
+	}
+	for id, w := range l.windows {
+		if now.Sub(w.start) >= longest {
+			delete(l.windows, id)
+		}
+	}
+}
+
+// Middleware returns an HTTP middleware enforcing the plan of each request,
+// answering 429 Too Many Requests over the limit and exposing the remaining
+// requests in X-RateLimit headers
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := l.options.Key(r)
+		plan := l.options.Plan(r, key)
+		result := l.Allow(key, plan)
+
+		if result.Limit >= 0 {
+			w.Header().Set(HeaderLimit, strconv.Itoa(result.Limit))
+			w.Header().Set(HeaderRemaining, strconv.Itoa(result.Remaining))
+			w.Header().Set(HeaderReset, strconv.FormatInt(result.Reset.Unix(), 10))
+		}
+
+		if !result.Allowed {
+			retryAfter := int(result.Reset.Sub(clock.Now()).Seconds() + 0.999)
+			logger.Warn("Rate limit exceeded", append([]interface{}{"key", key, "plan", plan, "path", r.URL.Path}, tenant.LogAttrs(r.Context())...)...)
+			jsonData, _ := json.Marshal(map[string]string{"error": "Too many requests"})
+			w.Header().Set(HeaderRetryAfter, strconv.Itoa(retryAfter))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write(jsonData)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
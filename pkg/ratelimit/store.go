@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket is the state of a token bucket after taking a token
+type Bucket struct {
+	Allowed bool
+	// Limit is the capacity of the bucket
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// RetryAfter is the wait for the next token of a rejected request
+	RetryAfter time.Duration
+	// Reset is when the bucket is full again
+	Reset time.Time
+}
+
+// Store keeps the token buckets of a Throttler. The buckets of a limit hold
+// Requests tokens and refill at Requests per Window. Implementations backed
+// by shared stores such as Redis must take tokens atomically, e.g. with a
+// Lua script, so instances of an application share the limits.
+type Store interface {
+	// Take removes a token from the bucket of key at now, reporting
+	// whether one was available
+	Take(key string, limit Limit, now time.Time) (Bucket, error)
+}
+
+// bucket is a token bucket of the memory store
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	window  time.Duration
+}
+
+// MemoryStore keeps token buckets in memory, for a single instance
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an in-memory bucket store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take removes a token from the bucket of key
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Bucket, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: capacity, updated: now, window: limit.Window}
+		s.buckets[key] = b
+	}
+	return take(b, limit, now), nil
+}
+
+// take refills a bucket for the time elapsed since its last update and
+// removes a token when one is available
+func take(b *bucket, limit Limit, now time.Time) Bucket {
+	result := Bucket{Limit: limit.Requests}
+	if limit.Requests <= 0 {
+		result.RetryAfter = limit.Window
+		result.Reset = now.Add(limit.Window)
+		return result
+	}
+
+	capacity := float64(limit.Requests)
+	perToken := limit.Window / time.Duration(limit.Requests)
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+float64(elapsed)/float64(perToken))
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = now.Add(time.Duration((capacity - b.tokens) * float64(perToken)))
+	return result
+}
+
+// sweep drops buckets idle long enough to be full, at most once per minute
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.window {
+			delete(s.buckets, key)
+		}
+	}
+}
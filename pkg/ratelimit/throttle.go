@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+// TagThrottle limits the requests of a route per key with a token bucket,
+// e.g. `throttle:"10/1m"` allows bursts of 10 requests refilled at 10 per
+// minute. `throttle:"-"` opts a route out of the limit of its module.
+const TagThrottle = "throttle"
+
+// ProviderName is the name of the Throttler provided by a ThrottlerModule
+const ProviderName = "Throttler"
+
+// ThrottlerOptions configures a Throttler
+type ThrottlerOptions struct {
+	// Store keeps the buckets; defaults to an in-memory store
+	Store Store
+	// Key returns the key requests are counted by, such as a user ID or an
+	// API key. Defaults to the client IP.
+	Key func(r *http.Request) string
+}
+
+// Throttler enforces the throttle tags of routes, keeping a token bucket
+// per route and key
+type Throttler struct {
+	options ThrottlerOptions
+}
+
+// NewThrottler creates a throttler
+func NewThrottler(opts ThrottlerOptions) *Throttler {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.Key == nil {
+		opts.Key = IPKey
+	}
+	return &Throttler{options: opts}
+}
+
+// IPKey counts requests by client IP
+func IPKey(r *http.Request) string {
+	return "ip:" + realip.ClientIP(r)
+}
+
+// ThrottlerModule provides the Throttler used by throttle tags
+type ThrottlerModule struct{}
+
+// NewThrottlerModule registers a global ThrottlerModule configuring the
+// throttle tags of every module, before the application starts:
+//
+//	var _ = ratelimit.NewThrottlerModule(ratelimit.ThrottlerOptions{Store: redisStore})
+//
+// Routes are throttled with the defaults without it.
+func NewThrottlerModule(opts ThrottlerOptions) *ThrottlerModule {
+	throttler := NewThrottler(opts)
+	m := &ThrottlerModule{}
+	module.New(module.ModuleConfig{
+		Providers: []interface{}{throttler},
+		Exports:   []interface{}{throttler},
+		Global:    true,
+	})(m)
+	return m
+}
+
+// Take removes a token from the bucket of the request key for a route
+func (t *Throttler) Take(r *http.Request, route string, limit Limit) (Bucket, error) {
+	key := route + "|" + limit.String() + "|" + t.options.Key(r)
+	return t.options.Store.Take(key, limit, clock.Now())
+}
+
+// Middleware returns an HTTP middleware throttling the requests of a route,
+// answering 429 Too Many Requests with Retry-After once the bucket of the
+// key is empty. Requests pass through when the store fails.
+func (t *Throttler) Middleware(route string, limit Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bucket, err := t.Take(r, route, limit)
+			if err != nil {
+				logger.Error("Throttler store failed", "route", route, "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(HeaderLimit, strconv.Itoa(bucket.Limit))
+			w.Header().Set(HeaderRemaining, strconv.Itoa(bucket.Remaining))
+			w.Header().Set(HeaderReset, strconv.FormatInt(bucket.Reset.Unix(), 10))
+
+			if !bucket.Allowed {
+				retryAfter := int(bucket.RetryAfter.Seconds() + 0.999)
+				logger.Warn("Route throttled", "route", route, "limit", limit.String(), "key", t.options.Key(r))
+				jsonData, _ := json.Marshal(map[string]string{"error": "Too many requests"})
+				w.Header().Set(HeaderRetryAfter, strconv.Itoa(retryAfter))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				w.Write(jsonData)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
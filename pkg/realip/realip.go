@@ -0,0 +1,148 @@
+package realip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderForwardedFor is the standard proxy chain header
+	HeaderForwardedFor = "X-Forwarded-For"
+	// HeaderRealIP is the single client IP header set by some load balancers
+	HeaderRealIP = "X-Real-IP"
+)
+
+// contextKey is the key used to store the resolved IP in the request context
+type contextKey struct{}
+
+// Resolver resolves the real client IP based on a set of trusted proxies
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// NewResolver creates a resolver trusting the given proxy IPs or CIDRs
+func NewResolver(trustedProxies ...string) (*Resolver, error) {
+	networks, err := ParseNetworks(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{trusted: networks}, nil
+}
+
+// IsTrusted checks if an IP belongs to one of the trusted proxy networks
+func (res *Resolver) IsTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range res.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the client IP for a request.
+// Proxy headers are only honored when the direct peer is a trusted proxy.
+// Every X-Forwarded-For line is joined, proxies appending their own line,
+// and the hops are walked from the closest: the first untrusted address
+// wins. A malformed hop stops the walk at the last address known, since
+// the hops before it cannot be attributed. Without X-Forwarded-For,
+// X-Real-IP is used, and finally RemoteAddr.
+func (res *Resolver) Resolve(r *http.Request) string {
+	remoteIP := RemoteIP(r)
+	if !res.IsTrusted(net.ParseIP(remoteIP)) {
+		return remoteIP
+	}
+
+	if lines := r.Header.Values(HeaderForwardedFor); len(lines) > 0 {
+		client := remoteIP
+		hops := strings.Split(strings.Join(lines, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(hops[i]))
+			if ip == nil {
+				break
+			}
+			client = ip.String()
+			if !res.IsTrusted(ip) {
+				break
+			}
+		}
+		return client
+	}
+
+	if realIP := net.ParseIP(strings.TrimSpace(r.Header.Get(HeaderRealIP))); realIP != nil {
+		return realIP.String()
+	}
+
+	return remoteIP
+}
+
+// Middleware returns an HTTP middleware that stores the resolved IP in the request context
+func Middleware(resolver *Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolver.Resolve(r)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, ip)))
+		})
+	}
+}
+
+// FromRequest returns the resolved client IP stored by Middleware, if any
+func FromRequest(r *http.Request) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	ip, ok := r.Context().Value(contextKey{}).(string)
+	return ip, ok
+}
+
+// ClientIP returns the resolved client IP, falling back to RemoteAddr
+func ClientIP(r *http.Request) string {
+	if ip, ok := FromRequest(r); ok {
+		return ip
+	}
+	return RemoteIP(r)
+}
+
+// RemoteIP returns the IP of the direct peer, without the port
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ParseNetworks parses a list of IPs or CIDRs into networks
+func ParseNetworks(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address: %s", entry)
+			}
+			if ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %s: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
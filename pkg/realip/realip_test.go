@@ -0,0 +1,68 @@
+package realip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	resolver, err := NewResolver("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded []string
+		realIP    string
+		want      string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:1234", []string{"198.51.100.1"}, "198.51.100.2", "203.0.113.9"},
+		{"trusted peer without headers", "10.0.0.1:1234", nil, "", "10.0.0.1"},
+		{"single hop", "10.0.0.1:1234", []string{"198.51.100.1"}, "", "198.51.100.1"},
+		{"trusted hops skipped", "10.0.0.1:1234", []string{"198.51.100.1, 10.0.0.2, 10.0.0.3"}, "", "198.51.100.1"},
+		{"spoofed leftmost hop ignored", "10.0.0.1:1234", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"spoofed first line ignored", "10.0.0.1:1234", []string{"1.2.3.4", "198.51.100.1"}, "", "198.51.100.1"},
+		{"lines joined in order", "10.0.0.1:1234", []string{"198.51.100.1", "10.0.0.2"}, "", "198.51.100.1"},
+		{"malformed hop stops the walk", "10.0.0.1:1234", []string{"198.51.100.1, garbage, 10.0.0.2"}, "", "10.0.0.2"},
+		{"every hop trusted", "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "", "10.0.0.3"},
+		{"real IP header", "10.0.0.1:1234", nil, "198.51.100.2", "198.51.100.2"},
+		{"forwarded wins over real IP", "10.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.2", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, line := range tt.forwarded {
+				r.Header.Add(HeaderForwardedFor, line)
+			}
+			if tt.realIP != "" {
+				r.Header.Set(HeaderRealIP, tt.realIP)
+			}
+			if got := resolver.Resolve(r); got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	tests := []struct {
+		entry   string
+		wantErr bool
+	}{
+		{"10.0.0.1", false},
+		{"10.0.0.0/8", false},
+		{"::1", false},
+		{"2001:db8::/32", false},
+		{"not-an-ip", true},
+		{"10.0.0.0/99", true},
+	}
+	for _, tt := range tests {
+		_, err := ParseNetworks([]string{tt.entry})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseNetworks(%q) error = %v, wantErr %v", tt.entry, err, tt.wantErr)
+		}
+	}
+}
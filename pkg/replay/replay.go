@@ -0,0 +1,196 @@
+package replay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+const (
+	// TagReplay enables replay protection on a route field ("true" or a window like "30s")
+	TagReplay = "replay"
+
+	// HeaderNonce carries the unique request identifier
+	HeaderNonce = "X-Nonce"
+	// HeaderTimestamp carries the request creation time in unix seconds
+	HeaderTimestamp = "X-Timestamp"
+	// HeaderSignature carries the signature of a request, see Sign
+	HeaderSignature = "X-Signature"
+
+	// DefaultWindow is the maximum accepted clock skew of a request timestamp
+	DefaultWindow = 5 * time.Minute
+
+	// nonceKeyPrefix namespaces nonces inside the store
+	nonceKeyPrefix = "replay:nonce:"
+)
+
+// Options configures replay protection
+type Options struct {
+	// Store keeps seen nonces; defaults to an in-memory store
+	Store cache.Store
+	// Window is the accepted timestamp skew; defaults to DefaultWindow
+	Window time.Duration
+	// Secret is shared with the clients signing their requests, see Sign.
+	// Requests must then carry their signature in X-Signature, which ties
+	// the nonce and timestamp to the method, URI and body. Without a
+	// secret, anyone may send a captured request again under a fresh nonce
+	// and timestamp: protection only deduplicates the retries of honest
+	// clients.
+	Secret []byte
+}
+
+// Protector rejects requests whose nonce was already seen or whose
+// timestamp is stale, and unsigned requests when it has a secret
+type Protector struct {
+	store  cache.Store
+	window time.Duration
+	secret []byte
+}
+
+// New creates a replay protector
+func New(opts Options) *Protector {
+	if opts.Store == nil {
+		opts.Store = cache.NewMemoryStore()
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+
+	return &Protector{
+		store:  opts.Store,
+		window: opts.Window,
+		secret: opts.Secret,
+	}
+}
+
+// Sign returns the signature of a request sent with a nonce and timestamp:
+// the hex encoded HMAC-SHA256, keyed by secret, of the method, request URI
+// (path and query), hex encoded SHA-256 of the body, nonce and timestamp,
+// separated by newlines
+func Sign(secret []byte, method, requestURI string, body []byte, nonce, timestamp string) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{method, requestURI, hex.EncodeToString(bodyHash[:]), nonce, timestamp}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature of a request, leaving its body readable
+func (p *Protector) verify(r *http.Request, nonce, timestamp string) (bool, error) {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			return false, err
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	signature, err := hex.DecodeString(strings.TrimSpace(r.Header.Get(HeaderSignature)))
+	if err != nil {
+		return false, nil
+	}
+	expected, _ := hex.DecodeString(Sign(p.secret, r.Method, r.URL.RequestURI(), body, nonce, timestamp))
+	return hmac.Equal(signature, expected), nil
+}
+
+// ParseWindow parses a replay tag value; "true" selects the default window
+func ParseWindow(tag string) (time.Duration, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" || tag == "true" {
+		return 0, nil
+	}
+	return time.ParseDuration(tag)
+}
+
+// Middleware returns an HTTP middleware enforcing replay protection.
+// A zero window uses the protector default.
+func (p *Protector) Middleware(window time.Duration) func(http.Handler) http.Handler {
+	if window <= 0 {
+		window = p.window
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := strings.TrimSpace(r.Header.Get(HeaderNonce))
+			timestamp := strings.TrimSpace(r.Header.Get(HeaderTimestamp))
+			if nonce == "" || timestamp == "" {
+				reject(w, r, http.StatusBadRequest, "Missing replay protection headers")
+				return
+			}
+
+			seconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				reject(w, r, http.StatusBadRequest, "Invalid request timestamp")
+				return
+			}
+
+			skew := clock.Since(time.Unix(seconds, 0))
+			if skew < 0 {
+				skew = -skew
+			}
+			if skew > window {
+				reject(w, r, http.StatusUnauthorized, "Request timestamp outside the accepted window")
+				return
+			}
+
+			// Unsigned requests must not use up the nonces of signed ones
+			if len(p.secret) > 0 {
+				valid, err := p.verify(r, nonce, timestamp)
+				if err != nil {
+					status := http.StatusBadRequest
+					var coder interface{ StatusCode() int }
+					if errors.As(err, &coder) {
+						status = coder.StatusCode()
+					}
+					reject(w, r, status, "Invalid request body")
+					return
+				}
+				if !valid {
+					reject(w, r, http.StatusUnauthorized, "Invalid request signature")
+					return
+				}
+			}
+
+			// Nonces only need to outlive the window in both directions
+			stored, err := p.store.SetIfAbsent(nonceKeyPrefix+nonce, []byte(timestamp), 2*window)
+			if err != nil {
+				logger.Error("Replay protection store failed", "error", err)
+				reject(w, r, http.StatusServiceUnavailable, "Replay protection unavailable")
+				return
+			}
+			if !stored {
+				reject(w, r, http.StatusConflict, "Replayed request")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reject writes a JSON error and logs the rejected attempt
+func reject(w http.ResponseWriter, r *http.Request, status int, message string) {
+	logger.Warn("Request rejected by replay protection",
+		"ip", realip.ClientIP(r),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"reason", message)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"error": "` + message + `"}`))
+}
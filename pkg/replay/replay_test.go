@@ -0,0 +1,118 @@
+package replay
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/cache"
+)
+
+func TestMiddleware(t *testing.T) {
+	secret := []byte("secret")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name       string
+		secret     []byte
+		method     string
+		target     string
+		body       string
+		nonce      string
+		timestamp  string
+		signature  func() string
+		wantStatus int
+	}{
+		{name: "missing headers", method: http.MethodPost, target: "/orders", wantStatus: http.StatusBadRequest},
+		{name: "invalid timestamp", method: http.MethodPost, target: "/orders", nonce: "n1", timestamp: "yesterday", wantStatus: http.StatusBadRequest},
+		{name: "stale timestamp", method: http.MethodPost, target: "/orders", nonce: "n2", timestamp: stale, wantStatus: http.StatusUnauthorized},
+		{name: "unsigned", method: http.MethodPost, target: "/orders", nonce: "n3", timestamp: now, wantStatus: http.StatusOK},
+		{
+			name: "signed", secret: secret, method: http.MethodPost, target: "/orders?dry=1", body: `{"amount":10}`, nonce: "n4", timestamp: now,
+			signature: func() string {
+				return Sign(secret, http.MethodPost, "/orders?dry=1", []byte(`{"amount":10}`), "n4", now)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{name: "missing signature", secret: secret, method: http.MethodPost, target: "/orders", nonce: "n5", timestamp: now, wantStatus: http.StatusUnauthorized},
+		{
+			name: "signature of another body", secret: secret, method: http.MethodPost, target: "/orders", body: `{"amount":1000}`, nonce: "n6", timestamp: now,
+			signature:  func() string { return Sign(secret, http.MethodPost, "/orders", []byte(`{"amount":10}`), "n6", now) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "signature of another path", secret: secret, method: http.MethodPost, target: "/refunds", nonce: "n7", timestamp: now,
+			signature:  func() string { return Sign(secret, http.MethodPost, "/orders", nil, "n7", now) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "signature of another nonce", secret: secret, method: http.MethodPost, target: "/orders", nonce: "n8", timestamp: now,
+			signature:  func() string { return Sign(secret, http.MethodPost, "/orders", nil, "other", now) },
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := cache.NewMemoryStore()
+			defer store.Close()
+			protector := New(Options{Store: store, Secret: tt.secret})
+
+			var received string
+			handler := protector.Middleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = string(body)
+			}))
+			request := func() *httptest.ResponseRecorder {
+				r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+				r.Header.Set(HeaderNonce, tt.nonce)
+				r.Header.Set(HeaderTimestamp, tt.timestamp)
+				if tt.signature != nil {
+					r.Header.Set(HeaderSignature, tt.signature())
+				}
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+				return w
+			}
+
+			w := request()
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if received != tt.body {
+				t.Errorf("handler read body %q, want %q", received, tt.body)
+			}
+			if again := request(); again.Code != http.StatusConflict {
+				t.Errorf("replayed request status = %d, want %d", again.Code, http.StatusConflict)
+			}
+		})
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{tag: "", want: 0},
+		{tag: "true", want: 0},
+		{tag: "30s", want: 30 * time.Second},
+		{tag: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			got, err := ParseWindow(tt.tag)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseWindow(%q) = %v, %v, want %v, error %v", tt.tag, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
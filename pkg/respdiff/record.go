@@ -0,0 +1,70 @@
+package respdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// DefaultMaxBodySize bounds the request bodies kept by a Recorder
+const DefaultMaxBodySize = 64 << 10
+
+// recordedHeaders are kept by a Recorder; credentials and cookies are left
+// out so corpora can be shared
+var recordedHeaders = []string{"Accept", "Accept-Language", "Content-Type", "X-Tenant-ID"}
+
+// Recorder writes the requests of an application as a corpus, e.g. from a
+// staging environment, for replay with Compare
+type Recorder struct {
+	mutex       sync.Mutex
+	encoder     *json.Encoder
+	maxBodySize int64
+}
+
+// NewRecorder creates a recorder writing JSON Lines to w. Requests with
+// bodies over maxBodySize, DefaultMaxBodySize when zero, are not recorded.
+func NewRecorder(w io.Writer, maxBodySize int64) *Recorder {
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	return &Recorder{encoder: json.NewEncoder(w), maxBodySize: maxBodySize}
+}
+
+// Middleware returns an HTTP middleware recording every request before
+// serving it with its body intact
+func (rec *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := Request{Method: r.Method, Path: r.URL.RequestURI(), Header: http.Header{}}
+		for _, name := range recordedHeaders {
+			if values := r.Header.Values(name); len(values) > 0 {
+				request.Header[name] = values
+			}
+		}
+
+		recordable := true
+		if r.Body != nil && r.Body != http.NoBody {
+			original := r.Body
+			body, err := io.ReadAll(io.LimitReader(original, rec.maxBodySize+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(body), original), original}
+			recordable = err == nil && int64(len(body)) <= rec.maxBodySize
+			request.Body = string(body)
+		}
+
+		if recordable {
+			rec.mutex.Lock()
+			err := rec.encoder.Encode(request)
+			rec.mutex.Unlock()
+			if err != nil {
+				logger.Warn("Failed to record request", "path", r.URL.Path, "error", err)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,146 @@
+package respdiff
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/golden"
+)
+
+// Request is a recorded request of a corpus, one JSON object per line
+type Request struct {
+	// Name identifies the request in reports, "METHOD path" when empty
+	Name   string      `json:"name,omitempty"`
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// String returns the name of the request
+func (r Request) String() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Method + " " + r.Path
+}
+
+// ReadCorpus reads requests written as JSON Lines, skipping blank lines
+func ReadCorpus(reader io.Reader) ([]Request, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+
+	corpus := make([]Request, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var request Request
+		if err := json.Unmarshal(text, &request); err != nil {
+			return nil, fmt.Errorf("respdiff: invalid request at line %d: %w", line, err)
+		}
+		if request.Method == "" || !strings.HasPrefix(request.Path, "/") {
+			return nil, fmt.Errorf("respdiff: request at line %d needs a method and an absolute path", line)
+		}
+		corpus = append(corpus, request)
+	}
+	return corpus, scanner.Err()
+}
+
+// LoadCorpus reads the corpus file at path
+func LoadCorpus(path string) ([]Request, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadCorpus(file)
+}
+
+// Options configures a comparison
+type Options struct {
+	// Scrubbers mask the volatile parts of both responses, such as
+	// generated IDs, before they are compared
+	Scrubbers []golden.Scrubber
+	// IgnoreHeaders are left out of the comparison, e.g. X-Request-ID
+	IgnoreHeaders []string
+}
+
+// Difference is a request answered differently by the two builds
+type Difference struct {
+	Request   Request
+	Baseline  *golden.Snapshot
+	Candidate *golden.Snapshot
+	// Diff describes the first differing line of the snapshots
+	Diff string
+}
+
+// Report holds the outcome of a comparison
+type Report struct {
+	Compared    int
+	Differences []Difference
+}
+
+// Equal reports whether every request was answered the same way
+func (r *Report) Equal() bool {
+	return len(r.Differences) == 0
+}
+
+// Print writes a summary followed by the diff of each difference
+func (r *Report) Print(w io.Writer) {
+	fmt.Fprintf(w, "%d requests compared, %d differ\n", r.Compared, len(r.Differences))
+	for _, difference := range r.Differences {
+		fmt.Fprintf(w, "\n%s\n%s\n", difference.Request, difference.Diff)
+	}
+}
+
+// Compare replays the corpus in order against the baseline and candidate
+// builds of an application, e.g. the server of the current release and of
+// a refactored router, and reports the responses that differ in status,
+// headers or body. Requests run sequentially, on the baseline first.
+func Compare(corpus []Request, baseline, candidate http.Handler, opts Options) *Report {
+	report := &Report{Differences: make([]Difference, 0)}
+	for _, request := range corpus {
+		report.Compared++
+
+		want := replay(baseline, request, opts)
+		got := replay(candidate, request, opts)
+		if diff := golden.Diff(string(want.Bytes()), string(got.Bytes())); diff != "" {
+			report.Differences = append(report.Differences, Difference{
+				Request:   request,
+				Baseline:  want,
+				Candidate: got,
+				Diff:      diff,
+			})
+		}
+	}
+	return report
+}
+
+// replay serves a request and returns its scrubbed response
+func replay(handler http.Handler, request Request, opts Options) *golden.Snapshot {
+	r := httptest.NewRequest(request.Method, request.Path, strings.NewReader(request.Body))
+	for name, values := range request.Header {
+		r.Header[http.CanonicalHeaderKey(name)] = values
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+
+	snapshot := golden.FromRecorder(rec)
+	for _, name := range opts.IgnoreHeaders {
+		snapshot.Header.Del(name)
+	}
+	for _, scrub := range opts.Scrubbers {
+		scrub(snapshot)
+	}
+	return snapshot
+}
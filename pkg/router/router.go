@@ -3,8 +3,11 @@ package router
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/kevenmiano/nestgo/pkg/logger"
 	"github.com/kevenmiano/nestgo/pkg/server"
@@ -20,8 +23,9 @@ type Route struct {
 
 // Router manages HTTP routes
 type Router struct {
-	routes []Route
-	server *server.Server
+	routes    []Route
+	server    *server.Server
+	discovery sync.Once
 }
 
 // NewRouter creates a new router instance
@@ -81,16 +85,49 @@ func (r *Router) PrintRoutes() {
 	}
 }
 
+// Use adds HTTP middleware applied to every route
+func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {
+	r.server.Use(middlewares...)
+}
+
+// Server returns the underlying HTTP server
+func (r *Router) Server() *server.Server {
+	return r.server
+}
+
 // StartServer starts the HTTP server
 func (r *Router) StartServer(port string) error {
-	// Discover and register routes from modules
-	routeDiscovery := server.NewRouteDiscovery(r.server)
-	routeDiscovery.DiscoverAndRegisterRoutes()
+	r.discoverRoutes()
 
 	// Start the server
 	return r.server.Start(port)
 }
 
+// ServeListener starts the HTTP server on a listener
+func (r *Router) ServeListener(listener net.Listener) error {
+	r.discoverRoutes()
+
+	// Start the server
+	return r.server.Serve(listener)
+}
+
+// ServeHost starts a named host of the HTTP server on a listener
+func (r *Router) ServeHost(name string, listener net.Listener) error {
+	r.discoverRoutes()
+
+	// Start the host
+	return r.server.ServeHost(name, listener)
+}
+
+// discoverRoutes discovers and registers routes from modules, once for the
+// main server and its hosts
+func (r *Router) discoverRoutes() {
+	r.discovery.Do(func() {
+		routeDiscovery := server.NewRouteDiscovery(r.server)
+		routeDiscovery.DiscoverAndRegisterRoutes()
+	})
+}
+
 // Shutdown gracefully shuts down the server
 func (r *Router) Shutdown(ctx context.Context) error {
 	return r.server.Shutdown(ctx)
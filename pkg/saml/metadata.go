@@ -0,0 +1,126 @@
+package saml
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SAML namespaces
+const (
+	namespaceMetadata  = "urn:oasis:names:tc:SAML:2.0:metadata"
+	namespaceProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	namespaceAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+)
+
+// entityDescriptor is the metadata of the service provider
+type entityDescriptor struct {
+	XMLName    xml.Name        `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID   string          `xml:"entityID,attr"`
+	Descriptor spSSODescriptor `xml:"SPSSODescriptor"`
+}
+
+// spSSODescriptor declares the assertion consumer service
+type spSSODescriptor struct {
+	AuthnRequestsSigned        bool                     `xml:"AuthnRequestsSigned,attr"`
+	WantAssertionsSigned       bool                     `xml:"WantAssertionsSigned,attr"`
+	ProtocolSupportEnumeration string                   `xml:"protocolSupportEnumeration,attr"`
+	AssertionConsumerService   assertionConsumerService `xml:"AssertionConsumerService"`
+}
+
+// assertionConsumerService is an endpoint receiving responses
+type assertionConsumerService struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     int    `xml:"index,attr"`
+	IsDefault bool   `xml:"isDefault,attr"`
+}
+
+// metadata returns the metadata of the service provider
+func (sp *ServiceProvider) metadata() entityDescriptor {
+	return entityDescriptor{
+		EntityID: sp.options.EntityID,
+		Descriptor: spSSODescriptor{
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: namespaceProtocol,
+			AssertionConsumerService: assertionConsumerService{
+				Binding:   bindingPOST,
+				Location:  sp.options.ACSURL,
+				IsDefault: true,
+			},
+		},
+	}
+}
+
+// authnRequest is the authentication request sent to the identity provider
+type authnRequest struct {
+	XMLName      xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID           string   `xml:"ID,attr"`
+	Version      string   `xml:"Version,attr"`
+	IssueInstant string   `xml:"IssueInstant,attr"`
+	Destination  string   `xml:"Destination,attr"`
+	ACSURL       string   `xml:"AssertionConsumerServiceURL,attr"`
+	Binding      string   `xml:"ProtocolBinding,attr"`
+	Issuer       string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+}
+
+// idpMetadata is the part of identity provider metadata read by
+// ParseMetadata
+type idpMetadata struct {
+	XMLName    xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID   string   `xml:"entityID,attr"`
+	Descriptor *struct {
+		Keys []struct {
+			Use          string   `xml:"use,attr"`
+			Certificates []string `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo>X509Data>X509Certificate"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata KeyDescriptor"`
+		Services []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"urn:oasis:names:tc:SAML:2.0:metadata SingleSignOnService"`
+	} `xml:"urn:oasis:names:tc:SAML:2.0:metadata IDPSSODescriptor"`
+}
+
+// ParseMetadata reads the entity ID, HTTP-Redirect SSO URL and signing
+// certificates from the metadata of an identity provider
+func ParseMetadata(data []byte) (IdentityProvider, error) {
+	var metadata idpMetadata
+	if err := xml.Unmarshal(data, &metadata); err != nil {
+		return IdentityProvider{}, fmt.Errorf("saml: invalid metadata: %w", err)
+	}
+	if metadata.Descriptor == nil {
+		return IdentityProvider{}, errors.New("saml: metadata does not describe an identity provider")
+	}
+
+	idp := IdentityProvider{EntityID: metadata.EntityID}
+	for _, service := range metadata.Descriptor.Services {
+		if service.Binding == bindingRedirect {
+			idp.SSOURL = service.Location
+			break
+		}
+	}
+	for _, key := range metadata.Descriptor.Keys {
+		if key.Use != "" && key.Use != "signing" {
+			continue
+		}
+		for _, encoded := range key.Certificates {
+			der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+			if err != nil {
+				return IdentityProvider{}, fmt.Errorf("saml: invalid metadata certificate: %w", err)
+			}
+			certificate, err := x509.ParseCertificate(der)
+			if err != nil {
+				return IdentityProvider{}, fmt.Errorf("saml: invalid metadata certificate: %w", err)
+			}
+			idp.Certificates = append(idp.Certificates, certificate)
+		}
+	}
+
+	if idp.EntityID == "" || idp.SSOURL == "" || len(idp.Certificates) == 0 {
+		return IdentityProvider{}, errors.New("saml: metadata lacks the entity ID, HTTP-Redirect SSO service or signing certificate")
+	}
+	return idp, nil
+}
@@ -0,0 +1,265 @@
+package saml
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+)
+
+const (
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// invalid returns an error wrapping ErrInvalidResponse
+func invalid(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidResponse, fmt.Sprintf(format, args...))
+}
+
+// ParseResponse validates a base64 encoded SAMLResponse, as posted to the
+// assertion consumer service, and returns the principal of its assertion.
+// Rejected responses return an error wrapping ErrInvalidResponse. Each
+// assertion, and each request, is accepted once.
+func (sp *ServiceProvider) ParseResponse(encoded string) (*auth.Principal, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil || len(data) == 0 {
+		return nil, invalid("malformed encoding")
+	}
+	response, err := parse(data)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	if !response.is(namespaceProtocol, "Response") || response.attr("Version") != "2.0" {
+		return nil, invalid("not a SAML 2.0 response")
+	}
+	if err := sp.checkResponse(response); err != nil {
+		return nil, err
+	}
+
+	if response.child(namespaceAssertion, "EncryptedAssertion") != nil {
+		return nil, invalid("encrypted assertions are not supported")
+	}
+	assertions := response.all(namespaceAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, invalid("expected one assertion, got %d", len(assertions))
+	}
+	assertion := assertions[0]
+
+	// The assertion read below must be the signed element itself, or be
+	// enclosed in it
+	certificates := sp.options.IdentityProvider.Certificates
+	responseSigned, err := verifySignature(response, certificates)
+	if err != nil {
+		return nil, invalid("response signature: %v", err)
+	}
+	assertionSigned, err := verifySignature(assertion, certificates)
+	if err != nil {
+		return nil, invalid("assertion signature: %v", err)
+	}
+	if !responseSigned && !assertionSigned {
+		return nil, invalid("unsigned assertion")
+	}
+
+	inResponseTo := response.attr("InResponseTo")
+	expires, err := sp.checkAssertion(assertion, inResponseTo)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := sp.principal(assertion)
+	if err != nil {
+		return nil, err
+	}
+	if err := sp.consume(inResponseTo, assertion.attr("ID"), expires); err != nil {
+		return nil, err
+	}
+	return principal, nil
+}
+
+// checkResponse checks the destination, issuer and status of a response
+func (sp *ServiceProvider) checkResponse(response *element) error {
+	if destination := response.attr("Destination"); destination != "" && destination != sp.options.ACSURL {
+		return invalid("unexpected destination %q", destination)
+	}
+	if issuer := response.child(namespaceAssertion, "Issuer"); issuer != nil && issuer.text() != sp.options.IdentityProvider.EntityID {
+		return invalid("unexpected issuer %q", issuer.text())
+	}
+
+	var code *element
+	if status := response.child(namespaceProtocol, "Status"); status != nil {
+		code = status.child(namespaceProtocol, "StatusCode")
+	}
+	if code == nil {
+		return invalid("missing status")
+	}
+	if value := code.attr("Value"); value != statusSuccess {
+		if nested := code.child(namespaceProtocol, "StatusCode"); nested != nil {
+			value += " " + nested.attr("Value")
+		}
+		return invalid("identity provider answered %s", value)
+	}
+	return nil
+}
+
+// checkAssertion checks the issuer, subject confirmation and conditions of
+// an assertion, returning the time until which it must not be accepted
+// again
+func (sp *ServiceProvider) checkAssertion(assertion *element, inResponseTo string) (time.Time, error) {
+	if assertion.attr("ID") == "" {
+		return time.Time{}, invalid("assertion without ID")
+	}
+	if issuer := assertion.child(namespaceAssertion, "Issuer"); issuer == nil || issuer.text() != sp.options.IdentityProvider.EntityID {
+		return time.Time{}, invalid("unexpected assertion issuer")
+	}
+	subject := assertion.child(namespaceAssertion, "Subject")
+	if subject == nil {
+		return time.Time{}, invalid("assertion without subject")
+	}
+
+	now := clock.Now()
+	skew := sp.options.ClockSkew
+	expires, confirmed := time.Time{}, false
+	for _, confirmation := range subject.all(namespaceAssertion, "SubjectConfirmation") {
+		data := confirmation.child(namespaceAssertion, "SubjectConfirmationData")
+		if confirmation.attr("Method") != methodBearer || data == nil {
+			continue
+		}
+		notOnOrAfter, err := parseTime(data.attr("NotOnOrAfter"))
+		if err != nil || !now.Before(notOnOrAfter.Add(skew)) {
+			continue
+		}
+		if notBefore, err := parseTime(data.attr("NotBefore")); err == nil && now.Add(skew).Before(notBefore) {
+			continue
+		}
+		if data.attr("Recipient") != sp.options.ACSURL || data.attr("InResponseTo") != inResponseTo {
+			continue
+		}
+		expires, confirmed = notOnOrAfter.Add(skew), true
+		break
+	}
+	if !confirmed {
+		return time.Time{}, invalid("no valid bearer subject confirmation")
+	}
+
+	conditions := assertion.child(namespaceAssertion, "Conditions")
+	if conditions == nil {
+		return time.Time{}, invalid("assertion without conditions")
+	}
+	if notBefore, err := parseTime(conditions.attr("NotBefore")); err == nil && now.Add(skew).Before(notBefore) {
+		return time.Time{}, invalid("assertion not valid yet")
+	}
+	if notOnOrAfter, err := parseTime(conditions.attr("NotOnOrAfter")); err == nil && !now.Before(notOnOrAfter.Add(skew)) {
+		return time.Time{}, invalid("assertion expired")
+	}
+	restrictions := conditions.all(namespaceAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return time.Time{}, invalid("assertion without audience restriction")
+	}
+	for _, restriction := range restrictions {
+		audiences := make([]string, 0, 1)
+		for _, audience := range restriction.all(namespaceAssertion, "Audience") {
+			audiences = append(audiences, audience.text())
+		}
+		if !slices.Contains(audiences, sp.options.EntityID) {
+			return time.Time{}, invalid("assertion intended for another audience")
+		}
+	}
+	return expires, nil
+}
+
+// principal maps the subject and attributes of an assertion to a principal
+func (sp *ServiceProvider) principal(assertion *element) (*auth.Principal, error) {
+	attributes := make(map[string][]string)
+	var names []string
+	for _, statement := range assertion.all(namespaceAssertion, "AttributeStatement") {
+		for _, attribute := range statement.all(namespaceAssertion, "Attribute") {
+			name := attribute.attr("Name")
+			if _, seen := attributes[name]; !seen {
+				names = append(names, name)
+			}
+			values := attributes[name]
+			for _, value := range attribute.all(namespaceAssertion, "AttributeValue") {
+				values = append(values, value.text())
+			}
+			attributes[name] = values
+		}
+	}
+
+	principal := &auth.Principal{Strategy: "saml", Claims: make(map[string]interface{}, len(names))}
+	for _, name := range names {
+		if values := attributes[name]; len(values) == 1 {
+			principal.Claims[name] = values[0]
+		} else {
+			principal.Claims[name] = values
+		}
+	}
+
+	if nameID := assertion.child(namespaceAssertion, "Subject").child(namespaceAssertion, "NameID"); nameID != nil {
+		principal.Subject = nameID.text()
+	}
+	if sp.options.SubjectAttribute != "" {
+		values := attributes[sp.options.SubjectAttribute]
+		if len(values) == 0 {
+			return nil, invalid("missing subject attribute %q", sp.options.SubjectAttribute)
+		}
+		principal.Subject = values[0]
+	}
+	if principal.Subject == "" {
+		return nil, invalid("assertion without subject name")
+	}
+
+	for _, value := range attributes[sp.options.RolesAttribute] {
+		role := value
+		if sp.options.RoleMapping != nil {
+			role = sp.options.RoleMapping[value]
+		}
+		if role != "" && !slices.Contains(principal.Roles, role) {
+			principal.Roles = append(principal.Roles, role)
+		}
+	}
+	return principal, nil
+}
+
+// consume marks the request answered by a response, and its assertion, as
+// used
+func (sp *ServiceProvider) consume(inResponseTo, assertionID string, expires time.Time) error {
+	store := sp.options.Store
+	if inResponseTo == "" {
+		if !sp.options.AllowIdPInitiated {
+			return invalid("unsolicited response")
+		}
+	} else {
+		_, pending, err := store.Get(requestKeyPrefix + inResponseTo)
+		if err != nil {
+			return err
+		}
+		if !pending {
+			return invalid("unknown or expired request %q", inResponseTo)
+		}
+		if err := store.Delete(requestKeyPrefix + inResponseTo); err != nil {
+			return err
+		}
+	}
+
+	ttl := expires.Sub(clock.Now())
+	if ttl <= 0 {
+		ttl = time.Second
+	}
+	added, err := store.SetIfAbsent(assertionKeyPrefix+assertionID, []byte{1}, ttl)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return invalid("assertion %q already used", assertionID)
+	}
+	return nil
+}
+
+// parseTime parses an xs:dateTime attribute
+func parseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, value)
+}
@@ -0,0 +1,255 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/realip"
+)
+
+const (
+	// DefaultMetadataPath serves the service provider metadata
+	DefaultMetadataPath = "/saml/metadata"
+	// DefaultLoginPath redirects browsers to the identity provider
+	DefaultLoginPath = "/saml/login"
+	// DefaultClockSkew tolerates clock differences with the identity
+	// provider when checking assertion validity
+	DefaultClockSkew = 90 * time.Second
+	// DefaultRequestTTL is how long an authentication request may be
+	// answered
+	DefaultRequestTTL = 5 * time.Minute
+	// MaxResponseSize bounds the form posted to the assertion consumer
+	// service
+	MaxResponseSize = 1 << 20
+
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+
+	// requestKeyPrefix and assertionKeyPrefix namespace the pending
+	// requests and consumed assertions inside the store
+	requestKeyPrefix   = "saml:request:"
+	assertionKeyPrefix = "saml:assertion:"
+)
+
+// IdentityProvider is the identity provider trusted by a service provider,
+// usually read from its metadata with ParseMetadata
+type IdentityProvider struct {
+	EntityID string
+	// SSOURL receives authentication requests with the HTTP-Redirect binding
+	SSOURL string
+	// Certificates verify the signatures of responses and assertions. Keep
+	// the current and next ones while the identity provider rotates keys.
+	Certificates []*x509.Certificate
+}
+
+// Options configures a ServiceProvider
+type Options struct {
+	// EntityID identifies the service provider to the identity provider
+	EntityID string
+	// ACSURL is the absolute URL of the assertion consumer service, as
+	// reached by browsers. Its path is the route of ServeACS.
+	ACSURL string
+	// IdentityProvider issues the assertions
+	IdentityProvider IdentityProvider
+	// MetadataPath and LoginPath default to DefaultMetadataPath and
+	// DefaultLoginPath
+	MetadataPath string
+	LoginPath    string
+	// ClockSkew defaults to DefaultClockSkew
+	ClockSkew time.Duration
+	// SubjectAttribute names the attribute used as principal subject
+	// instead of the NameID
+	SubjectAttribute string
+	// RolesAttribute names the attribute holding the groups or roles of the
+	// user, mapped to principal roles by RoleMapping. Without a mapping the
+	// values are the roles.
+	RolesAttribute string
+	RoleMapping    map[string]string
+	// AllowIdPInitiated accepts responses not answering a request of the
+	// login endpoint. They are not bound to a login started by the browser.
+	AllowIdPInitiated bool
+	// Store keeps pending requests and consumed assertion IDs; defaults to
+	// an in-memory store, which only suits a single instance
+	Store cache.Store
+	// OnLogin is called with the principal of a valid response and the
+	// relay state sent to the login endpoint, to start the session of the
+	// user, e.g. set a cookie and redirect. The relay state comes from the
+	// browser and must be validated before redirecting to it.
+	OnLogin func(w http.ResponseWriter, r *http.Request, principal *auth.Principal, relayState string)
+}
+
+// ServiceProvider authenticates users with a SAML 2.0 identity provider
+// through the web browser SSO profile: ServeLogin redirects to the
+// identity provider, which posts its response to ServeACS. Responses must
+// be signed by the identity provider, as a whole or on the assertion, with
+// exclusive canonicalization; encrypted assertions are not supported.
+type ServiceProvider struct {
+	options Options
+	acsPath string
+}
+
+// ErrInvalidResponse is wrapped by the errors of rejected SAML responses
+var ErrInvalidResponse = errors.New("invalid SAML response")
+
+// New creates a service provider
+func New(opts Options) (*ServiceProvider, error) {
+	required := []struct {
+		name string
+		set  bool
+	}{
+		{"entity ID", opts.EntityID != ""},
+		{"ACS URL", opts.ACSURL != ""},
+		{"identity provider entity ID", opts.IdentityProvider.EntityID != ""},
+		{"identity provider SSO URL", opts.IdentityProvider.SSOURL != ""},
+		{"identity provider certificate", len(opts.IdentityProvider.Certificates) > 0},
+		{"OnLogin callback", opts.OnLogin != nil},
+	}
+	for _, field := range required {
+		if !field.set {
+			return nil, fmt.Errorf("saml: %s is required", field.name)
+		}
+	}
+	acs, err := url.Parse(opts.ACSURL)
+	if err != nil || !acs.IsAbs() {
+		return nil, fmt.Errorf("saml: ACS URL %q must be absolute", opts.ACSURL)
+	}
+	if _, err := url.Parse(opts.IdentityProvider.SSOURL); err != nil {
+		return nil, fmt.Errorf("saml: invalid identity provider SSO URL: %w", err)
+	}
+
+	if opts.MetadataPath == "" {
+		opts.MetadataPath = DefaultMetadataPath
+	}
+	if opts.LoginPath == "" {
+		opts.LoginPath = DefaultLoginPath
+	}
+	if opts.ClockSkew <= 0 {
+		opts.ClockSkew = DefaultClockSkew
+	}
+	if opts.Store == nil {
+		opts.Store = cache.NewMemoryStore()
+	}
+
+	acsPath := acs.Path
+	if acsPath == "" {
+		acsPath = "/"
+	}
+	return &ServiceProvider{options: opts, acsPath: acsPath}, nil
+}
+
+// MetadataPath returns the route of ServeMetadata
+func (sp *ServiceProvider) MetadataPath() string {
+	return sp.options.MetadataPath
+}
+
+// LoginPath returns the route of ServeLogin
+func (sp *ServiceProvider) LoginPath() string {
+	return sp.options.LoginPath
+}
+
+// ACSPath returns the route of ServeACS
+func (sp *ServiceProvider) ACSPath() string {
+	return sp.acsPath
+}
+
+// ServeMetadata writes the service provider metadata, to register it with
+// the identity provider
+func (sp *ServiceProvider) ServeMetadata(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(sp.metadata())
+}
+
+// ServeLogin redirects the browser to the identity provider with an
+// authentication request. The RelayState query parameter is handed back to
+// OnLogin.
+func (sp *ServiceProvider) ServeLogin(w http.ResponseWriter, r *http.Request) {
+	location, err := sp.loginURL(r.URL.Query().Get("RelayState"))
+	if err != nil {
+		logger.Error("Failed to create SAML authentication request", "error", err)
+		reject(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// loginURL creates and records an authentication request, returning the
+// identity provider URL it is sent to
+func (sp *ServiceProvider) loginURL(relayState string) (string, error) {
+	random, err := ids.Hex(20)
+	if err != nil {
+		return "", err
+	}
+	request := authnRequest{
+		ID:           "_" + random,
+		Version:      "2.0",
+		IssueInstant: clock.Now().UTC().Format(time.RFC3339),
+		Destination:  sp.options.IdentityProvider.SSOURL,
+		ACSURL:       sp.options.ACSURL,
+		Binding:      bindingPOST,
+		Issuer:       sp.options.EntityID,
+	}
+	if err := sp.options.Store.Set(requestKeyPrefix+request.ID, []byte{1}, DefaultRequestTTL); err != nil {
+		return "", err
+	}
+
+	// The HTTP-Redirect binding deflates the request before encoding it
+	var deflated bytes.Buffer
+	writer, _ := flate.NewWriter(&deflated, flate.BestCompression)
+	if err := xml.NewEncoder(writer).Encode(request); err != nil {
+		return "", err
+	}
+	writer.Close()
+
+	location, _ := url.Parse(sp.options.IdentityProvider.SSOURL)
+	query := location.Query()
+	query.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		query.Set("RelayState", relayState)
+	}
+	location.RawQuery = query.Encode()
+	return location.String(), nil
+}
+
+// ServeACS is the assertion consumer service: it validates the response
+// posted by the identity provider and calls OnLogin with its principal
+func (sp *ServiceProvider) ServeACS(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxResponseSize)
+	if err := r.ParseForm(); err != nil {
+		reject(w, http.StatusBadRequest, "Invalid SAML response")
+		return
+	}
+
+	principal, err := sp.ParseResponse(r.PostForm.Get("SAMLResponse"))
+	if errors.Is(err, ErrInvalidResponse) {
+		logger.Warn("SAML response rejected", "ip", realip.ClientIP(r), "error", err)
+		reject(w, http.StatusUnauthorized, "Invalid SAML response")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to validate SAML response", "error", err)
+		reject(w, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	sp.options.OnLogin(w, r, principal, r.PostForm.Get("RelayState"))
+}
+
+// reject writes a JSON error
+func reject(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write([]byte(`{"error": "` + message + `"}`))
+}
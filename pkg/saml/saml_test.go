@@ -0,0 +1,437 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+)
+
+const (
+	testEntityID = "https://app.example.com/saml"
+	testACSURL   = "https://app.example.com/saml/acs"
+	testIdP      = "https://idp.example.com"
+
+	responseSignature  = "{{responseSignature}}"
+	assertionSignature = "{{assertionSignature}}"
+)
+
+// responseTemplate is a response with signature placeholders, filled by
+// fixture.response
+const responseTemplate = `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_response" Version="2.0" IssueInstant="{{now}}" Destination="{{destination}}" InResponseTo="{{request}}">
+  <saml:Issuer>https://idp.example.com</saml:Issuer>
+  {{responseSignature}}
+  <samlp:Status><samlp:StatusCode Value="{{status}}"/></samlp:Status>
+  <saml:Assertion ID="_assertion" Version="2.0" IssueInstant="{{now}}">
+    <saml:Issuer>https://idp.example.com</saml:Issuer>
+    {{assertionSignature}}
+    <saml:Subject>
+      <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">ana@example.com</saml:NameID>
+      <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+        <saml:SubjectConfirmationData NotOnOrAfter="{{expires}}" Recipient="{{recipient}}" InResponseTo="{{request}}"/>
+      </saml:SubjectConfirmation>
+    </saml:Subject>
+    <saml:Conditions NotBefore="{{now}}" NotOnOrAfter="{{expires}}">
+      <saml:AudienceRestriction><saml:Audience>{{audience}}</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="groups">
+        <saml:AttributeValue xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="xs:string">admins</saml:AttributeValue>
+        <saml:AttributeValue>staff</saml:AttributeValue>
+      </saml:Attribute>
+      <saml:Attribute Name="email"><saml:AttributeValue>ana@example.com</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`
+
+// fixture is the content of a test response
+type fixture struct {
+	destination string
+	recipient   string
+	audience    string
+	status      string
+	expires     time.Time
+}
+
+// newFixture returns the fields of a valid response
+func newFixture() fixture {
+	return fixture{
+		destination: testACSURL,
+		recipient:   testACSURL,
+		audience:    testEntityID,
+		status:      statusSuccess,
+		expires:     time.Now().Add(5 * time.Minute),
+	}
+}
+
+// response renders the template answering request, signing the response
+// and assertion with the given keys when not nil
+func (f fixture) response(t *testing.T, request string, signResponse, signAssertion crypto.Signer) string {
+	t.Helper()
+	document := strings.NewReplacer(
+		"{{now}}", time.Now().UTC().Format(time.RFC3339),
+		"{{destination}}", f.destination,
+		"{{request}}", request,
+		"{{status}}", f.status,
+		"{{expires}}", f.expires.UTC().Format(time.RFC3339),
+		"{{recipient}}", f.recipient,
+		"{{audience}}", f.audience,
+	).Replace(responseTemplate)
+
+	// The assertion is signed first, the response signature covers it
+	if signAssertion != nil {
+		document = sign(t, document, assertionSignature, "_assertion", signAssertion)
+	}
+	document = strings.Replace(document, assertionSignature, "", 1)
+	if signResponse != nil {
+		document = sign(t, document, responseSignature, "_response", signResponse)
+	}
+	return strings.Replace(document, responseSignature, "", 1)
+}
+
+// sign replaces placeholder with the enveloped signature of the element
+// identified by id
+func sign(t *testing.T, document, placeholder, id string, key crypto.Signer) string {
+	t.Helper()
+	root, err := parse([]byte(strings.Replace(document, placeholder, "", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := find(root, id)
+	digest := sha256.Sum256(canonicalize(signed, nil, []string{"xs"}))
+
+	method := "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		method = "http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256"
+	}
+	signedInfo := `<ds:SignedInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` +
+		`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/>` +
+		`<ds:SignatureMethod Algorithm="` + method + `"/>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"><ec:InclusiveNamespaces xmlns:ec="http://www.w3.org/2001/10/xml-exc-c14n#" PrefixList="xs"/></ds:Transform>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue>` +
+		`</ds:Reference></ds:SignedInfo>`
+	info, err := parse([]byte(signedInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(canonicalize(info, nil, nil))
+
+	var value []byte
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		value = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		if value, err = key.Sign(rand.Reader, sum[:], crypto.SHA256); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signature := `<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` + signedInfo +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(value) + `</ds:SignatureValue></ds:Signature>`
+	return strings.Replace(document, placeholder, signature, 1)
+}
+
+// find returns the element of a tree with an ID
+func find(e *element, id string) *element {
+	if e.attr("ID") == id {
+		return e
+	}
+	for _, child := range e.children {
+		if child.element != nil {
+			if found := find(child.element, id); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// certificate returns a self-signed certificate of key
+func certificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	t.Helper()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+// newServiceProvider returns a service provider trusting the certificates
+// and recording the principals it logs in
+func newServiceProvider(t *testing.T, allowIdPInitiated bool, certificates ...*x509.Certificate) (*ServiceProvider, *[]*auth.Principal) {
+	t.Helper()
+	var principals []*auth.Principal
+	sp, err := New(Options{
+		EntityID: testEntityID,
+		ACSURL:   testACSURL,
+		IdentityProvider: IdentityProvider{
+			EntityID:     testIdP,
+			SSOURL:       testIdP + "/sso",
+			Certificates: certificates,
+		},
+		RolesAttribute:    "groups",
+		RoleMapping:       map[string]string{"admins": "admin"},
+		AllowIdPInitiated: allowIdPInitiated,
+		OnLogin: func(w http.ResponseWriter, r *http.Request, principal *auth.Principal, relayState string) {
+			principals = append(principals, principal)
+			http.Redirect(w, r, relayState, http.StatusSeeOther)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sp, &principals
+}
+
+// login starts a login and returns the authentication request sent to the
+// identity provider and the relay state
+func login(t *testing.T, sp *ServiceProvider, relayState string) (authnRequest, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	sp.ServeLogin(w, httptest.NewRequest(http.MethodGet, sp.LoginPath()+"?RelayState="+url.QueryEscape(relayState), nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d, want %d", w.Code, http.StatusFound)
+	}
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deflated, err := base64.StdEncoding.DecodeString(location.Query().Get("SAMLRequest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inflated, err := io.ReadAll(flate.NewReader(bytes.NewReader(deflated)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request authnRequest
+	if err := xml.Unmarshal(inflated, &request); err != nil {
+		t.Fatal(err)
+	}
+	return request, location.Query().Get("RelayState")
+}
+
+// post sends a response to the assertion consumer service
+func post(sp *ServiceProvider, response string) *httptest.ResponseRecorder {
+	form := url.Values{
+		"SAMLResponse": {base64.StdEncoding.EncodeToString([]byte(response))},
+		"RelayState":   {"/dashboard"},
+	}
+	r := httptest.NewRequest(http.MethodPost, sp.ACSPath(), strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	sp.ServeACS(w, r)
+	return w
+}
+
+func TestServeACS(t *testing.T) {
+	idpKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trusted := []*x509.Certificate{certificate(t, idpKey), certificate(t, ecKey)}
+
+	tests := []struct {
+		name              string
+		fixture           func(f *fixture)
+		signResponse      crypto.Signer
+		signAssertion     crypto.Signer
+		tamper            func(response string) string
+		unsolicited       bool
+		allowIdPInitiated bool
+		replay            bool
+		wantStatus        int
+	}{
+		{name: "signed assertion", signAssertion: idpKey, wantStatus: http.StatusSeeOther},
+		{name: "signed response", signResponse: idpKey, wantStatus: http.StatusSeeOther},
+		{name: "signed response and assertion", signResponse: idpKey, signAssertion: idpKey, wantStatus: http.StatusSeeOther},
+		{name: "ecdsa signature", signAssertion: ecKey, wantStatus: http.StatusSeeOther},
+		{name: "idp initiated allowed", signAssertion: idpKey, unsolicited: true, allowIdPInitiated: true, wantStatus: http.StatusSeeOther},
+		{name: "unsigned", wantStatus: http.StatusUnauthorized},
+		{name: "untrusted key", signAssertion: otherKey, wantStatus: http.StatusUnauthorized},
+		{
+			name: "modified after signing", signAssertion: idpKey,
+			tamper:     func(response string) string { return strings.Replace(response, ">admins<", ">owners<", 1) },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "signed assertion wrapped with another", signAssertion: idpKey,
+			tamper: func(response string) string {
+				start := strings.Index(response, "<saml:Assertion")
+				end := strings.Index(response, "</saml:Assertion>") + len("</saml:Assertion>")
+				forged := strings.Replace(strings.Replace(response[start:end], "ana@", "eve@", -1), `ID="_assertion"`, `ID="_forged"`, 1)
+				return response[:start] + forged + response[start:]
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{name: "other audience", fixture: func(f *fixture) { f.audience = "https://other.example.com" }, signAssertion: idpKey, wantStatus: http.StatusUnauthorized},
+		{name: "other recipient", fixture: func(f *fixture) { f.recipient = "https://other.example.com/acs" }, signAssertion: idpKey, wantStatus: http.StatusUnauthorized},
+		{name: "other destination", fixture: func(f *fixture) { f.destination = "https://other.example.com/acs" }, signAssertion: idpKey, wantStatus: http.StatusUnauthorized},
+		{name: "expired", fixture: func(f *fixture) { f.expires = time.Now().Add(-time.Hour) }, signAssertion: idpKey, wantStatus: http.StatusUnauthorized},
+		{
+			name: "failed authentication", signResponse: idpKey,
+			fixture:    func(f *fixture) { f.status = "urn:oasis:names:tc:SAML:2.0:status:Responder" },
+			wantStatus: http.StatusUnauthorized,
+		},
+		{name: "unsolicited", signAssertion: idpKey, unsolicited: true, wantStatus: http.StatusUnauthorized},
+		{name: "replayed", signAssertion: idpKey, allowIdPInitiated: true, unsolicited: true, replay: true, wantStatus: http.StatusUnauthorized},
+		{name: "replayed answer to a request", signAssertion: idpKey, replay: true, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp, principals := newServiceProvider(t, tt.allowIdPInitiated, trusted...)
+			request := ""
+			if !tt.unsolicited {
+				authn, relayState := login(t, sp, "/dashboard")
+				if relayState != "/dashboard" || authn.Issuer != testEntityID || authn.ACSURL != testACSURL {
+					t.Fatalf("authentication request = %+v, relay state %q", authn, relayState)
+				}
+				request = authn.ID
+			}
+
+			f := newFixture()
+			if tt.fixture != nil {
+				tt.fixture(&f)
+			}
+			response := f.response(t, request, tt.signResponse, tt.signAssertion)
+			if tt.tamper != nil {
+				response = tt.tamper(response)
+			}
+			if tt.replay {
+				if w := post(sp, response); w.Code != http.StatusSeeOther {
+					t.Fatalf("first post status = %d %s", w.Code, w.Body.String())
+				}
+				*principals = nil
+			}
+
+			w := post(sp, response)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", w.Code, w.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusSeeOther {
+				if len(*principals) != 0 {
+					t.Errorf("OnLogin called for a rejected response")
+				}
+				return
+			}
+
+			if location := w.Header().Get("Location"); location != "/dashboard" {
+				t.Errorf("redirected to %q, want the relay state", location)
+			}
+			principal := (*principals)[0]
+			if principal.Subject != "ana@example.com" || principal.Strategy != "saml" {
+				t.Errorf("principal = %s via %s", principal.Subject, principal.Strategy)
+			}
+			if len(principal.Roles) != 1 || !principal.HasRole("admin") {
+				t.Errorf("roles = %v, want [admin]", principal.Roles)
+			}
+			if principal.Claims["email"] != "ana@example.com" {
+				t.Errorf("email claim = %v", principal.Claims["email"])
+			}
+		})
+	}
+}
+
+func TestServeMetadata(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp, _ := newServiceProvider(t, false, certificate(t, key))
+
+	w := httptest.NewRecorder()
+	sp.ServeMetadata(w, httptest.NewRequest(http.MethodGet, sp.MetadataPath(), nil))
+
+	var metadata entityDescriptor
+	if err := xml.Unmarshal(w.Body.Bytes(), &metadata); err != nil {
+		t.Fatal(err)
+	}
+	if metadata.EntityID != testEntityID || metadata.Descriptor.AssertionConsumerService.Location != testACSURL || !metadata.Descriptor.WantAssertionsSigned {
+		t.Errorf("metadata = %+v", metadata)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(certificate(t, key).Raw)
+	metadata := func(use, binding string) string {
+		return `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="` + testIdP + `">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="` + use + `"><ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:X509Data><ds:X509Certificate>
+      ` + encoded + `
+    </ds:X509Certificate></ds:X509Data></ds:KeyInfo></md:KeyDescriptor>
+    <md:SingleSignOnService Binding="` + binding + `" Location="` + testIdP + `/sso"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+	}
+
+	tests := []struct {
+		name     string
+		metadata string
+		wantErr  bool
+	}{
+		{name: "signing key", metadata: metadata("signing", bindingRedirect)},
+		{name: "key without use", metadata: metadata("", bindingRedirect)},
+		{name: "encryption key only", metadata: metadata("encryption", bindingRedirect), wantErr: true},
+		{name: "post binding only", metadata: metadata("signing", bindingPOST), wantErr: true},
+		{name: "service provider", metadata: `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="x"/>`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp, err := ParseMetadata([]byte(tt.metadata))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMetadata() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (idp.EntityID != testIdP || idp.SSOURL != testIdP+"/sso" || len(idp.Certificates) != 1) {
+				t.Errorf("ParseMetadata() = %+v", idp)
+			}
+		})
+	}
+}
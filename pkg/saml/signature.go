@@ -0,0 +1,180 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// XML signature namespace and algorithms
+const (
+	namespaceDSig = "http://www.w3.org/2000/09/xmldsig#"
+
+	algorithmExclusiveC14N = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algorithmEnveloped     = namespaceDSig + "enveloped-signature"
+)
+
+// signatureHashes are the accepted signature methods. SHA-1 based ones are
+// rejected.
+var signatureHashes = map[string]crypto.Hash{
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha384":   crypto.SHA384,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   crypto.SHA512,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha512": crypto.SHA512,
+}
+
+// digestHashes are the accepted digest methods
+var digestHashes = map[string]crypto.Hash{
+	"http://www.w3.org/2001/04/xmlenc#sha256":       crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#sha384": crypto.SHA384,
+	"http://www.w3.org/2001/04/xmlenc#sha512":       crypto.SHA512,
+}
+
+// verifySignature checks the enveloped signature of e against the trusted
+// certificates, reporting false when e is not signed. Only a signature
+// child of e referencing e itself is considered, so the verified content is
+// the element the caller reads rather than one found by ID elsewhere in
+// the document.
+func verifySignature(e *element, certificates []*x509.Certificate) (bool, error) {
+	signatures := e.all(namespaceDSig, "Signature")
+	if len(signatures) == 0 {
+		return false, nil
+	}
+	if len(signatures) > 1 {
+		return false, errors.New("multiple signatures")
+	}
+	signature := signatures[0]
+
+	signedInfo := signature.child(namespaceDSig, "SignedInfo")
+	if signedInfo == nil {
+		return false, errors.New("signature without signed info")
+	}
+	method := signedInfo.child(namespaceDSig, "CanonicalizationMethod")
+	if method == nil || method.attr("Algorithm") != algorithmExclusiveC14N {
+		return false, errors.New("unsupported canonicalization method")
+	}
+	signatureMethod := signedInfo.child(namespaceDSig, "SignatureMethod")
+	if signatureMethod == nil {
+		return false, errors.New("signature without signature method")
+	}
+	hash, ok := signatureHashes[signatureMethod.attr("Algorithm")]
+	if !ok {
+		return false, fmt.Errorf("unsupported signature method %q", signatureMethod.attr("Algorithm"))
+	}
+
+	references := signedInfo.all(namespaceDSig, "Reference")
+	if len(references) != 1 {
+		return false, fmt.Errorf("expected one signature reference, got %d", len(references))
+	}
+	if err := verifyReference(e, signature, references[0]); err != nil {
+		return false, err
+	}
+
+	value, err := decodeBase64(signature.child(namespaceDSig, "SignatureValue"))
+	if err != nil {
+		return false, errors.New("malformed signature value")
+	}
+	signed := canonicalize(signedInfo, nil, inclusivePrefixes(method))
+	for _, certificate := range certificates {
+		if checkSignature(certificate.PublicKey, hash, signed, value) {
+			return true, nil
+		}
+	}
+	return false, errors.New("signature does not match the identity provider certificates")
+}
+
+// verifyReference checks that a signature reference digests e, enveloping
+// the signature
+func verifyReference(e, signature, reference *element) error {
+	if id := e.attr("ID"); id == "" || reference.attr("URI") != "#"+id {
+		return errors.New("signature does not reference the signed element")
+	}
+
+	var inclusive []string
+	canonicalized := false
+	if transforms := reference.child(namespaceDSig, "Transforms"); transforms != nil {
+		for _, transform := range transforms.all(namespaceDSig, "Transform") {
+			switch transform.attr("Algorithm") {
+			case algorithmEnveloped:
+			case algorithmExclusiveC14N:
+				inclusive = inclusivePrefixes(transform)
+				canonicalized = true
+			default:
+				return fmt.Errorf("unsupported transform %q", transform.attr("Algorithm"))
+			}
+		}
+	}
+	if !canonicalized {
+		return errors.New("signature reference is not canonicalized")
+	}
+
+	digestMethod := reference.child(namespaceDSig, "DigestMethod")
+	if digestMethod == nil {
+		return errors.New("signature reference without digest method")
+	}
+	hash, ok := digestHashes[digestMethod.attr("Algorithm")]
+	if !ok {
+		return fmt.Errorf("unsupported digest method %q", digestMethod.attr("Algorithm"))
+	}
+	expected, err := decodeBase64(reference.child(namespaceDSig, "DigestValue"))
+	if err != nil {
+		return errors.New("malformed digest value")
+	}
+
+	digest := hash.New()
+	digest.Write(canonicalize(e, signature, inclusive))
+	if !bytes.Equal(digest.Sum(nil), expected) {
+		return errors.New("digest mismatch, the signed element was modified")
+	}
+	return nil
+}
+
+// inclusivePrefixes returns the InclusiveNamespaces prefix list of an
+// exclusive canonicalization method or transform
+func inclusivePrefixes(method *element) []string {
+	if namespaces := method.child(algorithmExclusiveC14N, "InclusiveNamespaces"); namespaces != nil {
+		return strings.Fields(namespaces.attr("PrefixList"))
+	}
+	return nil
+}
+
+// decodeBase64 decodes the base64 text of an element, which may be wrapped
+func decodeBase64(e *element) ([]byte, error) {
+	if e == nil {
+		return nil, errors.New("missing value")
+	}
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e.text()), ""))
+}
+
+// checkSignature verifies an RSA PKCS #1 v1.5 or ECDSA signature of data.
+// XML signatures encode ECDSA ones as the concatenated r and s values.
+func checkSignature(key crypto.PublicKey, hash crypto.Hash, data, signature []byte) bool {
+	digest := hash.New()
+	digest.Write(data)
+	sum := digest.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, hash, sum, signature) == nil
+	case *ecdsa.PublicKey:
+		if len(signature) == 0 || len(signature)%2 != 0 {
+			return false
+		}
+		half := len(signature) / 2
+		r := new(big.Int).SetBytes(signature[:half])
+		s := new(big.Int).SetBytes(signature[half:])
+		return ecdsa.Verify(key, sum, r, s)
+	}
+	return false
+}
@@ -0,0 +1,294 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// namespaceXML is bound to the xml prefix without a declaration
+const namespaceXML = "http://www.w3.org/XML/1998/namespace"
+
+// element is a node of a parsed document. It keeps the prefixes and
+// namespace declarations as written, which canonicalization needs and
+// encoding/xml resolves away.
+type element struct {
+	parent   *element
+	prefix   string
+	local    string
+	attrs    []xml.Attr // Name.Space holds the prefix of the attribute
+	children []content
+}
+
+// content is a child of an element, either an element or text
+type content struct {
+	element *element
+	text    string
+}
+
+// parse reads a document into a tree, dropping comments and processing
+// instructions. Document type declarations are rejected.
+func parse(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *element
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			e := &element{parent: current, prefix: t.Name.Space, local: t.Name.Local, attrs: t.Copy().Attr}
+			if current != nil {
+				current.children = append(current.children, content{element: e})
+			} else if root != nil {
+				return nil, errors.New("multiple root elements")
+			} else {
+				root = e
+			}
+			current = e
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.prefix || t.Name.Local != current.local {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, content{text: string(t)})
+			} else if len(bytes.TrimSpace(t)) > 0 {
+				return nil, errors.New("text outside the root element")
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations are not allowed")
+		}
+	}
+	if root == nil || current != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return root, nil
+}
+
+// lookup returns the namespace bound to a prefix in the scope of e
+func (e *element) lookup(prefix string) (string, bool) {
+	for n := e; n != nil; n = n.parent {
+		for _, attr := range n.attrs {
+			if prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns" ||
+				prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix {
+				return attr.Value, true
+			}
+		}
+	}
+	if prefix == "xml" {
+		return namespaceXML, true
+	}
+	return "", false
+}
+
+// is reports whether e is the element local of the namespace space
+func (e *element) is(space, local string) bool {
+	namespace, _ := e.lookup(e.prefix)
+	return e.local == local && namespace == space
+}
+
+// all returns the child elements named local in the namespace space
+func (e *element) all(space, local string) []*element {
+	var found []*element
+	for _, child := range e.children {
+		if child.element != nil && child.element.is(space, local) {
+			found = append(found, child.element)
+		}
+	}
+	return found
+}
+
+// child returns the first child element named local in the namespace
+// space, or nil
+func (e *element) child(space, local string) *element {
+	if found := e.all(space, local); len(found) > 0 {
+		return found[0]
+	}
+	return nil
+}
+
+// attr returns the value of an unprefixed attribute
+func (e *element) attr(local string) string {
+	for _, attr := range e.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// text returns the text of e, without surrounding whitespace
+func (e *element) text() string {
+	var b strings.Builder
+	for _, child := range e.children {
+		if child.element == nil {
+			b.WriteString(child.text)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// isNamespaceDeclaration reports whether attr declares a namespace
+func isNamespaceDeclaration(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || attr.Name.Space == "" && attr.Name.Local == "xmlns"
+}
+
+// canonicalize returns the exclusive canonical form (xml-exc-c14n,
+// without comments) of the subtree of e, leaving out the excluded element,
+// e.g. an enveloped signature. The prefixes of inclusive, "#default" for
+// the default namespace, are rendered as by inclusive canonicalization.
+func canonicalize(e, excluded *element, inclusive []string) []byte {
+	c := &canonicalizer{excluded: excluded}
+	for _, prefix := range inclusive {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		c.inclusive = append(c.inclusive, prefix)
+	}
+	c.element(e, map[string]string{})
+	return c.buf.Bytes()
+}
+
+// canonicalizer writes the canonical form of a subtree
+type canonicalizer struct {
+	buf       bytes.Buffer
+	excluded  *element
+	inclusive []string
+}
+
+// element writes e given the namespaces rendered by its output ancestors
+func (c *canonicalizer) element(e *element, rendered map[string]string) {
+	// Exclusive canonicalization only renders the namespaces visibly
+	// utilized by the element and its attributes
+	utilized := append([]string{e.prefix}, c.inclusive...)
+	type attribute struct {
+		namespace string
+		attr      xml.Attr
+	}
+	var attrs []attribute
+	for _, attr := range e.attrs {
+		if isNamespaceDeclaration(attr) {
+			continue
+		}
+		namespace := ""
+		if attr.Name.Space != "" {
+			namespace, _ = e.lookup(attr.Name.Space)
+			utilized = append(utilized, attr.Name.Space)
+		}
+		attrs = append(attrs, attribute{namespace: namespace, attr: attr})
+	}
+
+	declared := make(map[string]string)
+	for _, prefix := range utilized {
+		if prefix == "xml" {
+			continue
+		}
+		namespace, found := e.lookup(prefix)
+		if previous, ok := rendered[prefix]; ok && previous == namespace || !ok && namespace == "" {
+			continue
+		}
+		if !found && prefix != "" {
+			continue
+		}
+		declared[prefix] = namespace
+	}
+	prefixes := make([]string, 0, len(declared))
+	for prefix := range declared {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].namespace != attrs[j].namespace {
+			return attrs[i].namespace < attrs[j].namespace
+		}
+		return attrs[i].attr.Name.Local < attrs[j].attr.Name.Local
+	})
+
+	c.buf.WriteByte('<')
+	c.name(e.prefix, e.local)
+	for _, prefix := range prefixes {
+		c.buf.WriteString(" xmlns")
+		if prefix != "" {
+			c.buf.WriteByte(':')
+			c.buf.WriteString(prefix)
+		}
+		c.buf.WriteString(`="`)
+		c.escape(declared[prefix], true)
+		c.buf.WriteByte('"')
+	}
+	for _, a := range attrs {
+		c.buf.WriteByte(' ')
+		c.name(a.attr.Name.Space, a.attr.Name.Local)
+		c.buf.WriteString(`="`)
+		c.escape(a.attr.Value, true)
+		c.buf.WriteByte('"')
+	}
+	c.buf.WriteByte('>')
+
+	if len(declared) > 0 {
+		inherited := make(map[string]string, len(rendered)+len(declared))
+		for prefix, namespace := range rendered {
+			inherited[prefix] = namespace
+		}
+		for prefix, namespace := range declared {
+			inherited[prefix] = namespace
+		}
+		rendered = inherited
+	}
+	for _, child := range e.children {
+		switch {
+		case child.element == nil:
+			c.escape(child.text, false)
+		case child.element != c.excluded:
+			c.element(child.element, rendered)
+		}
+	}
+
+	c.buf.WriteString("</")
+	c.name(e.prefix, e.local)
+	c.buf.WriteByte('>')
+}
+
+// name writes a qualified name
+func (c *canonicalizer) name(prefix, local string) {
+	if prefix != "" {
+		c.buf.WriteString(prefix)
+		c.buf.WriteByte(':')
+	}
+	c.buf.WriteString(local)
+}
+
+// escape writes text or an attribute value with the canonical escapes
+func (c *canonicalizer) escape(s string, attribute bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			c.buf.WriteString("&amp;")
+		case r == '<':
+			c.buf.WriteString("&lt;")
+		case r == '>' && !attribute:
+			c.buf.WriteString("&gt;")
+		case r == '"' && attribute:
+			c.buf.WriteString("&quot;")
+		case r == '\t' && attribute:
+			c.buf.WriteString("&#x9;")
+		case r == '\n' && attribute:
+			c.buf.WriteString("&#xA;")
+		case r == '\r':
+			c.buf.WriteString("&#xD;")
+		default:
+			c.buf.WriteRune(r)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package saml
+
+import (
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		document  string
+		child     bool
+		inclusive []string
+		want      string
+	}{
+		{
+			name:     "sorted namespaces and attributes",
+			document: `<a:root xmlns:b="urn:b" xmlns:a="urn:a" z="1" b:y="2" a="3"><child/></a:root>`,
+			want:     `<a:root xmlns:a="urn:a" xmlns:b="urn:b" a="3" z="1" b:y="2"><child></child></a:root>`,
+		},
+		{
+			name:     "namespaces rendered where utilized",
+			document: `<root xmlns:x="urn:x"><x:child/></root>`,
+			want:     `<root><x:child xmlns:x="urn:x"></x:child></root>`,
+		},
+		{
+			name:     "subtree with inherited namespace",
+			document: `<p:root xmlns:p="urn:p" xmlns:q="urn:q"><p:child a="1">text</p:child></p:root>`,
+			child:    true,
+			want:     `<p:child xmlns:p="urn:p" a="1">text</p:child>`,
+		},
+		{
+			name:     "redundant declaration",
+			document: `<p:root xmlns:p="urn:p"><p:child xmlns:p="urn:p"/></p:root>`,
+			want:     `<p:root xmlns:p="urn:p"><p:child></p:child></p:root>`,
+		},
+		{
+			name:     "default namespace undeclared",
+			document: `<root xmlns="urn:d"><child xmlns=""/></root>`,
+			want:     `<root xmlns="urn:d"><child xmlns=""></child></root>`,
+		},
+		{
+			name:     "escapes",
+			document: "<root a=\"&quot;&lt;&#9;>\">x &amp; &lt; &gt; \"y\"&#13;</root>",
+			want:     "<root a=\"&quot;&lt;&#x9;>\">x &amp; &lt; &gt; \"y\"&#xD;</root>",
+		},
+		{
+			name:     "comments and processing instructions",
+			document: `<root><!-- comment --><?target data?>text</root>`,
+			want:     `<root>text</root>`,
+		},
+		{
+			name:      "inclusive prefixes",
+			document:  `<root xmlns:xs="urn:xs" xmlns:unused="urn:u"><a/></root>`,
+			inclusive: []string{"xs"},
+			want:      `<root xmlns:xs="urn:xs"><a></a></root>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := parse([]byte(tt.document))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.child {
+				root = root.children[0].element
+			}
+			if got := string(canonicalize(root, nil, tt.inclusive)); got != tt.want {
+				t.Errorf("canonicalize() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{name: "document type", document: `<!DOCTYPE root [<!ENTITY x "y">]><root>&x;</root>`},
+		{name: "mismatched end element", document: `<root><a></b></root>`},
+		{name: "multiple roots", document: `<a/><b/>`},
+		{name: "unclosed", document: `<root><a>`},
+		{name: "empty", document: ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse([]byte(tt.document)); err == nil {
+				t.Error("parse() succeeded, want an error")
+			}
+		})
+	}
+}
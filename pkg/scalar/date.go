@@ -0,0 +1,74 @@
+package scalar
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+)
+
+// DateLayout is the text form of a Date
+const DateLayout = "2006-01-02"
+
+// Date is a calendar date without time of day or time zone, e.g. a birth
+// date, in the text form "2006-01-02"
+type Date struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+func init() {
+	codec.Register(ParseDate, Date.String)
+}
+
+// DateOf returns the date of a time in its location
+func DateOf(t time.Time) Date {
+	year, month, day := t.Date()
+	return Date{Year: year, Month: month, Day: day}
+}
+
+// ParseDate parses a date in the "2006-01-02" form
+func ParseDate(text string) (Date, error) {
+	parsed, err := time.Parse(DateLayout, text)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", text)
+	}
+	return DateOf(parsed), nil
+}
+
+// String formats the date in the "2006-01-02" form
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// In returns the start of the date in a location
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Before reports whether d is before other
+func (d Date) Before(other Date) bool {
+	return d.In(time.UTC).Before(other.In(time.UTC))
+}
+
+// After reports whether d is after other
+func (d Date) After(other Date) bool {
+	return d.In(time.UTC).After(other.In(time.UTC))
+}
+
+// MarshalText formats the date with its registered codec
+func (d Date) MarshalText() ([]byte, error) {
+	text, err := codec.Format(d)
+	return []byte(text), err
+}
+
+// UnmarshalText parses the date with its registered codec
+func (d *Date) UnmarshalText(text []byte) error {
+	parsed, err := codec.Parse[Date](string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
@@ -0,0 +1,90 @@
+package scalar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+)
+
+// Money is an amount in minor units (cents) with an optional ISO 4217
+// currency code. Its text form is "12.34 BRL", or "12.34" without currency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func init() {
+	codec.Register(ParseMoney, Money.String)
+}
+
+// ParseMoney parses an amount with up to two decimal places followed by an
+// optional three-letter currency code
+func ParseMoney(text string) (Money, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 2 {
+		return Money{}, fmt.Errorf("invalid money %q", text)
+	}
+
+	money := Money{}
+	if len(fields) == 2 {
+		currency := strings.ToUpper(fields[1])
+		if len(currency) != 3 || strings.Trim(currency, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return Money{}, fmt.Errorf("invalid currency %q", fields[1])
+		}
+		money.Currency = currency
+	}
+
+	amount := fields[0]
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+
+	units, cents, hasCents := strings.Cut(amount, ".")
+	if units == "" || len(cents) > 2 || (hasCents && cents == "") {
+		return Money{}, fmt.Errorf("invalid money %q: at most two decimal places are allowed", text)
+	}
+	cents += strings.Repeat("0", 2-len(cents))
+
+	value, err := strconv.ParseInt(units+cents, 10, 64)
+	if err != nil || strings.ContainsAny(units+cents, "+-") {
+		return Money{}, fmt.Errorf("invalid money %q", text)
+	}
+	if negative {
+		value = -value
+	}
+	money.Amount = value
+	return money, nil
+}
+
+// String formats the amount with two decimal places and the currency
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	text := fmt.Sprintf("%s%d.%02d", sign, amount/100, amount%100)
+	if m.Currency != "" {
+		text += " " + m.Currency
+	}
+	return text
+}
+
+// MarshalText formats the money with its registered codec
+func (m Money) MarshalText() ([]byte, error) {
+	text, err := codec.Format(m)
+	return []byte(text), err
+}
+
+// UnmarshalText parses the money with its registered codec
+func (m *Money) UnmarshalText(text []byte) error {
+	parsed, err := codec.Parse[Money](string(text))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
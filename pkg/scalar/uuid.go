@@ -0,0 +1,66 @@
+package scalar
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+)
+
+// UUID is a 128-bit universally unique identifier in canonical text form,
+// e.g. "123e4567-e89b-12d3-a456-426614174000"
+type UUID [16]byte
+
+func init() {
+	codec.Register(ParseUUID, UUID.String)
+}
+
+// NewUUID generates a random version 4 UUID
+func NewUUID() UUID {
+	raw, err := ids.Bytes(len(UUID{}))
+	if err != nil {
+		panic(fmt.Sprintf("scalar: failed to generate UUID: %v", err))
+	}
+	var id UUID
+	copy(id[:], raw)
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return id
+}
+
+// ParseUUID parses a UUID in canonical form, in any letter case
+func ParseUUID(text string) (UUID, error) {
+	var id UUID
+	if len(text) != 36 || text[8] != '-' || text[13] != '-' || text[18] != '-' || text[23] != '-' {
+		return id, fmt.Errorf("invalid UUID %q", text)
+	}
+
+	digits := text[0:8] + text[9:13] + text[14:18] + text[19:23] + text[24:36]
+	if _, err := hex.Decode(id[:], []byte(digits)); err != nil {
+		return UUID{}, fmt.Errorf("invalid UUID %q", text)
+	}
+	return id, nil
+}
+
+// String formats the UUID in lowercase canonical form
+func (id UUID) String() string {
+	digits := hex.EncodeToString(id[:])
+	return digits[0:8] + "-" + digits[8:12] + "-" + digits[12:16] + "-" + digits[16:20] + "-" + digits[20:32]
+}
+
+// MarshalText formats the UUID with its registered codec
+func (id UUID) MarshalText() ([]byte, error) {
+	text, err := codec.Format(id)
+	return []byte(text), err
+}
+
+// UnmarshalText parses the UUID with its registered codec
+func (id *UUID) UnmarshalText(text []byte) error {
+	parsed, err := codec.Parse[UUID](string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
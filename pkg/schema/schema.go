@@ -0,0 +1,203 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+)
+
+// JSON types of a Field
+const (
+	TypeString  = "string"
+	TypeInteger = "integer"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeAny     = "any"
+)
+
+// Field describes the JSON shape of a value
+type Field struct {
+	Type     string           `json:"type"`
+	Required bool             `json:"required,omitempty"`
+	Fields   map[string]Field `json:"fields,omitempty"`
+	Items    *Field           `json:"items,omitempty"`
+}
+
+// Schema is a versioned DTO shape
+type Schema struct {
+	Version int              `json:"version"`
+	Fields  map[string]Field `json:"fields"`
+}
+
+// Describe returns the JSON shape of a struct type. Types with a codec are
+// strings; fields tagged required or with the required validate rule are
+// required.
+func Describe(t reflect.Type) map[string]Field {
+	return describe(t, make(map[reflect.Type]bool)).Fields
+}
+
+// describe returns the shape of a type, stopping at recursive types
+func describe(t reflect.Type, visiting map[reflect.Type]bool) Field {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if _, ok := codec.Lookup(t); ok {
+		return Field{Type: TypeString}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return Field{Type: TypeString}
+	case reflect.Bool:
+		return Field{Type: TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Field{Type: TypeInteger}
+	case reflect.Float32, reflect.Float64:
+		return Field{Type: TypeNumber}
+	case reflect.Slice, reflect.Array:
+		items := describe(t.Elem(), visiting)
+		return Field{Type: TypeArray, Items: &items}
+	case reflect.Map:
+		return Field{Type: TypeObject}
+	case reflect.Struct:
+		if visiting[t] {
+			return Field{Type: TypeObject}
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		fields := make(map[string]Field)
+		for _, structField := range codec.Fields(t) {
+			field := describe(t.FieldByIndex(structField.Index).Type, visiting)
+			field.Required = isRequired(structField.Tag)
+			fields[structField.Name] = field
+		}
+		return Field{Type: TypeObject, Fields: fields}
+	}
+	return Field{Type: TypeAny}
+}
+
+// isRequired reports whether a field must be present
+func isRequired(tag reflect.StructTag) bool {
+	if tag.Get(controller.TagRequired) == controller.TagValueTrue {
+		return true
+	}
+	for _, rule := range strings.Split(tag.Get(controller.TagValidate), ",") {
+		if strings.TrimSpace(rule) == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// Change is a difference between a DTO and its baseline
+type Change struct {
+	DTO      string `json:"dto"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+	Breaking bool   `json:"breaking"`
+}
+
+func (c Change) String() string {
+	kind := "compatible"
+	if c.Breaking {
+		kind = "breaking"
+	}
+	return fmt.Sprintf("%s.%s: %s (%s)", c.DTO, c.Path, c.Message, kind)
+}
+
+// Diff lists the changes from the fields of old to the fields of current.
+// Removing a field, changing its type and adding or requiring a required
+// field break clients; adding an optional field does not.
+func Diff(dto string, old, current map[string]Field) []Change {
+	changes := make([]Change, 0)
+	diffFields(dto, "", old, current, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// diffFields compares two field sets under a path
+func diffFields(dto, path string, old, current map[string]Field, changes *[]Change) {
+	for name, oldField := range old {
+		fieldPath := joinPath(path, name)
+		currentField, exists := current[name]
+		if !exists {
+			*changes = append(*changes, Change{DTO: dto, Path: fieldPath, Message: "field removed", Breaking: true})
+			continue
+		}
+		diffField(dto, fieldPath, oldField, currentField, changes)
+	}
+
+	for name, currentField := range current {
+		if _, exists := old[name]; exists {
+			continue
+		}
+		fieldPath := joinPath(path, name)
+		if currentField.Required {
+			*changes = append(*changes, Change{DTO: dto, Path: fieldPath, Message: "required field added", Breaking: true})
+		} else {
+			*changes = append(*changes, Change{DTO: dto, Path: fieldPath, Message: "optional field added"})
+		}
+	}
+}
+
+// diffField compares a field present in both versions
+func diffField(dto, path string, old, current Field, changes *[]Change) {
+	if old.Type != current.Type {
+		*changes = append(*changes, Change{DTO: dto, Path: path, Message: fmt.Sprintf("type changed from %s to %s", old.Type, current.Type), Breaking: true})
+		return
+	}
+	if !old.Required && current.Required {
+		*changes = append(*changes, Change{DTO: dto, Path: path, Message: "field became required", Breaking: true})
+	}
+	if old.Required && !current.Required {
+		*changes = append(*changes, Change{DTO: dto, Path: path, Message: "field became optional"})
+	}
+
+	if old.Items != nil && current.Items != nil {
+		diffField(dto, path+"[]", *old.Items, *current.Items, changes)
+	}
+	diffFields(dto, path, old.Fields, current.Fields, changes)
+}
+
+// joinPath appends a field name to a path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// Baseline is the committed shape of every registered DTO, by name
+type Baseline map[string]Schema
+
+// ReadBaseline reads a baseline file
+func ReadBaseline(path string) (Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	baseline := make(Baseline)
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("schema: invalid baseline %s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+// WriteBaseline writes a baseline file, indented for review in diffs
+func WriteBaseline(path string, baseline Baseline) error {
+	data, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
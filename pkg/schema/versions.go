@@ -0,0 +1,297 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// HeaderVersion carries the DTO version a client sends and expects; without
+// it requests and responses use the current version
+const HeaderVersion = "X-Schema-Version"
+
+// Adapter converts a DTO between a version and the previous one
+type Adapter struct {
+	// Upgrade converts a request body of the previous version to this one
+	Upgrade func(body map[string]interface{}) (map[string]interface{}, error)
+	// Downgrade converts a response body of this version to the previous one
+	Downgrade func(body map[string]interface{}) (map[string]interface{}, error)
+}
+
+// dto is a registered DTO and its adapters, keyed by the version they
+// convert to
+type dto struct {
+	name     string
+	t        reflect.Type
+	version  int
+	adapters map[int]Adapter
+}
+
+var (
+	registryMutex sync.RWMutex
+	byName        = make(map[string]*dto)
+	byType        = make(map[reflect.Type]*dto)
+)
+
+// Register tracks a DTO at its current version, e.g.
+//
+//	var _ = schema.Register("User", User{}, 2)
+//
+// It returns true so it can be called from a package-level declaration.
+func Register(name string, value interface{}, version int) bool {
+	t := reflect.TypeOf(value)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("schema: DTO %s must be a struct, got %s", name, t))
+	}
+	if version < 1 {
+		panic(fmt.Sprintf("schema: DTO %s version must be at least 1", name))
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	adapters := make(map[int]Adapter)
+	if existing, ok := byName[name]; ok {
+		adapters = existing.adapters
+		delete(byType, existing.t)
+	}
+	entry := &dto{name: name, t: t, version: version, adapters: adapters}
+	byName[name] = entry
+	byType[t] = entry
+	return true
+}
+
+// Adapt registers the adapter converting a DTO between version-1 and
+// version, so clients of older versions keep being served. Like Register,
+// it returns true for package-level declarations.
+func Adapt(name string, version int, adapter Adapter) bool {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	entry, ok := byName[name]
+	if !ok {
+		entry = &dto{name: name, adapters: make(map[int]Adapter)}
+		byName[name] = entry
+	}
+	entry.adapters[version] = adapter
+	return true
+}
+
+// Registered returns the current schema of every registered DTO
+func Registered() Baseline {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	current := make(Baseline, len(byName))
+	for name, entry := range byName {
+		if entry.t == nil {
+			continue
+		}
+		current[name] = Schema{Version: entry.version, Fields: Describe(entry.t)}
+	}
+	return current
+}
+
+// CheckError lists the breaking changes found against a baseline
+type CheckError struct {
+	Problems []string
+}
+
+func (e *CheckError) Error() string {
+	return "incompatible DTO changes:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Check compares the registered DTOs to a baseline. A DTO may change
+// compatibly at the same version; breaking changes need a new version with
+// adapters from every baseline version, otherwise Check returns a
+// CheckError. The changes found are returned either way.
+func Check(baseline Baseline) ([]Change, error) {
+	current := Registered()
+	changes := make([]Change, 0)
+	problems := make([]string, 0)
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	for _, name := range sortedNames(baseline) {
+		old := baseline[name]
+		now, exists := current[name]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("%s: DTO removed", name))
+			continue
+		}
+
+		diff := Diff(name, old.Fields, now.Fields)
+		changes = append(changes, diff...)
+
+		switch {
+		case now.Version < old.Version:
+			problems = append(problems, fmt.Sprintf("%s: version %d is older than baseline version %d", name, now.Version, old.Version))
+		case now.Version == old.Version:
+			for _, change := range diff {
+				if change.Breaking {
+					problems = append(problems, fmt.Sprintf("%s; bump the version of %s and add an adapter", change, name))
+				}
+			}
+		default:
+			for version := old.Version + 1; version <= now.Version; version++ {
+				if _, ok := byName[name].adapters[version]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: no adapter from version %d to %d", name, version-1, version))
+				}
+			}
+		}
+	}
+
+	for _, name := range sortedNames(current) {
+		if _, exists := baseline[name]; !exists {
+			changes = append(changes, Change{DTO: name, Path: "*", Message: "DTO added"})
+		}
+	}
+
+	if len(problems) > 0 {
+		return changes, &CheckError{Problems: problems}
+	}
+	return changes, nil
+}
+
+// sortedNames returns the DTO names of a baseline in order
+func sortedNames(baseline Baseline) []string {
+	names := make([]string, 0, len(baseline))
+	for name := range baseline {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VersionError reports an unsupported requested version. It maps to
+// 400 Bad Request.
+type VersionError struct {
+	DTO     string
+	Version string
+	Reason  string
+}
+
+func (e *VersionError) Error() string {
+	return fmt.Sprintf("unsupported %s %q for %s: %s", HeaderVersion, e.Version, e.DTO, e.Reason)
+}
+
+// StatusCode returns the HTTP status code of version errors
+func (e *VersionError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+// UpgradeRequest converts a JSON request body sent at the version requested
+// by r to the current version of its DTO type
+func UpgradeRequest(r *http.Request, data []byte, t reflect.Type) ([]byte, error) {
+	return convert(r, data, t, func(entry *dto, from int, body map[string]interface{}) (map[string]interface{}, error) {
+		for version := from + 1; version <= entry.version; version++ {
+			adapter := entry.adapters[version]
+			if adapter.Upgrade == nil {
+				continue
+			}
+			var err error
+			if body, err = adapter.Upgrade(body); err != nil {
+				return nil, err
+			}
+		}
+		return body, nil
+	})
+}
+
+// DowngradeResponse converts a JSON response body of value, a DTO or a
+// slice of DTOs, to the version requested by r
+func DowngradeResponse(r *http.Request, data []byte, value interface{}) ([]byte, error) {
+	if value == nil {
+		return data, nil
+	}
+	return convert(r, data, reflect.TypeOf(value), func(entry *dto, to int, body map[string]interface{}) (map[string]interface{}, error) {
+		for version := entry.version; version > to; version-- {
+			adapter := entry.adapters[version]
+			if adapter.Downgrade == nil {
+				continue
+			}
+			var err error
+			if body, err = adapter.Downgrade(body); err != nil {
+				return nil, err
+			}
+		}
+		return body, nil
+	})
+}
+
+// convert applies step to the JSON objects of a DTO type, or of each element
+// of a DTO slice, when r requests an older version
+func convert(r *http.Request, data []byte, t reflect.Type, step func(*dto, int, map[string]interface{}) (map[string]interface{}, error)) ([]byte, error) {
+	requested := r.Header.Get(HeaderVersion)
+	if requested == "" || len(data) == 0 {
+		return data, nil
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	list := t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	if list {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+	}
+
+	registryMutex.RLock()
+	entry, ok := byType[t]
+	registryMutex.RUnlock()
+	if !ok {
+		return data, nil
+	}
+
+	version, err := strconv.Atoi(requested)
+	if err != nil || version < 1 {
+		return nil, &VersionError{DTO: entry.name, Version: requested, Reason: "not a version number"}
+	}
+	if version > entry.version {
+		return nil, &VersionError{DTO: entry.name, Version: requested, Reason: fmt.Sprintf("the current version is %d", entry.version)}
+	}
+	if version == entry.version {
+		return data, nil
+	}
+	for v := version + 1; v <= entry.version; v++ {
+		if _, ok := entry.adapters[v]; !ok {
+			return nil, &VersionError{DTO: entry.name, Version: requested, Reason: fmt.Sprintf("no adapter from version %d", v-1)}
+		}
+	}
+
+	if !list {
+		var body map[string]interface{}
+		if err := json.Unmarshal(data, &body); err != nil || body == nil {
+			return data, nil
+		}
+		if body, err = step(entry, version, body); err != nil {
+			return nil, err
+		}
+		return json.Marshal(body)
+	}
+
+	var bodies []map[string]interface{}
+	if err := json.Unmarshal(data, &bodies); err != nil {
+		return data, nil
+	}
+	for i, body := range bodies {
+		if body == nil {
+			continue
+		}
+		if bodies[i], err = step(entry, version, body); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(bodies)
+}
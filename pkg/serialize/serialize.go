@@ -0,0 +1,186 @@
+package serialize
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+)
+
+const (
+	// TagExclude set to "true" leaves a field out of responses while still
+	// binding it from request bodies, unlike json:"-":
+	// `json:"passwordHash" exclude:"true"`
+	TagExclude = "exclude"
+	// TagExpose lists the serialization groups a field is written for:
+	// `json:"email" expose:"admin,owner"`
+	TagExpose = "expose"
+	// TagGroups lists the serialization groups of a route:
+	// `route:"GET /:id" groups:"admin"`
+	TagGroups = "groups"
+)
+
+// groupsKey is the key of the serialization groups in a request context
+type groupsKey struct{}
+
+// WithGroups returns a copy of ctx adding serialization groups, e.g. from a
+// middleware granting the admin group to administrators
+func WithGroups(ctx context.Context, groups ...string) context.Context {
+	all := append(append([]string{}, GroupsFromContext(ctx)...), groups...)
+	return context.WithValue(ctx, groupsKey{}, all)
+}
+
+// GroupsFromContext returns the serialization groups of a request
+func GroupsFromContext(ctx context.Context) []string {
+	groups, _ := ctx.Value(groupsKey{}).([]string)
+	return groups
+}
+
+// ParseGroups parses a comma separated list of groups
+func ParseGroups(tag string) []string {
+	groups := make([]string, 0)
+	for _, group := range strings.Split(tag, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// Excluded reports whether a field is never written, see TagExclude
+func Excluded(tag reflect.StructTag) bool {
+	return tag.Get(TagExclude) == controller.TagValueTrue
+}
+
+// hidden reports whether a field is left out for the groups
+func hidden(tag reflect.StructTag, groups []string) bool {
+	if Excluded(tag) {
+		return true
+	}
+	exposed, ok := tag.Lookup(TagExpose)
+	if !ok {
+		return false
+	}
+	allowed := ParseGroups(exposed)
+	if len(allowed) == 0 {
+		return false
+	}
+	for _, group := range groups {
+		for _, candidate := range allowed {
+			if group == candidate {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	// hidesCache caches whether a type has fields hidden by tags
+	hidesCache sync.Map
+)
+
+// Hides reports whether values of type t may hold fields tagged exclude or
+// expose. Interface types may hold such values and are checked on their
+// dynamic value.
+func Hides(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if cached, ok := hidesCache.Load(t); ok {
+		return cached.(bool)
+	}
+
+	// Store false first so recursive types terminate
+	hidesCache.Store(t, false)
+	has := false
+	switch t.Kind() {
+	case reflect.Interface:
+		has = true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		has = Hides(t.Elem())
+	case reflect.Struct:
+		// Types encoding themselves do not write their fields
+		if t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType) {
+			break
+		}
+		for _, field := range codec.Fields(t) {
+			_, exposed := field.Tag.Lookup(TagExpose)
+			if exposed || Excluded(field.Tag) || Hides(t.FieldByIndex(field.Index).Type) {
+				has = true
+				break
+			}
+		}
+	}
+	hidesCache.Store(t, has)
+	return has
+}
+
+// Filter removes from the JSON encoding of value the fields excluded or not
+// exposed to the groups
+func Filter(data []byte, value interface{}, groups []string) ([]byte, error) {
+	if value == nil || !Hides(reflect.TypeOf(value)) {
+		return data, nil
+	}
+
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return json.Marshal(filter(reflect.ValueOf(value), tree, groups))
+}
+
+// filter walks a value alongside its decoded JSON tree, deleting the
+// properties of hidden fields
+func filter(v reflect.Value, node interface{}, groups []string) interface{} {
+	if !v.IsValid() || node == nil || !Hides(v.Type()) {
+		return node
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			filter(v.Elem(), node, groups)
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := node.([]interface{}); ok {
+			for i := 0; i < v.Len() && i < len(items); i++ {
+				filter(v.Index(i), items[i], groups)
+			}
+		}
+	case reflect.Map:
+		if entries, ok := node.(map[string]interface{}); ok {
+			iter := v.MapRange()
+			for iter.Next() {
+				if entry, ok := entries[fmt.Sprint(iter.Key().Interface())]; ok {
+					filter(iter.Value(), entry, groups)
+				}
+			}
+		}
+	case reflect.Struct:
+		if object, ok := node.(map[string]interface{}); ok {
+			for _, field := range codec.Fields(v.Type()) {
+				entry, ok := object[field.Name]
+				if !ok {
+					continue
+				}
+				if hidden(field.Tag, groups) {
+					delete(object, field.Name)
+					continue
+				}
+				filter(v.FieldByIndex(field.Index), entry, groups)
+			}
+		}
+	}
+	return node
+}
@@ -0,0 +1,476 @@
+package server
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+	"github.com/kevenmiano/nestgo/pkg/schema"
+	"github.com/kevenmiano/nestgo/pkg/union"
+	"github.com/kevenmiano/nestgo/pkg/upload"
+	"github.com/kevenmiano/nestgo/pkg/validation"
+)
+
+// timeType is bound with the time policy of the route
+var timeType = reflect.TypeOf(time.Time{})
+
+// textUnmarshalerType is used to bind parameters into custom types
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// argBinder builds one handler argument from the request
+type argBinder func(r *http.Request) (reflect.Value, error)
+
+// bindError is returned when a request cannot be bound to handler arguments
+type bindError struct {
+	status  int
+	message string
+}
+
+func (e *bindError) Error() string {
+	return e.message
+}
+
+// StatusCode returns the HTTP status code of the binding failure
+func (e *bindError) StatusCode() int {
+	return e.status
+}
+
+// newArgBinders maps the inputs of a handler function to request values.
+// Path parameters are bound positionally, in the order they appear in the path,
+// to scalar arguments; a struct with query tagged fields receives the query
+// string, and any other struct, map, slice or union argument the JSON body.
+// Values go through the route pipes before being converted to the argument type.
+func newArgBinders(funcType reflect.Type, paramNames []string, pipes argPipes) ([]argBinder, error) {
+	binders := make([]argBinder, 0, funcType.NumIn())
+	nextParam := 0
+	hasBody := false
+
+	for i := 0; i < funcType.NumIn(); i++ {
+		argType := funcType.In(i)
+
+		if argType == contextType {
+			binders = append(binders, contextBinder)
+			continue
+		}
+
+		if argType == fileType || argType == filesType {
+			binders = append(binders, uploadBinder(argType))
+			continue
+		}
+
+		if argType == principalType {
+			binders = append(binders, principalBinder)
+			continue
+		}
+
+		if isQueryType(argType) {
+			binder, err := queryBinder(argType, pipes)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d (%s): %w", i, argType, err)
+			}
+			binders = append(binders, binder)
+			continue
+		}
+
+		if isBodyType(argType) {
+			if hasBody {
+				return nil, fmt.Errorf("argument %d (%s): only one body argument is allowed", i, argType)
+			}
+			hasBody = true
+			binders = append(binders, bodyBinder(argType, pipes.forParam(pipe.BodyParam)))
+			continue
+		}
+
+		if nextParam >= len(paramNames) {
+			return nil, fmt.Errorf("argument %d (%s) has no matching path parameter", i, argType)
+		}
+
+		if !canConvertParam(argType) {
+			return nil, fmt.Errorf("path parameter %s cannot be bound to %s", paramNames[nextParam], argType)
+		}
+
+		binders = append(binders, pathParamBinder(paramNames[nextParam], argType, pipes.forParam(paramNames[nextParam])))
+		nextParam++
+	}
+
+	return binders, nil
+}
+
+// SourceUpload is the source of arguments receiving the uploaded files
+const SourceUpload pipe.Source = "upload"
+
+// Argument is a handler argument bound from the request
+type Argument struct {
+	// Source is where the value comes from: the path, the query string,
+	// the body or the uploaded files
+	Source pipe.Source
+	// Name is the path parameter of path arguments
+	Name string
+	// Type is the argument type, a struct with query tagged fields for
+	// query arguments
+	Type reflect.Type
+}
+
+// routeArguments describes the arguments of a handler bound from the
+// request, as newArgBinders binds them. Arguments provided by the
+// framework, such as *controller.Context, are left out.
+func routeArguments(funcType reflect.Type, paramNames []string) []Argument {
+	arguments := make([]Argument, 0, funcType.NumIn())
+	nextParam := 0
+	for i := 0; i < funcType.NumIn(); i++ {
+		argType := funcType.In(i)
+		switch {
+		case argType == contextType || argType == principalType:
+		case argType == fileType || argType == filesType:
+			arguments = append(arguments, Argument{Source: SourceUpload, Type: argType})
+		case isQueryType(argType):
+			arguments = append(arguments, Argument{Source: pipe.SourceQuery, Type: argType})
+		case isBodyType(argType):
+			arguments = append(arguments, Argument{Source: pipe.SourceBody, Name: pipe.BodyParam, Type: argType})
+		case nextParam < len(paramNames):
+			arguments = append(arguments, Argument{Source: pipe.SourcePath, Name: paramNames[nextParam], Type: argType})
+			nextParam++
+		}
+	}
+	return arguments
+}
+
+// contextType is the type of *controller.Context arguments
+var contextType = reflect.TypeOf((*controller.Context)(nil))
+
+// writerKey is the key used to store the response writer in the request context
+type writerKey struct{}
+
+// withResponseWriter returns a copy of r carrying the writer of its response
+func withResponseWriter(r *http.Request, w http.ResponseWriter) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), writerKey{}, w))
+}
+
+// fileType and filesType are the types of uploaded file arguments
+var (
+	fileType  = reflect.TypeOf((*upload.File)(nil))
+	filesType = reflect.TypeOf([]*upload.File(nil))
+)
+
+// takesUpload reports whether a handler declares uploaded file arguments
+func takesUpload(funcType reflect.Type) bool {
+	for i := 0; i < funcType.NumIn(); i++ {
+		if funcType.In(i) == fileType || funcType.In(i) == filesType {
+			return true
+		}
+	}
+	return false
+}
+
+// uploadBinder passes the files parsed by the upload middleware of the
+// route, the first one or nil to *upload.File arguments
+func uploadBinder(argType reflect.Type) argBinder {
+	return func(r *http.Request) (reflect.Value, error) {
+		files := upload.Files(r)
+		if argType == filesType {
+			return reflect.ValueOf(files), nil
+		}
+		if len(files) == 0 {
+			return reflect.Zero(fileType), nil
+		}
+		return reflect.ValueOf(files[0]), nil
+	}
+}
+
+// principalType is the type of authenticated principal arguments
+var principalType = reflect.TypeOf((*auth.Principal)(nil))
+
+// principalBinder passes the principal authenticated by the AuthGuard of
+// the route, or nil for routes without one
+func principalBinder(r *http.Request) (reflect.Value, error) {
+	principal, _ := auth.FromRequest(r)
+	return reflect.ValueOf(principal), nil
+}
+
+// contextBinder passes the Context of the request with a copy of the path
+// parameters matched by the router
+func contextBinder(r *http.Request) (reflect.Value, error) {
+	w, _ := r.Context().Value(writerKey{}).(http.ResponseWriter)
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	return reflect.ValueOf(controller.NewContext(w, r, params)), nil
+}
+
+// argsPool recycles the argument slices of handlers. The slices are only
+// read by reflect calls, which copy the arguments, so nothing references
+// them once the handler returned.
+var argsPool = sync.Pool{
+	New: func() interface{} {
+		args := make([]reflect.Value, 0, 4)
+		return &args
+	},
+}
+
+// releaseArgs returns the argument slice of a handler to the pool once the
+// handler returned, dropping the arguments it holds
+func releaseArgs(args []reflect.Value) {
+	clear(args)
+	args = args[:0]
+	argsPool.Put(&args)
+}
+
+// isBodyType reports whether an argument type is bound from the request body
+func isBodyType(t reflect.Type) bool {
+	if _, ok := codec.Lookup(t); ok || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		return true
+	case reflect.Ptr:
+		return t.Elem().Kind() == reflect.Struct
+	case reflect.Interface:
+		return union.IsUnion(t)
+	}
+	return false
+}
+
+// bodyBinder decodes the JSON request body into the argument type and
+// validates it when the type declares validate tags. Union values decode
+// into the variant named by their discriminator, timestamps are parsed
+// with the time policy of the route and bodies of older DTO versions are
+// upgraded. An empty body binds the zero value.
+func bodyBinder(argType reflect.Type, pipes []pipe.Pipe) argBinder {
+	metadata := pipe.ArgumentMetadata{Source: pipe.SourceBody, Name: pipe.BodyParam, Type: argType}
+	validate := validation.HasRules(argType)
+	decode := func(data []byte, target interface{}) error {
+		return json.Unmarshal(data, target)
+	}
+	if union.Contains(argType) {
+		decode = union.Unmarshal
+	}
+
+	return func(r *http.Request) (reflect.Value, error) {
+		target := reflect.New(argType)
+		if argType.Kind() == reflect.Ptr {
+			target.Elem().Set(reflect.New(argType.Elem()))
+		}
+
+		if r.Body != nil {
+			var raw json.RawMessage
+			err := json.NewDecoder(r.Body).Decode(&raw)
+			if err == nil {
+				raw, err = codec.TimePolicyFromContext(r.Context()).NormalizeJSON(raw, argType)
+			}
+			if err == nil {
+				raw, err = schema.UpgradeRequest(r, raw, argType)
+			}
+			if err == nil {
+				err = decode(raw, target.Interface())
+			}
+			var versionErr *schema.VersionError
+			if errors.As(err, &versionErr) {
+				return reflect.Value{}, err
+			}
+			// Limit errors of the body keep their status, e.g. 413
+			var coder statusCoder
+			if errors.As(err, &coder) {
+				return reflect.Value{}, err
+			}
+			if err != nil && !errors.Is(err, io.EOF) {
+				return reflect.Value{}, &bindError{
+					status:  http.StatusBadRequest,
+					message: "Invalid request body: " + err.Error(),
+				}
+			}
+		}
+
+		value := target.Elem()
+		if len(pipes) > 0 {
+			piped, err := applyPipes(value.Interface(), metadata, pipes)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			value = piped
+		}
+
+		// Enforce validate tags before the handler runs
+		if validate {
+			if err := validation.Validate(value.Interface()); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		return value, nil
+	}
+}
+
+// pathParamBinder binds a path parameter converted to the argument type
+func pathParamBinder(name string, argType reflect.Type, pipes []pipe.Pipe) argBinder {
+	metadata := pipe.ArgumentMetadata{Source: pipe.SourcePath, Name: name, Type: argType}
+
+	return func(r *http.Request) (reflect.Value, error) {
+		raw := PathParam(r, name)
+
+		if len(pipes) > 0 {
+			return applyPipes(raw, metadata, pipes)
+		}
+
+		var value reflect.Value
+		var err error
+		if argType == timeType {
+			var parsed time.Time
+			parsed, err = codec.TimePolicyFromContext(r.Context()).Parse(raw)
+			value = reflect.ValueOf(parsed)
+		} else {
+			value, err = convertParam(raw, argType)
+		}
+		if err != nil {
+			return reflect.Value{}, &bindError{
+				status:  http.StatusBadRequest,
+				message: fmt.Sprintf("Invalid value for parameter %s: %q", name, raw),
+			}
+		}
+		return value, nil
+	}
+}
+
+// applyPipes runs the pipes over a value and converts the result to the argument type
+func applyPipes(value interface{}, metadata pipe.ArgumentMetadata, pipes []pipe.Pipe) (reflect.Value, error) {
+	transformed, err := pipe.Apply(value, metadata, pipes)
+	if err != nil {
+		var coder statusCoder
+		if errors.As(err, &coder) {
+			return reflect.Value{}, err
+		}
+		return reflect.Value{}, &bindError{status: http.StatusBadRequest, message: err.Error()}
+	}
+
+	converted, err := toArgValue(transformed, metadata.Type)
+	if err != nil {
+		return reflect.Value{}, &bindError{
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("Invalid value for parameter %s: %v", metadata.Name, transformed),
+		}
+	}
+	return converted, nil
+}
+
+// toArgValue converts a pipe result to the argument type. Strings are
+// parsed like raw parameters; numbers convert between numeric kinds.
+func toArgValue(value interface{}, argType reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(argType), nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Type().AssignableTo(argType) {
+		return v, nil
+	}
+	if raw, ok := value.(string); ok {
+		return convertParam(raw, argType)
+	}
+	if isNumeric(v.Kind()) && isNumeric(argType.Kind()) {
+		return v.Convert(argType), nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %s", value, argType)
+}
+
+// isNumeric reports whether a kind is an integer or float kind
+func isNumeric(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// canConvertParam reports whether a string parameter can be converted to t
+func canConvertParam(t reflect.Type) bool {
+	if _, ok := codec.Lookup(t); ok || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// convertParam converts a raw string parameter to a value of type t,
+// preferring the codec registered for t
+func convertParam(raw string, t reflect.Type) (reflect.Value, error) {
+	value := reflect.New(t).Elem()
+
+	if c, ok := codec.Lookup(t); ok {
+		parsed, err := c.Parse(raw)
+		if err != nil {
+			return value, err
+		}
+		return reflect.ValueOf(parsed), nil
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+		return value, err
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return value, err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, t.Bits())
+		if err != nil {
+			return value, err
+		}
+		value.SetFloat(parsed)
+	default:
+		return value, fmt.Errorf("unsupported parameter type %s", t)
+	}
+
+	return value, nil
+}
+
+// bindArgs builds the handler arguments for a request
+func bindArgs(r *http.Request, binders []argBinder) ([]reflect.Value, error) {
+	args := (*argsPool.Get().(*[]reflect.Value))[:0]
+	for _, binder := range binders {
+		arg, err := binder(r)
+		if err != nil {
+			releaseArgs(args)
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return args, nil
+}
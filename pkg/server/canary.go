@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/kevenmiano/nestgo/pkg/canary"
+	"github.com/kevenmiano/nestgo/pkg/interceptor"
+)
+
+// canaryHandler returns the handler of a route declaring a canary field: the
+// canary field serves requests on the canary track and the route field the
+// others. Routes without the tag are returned unchanged.
+func (s *Server) canaryHandler(controllerType reflect.Type, controllerValue reflect.Value, spec routeSpec, stable http.HandlerFunc, binders []argBinder, interceptors []interceptor.Interceptor) (http.HandlerFunc, error) {
+	name := spec.field.Tag.Get(canary.TagCanary)
+	if name == "" {
+		return stable, nil
+	}
+
+	field, ok := controllerType.FieldByName(name)
+	if !ok {
+		return nil, fmt.Errorf("invalid %s tag: field %s not found on %s", canary.TagCanary, name, controllerType.Name())
+	}
+	if field.Type != spec.field.Type {
+		return nil, fmt.Errorf("invalid %s tag: field %s is %s, expected %s", canary.TagCanary, name, field.Type, spec.field.Type)
+	}
+
+	method, err := routeMethod(controllerValue, controllerRouteMethods(controllerValue)[name], controllerValue.FieldByIndex(field.Index))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tag: %w", canary.TagCanary, err)
+	}
+
+	alternate := s.createHandlerWithField(controllerValue.FieldByIndex(field.Index), controllerValue, method, binders, interceptors)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if canary.IsCanary(r.Context()) {
+			alternate(w, r)
+			return
+		}
+		stable(w, r)
+	}, nil
+}
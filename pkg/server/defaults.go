@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/guard"
+	"github.com/kevenmiano/nestgo/pkg/interceptor"
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/ratelimit"
+)
+
+// routeDefaults are the resolved route defaults of a module
+type routeDefaults struct {
+	prefix       string
+	guards       []guard.Guard
+	interceptors []interceptor.Interceptor
+	throttle     string
+	server       string
+}
+
+// moduleRouteDefaults resolves the prefix, guards, interceptors, throttle
+// and server declared in a module configuration
+func (s *Server) moduleRouteDefaults(moduleName string) (routeDefaults, error) {
+	moduleInstance, err := module.GetGlobalRegistry().GetModule(moduleName)
+	if err != nil {
+		return routeDefaults{}, nil
+	}
+	declared := module.RouteDefaultsOf(moduleInstance)
+
+	defaults := routeDefaults{prefix: declared.Prefix, throttle: declared.Throttle, server: declared.Server}
+	if _, err := s.routerFor(defaults.server); err != nil {
+		return routeDefaults{}, fmt.Errorf("module %s: %w", moduleName, err)
+	}
+	for _, entry := range declared.Guards {
+		g, err := s.resolveDeclaredGuard(entry)
+		if err != nil {
+			return routeDefaults{}, fmt.Errorf("module %s: %w", moduleName, err)
+		}
+		defaults.guards = append(defaults.guards, g)
+	}
+	for _, entry := range declared.Interceptors {
+		i, err := s.resolveDeclaredInterceptor(entry)
+		if err != nil {
+			return routeDefaults{}, fmt.Errorf("module %s: %w", moduleName, err)
+		}
+		defaults.interceptors = append(defaults.interceptors, i)
+	}
+	if defaults.throttle != "" {
+		if _, err := ratelimit.ParseLimit(defaults.throttle); err != nil {
+			return routeDefaults{}, fmt.Errorf("module %s: invalid throttle: %w", moduleName, err)
+		}
+	}
+	return defaults, nil
+}
+
+// resolveDeclaredGuard converts a declared guard, a guard.Guard or the name
+// of a registered guard, into a guard
+func (s *Server) resolveDeclaredGuard(declared interface{}) (guard.Guard, error) {
+	switch g := declared.(type) {
+	case guard.Guard:
+		return g, nil
+	case string:
+		return s.resolveGuard(g)
+	default:
+		return nil, fmt.Errorf("unsupported guard type %T", declared)
+	}
+}
+
+// resolveDeclaredInterceptor converts a declared interceptor, an
+// interceptor.Interceptor or the name of a registered interceptor, into an
+// interceptor
+func (s *Server) resolveDeclaredInterceptor(declared interface{}) (interceptor.Interceptor, error) {
+	switch i := declared.(type) {
+	case interceptor.Interceptor:
+		return i, nil
+	case string:
+		return s.resolveInterceptor(i)
+	default:
+		return nil, fmt.Errorf("unsupported interceptor type %T", declared)
+	}
+}
+
+// prefixPath prepends a module prefix to the base path of a controller
+func prefixPath(prefix, basePath string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return basePath
+	}
+	return "/" + prefix + "/" + strings.TrimPrefix(basePath, "/")
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/container"
+	"github.com/kevenmiano/nestgo/pkg/guard"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+)
+
+// SetContainer sets the DI container used to resolve guards, interceptors,
+// pipes and middlewares declared by name
+func (s *Server) SetContainer(c *container.Container) {
+	s.container = c
+}
+
+// RegisterGuard names a guard so it can be referenced by guards tags
+func (s *Server) RegisterGuard(name string, g guard.Guard) {
+	if s.namedGuards == nil {
+		s.namedGuards = make(map[string]guard.Guard)
+	}
+	s.namedGuards[name] = g
+	logger.Info("Guard registered", "name", name)
+}
+
+// UseGuards adds guards evaluated for every route, before controller and route guards
+func (s *Server) UseGuards(guards ...guard.Guard) {
+	s.globalGuards = append(s.globalGuards, guards...)
+}
+
+// resolveGuard finds a guard by name among registered guards and DI providers
+func (s *Server) resolveGuard(name string) (guard.Guard, error) {
+	if g, ok := s.namedGuards[name]; ok {
+		return g, nil
+	}
+
+	if s.container != nil {
+		if provider, ok := s.container.Get(name); ok {
+			g, ok := provider.(guard.Guard)
+			if !ok {
+				return nil, fmt.Errorf("provider %s does not implement CanActivate", name)
+			}
+			return g, nil
+		}
+	}
+
+	return nil, fmt.Errorf("guard %q is not registered", name)
+}
+
+// routeDescriptor describes a controller route for handlers, guards and
+// interceptors, see guard.RouteFromRequest
+func routeDescriptor(controllerType reflect.Type, spec routeSpec, ownership module.Ownership) guard.Route {
+	name := strings.TrimSpace(spec.field.Tag.Get(guard.TagName))
+	if name == "" {
+		name = controllerType.Name() + "." + spec.field.Name
+	}
+	return guard.Route{
+		Name:       name,
+		Module:     ownership.Module,
+		Controller: controllerType.Name(),
+		Handler:    spec.field.Name,
+		Method:     spec.httpMethod,
+		Path:       spec.fullPath,
+		Tag:        spec.field.Tag,
+	}
+}
+
+// routeMiddleware exposes the route through the request context, see
+// guard.RouteFromRequest
+func routeMiddleware(route guard.Route) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, guard.WithRoute(r, route))
+		})
+	}
+}
+
+// routeGuards builds the guard middleware of a route, evaluating global,
+// module, controller and route guards in that order. It returns nil when
+// the route has no guards.
+func (s *Server) routeGuards(controllerType reflect.Type, route guard.Route, moduleGuards []guard.Guard) (func(http.Handler) http.Handler, error) {
+	names := make([]string, 0)
+	if field, found := controllerType.FieldByName("BaseController"); found {
+		names = append(names, guard.ParseNames(field.Tag.Get(guard.TagGuards))...)
+	}
+	names = append(names, guard.ParseNames(route.Tag.Get(guard.TagGuards))...)
+
+	if len(s.globalGuards) == 0 && len(moduleGuards) == 0 && len(names) == 0 {
+		return nil, nil
+	}
+
+	guards := append([]guard.Guard{}, s.globalGuards...)
+	guards = append(guards, moduleGuards...)
+	for _, name := range names {
+		g, err := s.resolveGuard(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag: %w", guard.TagGuards, err)
+		}
+		guards = append(guards, g)
+	}
+
+	return guard.Middleware(route, guards...), nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// host is a named server hosting the routes of the modules declaring it,
+// e.g. an admin API on its own port
+type host struct {
+	router  Router
+	handler http.Handler
+	server  *http.Server
+}
+
+// routerFactory is implemented by routing backends creating empty routers
+// of their kind, so hosts route like the main server
+type routerFactory interface {
+	newRouter() Router
+}
+
+// newRouterLike returns an empty router of the kind of router, the default
+// backend for custom backends
+func newRouterLike(router Router) Router {
+	if factory, ok := router.(routerFactory); ok {
+		return factory.newRouter()
+	}
+	logger.Warn("Custom router cannot create host routers, using the default backend", "router", fmt.Sprintf("%T", router))
+	return defaultRouter()
+}
+
+// AddHost declares a named server for the modules naming it in their
+// configuration. Its routes run the global middlewares, guards,
+// interceptors and pipes of the main server, and share its container.
+func (s *Server) AddHost(name string) {
+	if s.hosts == nil {
+		s.hosts = make(map[string]*host)
+	}
+	h := &host{router: newRouterLike(s.router)}
+	h.handler = s.withSettings(chain(h.router, s.middlewares))
+	s.hosts[name] = h
+}
+
+// Hosts returns the names of the declared hosts, sorted
+func (s *Server) Hosts() []string {
+	names := make([]string, 0, len(s.hosts))
+	for name := range s.hosts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// routerFor returns the router of a host, the main router for the empty name
+func (s *Server) routerFor(name string) (Router, error) {
+	if name == "" {
+		return s.router, nil
+	}
+	h, ok := s.hosts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown server %q", name)
+	}
+	return h.router, nil
+}
+
+// ServeHost starts a named host on a listener. Shutdown closes it with the
+// main server.
+func (s *Server) ServeHost(name string, listener net.Listener) error {
+	h, ok := s.hosts[name]
+	if !ok {
+		listener.Close()
+		return fmt.Errorf("unknown server %q", name)
+	}
+
+	s.serversMu.Lock()
+	h.server = s.newHTTPServer(listener, h.handler)
+	s.serversMu.Unlock()
+
+	logger.Info("Server starting", "server", name, "address", listener.Addr().String(), "network", listener.Addr().Network())
+	return h.server.Serve(listener)
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// lockerType detects fields holding a lock by value
+var lockerType = reflect.TypeOf((*sync.Locker)(nil)).Elem()
+
+// controllerInstance gives each request the controller its handler runs on.
+// Handlers naming an exported method of a controller embedding
+// BaseController run on a per-request copy of the controller, the method
+// being called with the copy as its receiver, so concurrent requests never
+// share the HTTP context of BaseController. Other handlers, such as
+// closures and unexported method values, cannot be rebound to a copy:
+// unless they take a *controller.Context, they run on the shared controller
+// one request at a time, with its HTTP context set.
+type controllerInstance struct {
+	shared  reflect.Value
+	handler reflect.Value
+	// method is the method expression called on the copies, taking the
+	// receiver as its first argument; invalid for handlers on the shared
+	// controller
+	method reflect.Value
+	// lock is held while handlers run on the shared controller, and read
+	// locked while copying it
+	lock *sync.RWMutex
+	// serialized is true for handlers running on the shared controller
+	// with its HTTP context
+	serialized bool
+}
+
+// newControllerInstance prepares the instances of the controller serving
+// handler, controllerValue being the addressable controller struct and
+// method the name of the exported method of the route, empty for functions
+func (s *Server) newControllerInstance(controllerValue, handler reflect.Value, method string) *controllerInstance {
+	instance := &controllerInstance{shared: controllerValue, handler: handler}
+	if _, ok := controllerValue.Type().FieldByName("BaseController"); !ok {
+		return instance
+	}
+
+	instance.lock = s.controllerLock(controllerValue)
+	if method != "" {
+		m, _ := controllerValue.Addr().Type().MethodByName(method)
+		instance.method = m.Func
+		return instance
+	}
+
+	// Handlers receiving a Context don't rely on the HTTP context of the
+	// controller
+	if takesContext(handler.Type()) {
+		return instance
+	}
+
+	instance.serialized = true
+	s.warnSerialized(controllerValue)
+	return instance
+}
+
+// takesContext reports whether a handler declares a *controller.Context argument
+func takesContext(funcType reflect.Type) bool {
+	for i := 0; i < funcType.NumIn(); i++ {
+		if funcType.In(i) == contextType {
+			return true
+		}
+	}
+	return false
+}
+
+// serve calls fn with the function calling the handler on the controller
+// serving the request: a copy of the controller holding the HTTP context
+// for handler methods, the shared controller otherwise, holding its lock
+// while its HTTP context is set
+func (ci *controllerInstance) serve(s *Server, w http.ResponseWriter, r *http.Request, fn func(call func(args []reflect.Value) []reflect.Value)) {
+	if ci.serialized {
+		ci.lock.Lock()
+		defer ci.lock.Unlock()
+		s.setHTTPContext(ci.shared, w, r)
+	}
+	if !ci.method.IsValid() {
+		fn(ci.handler.Call)
+		return
+	}
+
+	clone := reflect.New(ci.shared.Type())
+	ci.lock.RLock()
+	clone.Elem().Set(ci.shared)
+	ci.lock.RUnlock()
+	s.setHTTPContext(clone.Elem(), w, r)
+	fn(func(args []reflect.Value) []reflect.Value {
+		in := make([]reflect.Value, 0, len(args)+1)
+		in = append(in, clone)
+		return ci.method.Call(append(in, args...))
+	})
+}
+
+// controllerLock returns the lock of a controller, shared by its routes
+func (s *Server) controllerLock(controllerValue reflect.Value) *sync.RWMutex {
+	lock, _ := s.controllerLocks.LoadOrStore(controllerValue.Addr().Pointer(), &sync.RWMutex{})
+	return lock.(*sync.RWMutex)
+}
+
+// warnSerialized warns once per controller that some of its handlers run
+// one request at a time
+func (s *Server) warnSerialized(controllerValue reflect.Value) {
+	if _, warned := s.serializedControllers.LoadOrStore(controllerValue.Addr().Pointer(), true); warned {
+		return
+	}
+	logger.Warn("Controller handlers are not exported methods and run one request at a time; bind them to exported methods with nestgo bind or take a *controller.Context argument to serve requests concurrently",
+		"controller", controllerValue.Type().Name())
+}
+
+// routeMethod returns the name of the exported method serving a route of a
+// controller embedding BaseController, empty when the route is served by a
+// function. name is the method named by RouteMethods or Route; when empty,
+// a handler that is a method value of an exported method is resolved to it.
+// The method must match the signature of the route, and the controller must
+// be safe to copy for each request.
+func routeMethod(controllerValue reflect.Value, name string, handler reflect.Value) (string, error) {
+	if name == "" {
+		return boundMethod(controllerValue, handler), nil
+	}
+	funcType := handler.Type()
+	controllerType := controllerValue.Type()
+	if _, ok := controllerType.FieldByName("BaseController"); !ok {
+		return "", nil
+	}
+
+	method, ok := reflect.PointerTo(controllerType).MethodByName(name)
+	if !ok {
+		return "", fmt.Errorf("%s has no exported method %s", controllerType.Name(), name)
+	}
+	// The method value drops the receiver from the signature
+	if bound := controllerValue.Addr().Method(method.Index).Type(); bound != funcType {
+		return "", fmt.Errorf("method %s.%s is %s, expected %s", controllerType.Name(), name, bound, funcType)
+	}
+	if field := lockField(controllerType, controllerType.Name()); field != "" {
+		return "", fmt.Errorf("%s holds a lock by value in %s and cannot be copied for each request; hold it by pointer or in a provider", controllerType.Name(), field)
+	}
+	return name, nil
+}
+
+// boundMethod returns the name of the exported method of the controller a
+// handler is a method value of, e.g. GetUser for c.GetUser, matching the
+// handler function against the functions of the controller methods. It
+// returns "" for other handlers, for mismatched signatures and for
+// controllers that cannot be copied, which run on the shared controller.
+func boundMethod(controllerValue reflect.Value, handler reflect.Value) string {
+	controllerType := controllerValue.Type()
+	if _, ok := controllerType.FieldByName("BaseController"); !ok {
+		return ""
+	}
+	if handler.Kind() != reflect.Func || handler.IsNil() {
+		return ""
+	}
+	fn := runtime.FuncForPC(handler.Pointer())
+	if fn == nil {
+		return ""
+	}
+
+	// Method values are compiled to a wrapper of the method function, named
+	// after it
+	for _, receiverType := range []reflect.Type{reflect.PointerTo(controllerType), controllerType} {
+		for i := 0; i < receiverType.NumMethod(); i++ {
+			method := receiverType.Method(i)
+			methodFn := runtime.FuncForPC(method.Func.Pointer())
+			if methodFn == nil || fn.Name() != methodFn.Name()+"-fm" {
+				continue
+			}
+			if controllerValue.Addr().MethodByName(method.Name).Type() != handler.Type() {
+				return ""
+			}
+			if lockField(controllerType, controllerType.Name()) != "" {
+				return ""
+			}
+			return method.Name
+		}
+	}
+	return ""
+}
+
+// lockField returns the path of a field of a struct holding a lock by
+// value, such as a sync.Mutex or an atomic value, which copying the struct
+// would copy
+func lockField(structType reflect.Type, path string) string {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if found := lockIn(field.Type, path+"."+field.Name); found != "" {
+			return found
+		}
+	}
+	return ""
+}
+
+// lockIn returns path when a value of fieldType holds a lock by value
+func lockIn(fieldType reflect.Type, path string) string {
+	if reflect.PointerTo(fieldType).Implements(lockerType) {
+		return path
+	}
+	switch fieldType.Kind() {
+	case reflect.Struct:
+		return lockField(fieldType, path)
+	case reflect.Array:
+		return lockIn(fieldType.Elem(), path+"[]")
+	}
+	return ""
+}
+
+// controllerRouteMethods returns the method names of the route fields of a
+// controller implementing controller.RouteMethodProvider
+func controllerRouteMethods(controllerValue reflect.Value) map[string]string {
+	if !controllerValue.CanAddr() {
+		return nil
+	}
+	if provider, ok := controllerValue.Addr().Interface().(controller.RouteMethodProvider); ok {
+		return provider.RouteMethods()
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// copyController declares a route per kind of handler
+type copyController struct {
+	controller.BaseController `baseUrl:"/copies"`
+
+	Closure    func(id int)                          `route:"GET /closure/:id"`
+	Unexported func(id int)                          `route:"GET /unexported/:id"`
+	Named      func(id int)                          `route:"GET /named/:id"`
+	WithCtx    func(ctx *controller.Context, id int) `route:"GET /context/:id"`
+	Exported   func(id int)                          `route:"GET /exported/:id"`
+}
+
+// ExportedHandler echoes the request path through BaseController
+func (c *copyController) ExportedHandler(id int) {
+	c.JSON(map[string]string{"path": c.Request.URL.Path})
+}
+
+// MethodValue echoes the request path through BaseController
+func (c *copyController) MethodValue(id int) {
+	c.JSON(map[string]string{"path": c.Request.URL.Path})
+}
+
+// NamedHandler echoes the request path through BaseController
+func (c *copyController) NamedHandler(id int) {
+	c.JSON(map[string]string{"path": c.Request.URL.Path})
+}
+
+func (c *copyController) unexported(id int) {
+	c.JSON(map[string]string{"path": c.Request.URL.Path})
+}
+
+// RouteMethods names the handler method of Named
+func (c *copyController) RouteMethods() map[string]string {
+	return map[string]string{"Named": "NamedHandler"}
+}
+
+// quietLogger discards the logs of a test
+func quietLogger(t testing.TB) {
+	t.Helper()
+	previous := logger.Logger
+	logger.Logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	t.Cleanup(func() { logger.Logger = previous })
+}
+
+func newCopyServer(t *testing.T) *Server {
+	t.Helper()
+	quietLogger(t)
+
+	c := &copyController{}
+	c.Closure = func(id int) {
+		c.JSON(map[string]string{"path": c.Request.URL.Path})
+	}
+	c.Unexported = c.unexported
+	c.Named = c.NamedHandler
+	c.Exported = c.ExportedHandler
+	c.Route("GET /method-value/:id", c.MethodValue)
+	c.Route("GET /method-name/:id", "MethodValue")
+	c.WithCtx = func(ctx *controller.Context, id int) {
+		ctx.JSON(http.StatusOK, map[string]string{"path": ctx.Request.URL.Path})
+	}
+
+	s := NewServer()
+	s.RegisterController("CopyModule", c, "/copies")
+	return s
+}
+
+func TestHandlersServeTheirOwnRequest(t *testing.T) {
+	s := newCopyServer(t)
+
+	tests := []struct {
+		name  string
+		route string
+	}{
+		{"closure", "/copies/closure"},
+		{"unexported method", "/copies/unexported"},
+		{"named method", "/copies/named"},
+		{"context", "/copies/context"},
+		{"exported method value", "/copies/exported"},
+		{"Route method value", "/copies/method-value"},
+		{"Route method name", "/copies/method-name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					path := fmt.Sprintf("%s/%d", tt.route, i)
+					w := httptest.NewRecorder()
+					s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+					if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"path":"`+path+`"`) {
+						t.Errorf("GET %s answered %d %s", path, w.Code, w.Body.String())
+					}
+				}(i)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestBoundMethod(t *testing.T) {
+	c := &copyController{}
+	controllerValue := reflect.ValueOf(c).Elem()
+
+	tests := []struct {
+		name    string
+		handler interface{}
+		want    string
+	}{
+		{"exported method value", c.ExportedHandler, "ExportedHandler"},
+		{"promoted method value", c.GetControllerName, ""},
+		{"unexported method value", c.unexported, ""},
+		{"closure", func(id int) {}, ""},
+		{"nil", (func(id int))(nil), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := boundMethod(controllerValue, reflect.ValueOf(tt.handler)); got != tt.want {
+				t.Errorf("boundMethod() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// lockedController holds a lock by value, so it cannot be copied
+type lockedController struct {
+	controller.BaseController `baseUrl:"/locked"`
+	mutex                     sync.Mutex
+
+	Get func() `route:"GET /"`
+}
+
+// GetHandler serves the route
+func (c *lockedController) GetHandler() {}
+
+// RouteMethods names the handler method of Get
+func (c *lockedController) RouteMethods() map[string]string {
+	return map[string]string{"Get": "GetHandler"}
+}
+
+func TestControllerHoldingLockIsRejected(t *testing.T) {
+	quietLogger(t)
+
+	c := &lockedController{}
+	c.Get = c.GetHandler
+	s := NewServer()
+	s.RegisterController("LockedModule", c, "/locked")
+
+	if routes := s.Routes(); len(routes) != 0 {
+		t.Fatalf("registered %d routes, want none", len(routes))
+	}
+}
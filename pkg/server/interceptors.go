@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kevenmiano/nestgo/pkg/interceptor"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// RegisterInterceptor names an interceptor so it can be referenced by interceptors tags
+func (s *Server) RegisterInterceptor(name string, i interceptor.Interceptor) {
+	if s.namedInterceptors == nil {
+		s.namedInterceptors = make(map[string]interceptor.Interceptor)
+	}
+	s.namedInterceptors[name] = i
+	logger.Info("Interceptor registered", "name", name)
+}
+
+// UseInterceptors adds interceptors wrapping every route, outside controller and route interceptors
+func (s *Server) UseInterceptors(interceptors ...interceptor.Interceptor) {
+	s.globalInterceptors = append(s.globalInterceptors, interceptors...)
+}
+
+// resolveInterceptor finds an interceptor by name among registered interceptors and DI providers
+func (s *Server) resolveInterceptor(name string) (interceptor.Interceptor, error) {
+	if i, ok := s.namedInterceptors[name]; ok {
+		return i, nil
+	}
+
+	if s.container != nil {
+		if provider, ok := s.container.Get(name); ok {
+			i, ok := provider.(interceptor.Interceptor)
+			if !ok {
+				return nil, fmt.Errorf("provider %s does not implement Intercept", name)
+			}
+			return i, nil
+		}
+	}
+
+	return nil, fmt.Errorf("interceptor %q is not registered", name)
+}
+
+// routeInterceptors returns the global, module, controller and route interceptors of a route, in that order
+func (s *Server) routeInterceptors(controllerType reflect.Type, spec routeSpec, moduleInterceptors []interceptor.Interceptor) ([]interceptor.Interceptor, error) {
+	names := make([]string, 0)
+	if field, found := controllerType.FieldByName("BaseController"); found {
+		names = append(names, interceptor.ParseNames(field.Tag.Get(interceptor.TagInterceptors))...)
+	}
+	names = append(names, interceptor.ParseNames(spec.field.Tag.Get(interceptor.TagInterceptors))...)
+
+	interceptors := append([]interceptor.Interceptor{}, s.globalInterceptors...)
+	interceptors = append(interceptors, moduleInterceptors...)
+	for _, name := range names {
+		i, err := s.resolveInterceptor(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag: %w", interceptor.TagInterceptors, err)
+		}
+		interceptors = append(interceptors, i)
+	}
+
+	return interceptors, nil
+}
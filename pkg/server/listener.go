@@ -0,0 +1,75 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UnixScheme prefixes the addresses of Unix domain sockets, e.g.
+// "unix:///run/app.sock"
+const UnixScheme = "unix://"
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+// Listen listens on a TCP address such as ":3000", or on a Unix domain socket
+// for addresses such as "unix:///run/app.sock". A stale socket file left by
+// a previous run is removed; the socket file is removed again once the
+// listener is closed.
+func Listen(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, UnixScheme)
+	if !ok {
+		if address == "" {
+			address = ":http"
+		}
+		return net.Listen("tcp", address)
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("missing socket path in address %q", address)
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale socket %s: %w", path, err)
+		}
+	}
+	return net.Listen("unix", path)
+}
+
+// ActivationListeners returns the sockets passed by systemd socket
+// activation, in the order of the socket unit, or none when the process was
+// not socket activated
+func ActivationListeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	// The sockets are not passed on to child processes
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	var errs []error
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("socket activation fd %d: %w", fd, err))
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, errors.Join(errs...)
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+)
+
+// TagMiddleware lists named middlewares applied to a controller (on its
+// BaseController field) or to a route field: `middleware:"auth,logging"`
+const TagMiddleware = "middleware"
+
+// Middleware processes a request before it reaches the route handler, like a
+// NestJS middleware. Calling next continues the pipeline; not calling it
+// short-circuits the request and the middleware is expected to respond.
+type Middleware interface {
+	Use(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+}
+
+// MiddlewareFunc adapts a function to the Middleware interface
+type MiddlewareFunc func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc)
+
+// Use calls f(w, r, next)
+func (f MiddlewareFunc) Use(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	f(w, r, next)
+}
+
+// Adapt converts a Middleware into a net/http middleware
+func Adapt(middleware Middleware) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			middleware.Use(w, r, next.ServeHTTP)
+		})
+	}
+}
+
+// moduleMiddlewareProvider is implemented by modules declaring middlewares
+type moduleMiddlewareProvider interface {
+	GetMiddlewares() []interface{}
+}
+
+// RegisterMiddleware names a middleware so it can be referenced by middleware tags
+func (s *Server) RegisterMiddleware(name string, middleware Middleware) {
+	if s.namedMiddlewares == nil {
+		s.namedMiddlewares = make(map[string]Middleware)
+	}
+	s.namedMiddlewares[name] = middleware
+	logger.Info("Middleware registered", "name", name)
+}
+
+// UseMiddleware adds middlewares applied to every route, in order
+func (s *Server) UseMiddleware(middlewares ...Middleware) {
+	for _, middleware := range middlewares {
+		s.Use(Adapt(middleware))
+	}
+}
+
+// namedMiddleware finds a middleware by name among registered middlewares
+// and DI providers
+func (s *Server) namedMiddleware(name string) (Middleware, error) {
+	if middleware, ok := s.namedMiddlewares[name]; ok {
+		return middleware, nil
+	}
+
+	if s.container != nil {
+		if provider, ok := s.container.Get(name); ok {
+			middleware, ok := provider.(Middleware)
+			if !ok {
+				return nil, fmt.Errorf("provider %s does not implement Use", name)
+			}
+			return middleware, nil
+		}
+	}
+
+	return nil, fmt.Errorf("middleware %q is not registered", name)
+}
+
+// resolveMiddleware converts a declared middleware into a net/http middleware.
+// Declarations may be a Middleware, a net/http middleware or the name of a
+// registered middleware or of a provider implementing Middleware.
+func (s *Server) resolveMiddleware(declared interface{}) (func(http.Handler) http.Handler, error) {
+	switch m := declared.(type) {
+	case Middleware:
+		return Adapt(m), nil
+	case func(http.Handler) http.Handler:
+		return m, nil
+	case func(http.ResponseWriter, *http.Request, http.HandlerFunc):
+		return Adapt(MiddlewareFunc(m)), nil
+	case string:
+		middleware, err := s.namedMiddleware(m)
+		if err != nil {
+			return nil, err
+		}
+		return Adapt(middleware), nil
+	default:
+		return nil, fmt.Errorf("unsupported middleware type %T", declared)
+	}
+}
+
+// taggedMiddlewares resolves the names listed in a middleware tag
+func (s *Server) taggedMiddlewares(tag reflect.StructTag) ([]func(http.Handler) http.Handler, error) {
+	middlewares := make([]func(http.Handler) http.Handler, 0)
+
+	for _, name := range strings.Split(tag.Get(TagMiddleware), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		middleware, err := s.resolveMiddleware(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag: %w", TagMiddleware, err)
+		}
+		middlewares = append(middlewares, middleware)
+	}
+
+	return middlewares, nil
+}
+
+// moduleMiddlewares resolves the middlewares declared in a module configuration
+func (s *Server) moduleMiddlewares(moduleName string) ([]func(http.Handler) http.Handler, error) {
+	middlewares := make([]func(http.Handler) http.Handler, 0)
+
+	moduleInstance, err := module.GetGlobalRegistry().GetModule(moduleName)
+	if err != nil {
+		return middlewares, nil
+	}
+
+	provider, ok := moduleInstance.(moduleMiddlewareProvider)
+	if !ok {
+		return middlewares, nil
+	}
+
+	for _, declared := range provider.GetMiddlewares() {
+		middleware, err := s.resolveMiddleware(declared)
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", moduleName, err)
+		}
+		middlewares = append(middlewares, middleware)
+	}
+
+	return middlewares, nil
+}
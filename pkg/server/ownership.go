@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/kevenmiano/nestgo/pkg/module"
+	"github.com/kevenmiano/nestgo/pkg/tenant"
+)
+
+// RouteInfo describes a registered route and the team owning it, if any
+type RouteInfo struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Server is the host serving the route, the main server when empty
+	Server string `json:"server,omitempty"`
+	module.Ownership
+	// Controller and Handler name the controller and the route field or
+	// method serving controller routes
+	Controller string `json:"controller,omitempty"`
+	Handler    string `json:"handler,omitempty"`
+	// Signature is the type of the handler function of controller routes
+	Signature reflect.Type `json:"-"`
+	// Tag holds the tags of the route field or method route
+	Tag reflect.StructTag `json:"-"`
+	// Arguments describes the handler arguments bound from the request
+	Arguments []Argument `json:"-"`
+}
+
+// Routes returns the registered routes in registration order
+func (s *Server) Routes() []RouteInfo {
+	routes := make([]RouteInfo, len(s.routes))
+	copy(routes, s.routes)
+	return routes
+}
+
+// routeOwnership returns the ownership declared by a module
+func routeOwnership(moduleName string) module.Ownership {
+	moduleInstance, err := module.GetGlobalRegistry().GetModule(moduleName)
+	if err != nil {
+		return module.Ownership{Module: moduleName}
+	}
+	return module.OwnershipOf(moduleInstance)
+}
+
+// ownershipMiddleware exposes the ownership of the route through the
+// request context, see module.OwnershipFromContext
+func ownershipMiddleware(ownership module.Ownership) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(module.WithOwnership(r.Context(), ownership)))
+		})
+	}
+}
+
+// requestAttrs returns the ownership and tenant of the request r as logger
+// attributes
+func requestAttrs(r *http.Request) []interface{} {
+	return append(ownershipAttrs(r), tenant.LogAttrs(r.Context())...)
+}
+
+// ownershipAttrs returns the module, owner and tags of the route serving r
+// as logger attributes
+func ownershipAttrs(r *http.Request) []interface{} {
+	ownership, ok := module.OwnershipFromContext(r.Context())
+	if !ok {
+		return nil
+	}
+	attrs := []interface{}{"module", ownership.Module}
+	if ownership.Owner != "" {
+		attrs = append(attrs, "owner", ownership.Owner)
+	}
+	if len(ownership.Tags) > 0 {
+		attrs = append(attrs, "tags", ownership.Tags)
+	}
+	return attrs
+}
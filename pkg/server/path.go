@@ -0,0 +1,189 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PathPart is a literal or a parameter of a route path
+type PathPart struct {
+	// Literal is the text of literal parts
+	Literal string
+	// Param is the name of parameter parts
+	Param string
+	// Constraint is the regex a parameter must match, if any
+	Constraint string
+	// Wildcard is true for a trailing parameter matching the rest of the path
+	Wildcard bool
+}
+
+// RoutePath is a parsed route path, translated to its own syntax by each
+// Router backend. Paths support named parameters (/users/:id,
+// /:userId/posts/:postId), regex constraints (/users/:id<[0-9]+>) and a
+// trailing wildcard matching the rest of the path (/files/*filepath).
+type RoutePath struct {
+	// Pattern is the path as declared
+	Pattern string
+	Parts   []PathPart
+}
+
+// Params returns the parameter names in the order they appear
+func (p RoutePath) Params() []string {
+	params := make([]string, 0)
+	for _, part := range p.Parts {
+		if part.Param != "" {
+			params = append(params, part.Param)
+		}
+	}
+	return params
+}
+
+// Wildcard reports whether the path ends with a wildcard parameter
+func (p RoutePath) Wildcard() bool {
+	return len(p.Parts) > 0 && p.Parts[len(p.Parts)-1].Wildcard
+}
+
+// Format writes the path with parameters rendered by param, e.g.
+// {name} for a backend using brace syntax
+func (p RoutePath) Format(param func(PathPart) string) string {
+	var builder strings.Builder
+	for _, part := range p.Parts {
+		if part.Param == "" {
+			builder.WriteString(part.Literal)
+		} else {
+			builder.WriteString(param(part))
+		}
+	}
+	return builder.String()
+}
+
+// ParsePath parses a route path
+func ParsePath(path string) (RoutePath, error) {
+	parsed := RoutePath{Pattern: path, Parts: make([]PathPart, 0)}
+	seen := make(map[string]bool)
+	literal := 0
+
+	for i := 0; i < len(path); {
+		switch c := path[i]; c {
+		case ':', '*':
+			name, end := scanParamName(path, i+1)
+			if name == "" {
+				return RoutePath{}, fmt.Errorf("path %s: missing parameter name at position %d", path, i)
+			}
+			if seen[name] {
+				return RoutePath{}, fmt.Errorf("path %s: duplicate parameter %s", path, name)
+			}
+			seen[name] = true
+			if literal < i {
+				parsed.Parts = append(parsed.Parts, PathPart{Literal: path[literal:i]})
+			}
+
+			if c == '*' {
+				if end != len(path) || i == 0 || path[i-1] != '/' {
+					return RoutePath{}, fmt.Errorf("path %s: wildcard %s must be the last segment", path, name)
+				}
+				parsed.Parts = append(parsed.Parts, PathPart{Param: name, Wildcard: true})
+				i, literal = end, end
+				continue
+			}
+
+			pattern, next, err := scanConstraint(path, end)
+			if err != nil {
+				return RoutePath{}, err
+			}
+			parsed.Parts = append(parsed.Parts, PathPart{Param: name, Constraint: pattern})
+			i, literal = next, next
+		case '{', '}':
+			return RoutePath{}, fmt.Errorf("path %s: braces are not allowed, use :name parameters", path)
+		default:
+			i++
+		}
+	}
+
+	if literal < len(path) {
+		parsed.Parts = append(parsed.Parts, PathPart{Literal: path[literal:]})
+	}
+	return parsed, nil
+}
+
+// scanParamName reads a parameter name starting at start, returning it and
+// the index following it
+func scanParamName(path string, start int) (string, int) {
+	end := start
+	for end < len(path) {
+		c := path[end]
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isLetter && (end == start || c < '0' || c > '9') {
+			break
+		}
+		end++
+	}
+	return path[start:end], end
+}
+
+// scanConstraint reads an optional <regex> constraint starting at start,
+// returning the regex and the index following it. Angle brackets inside
+// the regex must be balanced.
+func scanConstraint(path string, start int) (string, int, error) {
+	if start >= len(path) || path[start] != '<' {
+		return "", start, nil
+	}
+
+	depth := 0
+	for i := start; i < len(path); i++ {
+		switch path[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				pattern := path[start+1 : i]
+				if pattern == "" {
+					return "", 0, fmt.Errorf("path %s: empty constraint at position %d", path, start)
+				}
+				re, err := regexp.Compile(pattern)
+				if err != nil {
+					return "", 0, fmt.Errorf("path %s: invalid constraint %q: %w", path, pattern, err)
+				}
+				// Capturing groups would shift the parameters of regex routers
+				if re.NumSubexp() > 0 {
+					return "", 0, fmt.Errorf("path %s: constraint %q must use non-capturing groups (?:...)", path, pattern)
+				}
+				return pattern, i + 1, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("path %s: unterminated constraint at position %d", path, start)
+}
+
+// routeTier orders route registration, as routers like gorilla mux match
+// routes in registration order: static paths come first so /users/me is not
+// shadowed by /users/:id, and wildcards last
+type routeTier int
+
+const (
+	tierStatic routeTier = iota
+	tierParameterized
+	tierWildcard
+)
+
+// pathTier returns the registration tier of a path
+func pathTier(path RoutePath) routeTier {
+	switch {
+	case path.Wildcard():
+		return tierWildcard
+	case len(path.Params()) > 0:
+		return tierParameterized
+	}
+	return tierStatic
+}
+
+// pathParamNames returns the parameter names of a route path in order
+func pathParamNames(path string) []string {
+	parsed, err := ParsePath(path)
+	if err != nil {
+		return nil
+	}
+	return parsed.Params()
+}
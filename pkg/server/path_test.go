@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+)
+
+// orderController declares its parameterized and wildcard routes before
+// the static routes they would shadow
+type orderController struct {
+	controller.BaseController `baseUrl:"/users"`
+
+	Any  func(ctx *controller.Context) `route:"GET /*path"`
+	ByID func(ctx *controller.Context) `route:"GET /:id"`
+	Me   func(ctx *controller.Context) `route:"GET /me"`
+	Post func(ctx *controller.Context) `route:"GET /:id/posts"`
+}
+
+func TestPathTier(t *testing.T) {
+	tests := []struct {
+		path string
+		want routeTier
+	}{
+		{path: "/users", want: tierStatic},
+		{path: "/users/me", want: tierStatic},
+		{path: "/users/:id", want: tierParameterized},
+		{path: "/users/:id/posts", want: tierParameterized},
+		{path: "/files/*path", want: tierWildcard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			parsed, err := ParsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pathTier(parsed); got != tt.want {
+				t.Errorf("pathTier(%q) = %d, want %d", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteOrder(t *testing.T) {
+	quietLogger(t)
+
+	reply := func(name string) func(ctx *controller.Context) {
+		return func(ctx *controller.Context) {
+			ctx.JSON(http.StatusOK, map[string]string{"route": name})
+		}
+	}
+	c := &orderController{Any: reply("any"), ByID: reply("id"), Me: reply("me"), Post: reply("posts")}
+
+	routers := map[string]Router{"default": defaultRouter(), "servemux": NewServeMuxRouter()}
+	for name, router := range routers {
+		s := NewServer()
+		s.SetRouter(router)
+		s.RegisterController("UsersModule", c, "/users")
+
+		tests := []struct {
+			path string
+			want string
+		}{
+			{path: "/users/me", want: "me"},
+			{path: "/users/42", want: "id"},
+			{path: "/users/42/posts", want: "posts"},
+			{path: "/users/42/posts/7", want: "any"},
+		}
+		for _, tt := range tests {
+			t.Run(name+tt.path, func(t *testing.T) {
+				recorder := httptest.NewRecorder()
+				s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				if !strings.Contains(recorder.Body.String(), `"route":"`+tt.want+`"`) {
+					t.Errorf("GET %s = %d %s, want route %q", tt.path, recorder.Code, recorder.Body.String(), tt.want)
+				}
+			})
+		}
+	}
+}
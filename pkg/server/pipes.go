@@ -0,0 +1,93 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+)
+
+// argPipes are the pipes applied to the arguments of a route
+type argPipes struct {
+	all     []pipe.Pipe
+	byParam map[string][]pipe.Pipe
+}
+
+// forParam returns the pipes applied to a parameter, route-wide pipes first
+func (ap argPipes) forParam(name string) []pipe.Pipe {
+	pipes := append([]pipe.Pipe{}, ap.all...)
+	return append(pipes, ap.byParam[name]...)
+}
+
+// RegisterPipe names a pipe so it can be referenced by pipes tags
+func (s *Server) RegisterPipe(name string, p pipe.Pipe) {
+	s.namedPipes[name] = p
+	logger.Info("Pipe registered", "name", name)
+}
+
+// UsePipes adds pipes applied to the arguments of every route, before route pipes
+func (s *Server) UsePipes(pipes ...pipe.Pipe) {
+	s.globalPipes = append(s.globalPipes, pipes...)
+}
+
+// resolvePipe finds a pipe by name among registered pipes and DI providers
+func (s *Server) resolvePipe(name string) (pipe.Pipe, error) {
+	if p, ok := s.namedPipes[name]; ok {
+		return p, nil
+	}
+
+	if s.container != nil {
+		if provider, ok := s.container.Get(name); ok {
+			p, ok := provider.(pipe.Pipe)
+			if !ok {
+				return nil, fmt.Errorf("provider %s does not implement Transform", name)
+			}
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("pipe %q is not registered", name)
+}
+
+// routePipes resolves the global pipes and the pipes tag of a route field
+func (s *Server) routePipes(field reflect.StructField, paramNames []string) (argPipes, error) {
+	pipes := argPipes{
+		all:     append([]pipe.Pipe{}, s.globalPipes...),
+		byParam: make(map[string][]pipe.Pipe),
+	}
+
+	all, byParam := pipe.ParseTag(field.Tag.Get(pipe.TagPipes))
+	for _, name := range all {
+		p, err := s.resolvePipe(name)
+		if err != nil {
+			return pipes, fmt.Errorf("invalid %s tag: %w", pipe.TagPipes, err)
+		}
+		pipes.all = append(pipes.all, p)
+	}
+
+	for param, names := range byParam {
+		if param != pipe.BodyParam && !containsString(paramNames, param) {
+			return pipes, fmt.Errorf("invalid %s tag: unknown parameter %q", pipe.TagPipes, param)
+		}
+		for _, name := range names {
+			p, err := s.resolvePipe(name)
+			if err != nil {
+				return pipes, fmt.Errorf("invalid %s tag: %w", pipe.TagPipes, err)
+			}
+			pipes.byParam[param] = append(pipes.byParam[param], p)
+		}
+	}
+
+	return pipes, nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
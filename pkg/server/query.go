@@ -0,0 +1,241 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+	"github.com/kevenmiano/nestgo/pkg/validation"
+)
+
+// queryField is a struct field bound from a query parameter
+type queryField struct {
+	name  string
+	index []int
+	typ   reflect.Type
+	// def is the default value, set when the parameter is missing
+	def    string
+	hasDef bool
+}
+
+// queryFields returns the fields of a struct type tagged with query,
+// including those of embedded structs, or nil for other types
+func queryFields(t reflect.Type) []queryField {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := make([]queryField, 0)
+	for _, field := range reflect.VisibleFields(t) {
+		name, ok := field.Tag.Lookup(controller.TagQuery)
+		if !ok || name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		def, hasDef := field.Tag.Lookup(controller.TagDefault)
+		fields = append(fields, queryField{name: name, index: field.Index, typ: field.Type, def: def, hasDef: hasDef})
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// isQueryType reports whether an argument type is bound from the query
+// string, i.e. a struct with query tagged fields
+func isQueryType(t reflect.Type) bool {
+	return queryFields(t) != nil
+}
+
+// queryParamNames returns the query parameters bound by the arguments of a
+// handler function, so pipes tags can target them
+func queryParamNames(funcType reflect.Type) []string {
+	names := make([]string, 0)
+	for i := 0; i < funcType.NumIn(); i++ {
+		for _, field := range queryFields(funcType.In(i)) {
+			names = append(names, field.name)
+		}
+	}
+	return names
+}
+
+// canConvertQuery reports whether a query parameter can be bound to t.
+// Slices take every occurrence of the parameter, maps with string keys the
+// name[key] parameters and pointers stay nil when it is missing.
+func canConvertQuery(t reflect.Type) bool {
+	if t.Kind() == reflect.Map {
+		return t.Key().Kind() == reflect.String && canConvertParam(t.Elem())
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && !canConvertParam(t) {
+		t = t.Elem()
+	}
+	return canConvertParam(t)
+}
+
+// queryBinder fills a struct argument from the query string and validates it
+// when the type declares validate tags. Each value goes through the route
+// pipes for its parameter before being converted to the field type;
+// repeated or comma separated values fill slices and name[key] parameters
+// fill maps.
+//
+//	type ListQuery struct {
+//		Page   int               `query:"page" default:"1" validate:"min=1"`
+//		Limit  int               `query:"limit" default:"20" validate:"max=100"`
+//		Tags   []string          `query:"tag"`
+//		Filter map[string]string `query:"filter"`
+//	}
+func queryBinder(argType reflect.Type, pipes argPipes) (argBinder, error) {
+	fields := queryFields(argType)
+	for _, field := range fields {
+		if !canConvertQuery(field.typ) {
+			return nil, fmt.Errorf("query parameter %s cannot be bound to %s", field.name, field.typ)
+		}
+	}
+	validate := validation.HasRules(argType)
+
+	return func(r *http.Request) (reflect.Value, error) {
+		target := reflect.New(argType).Elem()
+		structValue := target
+		if argType.Kind() == reflect.Ptr {
+			target.Set(reflect.New(argType.Elem()))
+			structValue = target.Elem()
+		}
+
+		query := r.URL.Query()
+		for _, field := range fields {
+			fieldValue, err := structValue.FieldByIndexErr(field.index)
+			if err != nil {
+				// Promoted through a nil embedded pointer
+				continue
+			}
+
+			if field.typ.Kind() == reflect.Map {
+				value, err := bindQueryMap(r, field, query, pipes.forParam(field.name))
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				if value.IsValid() {
+					fieldValue.Set(value)
+				}
+				continue
+			}
+
+			raws, ok := query[field.name]
+			if !ok && field.hasDef {
+				raws, ok = []string{field.def}, true
+			}
+			if !ok {
+				continue
+			}
+
+			value, err := bindQueryValue(r, field, raws, pipes.forParam(field.name))
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			fieldValue.Set(value)
+		}
+
+		// Enforce validate tags before the handler runs
+		if validate {
+			if err := validation.Validate(target.Interface()); err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		return target, nil
+	}, nil
+}
+
+// bindQueryValue converts the raw values of a query parameter to the field type
+func bindQueryValue(r *http.Request, field queryField, raws []string, pipes []pipe.Pipe) (reflect.Value, error) {
+	t := field.typ
+	if t.Kind() == reflect.Ptr {
+		value, err := bindQueryValue(r, queryField{name: field.name, typ: t.Elem()}, raws, pipes)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(value)
+		return ptr, nil
+	}
+
+	if t.Kind() == reflect.Slice && !canConvertParam(t) {
+		items := make([]string, 0, len(raws))
+		for _, raw := range raws {
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		slice := reflect.MakeSlice(t, len(items), len(items))
+		for i, item := range items {
+			value, err := bindQueryScalar(r, field.name, item, t.Elem(), pipes)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			slice.Index(i).Set(value)
+		}
+		return slice, nil
+	}
+
+	return bindQueryScalar(r, field.name, raws[0], t, pipes)
+}
+
+// bindQueryMap collects the name[key] parameters of a map field, e.g.
+// filter[status]=active. It returns an invalid value when there are none.
+func bindQueryMap(r *http.Request, field queryField, query url.Values, pipes []pipe.Pipe) (reflect.Value, error) {
+	var entries reflect.Value
+	for param, raws := range query {
+		key, ok := strings.CutPrefix(param, field.name+"[")
+		if !ok || !strings.HasSuffix(key, "]") || len(raws) == 0 {
+			continue
+		}
+		key = strings.TrimSuffix(key, "]")
+
+		value, err := bindQueryScalar(r, param, raws[0], field.typ.Elem(), pipes)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !entries.IsValid() {
+			entries = reflect.MakeMap(field.typ)
+		}
+		entries.SetMapIndex(reflect.ValueOf(key).Convert(field.typ.Key()), value)
+	}
+	return entries, nil
+}
+
+// bindQueryScalar converts one raw query value, like a path parameter
+func bindQueryScalar(r *http.Request, name, raw string, t reflect.Type, pipes []pipe.Pipe) (reflect.Value, error) {
+	if len(pipes) > 0 {
+		return applyPipes(raw, pipe.ArgumentMetadata{Source: pipe.SourceQuery, Name: name, Type: t}, pipes)
+	}
+
+	var value reflect.Value
+	var err error
+	if t == timeType {
+		parsed, parseErr := codec.TimePolicyFromContext(r.Context()).Parse(raw)
+		value, err = reflect.ValueOf(parsed), parseErr
+	} else {
+		value, err = convertParam(raw, t)
+	}
+	if err != nil {
+		return reflect.Value{}, &bindError{
+			status:  http.StatusBadRequest,
+			message: fmt.Sprintf("Invalid value for query parameter %s: %q", name, raw),
+		}
+	}
+	return value, nil
+}
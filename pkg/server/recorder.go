@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// responseTracker records the response of a handler: whether it was
+// written, its status and its size. It holds the status set for the value
+// returned by the handler, drops superfluous WriteHeader calls instead of
+// letting net/http complain, and passes flushes and hijacks through for
+// streams, server-sent events and WebSockets.
+type responseTracker struct {
+	http.ResponseWriter
+	written  bool
+	hijacked bool
+	// status is the status set for the value returned by the handler
+	status int
+	// code is the status written, 0 until the response starts
+	code int
+	size int64
+}
+
+// trackerPool recycles the response trackers of handlers
+var trackerPool = sync.Pool{
+	New: func() interface{} {
+		return &responseTracker{}
+	},
+}
+
+// acquireTracker returns a pooled tracker of w
+func acquireTracker(w http.ResponseWriter) *responseTracker {
+	rt := trackerPool.Get().(*responseTracker)
+	rt.ResponseWriter = w
+	return rt
+}
+
+// releaseTracker returns a tracker to the pool once its handler returned
+func releaseTracker(rt *responseTracker) {
+	*rt = responseTracker{}
+	trackerPool.Put(rt)
+}
+
+// SetStatus sets the status of the value returned by the handler
+func (rt *responseTracker) SetStatus(code int) {
+	rt.status = code
+}
+
+// Written reports whether the response started, see controller.WriteTracker
+func (rt *responseTracker) Written() bool {
+	return rt.written
+}
+
+// Status returns the status written, 0 until the response starts
+func (rt *responseTracker) Status() int {
+	return rt.code
+}
+
+// Size returns the number of body bytes written
+func (rt *responseTracker) Size() int64 {
+	return rt.size
+}
+
+// start records the status of a response starting
+func (rt *responseTracker) start(code int) {
+	rt.written = true
+	rt.code = code
+}
+
+func (rt *responseTracker) WriteHeader(statusCode int) {
+	// Informational responses such as 103 Early Hints precede the final one
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		rt.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	if rt.written {
+		logger.Warn("Superfluous WriteHeader call ignored", "status", statusCode, "written", rt.code, "hijacked", rt.hijacked)
+		return
+	}
+	rt.start(statusCode)
+	rt.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rt *responseTracker) Write(data []byte) (int, error) {
+	if rt.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !rt.written {
+		rt.start(http.StatusOK)
+	}
+	n, err := rt.ResponseWriter.Write(data)
+	rt.size += int64(n)
+	return n, err
+}
+
+// Flush sends buffered data to the client, e.g. while streaming
+func (rt *responseTracker) Flush() {
+	if rt.hijacked {
+		return
+	}
+	if err := http.NewResponseController(rt.ResponseWriter).Flush(); err == nil && !rt.written {
+		rt.start(http.StatusOK)
+	}
+}
+
+// Hijack hands the connection over to the handler, e.g. for a WebSocket
+// upgrade. The returned value of the handler is not written afterwards.
+func (rt *responseTracker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(rt.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	rt.hijacked = true
+	rt.start(http.StatusSwitchingProtocols)
+	return conn, rw, nil
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (rt *responseTracker) Unwrap() http.ResponseWriter {
+	return rt.ResponseWriter
+}
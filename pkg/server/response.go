@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/domain"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/limits"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/schema"
+	"github.com/kevenmiano/nestgo/pkg/serialize"
+)
+
+// errorType is used to detect handler error results
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// statusCoder is implemented by errors mapped to an HTTP status code
+type statusCoder interface {
+	StatusCode() int
+}
+
+// errorBodier is implemented by errors providing a structured JSON body
+type errorBodier interface {
+	ErrorBody() interface{}
+}
+
+// checkResults validates the results of a handler function. Handlers may
+// return nothing, a value, an error, or a value followed by an error.
+func checkResults(funcType reflect.Type) error {
+	switch funcType.NumOut() {
+	case 0, 1:
+		return nil
+	case 2:
+		if !funcType.Out(1).Implements(errorType) {
+			return fmt.Errorf("second result must be an error, got %s", funcType.Out(1))
+		}
+		return nil
+	default:
+		return fmt.Errorf("handlers return at most a value and an error, got %d results", funcType.NumOut())
+	}
+}
+
+// splitResults separates the returned value from the returned error
+func splitResults(funcType reflect.Type, results []reflect.Value) (interface{}, bool, error) {
+	var value interface{}
+	hasValue := false
+	var err error
+
+	for i, result := range results {
+		if funcType.Out(i).Implements(errorType) && i == len(results)-1 {
+			if !result.IsNil() {
+				err = result.Interface().(error)
+			}
+			continue
+		}
+
+		hasValue = true
+		if !isNilValue(result) {
+			value = result.Interface()
+		}
+	}
+
+	return value, hasValue, err
+}
+
+// isNilValue reports whether a result holds a nil pointer, map, slice or interface
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface, reflect.Func, reflect.Chan:
+		return v.IsNil()
+	}
+	return false
+}
+
+// statusKey is the key used to store the status set by the handler in the request context
+type statusKey struct{}
+
+// withStatus returns a copy of r carrying the status set by its handler
+func withStatus(r *http.Request, status int) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), statusKey{}, status))
+}
+
+// defaultStatus returns the success status for a request method, unless
+// the handler set one
+func defaultStatus(r *http.Request) int {
+	if status, ok := r.Context().Value(statusKey{}).(int); ok {
+		return status
+	}
+	if r.Method == http.MethodPost {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+// writeResults serializes the value or error returned by a handler.
+// hasValue is false for handlers without a value result.
+func (s *Server) writeResults(w http.ResponseWriter, r *http.Request, value interface{}, hasValue bool, err error) {
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	switch response := value.(type) {
+	case controller.Response:
+		s.writeResponse(w, r, response)
+		return
+	case *controller.Response:
+		s.writeResponse(w, r, *response)
+		return
+	}
+
+	compact := encoder.JSONStyleFromContext(r.Context()).Compact
+	if !hasValue {
+		// No return value
+		if compact {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(defaultStatus(r))
+		w.Write([]byte(`{"message": "Field executed successfully"}`))
+		return
+	}
+
+	if value == nil {
+		// No data returned
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(defaultStatus(r))
+		if compact {
+			w.Write([]byte("null"))
+			return
+		}
+		w.Write([]byte(`{"message": "No data returned"}`))
+		return
+	}
+
+	body, contentType, err := s.encodeBody(w, r, value)
+	if err != nil {
+		logger.Error("Failed to serialize response", "error", err)
+		writeError(w, r, err)
+		return
+	}
+
+	logger.Debug("Controller field executed", "result", value)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(defaultStatus(r))
+	w.Write(body)
+}
+
+// writeResponse writes a Response returned by a handler
+func (s *Server) writeResponse(w http.ResponseWriter, r *http.Request, response controller.Response) {
+	for name, values := range response.Headers {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	status := response.Status
+	if status == 0 {
+		status = defaultStatus(r)
+	}
+
+	if response.Body == nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	body, contentType, err := s.encodeBody(w, r, response.Body)
+	if err != nil {
+		logger.Error("Failed to serialize response", "error", err)
+		writeError(w, r, err)
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// encodeBody serializes a response body with the encoder negotiated from
+// the Accept header, falling back to JSON, and returns its content type
+func (s *Server) encodeBody(w http.ResponseWriter, r *http.Request, value interface{}) ([]byte, string, error) {
+	w.Header().Add("Vary", "Accept")
+
+	encoders := s.Encoders()
+	for _, candidate := range encoders.Negotiate(r.Header.Get("Accept")) {
+		body, err := s.encodeWith(r, candidate.Encoder, candidate.ContentType, value)
+		if errors.Is(err, encoder.ErrUnsupported) {
+			continue
+		}
+		return body, candidate.ContentType, err
+	}
+
+	jsonEncoder, _ := encoders.Lookup(encoder.JSON)
+	body, err := s.encodeWith(r, jsonEncoder, encoder.JSON, value)
+	return body, encoder.JSON, err
+}
+
+// encodeWith serializes a response body with an encoder. The JSON form
+// leaves out the fields hidden from the serialization groups of the request,
+// writes timestamps in the time policy of the route and DTOs in the version
+// requested by the client, in the JSON style of the server; transcoders
+// start from it.
+func (s *Server) encodeWith(r *http.Request, e encoder.Encoder, contentType string, value interface{}) ([]byte, error) {
+	style := encoder.JSONStyleFromContext(r.Context())
+	transcoder, transcodes := e.(encoder.Transcoder)
+	if !transcodes && contentType != encoder.JSON {
+		return encoder.EncodeStyled(e, value, style)
+	}
+
+	jsonEncoder := e
+	if transcodes {
+		jsonEncoder, _ = s.Encoders().Lookup(encoder.JSON)
+	}
+	jsonData, err := encoder.EncodeStyled(jsonEncoder, value, style)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err = serialize.Filter(jsonData, value, serialize.GroupsFromContext(r.Context()))
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err = codec.TimePolicyFromContext(r.Context()).FormatJSON(jsonData, value)
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err = schema.DowngradeResponse(r, jsonData, value)
+	if err != nil {
+		return nil, err
+	}
+	if !transcodes {
+		return style.Format(jsonData), nil
+	}
+	return transcoder.Transcode(jsonData)
+}
+
+// writeError writes the JSON error response for an error returned by a
+// handler. Domain errors mapped on the server or in the domain package are
+// written as problems; other errors without a status code map to 500 and their message
+// is not exposed to the client; they are logged with the route's owner.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	// Handlers cancelled by the deadline of their route time out
+	if errors.Is(err, context.DeadlineExceeded) && r.Context().Err() != nil {
+		err = limits.ErrRequestTimeout
+	}
+
+	var coder statusCoder
+	style := encoder.JSONStyleFromContext(r.Context())
+	if mapping, ok := domain.RegistryFromContext(r.Context()).Lookup(err); ok && !errors.As(err, &coder) {
+		if mapping.Status >= http.StatusInternalServerError {
+			logger.Error("Handler returned an error", append([]interface{}{"error", err, "path", r.URL.Path}, requestAttrs(r)...)...)
+		}
+		jsonData, _ := json.Marshal(domain.NewProblem(err, mapping))
+		jsonData = style.Format(jsonData)
+		w.Header().Set("Content-Type", domain.ContentType)
+		w.WriteHeader(mapping.Status)
+		w.Write(jsonData)
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := "Internal server error"
+
+	if errors.As(err, &coder) {
+		status = coder.StatusCode()
+		message = err.Error()
+	} else {
+		logger.Error("Handler returned an error", append([]interface{}{"error", err, "path", r.URL.Path}, requestAttrs(r)...)...)
+	}
+
+	var body interface{} = map[string]string{"error": message}
+	var bodier errorBodier
+	if errors.As(err, &bodier) {
+		body = bodier.ErrorBody()
+	}
+	jsonData, _ := json.Marshal(body)
+	jsonData = style.Format(jsonData)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
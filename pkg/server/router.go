@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Router matches requests to route handlers. Backends translate route paths
+// to their own syntax and expose the matched parameters with WithPathParams,
+// so adapters for routers like chi or httprouter can be plugged in with
+// Server.SetRouter.
+type Router interface {
+	http.Handler
+	// Handle registers the handler of a method and path
+	Handle(method string, path RoutePath, handler http.Handler) error
+}
+
+// pathParamsKey is the key used to store path parameters in the request context
+type pathParamsKey struct{}
+
+// WithPathParams returns a copy of r carrying the path parameters matched by
+// a router. The map must not be reused once the handler returned: anything
+// holding the request, e.g. a goroutine started by the handler, may still
+// read it with PathParam.
+func WithPathParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params))
+}
+
+// PathParam returns a path parameter of the route serving r
+func PathParam(r *http.Request, name string) string {
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	return params[name]
+}
+
+// serveMuxRouter is the Router backend built on net/http ServeMux
+type serveMuxRouter struct {
+	mux *http.ServeMux
+}
+
+// NewServeMuxRouter returns a Router backed by net/http ServeMux, without
+// third party dependencies. ServeMux parameters span whole segments, so
+// paths like /files/:name.json are rejected; constraints are checked after
+// matching and answer 404 when they fail, and the most specific pattern
+// wins instead of the first registered one.
+func NewServeMuxRouter() Router {
+	return &serveMuxRouter{mux: http.NewServeMux()}
+}
+
+func (sr *serveMuxRouter) newRouter() Router {
+	return NewServeMuxRouter()
+}
+
+func (sr *serveMuxRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sr.mux.ServeHTTP(w, r)
+}
+
+func (sr *serveMuxRouter) Handle(method string, path RoutePath, handler http.Handler) (err error) {
+	constraints := make(map[string]*regexp.Regexp)
+	pattern := path.Format(func(part PathPart) string {
+		if part.Constraint != "" {
+			constraints[part.Param] = regexp.MustCompile("^(?:" + part.Constraint + ")$")
+		}
+		if part.Wildcard {
+			return "{" + part.Param + "...}"
+		}
+		return "{" + part.Param + "}"
+	})
+
+	for i, part := range path.Parts {
+		if part.Param == "" || part.Wildcard {
+			continue
+		}
+		before := i == 0 || strings.HasSuffix(path.Parts[i-1].Literal, "/")
+		after := i == len(path.Parts)-1 || strings.HasPrefix(path.Parts[i+1].Literal, "/")
+		if !before || !after {
+			return fmt.Errorf("path %s: ServeMux parameters must span a whole segment", path.Pattern)
+		}
+	}
+	// A trailing slash matches only itself, not the whole subtree
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+
+	// ServeMux panics on conflicting patterns
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("path %s: %v", path.Pattern, recovered)
+		}
+	}()
+
+	params := path.Params()
+	sr.mux.Handle(method+" "+pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The map outlives the handler in the request context, so it is
+		// not pooled, see BenchmarkPathParams for what pooling would save
+		values := make(map[string]string, len(params))
+		for _, name := range params {
+			value := r.PathValue(name)
+			if re, ok := constraints[name]; ok && !re.MatchString(value) {
+				http.NotFound(w, r)
+				return
+			}
+			values[name] = value
+		}
+		handler.ServeHTTP(w, WithPathParams(r, values))
+	}))
+	return nil
+}
@@ -0,0 +1,51 @@
+//go:build !nestgo_nomux
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// muxRouter is the Router backend built on gorilla/mux
+type muxRouter struct {
+	router *mux.Router
+}
+
+// NewMuxRouter returns a Router backed by gorilla/mux, the default backend.
+// Build with the nestgo_nomux tag to leave gorilla/mux out, making
+// net/http ServeMux the default.
+func NewMuxRouter() Router {
+	return &muxRouter{router: mux.NewRouter()}
+}
+
+// defaultRouter returns the backend used when none is set
+func defaultRouter() Router {
+	return NewMuxRouter()
+}
+
+func (mr *muxRouter) newRouter() Router {
+	return NewMuxRouter()
+}
+
+func (mr *muxRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	mr.router.ServeHTTP(w, r)
+}
+
+func (mr *muxRouter) Handle(method string, path RoutePath, handler http.Handler) error {
+	template := path.Format(func(part PathPart) string {
+		switch {
+		case part.Wildcard:
+			return "{" + part.Param + ":.*}"
+		case part.Constraint != "":
+			return "{" + part.Param + ":" + part.Constraint + "}"
+		}
+		return "{" + part.Param + "}"
+	})
+
+	route := mr.router.HandleFunc(template, func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, WithPathParams(r, mux.Vars(r)))
+	}).Methods(method)
+	return route.GetError()
+}
@@ -0,0 +1,8 @@
+//go:build nestgo_nomux
+
+package server
+
+// defaultRouter returns the backend used when none is set
+func defaultRouter() Router {
+	return NewServeMuxRouter()
+}
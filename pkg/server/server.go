@@ -2,78 +2,138 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/gorilla/mux"
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/compress"
+	"github.com/kevenmiano/nestgo/pkg/container"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/guard"
+	"github.com/kevenmiano/nestgo/pkg/interceptor"
+	"github.com/kevenmiano/nestgo/pkg/ipfilter"
+	"github.com/kevenmiano/nestgo/pkg/limits"
 	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/pipe"
+	"github.com/kevenmiano/nestgo/pkg/ratelimit"
+	"github.com/kevenmiano/nestgo/pkg/replay"
+	"github.com/kevenmiano/nestgo/pkg/serialize"
+	"github.com/kevenmiano/nestgo/pkg/upload"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	router *mux.Router
-	server *http.Server
+	router             Router
+	middlewares        []func(http.Handler) http.Handler
+	handler            http.Handler
+	server             *http.Server
+	hosts              map[string]*host
+	serversMu          sync.Mutex
+	replay             *replay.Protector
+	throttler          *ratelimit.Throttler
+	throttlerOnce      sync.Once
+	namedMiddlewares   map[string]Middleware
+	namedGuards        map[string]guard.Guard
+	globalGuards       []guard.Guard
+	namedInterceptors  map[string]interceptor.Interceptor
+	globalInterceptors []interceptor.Interceptor
+	namedPipes         map[string]pipe.Pipe
+	globalPipes        []pipe.Pipe
+	container          *container.Container
+	routes             []RouteInfo
+	timeouts           Timeouts
+	settings           settings
+	// controllerLocks holds the lock of each controller with handlers
+	// running on the shared instance
+	controllerLocks sync.Map
+	// serializedControllers holds the controllers warned about handlers
+	// running one request at a time
+	serializedControllers sync.Map
 }
 
-// responseTracker tracks if a response has been written
-type responseTracker struct {
-	http.ResponseWriter
-	written bool
+// NewServer creates a new HTTP server
+func NewServer() *Server {
+	s := &Server{
+		namedPipes: pipe.Builtins(),
+	}
+	s.SetRouter(defaultRouter())
+	return s
 }
 
-func (rt *responseTracker) Write(data []byte) (int, error) {
-	rt.written = true
-	return rt.ResponseWriter.Write(data)
+// SetRouter replaces the routing backend, e.g. with NewServeMuxRouter.
+// Routes registered before are dropped, so it must be called first.
+func (s *Server) SetRouter(router Router) {
+	s.router = router
+	s.routes = nil
+	for _, h := range s.hosts {
+		h.router = newRouterLike(router)
+	}
+	s.buildHandler()
 }
 
-func (rt *responseTracker) WriteHeader(statusCode int) {
-	rt.written = true
-	rt.ResponseWriter.WriteHeader(statusCode)
+// Use adds HTTP middleware applied to every request
+func (s *Server) Use(middlewares ...func(http.Handler) http.Handler) {
+	s.middlewares = append(s.middlewares, middlewares...)
+	s.buildHandler()
 }
 
-// NewServer creates a new HTTP server
-func NewServer() *Server {
-	router := mux.NewRouter()
-
-	// Test: Register a simple parameterized route directly
-	router.HandleFunc("/test/{id}", func(w http.ResponseWriter, r *http.Request) {
-		vars := mux.Vars(r)
-		id := vars["id"]
-		logger.Info("Test route hit", "id", id)
-		w.Write([]byte("Test route works! ID: " + id))
-	}).Methods("GET")
-
-	return &Server{
-		router: router,
+// buildHandler wraps the router with the global middlewares
+func (s *Server) buildHandler() {
+	s.handler = s.withSettings(chain(s.router, s.middlewares))
+	for _, h := range s.hosts {
+		h.handler = s.withSettings(chain(h.router, s.middlewares))
 	}
 }
 
-// RegisterRoute registers a route with the server
+// RegisterRoute registers a route with the server. Its requests carry a
+// guard.Route named after the path, like the controller routes.
 func (s *Server) RegisterRoute(method, path string, handler http.HandlerFunc) {
-	// Convert :id syntax to {id} syntax for Gorilla Mux
-	convertedPath := strings.ReplaceAll(path, ":id", "{id}")
-
-	route := s.router.HandleFunc(convertedPath, handler).Methods(method)
-	logger.Info("Route registered", "method", method, "originalPath", path, "convertedPath", convertedPath, "route", route)
-
-	// Debug: Test route matching after all routes are registered
-	if path == "/users/:id" && method == "PATCH" {
-		logger.Info("Testing route matching for /users/:id after all routes registered")
-		testReq, _ := http.NewRequest("GET", "http://localhost:3000/users/1", nil)
-		match := &mux.RouteMatch{}
-		if s.router.Match(testReq, match) {
-			logger.Info("Route match found", "route", match.Route)
-		} else {
-			logger.Warn("No route match found for /users/1")
-		}
+	route := guard.Route{Name: path, Method: method, Path: path}
+	s.registerRoute(RouteInfo{Method: method, Path: path}, routeMiddleware(route)(handler))
+}
+
+// registerRoute registers a route with the router of the host serving it,
+// the main one when info.Server is empty
+func (s *Server) registerRoute(info RouteInfo, handler http.Handler) {
+	router, err := s.routerFor(info.Server)
+	var parsed RoutePath
+	if err == nil {
+		parsed, err = ParsePath(info.Path)
 	}
+	if err == nil {
+		err = router.Handle(info.Method, parsed, handler)
+	}
+	if err != nil {
+		logger.Error("Skipping route with invalid path", "method", info.Method, "path", info.Path, "error", err)
+		return
+	}
+
+	logger.Info("Route registered", "method", info.Method, "path", info.Path)
+	s.routes = append(s.routes, info)
+}
+
+// routeSpec describes a route field parsed from a controller
+type routeSpec struct {
+	field      reflect.StructField
+	fieldValue reflect.Value
+	// method is the exported method serving the route on per-request
+	// controller copies, empty for functions
+	method     string
+	httpMethod string
+	subPath    string
+	fullPath   string
 }
 
 // RegisterController registers all routes from a controller
 func (s *Server) RegisterController(moduleName string, controller interface{}, basePath string) {
+	bindRoutes(controller)
+
 	controllerType := reflect.TypeOf(controller)
 	controllerValue := reflect.ValueOf(controller)
 
@@ -86,47 +146,164 @@ func (s *Server) RegisterController(moduleName string, controller interface{}, b
 
 	logger.Info("Processing controller fields", "controller", controllerType.Name(), "basePath", basePath, "fieldCount", controllerType.NumField())
 
-	// First pass: register parameterized routes
-	for i := 0; i < controllerType.NumField(); i++ {
-		field := controllerType.Field(i)
-		fieldValue := controllerValue.Field(i)
+	// Build module-scoped middlewares from the module configuration
+	moduleMiddlewares, err := s.moduleMiddlewares(moduleName)
+	if err != nil {
+		logger.Error("Skipping controller routes due to invalid configuration", "controller", controllerType.Name(), "error", err)
+		return
+	}
 
-		// Skip BaseController and non-function fields
-		if field.Name == "BaseController" || field.Type.Kind() != reflect.Func {
-			continue
-		}
+	// Resolve the prefix, guards, interceptors and throttle shared by the
+	// module's controllers
+	defaults, err := s.moduleRouteDefaults(moduleName)
+	if err != nil {
+		logger.Error("Skipping controller routes due to invalid configuration", "controller", controllerType.Name(), "error", err)
+		return
+	}
+	basePath = prefixPath(defaults.prefix, basePath)
 
-		// Check if field has route tag
-		routeTag := field.Tag.Get("route")
-		if routeTag == "" {
-			continue
-		}
+	// Build controller-scoped middlewares from BaseController tags
+	controllerMiddlewares, err := s.controllerMiddlewares(controllerType)
+	if err != nil {
+		logger.Error("Skipping controller routes due to invalid configuration", "controller", controllerType.Name(), "error", err)
+		return
+	}
 
-		// Parse route tag: "METHOD /path"
-		parts := strings.Fields(routeTag)
-		if len(parts) != 2 {
-			continue
-		}
+	// Routes carry the owner of the module for logs and reporting
+	ownership := routeOwnership(moduleName)
 
-		httpMethod := strings.ToUpper(parts[0])
-		subPath := parts[1]
+	specs := s.parseRouteSpecs(controllerType, controllerValue, basePath)
 
-		// Only register parameterized routes first
-		if strings.Contains(subPath, ":") {
-			// Combine basePath with subPath
-			fullPath := strings.TrimSuffix(basePath, "/") + subPath
+	// Register static routes first, then parameterized ones and wildcards
+	// last so they don't shadow more specific routes
+	for _, tier := range []routeTier{tierStatic, tierParameterized, tierWildcard} {
+		for _, spec := range specs {
+			parsed, err := ParsePath(spec.fullPath)
+			if err != nil {
+				if tier == tierStatic {
+					logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				}
+				continue
+			}
+			if pathTier(parsed) != tier {
+				continue
+			}
+
+			// Limits wrap the body and start the deadline before any
+			// middleware runs
+			limitsMiddleware, err := s.routeLimits(spec.field)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
+
+			routeMiddlewares, err := s.routeMiddlewares(spec.field)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
 
-			logger.Info("Registering parameterized route", "field", field.Name, "httpMethod", httpMethod, "fullPath", fullPath)
+			// Throttling runs before the guards, which may be costly
+			throttleMiddleware, err := s.routeThrottle(spec, defaults.throttle)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
+			if throttleMiddleware != nil {
+				routeMiddlewares = append(routeMiddlewares, throttleMiddleware)
+			}
+
+			// Guards run after every middleware, right before the handler
+			route := routeDescriptor(controllerType, spec, ownership)
+			guardMiddleware, err := s.routeGuards(controllerType, route, defaults.guards)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
+			if guardMiddleware != nil {
+				routeMiddlewares = append(routeMiddlewares, guardMiddleware)
+			}
+
+			// Uploads are read once the guards let the request through
+			uploadMiddleware, err := s.routeUploads(spec.field)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
+			if uploadMiddleware != nil {
+				routeMiddlewares = append(routeMiddlewares, uploadMiddleware)
+			}
+
+			interceptors, err := s.routeInterceptors(controllerType, spec, defaults.interceptors)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
 
-			// Create handler function with controller instance
-			handler := s.createHandlerWithField(fieldValue, controllerValue)
+			if tier != tierStatic {
+				logger.Info("Registering parameterized route", "field", spec.field.Name, "httpMethod", spec.httpMethod, "fullPath", spec.fullPath)
+			} else {
+				logger.Info("Registering non-parameterized route", "field", spec.field.Name, "httpMethod", spec.httpMethod, "fullPath", spec.fullPath)
+			}
+
+			// Map the handler arguments to request values
+			paramNames := pathParamNames(spec.subPath)
+			pipeParams := append(append([]string{}, paramNames...), queryParamNames(spec.field.Type)...)
+			pipes, err := s.routePipes(spec.field, pipeParams)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
+
+			binders, err := newArgBinders(spec.field.Type, paramNames, pipes)
+			if err == nil {
+				err = checkResults(spec.field.Type)
+			}
+			if err != nil {
+				logger.Error("Skipping route with unsupported handler signature", "field", spec.field.Name, "error", err)
+				continue
+			}
+
+			// Routes with a canary field dispatch on the deployment track
+			routeHandler, err := s.canaryHandler(controllerType, controllerValue, spec, s.createHandlerWithField(spec.fieldValue, controllerValue, spec.method, binders, interceptors), binders, interceptors)
+			if err != nil {
+				logger.Error("Skipping route due to invalid configuration", "field", spec.field.Name, "error", err)
+				continue
+			}
+
+			// Create handler function with controller instance, running module,
+			// controller and route middlewares in that order
+			middlewares := make([]func(http.Handler) http.Handler, 0, len(moduleMiddlewares)+len(controllerMiddlewares)+len(routeMiddlewares)+2)
+			middlewares = append(middlewares, ownershipMiddleware(ownership), routeMiddleware(route))
+			if limitsMiddleware != nil {
+				middlewares = append(middlewares, limitsMiddleware)
+			}
+			middlewares = append(middlewares, moduleMiddlewares...)
+			middlewares = append(middlewares, controllerMiddlewares...)
+			middlewares = append(middlewares, routeMiddlewares...)
+			handler := chain(routeHandler, middlewares)
 
 			// Register the route
-			s.RegisterRoute(httpMethod, fullPath, handler)
+			s.registerRoute(RouteInfo{
+				Method:     spec.httpMethod,
+				Path:       spec.fullPath,
+				Server:     defaults.server,
+				Ownership:  ownership,
+				Controller: controllerType.Name(),
+				Handler:    spec.field.Name,
+				Signature:  spec.field.Type,
+				Tag:        spec.field.Tag,
+				Arguments:  routeArguments(spec.field.Type, paramNames),
+			}, handler)
 		}
 	}
+}
+
+// parseRouteSpecs extracts the route fields declared with route tags
+func (s *Server) parseRouteSpecs(controllerType reflect.Type, controllerValue reflect.Value, basePath string) []routeSpec {
+	specs := make([]routeSpec, 0)
+	methods := controllerRouteMethods(controllerValue)
 
-	// Second pass: register non-parameterized routes
 	for i := 0; i < controllerType.NumField(); i++ {
 		field := controllerType.Field(i)
 		fieldValue := controllerValue.Field(i)
@@ -148,94 +325,325 @@ func (s *Server) RegisterController(moduleName string, controller interface{}, b
 			continue
 		}
 
-		httpMethod := strings.ToUpper(parts[0])
-		subPath := parts[1]
+		method, err := routeMethod(controllerValue, methods[field.Name], fieldValue)
+		if err != nil {
+			logger.Error("Skipping route with invalid handler method", "controller", controllerType.Name(), "field", field.Name, "error", err)
+			continue
+		}
 
-		// Only register non-parameterized routes
-		if !strings.Contains(subPath, ":") {
+		specs = append(specs, routeSpec{
+			field:      field,
+			fieldValue: fieldValue,
+			method:     method,
+			httpMethod: strings.ToUpper(parts[0]),
+			subPath:    parts[1],
 			// Combine basePath with subPath
-			fullPath := strings.TrimSuffix(basePath, "/") + subPath
+			fullPath: strings.TrimSuffix(basePath, "/") + parts[1],
+		})
+	}
+
+	// Routes declared on methods behave as route fields named after the method
+	if controllerValue.CanAddr() {
+		if router, ok := controllerValue.Addr().Interface().(controller.MethodRouter); ok {
+			for _, route := range router.MethodRoutes() {
+				parts := strings.Fields(route.Route)
+				handler := reflect.ValueOf(route.Handler)
+				methodName := ""
+				if route.Handler == nil {
+					// Routes naming a method are served by that method
+					handler = controllerValue.Addr().MethodByName(route.Name)
+					methodName = route.Name
+				}
+				if len(parts) != 2 || handler.Kind() != reflect.Func {
+					logger.Error("Skipping invalid method route", "controller", controllerType.Name(), "route", route.Route, "handler", route.Name)
+					continue
+				}
+				method, err := routeMethod(controllerValue, methodName, handler)
+				if err != nil {
+					logger.Error("Skipping route with invalid handler method", "controller", controllerType.Name(), "route", route.Route, "error", err)
+					continue
+				}
 
-			logger.Info("Registering non-parameterized route", "field", field.Name, "httpMethod", httpMethod, "fullPath", fullPath)
+				specs = append(specs, routeSpec{
+					field:      reflect.StructField{Name: route.Name, Type: handler.Type(), Tag: route.Tag},
+					fieldValue: handler,
+					method:     method,
+					httpMethod: strings.ToUpper(parts[0]),
+					subPath:    parts[1],
+					fullPath:   strings.TrimSuffix(basePath, "/") + parts[1],
+				})
+			}
+		}
+	}
 
-			// Create handler function with controller instance
-			handler := s.createHandlerWithField(fieldValue, controllerValue)
+	return specs
+}
 
-			// Register the route
-			s.RegisterRoute(httpMethod, fullPath, handler)
+// routeMiddlewares builds the middlewares configured through route field tags
+func (s *Server) routeMiddlewares(field reflect.StructField) ([]func(http.Handler) http.Handler, error) {
+	middlewares, err := s.taggedMiddlewares(field.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.responseMiddlewares(field.Tag)
+	if err != nil {
+		return nil, err
+	}
+	middlewares = append(response, middlewares...)
+
+	if tag, ok := field.Tag.Lookup(replay.TagReplay); ok {
+		window, err := replay.ParseWindow(tag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag: %w", replay.TagReplay, err)
 		}
+		middlewares = append(middlewares, s.replayProtector().Middleware(window))
 	}
+
+	return middlewares, nil
 }
 
-// serializeToJSON serializes data to JSONll
-func (s *Server) serializeToJSON(data interface{}) ([]byte, error) {
-	// Handle different types of data
-	switch v := data.(type) {
-	case []string:
-		// For string slices, wrap in a response object
-		response := map[string]interface{}{
-			"data":  v,
-			"count": len(v),
+// routeLimits returns the body size, read timeout and deadline middleware of
+// a route, from its limits tag over the default limits. It returns nil when
+// the route has no limits.
+func (s *Server) routeLimits(field reflect.StructField) (func(http.Handler) http.Handler, error) {
+	options, err := limits.ParseTag(field.Tag.Get(limits.TagLimits), s.RequestLimits())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tag: %w", limits.TagLimits, err)
+	}
+	if options.IsZero() {
+		return nil, nil
+	}
+	return limits.Middleware(options), nil
+}
+
+// routeUploads returns the upload middleware of routes declaring uploaded
+// file arguments or an upload tag, with the limits of the tag
+func (s *Server) routeUploads(field reflect.StructField) (func(http.Handler) http.Handler, error) {
+	tag, tagged := field.Tag.Lookup(upload.TagUpload)
+	if !tagged && !takesUpload(field.Type) {
+		return nil, nil
+	}
+
+	options, err := upload.ParseTag(tag, s.UploadDefaults())
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tag: %w", upload.TagUpload, err)
+	}
+	return upload.Middleware(options), nil
+}
+
+// responseMiddlewares builds the middlewares overriding the time policy,
+// Cache-Control header and compression of a controller or route from its tags
+func (s *Server) responseMiddlewares(tag reflect.StructTag) ([]func(http.Handler) http.Handler, error) {
+	middlewares := make([]func(http.Handler) http.Handler, 0)
+
+	if value, ok := tag.Lookup(codec.TagTime); ok {
+		policy, err := codec.ParseTimeTag(value, s.TimePolicy())
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag: %w", codec.TagTime, err)
+		}
+		middlewares = append(middlewares, timePolicyMiddleware(policy))
+	}
+
+	if value, ok := tag.Lookup(controller.TagCache); ok {
+		middlewares = append(middlewares, CacheControlMiddleware(value))
+	}
+
+	if groups := serialize.ParseGroups(tag.Get(serialize.TagGroups)); len(groups) > 0 {
+		middlewares = append(middlewares, groupsMiddleware(groups))
+	}
+
+	if value, ok := tag.Lookup(compress.TagCompress); ok {
+		options, err := compress.ParseTag(value, compress.Options{})
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s tag: %w", compress.TagCompress, err)
+		}
+		middlewares = append(middlewares, compress.Override(options))
+	}
+
+	return middlewares, nil
+}
+
+// CacheControlMiddleware returns an HTTP middleware setting the Cache-Control
+// header before the handler runs, e.g. "no-store" for every route. Cache
+// tags and handlers calling CacheControl override it.
+func CacheControlMiddleware(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// timePolicyMiddleware makes binding and serialization of a route use its time policy
+func timePolicyMiddleware(policy codec.TimePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(codec.WithTimePolicy(r.Context(), policy)))
+		})
+	}
+}
+
+// groupsMiddleware adds the serialization groups of a route to its requests
+func groupsMiddleware(groups []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(serialize.WithGroups(r.Context(), groups...)))
+		})
+	}
+}
+
+// SetReplayProtector sets the protector used by routes tagged with replay
+func (s *Server) SetReplayProtector(protector *replay.Protector) {
+	s.replay = protector
+}
+
+// replayProtector returns the configured protector, creating a default one on first use
+func (s *Server) replayProtector() *replay.Protector {
+	if s.replay == nil {
+		s.replay = replay.New(replay.Options{})
+	}
+	return s.replay
+}
+
+// SetThrottler sets the throttler used by routes tagged with throttle
+func (s *Server) SetThrottler(throttler *ratelimit.Throttler) {
+	s.throttler = throttler
+}
+
+// routeThrottler returns the configured throttler, the one provided by a
+// ThrottlerModule or a default one, resolved on first use once providers
+// are registered
+func (s *Server) routeThrottler() *ratelimit.Throttler {
+	s.throttlerOnce.Do(func() {
+		if s.throttler != nil {
+			return
 		}
-		return json.Marshal(response)
-	case map[string]interface{}:
-		// For maps, return as is
-		return json.Marshal(v)
-	case string:
-		// For strings, wrap in a response object
-		response := map[string]interface{}{
-			"message": v,
+		if s.container != nil {
+			if provider, ok := s.container.Get(ratelimit.ProviderName); ok {
+				if throttler, ok := provider.(*ratelimit.Throttler); ok {
+					s.throttler = throttler
+					return
+				}
+			}
 		}
-		return json.Marshal(response)
-	default:
-		// For other types, try to marshal directly
-		return json.Marshal(data)
+		s.throttler = ratelimit.NewThrottler(ratelimit.ThrottlerOptions{})
+	})
+	return s.throttler
+}
+
+// routeThrottle returns the throttle middleware of routes with a throttle
+// tag, or of every route of a module declaring a default limit. Routes opt
+// out of the module limit with `throttle:"-"`.
+func (s *Server) routeThrottle(spec routeSpec, defaultLimit string) (func(http.Handler) http.Handler, error) {
+	tag, ok := spec.field.Tag.Lookup(ratelimit.TagThrottle)
+	if !ok {
+		tag = defaultLimit
+	}
+	if tag == "" || tag == "-" {
+		return nil, nil
 	}
+	limit, err := ratelimit.ParseLimit(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s tag: %w", ratelimit.TagThrottle, err)
+	}
+
+	route := spec.httpMethod + " " + spec.fullPath
+	return func(next http.Handler) http.Handler {
+		var once sync.Once
+		var throttled http.Handler
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			once.Do(func() {
+				throttled = s.routeThrottler().Middleware(route, limit)(next)
+			})
+			throttled.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// controllerMiddlewares builds the middlewares configured through BaseController tags
+func (s *Server) controllerMiddlewares(controllerType reflect.Type) ([]func(http.Handler) http.Handler, error) {
+	middlewares := make([]func(http.Handler) http.Handler, 0)
+
+	field, found := controllerType.FieldByName("BaseController")
+	if !found {
+		return middlewares, nil
+	}
+
+	rules := ipfilter.ParseRules(field.Tag.Get(ipfilter.TagAllow), field.Tag.Get(ipfilter.TagDeny))
+	if !rules.IsEmpty() {
+		filter, err := ipfilter.New(controllerType.Name(), rules)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, filter.Middleware)
+	}
+
+	tagged, err := s.taggedMiddlewares(field.Tag)
+	if err != nil {
+		return nil, err
+	}
+	middlewares = append(middlewares, tagged...)
+
+	// Response settings apply to every route and can be overridden per route
+	response, err := s.responseMiddlewares(field.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return append(middlewares, response...), nil
+}
+
+// chain wraps a handler with middlewares, the first one being the outermost
+func chain(handler http.Handler, middlewares []func(http.Handler) http.Handler) http.HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler.ServeHTTP
 }
 
 // createHandlerWithField creates an HTTP handler with controller field
-func (s *Server) createHandlerWithField(fieldValue reflect.Value, controllerValue reflect.Value) http.HandlerFunc {
+func (s *Server) createHandlerWithField(fieldValue reflect.Value, controllerValue reflect.Value, method string, binders []argBinder, interceptors []interceptor.Interceptor) http.HandlerFunc {
+	instance := s.newControllerInstance(controllerValue, fieldValue, method)
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Debug: Log incoming request
-		logger.Info("Incoming request", "method", r.Method, "path", r.URL.Path, "rawQuery", r.URL.RawQuery)
+		logger.Debug("Incoming request", append([]interface{}{"method", r.Method, "path", r.URL.Path, "rawQuery", r.URL.RawQuery}, requestAttrs(r)...)...)
 
 		// Create a custom ResponseWriter to track if response was written
-		responseWriter := &responseTracker{ResponseWriter: w}
-
-		// Set HTTP context in BaseController if it exists
-		logger.Info("Setting HTTP context", "controllerType", controllerValue.Type().Name())
-		s.setHTTPContext(controllerValue, responseWriter, r)
-
-		// Call the field function directly
-		results := fieldValue.Call([]reflect.Value{})
-
-		// Only write default response if no response was written by the controller
-		if !responseWriter.written {
-			// Handle the response
-			if len(results) > 0 {
-				result := results[0].Interface()
-				if result != nil {
-					// Serialize to JSON
-					jsonData, err := s.serializeToJSON(result)
-					if err != nil {
-						logger.Error("Failed to serialize response", "error", err)
-						http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
-						return
-					}
-
-					logger.Info("Controller field executed", "result", result)
-					w.Write(jsonData)
-				} else {
-					// No data returned
-					w.Write([]byte(`{"message": "No data returned"}`))
+		responseWriter := acquireTracker(w)
+		defer releaseTracker(responseWriter)
+		r = withResponseWriter(r, responseWriter)
+
+		// Bind path parameters and body to the handler arguments
+		args, err := bindArgs(r, binders)
+		if err != nil {
+			logger.Warn("Failed to bind request", append([]interface{}{"path", r.URL.Path, "error", err}, requestAttrs(r)...)...)
+			writeError(responseWriter, r, err)
+			return
+		}
+		defer releaseArgs(args)
+
+		// Set HTTP context in BaseController of the instance serving the request
+		logger.Debug("Setting HTTP context", "controllerType", controllerValue.Type().Name())
+		instance.serve(s, responseWriter, r, func(callHandler func(args []reflect.Value) []reflect.Value) {
+			// Call the handler with the bound arguments, through the interceptors
+			returnsValue := false
+			call := func() (interface{}, error) {
+				value, hasValue, err := splitResults(fieldValue.Type(), callHandler(args))
+				returnsValue = hasValue
+				return value, err
+			}
+			value, err := interceptor.Chain(r, call, interceptors)()
+
+			// Only write the returned values if no response was written by the controller
+			if !responseWriter.written {
+				if responseWriter.status != 0 {
+					r = withStatus(r, responseWriter.status)
 				}
-			} else {
-				// No return value
-				w.Write([]byte(`{"message": "Field executed successfully"}`))
+				s.writeResults(responseWriter, r, value, returnsValue || value != nil, err)
 			}
-		}
+		})
 
-		logger.Info("Request handled", "method", r.Method, "path", r.URL.Path)
+		logger.Info("Request handled", append([]interface{}{"method", r.Method, "path", r.URL.Path, "status", responseWriter.Status(), "bytes", responseWriter.Size()}, requestAttrs(r)...)...)
 	}
 }
 
@@ -254,7 +662,7 @@ func (s *Server) setHTTPContext(controller reflect.Value, w http.ResponseWriter,
 			if baseController, ok := baseControllerPtr.Interface().(interface {
 				SetHTTPContext(http.ResponseWriter, *http.Request)
 			}); ok {
-				logger.Info("Setting HTTP context in BaseController")
+				logger.Debug("Setting HTTP context in BaseController")
 				baseController.SetHTTPContext(w, r)
 			} else {
 				logger.Warn("Failed to set HTTP context - type assertion failed")
@@ -264,45 +672,132 @@ func (s *Server) setHTTPContext(controller reflect.Value, w http.ResponseWriter,
 	}
 }
 
-// Start starts the HTTP server
+// bindRoutes binds the route fields of a controller implementing
+// controller.RouteBinder before they are read
+func bindRoutes(c interface{}) {
+	controller.BindRoutes(c)
+}
+
+// ServeHTTP handles a request in process, e.g. with an httptest recorder
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Start starts the HTTP server on a TCP address such as ":3000" or a Unix
+// domain socket address such as "unix:///run/app.sock"
 func (s *Server) Start(port string) error {
-	s.server = &http.Server{
-		Addr:         port,
-		Handler:      s.router,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	listener, err := Listen(port)
+	if err != nil {
+		return err
 	}
+	return s.Serve(listener)
+}
 
-	logger.Info("Server starting", "port", port)
+// Serve starts the HTTP server on a listener, e.g. one passed by systemd
+// socket activation. Shutdown closes the listener.
+func (s *Server) Serve(listener net.Listener) error {
+	s.serversMu.Lock()
+	s.server = s.newHTTPServer(listener, s.handler)
+	s.serversMu.Unlock()
+
+	logger.Info("Server starting", "address", listener.Addr().String(), "network", listener.Addr().Network())
 
 	// Print all registered routes
 	s.PrintRoutes()
 
-	return s.server.ListenAndServe()
+	return s.server.Serve(listener)
+}
+
+// Timeouts configures the connection timeouts of the HTTP servers; zero
+// keeps a default. Routes with a read timeout or deadline, see
+// limits.Options, replace them for their requests.
+type Timeouts struct {
+	// Read bounds reading a request, body included. Defaults to 15s.
+	Read time.Duration
+	// Write bounds writing a response, from the end of the request
+	// headers. Defaults to 15s.
+	Write time.Duration
+	// Idle bounds keep-alive connections between requests. Defaults to 60s.
+	Idle time.Duration
 }
 
-// Shutdown gracefully shuts down the server
+// withDefaults fills the unset timeouts
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Read == 0 {
+		t.Read = 15 * time.Second
+	}
+	if t.Write == 0 {
+		t.Write = 15 * time.Second
+	}
+	if t.Idle == 0 {
+		t.Idle = 60 * time.Second
+	}
+	return t
+}
+
+// SetTimeouts sets the connection timeouts of the main server and its
+// hosts. It must be called before they are served.
+func (s *Server) SetTimeouts(timeouts Timeouts) {
+	s.timeouts = timeouts
+}
+
+// newHTTPServer creates the HTTP server of a listener
+func (s *Server) newHTTPServer(listener net.Listener, handler http.Handler) *http.Server {
+	timeouts := s.timeouts.withDefaults()
+	return &http.Server{
+		Addr:         listener.Addr().String(),
+		Handler:      handler,
+		ReadTimeout:  timeouts.Read,
+		WriteTimeout: timeouts.Write,
+		IdleTimeout:  timeouts.Idle,
+	}
+}
+
+// Shutdown gracefully shuts down the server and its hosts
 func (s *Server) Shutdown(ctx context.Context) error {
+	s.serversMu.Lock()
+	servers := make(map[string]*http.Server, len(s.hosts)+1)
 	if s.server != nil {
-		logger.Info("Shutting down server...")
-		return s.server.Shutdown(ctx)
+		servers[""] = s.server
+	}
+	for name, h := range s.hosts {
+		if h.server != nil {
+			servers[name] = h.server
+		}
+	}
+	s.serversMu.Unlock()
+
+	var errs []error
+	for name, server := range servers {
+		if name == "" {
+			logger.Info("Shutting down server...")
+		} else {
+			logger.Info("Shutting down server...", "server", name)
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // PrintRoutes prints all registered routes
 func (s *Server) PrintRoutes() {
 	logger.Info("HTTP Routes registered")
-
-	// Walk through all routes
-	s.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
-		pathTemplate, _ := route.GetPathTemplate()
-		methods, _ := route.GetMethods()
-
-		for _, method := range methods {
-			logger.Info("Available route", "method", method, "path", pathTemplate)
+	for _, info := range s.routes {
+		attrs := []interface{}{"method", info.Method, "path", info.Path}
+		if info.Server != "" {
+			attrs = append(attrs, "server", info.Server)
 		}
-		return nil
-	})
+		if info.Module != "" {
+			attrs = append(attrs, "module", info.Module)
+		}
+		if info.Owner != "" {
+			attrs = append(attrs, "owner", info.Owner)
+		}
+		if len(info.Tags) > 0 {
+			attrs = append(attrs, "tags", info.Tags)
+		}
+		logger.Info("Available route", attrs...)
+	}
 }
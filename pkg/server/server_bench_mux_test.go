@@ -0,0 +1,11 @@
+//go:build !nestgo_nomux
+
+package server
+
+import "testing"
+
+func BenchmarkServeHTTPMux(b *testing.B) {
+	s := newBenchServer(b, NewMuxRouter())
+	b.Run("Context", func(b *testing.B) { benchServeHTTP(b, s, "/items/42") })
+	b.Run("Method", func(b *testing.B) { benchServeHTTP(b, s, "/items/42/name") })
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// benchItem is the value returned by the benchmark routes
+type benchItem struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// benchController serves the benchmark routes: a handler taking a Context
+// on the shared controller and an exported method on per-request copies
+type benchController struct {
+	controller.BaseController `baseUrl:"/items"`
+
+	GetItem  func(ctx *controller.Context, id int) (*benchItem, error) `route:"GET /:id"`
+	ItemName func(id int)                                              `route:"GET /:id/name"`
+}
+
+// ItemNameHandler writes the response through BaseController
+func (c *benchController) ItemNameHandler(id int) {
+	c.JSON(map[string]string{"name": "item"})
+}
+
+// RouteMethods names the handler method of ItemName
+func (c *benchController) RouteMethods() map[string]string {
+	return map[string]string{"ItemName": "ItemNameHandler"}
+}
+
+// newBenchServer returns a server with the benchmark routes on a router
+// backend, logging discarded
+func newBenchServer(b *testing.B, router Router) *Server {
+	b.Helper()
+	previous := logger.Logger
+	logger.Logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	b.Cleanup(func() { logger.Logger = previous })
+
+	c := &benchController{}
+	c.GetItem = func(ctx *controller.Context, id int) (*benchItem, error) {
+		return &benchItem{ID: id, Name: ctx.Param("id")}, nil
+	}
+	c.ItemName = c.ItemNameHandler
+
+	s := NewServer()
+	s.SetRouter(router)
+	s.RegisterController("BenchModule", c, "/items")
+	return s
+}
+
+// benchServeHTTP measures serving a request in process
+func benchServeHTTP(b *testing.B, s *Server, target string) {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			b.Fatalf("GET %s answered %d", target, w.Code)
+		}
+	}
+}
+
+func BenchmarkServeHTTPServeMux(b *testing.B) {
+	s := newBenchServer(b, NewServeMuxRouter())
+	b.Run("Context", func(b *testing.B) { benchServeHTTP(b, s, "/items/42") })
+	b.Run("Method", func(b *testing.B) { benchServeHTTP(b, s, "/items/42/name") })
+}
+
+// Sinks keep the results of the pooling benchmarks alive
+var (
+	argsSink    []reflect.Value
+	trackerSink *responseTracker
+	requestSink *http.Request
+)
+
+func BenchmarkHandlerArgs(b *testing.B) {
+	binder := func(r *http.Request) (reflect.Value, error) { return reflect.ValueOf(42), nil }
+	binders := []argBinder{binder, binder, binder}
+	r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			args, _ := bindArgs(r, binders)
+			argsSink = args
+			releaseArgs(args)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			args := make([]reflect.Value, 0, len(binders))
+			for _, binder := range binders {
+				arg, _ := binder(r)
+				args = append(args, arg)
+			}
+			argsSink = args
+		}
+	})
+}
+
+func BenchmarkResponseTracker(b *testing.B) {
+	w := httptest.NewRecorder()
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rt := acquireTracker(w)
+			rt.SetStatus(http.StatusOK)
+			trackerSink = rt
+			releaseTracker(rt)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			rt := &responseTracker{ResponseWriter: w}
+			rt.SetStatus(http.StatusOK)
+			trackerSink = rt
+		}
+	})
+}
+
+// BenchmarkPathParams measures what pooling the parameter maps of the
+// ServeMux backend would save. They are not pooled: the request keeps them
+// reachable past the handler, see WithPathParams.
+func BenchmarkPathParams(b *testing.B) {
+	params := []string{"id", "postId"}
+	r := httptest.NewRequest(http.MethodGet, "/users/42/posts/7", nil)
+	mapPool := sync.Pool{New: func() interface{} { return make(map[string]string, len(params)) }}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			values := mapPool.Get().(map[string]string)
+			for _, name := range params {
+				values[name] = "42"
+			}
+			requestSink = WithPathParams(r, values)
+			clear(values)
+			mapPool.Put(values)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			values := make(map[string]string, len(params))
+			for _, name := range params {
+				values[name] = "42"
+			}
+			requestSink = WithPathParams(r, values)
+		}
+	})
+}
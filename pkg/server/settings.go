@@ -0,0 +1,138 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/domain"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/limits"
+	"github.com/kevenmiano/nestgo/pkg/upload"
+)
+
+// settings holds the conventions of a server; unset ones fall back to the
+// package defaults, such as codec.CurrentTimePolicy, so servers of one
+// process can differ
+type settings struct {
+	timePolicy     *codec.TimePolicy
+	jsonStyle      *encoder.JSONStyle
+	encoders       *encoder.Registry
+	errorMappings  *domain.Registry
+	requestLimits  *limits.Options
+	uploadDefaults *upload.Options
+}
+
+// SetTimePolicy sets the time policy of the routes without a time tag, and
+// the base of time tags, see codec.TimePolicy. It must be called before the
+// controllers are registered.
+func (s *Server) SetTimePolicy(policy codec.TimePolicy) {
+	// An empty tag fills the unset fields of the policy
+	policy, _ = codec.ParseTimeTag("", policy)
+	s.settings.timePolicy = &policy
+	s.buildHandler()
+}
+
+// TimePolicy returns the time policy of the routes without a time tag
+func (s *Server) TimePolicy() codec.TimePolicy {
+	if s.settings.timePolicy != nil {
+		return *s.settings.timePolicy
+	}
+	return codec.CurrentTimePolicy()
+}
+
+// SetJSONStyle sets the layout of the JSON responses, see encoder.JSONStyle
+func (s *Server) SetJSONStyle(style encoder.JSONStyle) {
+	if style.Indent == "" {
+		style.Indent = encoder.DefaultIndent
+	}
+	s.settings.jsonStyle = &style
+	s.buildHandler()
+}
+
+// JSONStyle returns the layout of the JSON responses
+func (s *Server) JSONStyle() encoder.JSONStyle {
+	if s.settings.jsonStyle != nil {
+		return *s.settings.jsonStyle
+	}
+	return encoder.CurrentJSONStyle()
+}
+
+// RegisterEncoder sets the response encoder of a content type on the
+// server, over the encoders registered with encoder.Register
+func (s *Server) RegisterEncoder(contentType string, e encoder.Encoder) {
+	if s.settings.encoders == nil {
+		s.settings.encoders = encoder.NewRegistry()
+	}
+	s.settings.encoders.Register(contentType, e)
+}
+
+// Encoders returns the response encoders of the server
+func (s *Server) Encoders() *encoder.Registry {
+	if s.settings.encoders != nil {
+		return s.settings.encoders
+	}
+	return encoder.Default()
+}
+
+// RegisterErrorMapping maps domain errors matching target to a response on
+// the server, before the mappings registered with domain.Register
+func (s *Server) RegisterErrorMapping(target error, mapping domain.Mapping) {
+	if s.settings.errorMappings == nil {
+		s.settings.errorMappings = domain.NewRegistry()
+		s.buildHandler()
+	}
+	s.settings.errorMappings.Register(target, mapping)
+}
+
+// SetRequestLimits sets the limits of the routes without a limits tag, and
+// the base of limits tags, see limits.Options. It must be called before the
+// controllers are registered.
+func (s *Server) SetRequestLimits(options limits.Options) {
+	s.settings.requestLimits = &options
+}
+
+// RequestLimits returns the limits of the routes without a limits tag
+func (s *Server) RequestLimits() limits.Options {
+	if s.settings.requestLimits != nil {
+		return *s.settings.requestLimits
+	}
+	return limits.Defaults()
+}
+
+// SetUploadDefaults sets the upload options of the routes without an upload
+// tag, and the base of upload tags, see upload.Options. It must be called
+// before the controllers are registered.
+func (s *Server) SetUploadDefaults(options upload.Options) {
+	s.settings.uploadDefaults = &options
+}
+
+// UploadDefaults returns the upload options of the routes without an
+// upload tag
+func (s *Server) UploadDefaults() upload.Options {
+	if s.settings.uploadDefaults != nil {
+		return *s.settings.uploadDefaults
+	}
+	return upload.Defaults()
+}
+
+// withSettings carries the settings read while serving requests in their
+// context, for the packages reading them there, like binding and guards
+func (s *Server) withSettings(next http.Handler) http.Handler {
+	timePolicy, jsonStyle, errorMappings := s.settings.timePolicy, s.settings.jsonStyle, s.settings.errorMappings
+	if timePolicy == nil && jsonStyle == nil && errorMappings == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if timePolicy != nil {
+			ctx = codec.WithTimePolicy(ctx, *timePolicy)
+		}
+		if jsonStyle != nil {
+			ctx = encoder.WithJSONStyle(ctx, *jsonStyle)
+		}
+		if errorMappings != nil {
+			ctx = domain.WithRegistry(ctx, errorMappings)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
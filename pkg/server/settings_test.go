@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/domain"
+	"github.com/kevenmiano/nestgo/pkg/encoder"
+	"github.com/kevenmiano/nestgo/pkg/limits"
+)
+
+var errSettingsTest = errors.New("settings test")
+
+// settingsController exercises the conventions configured per server
+type settingsController struct {
+	controller.BaseController `baseUrl:"/settings"`
+
+	Time    func() (map[string]time.Time, error)         `route:"GET /time"`
+	Message func() (string, error)                       `route:"GET /message"`
+	Fail    func() (string, error)                       `route:"GET /fail"`
+	Slow    func(ctx *controller.Context) error          `route:"GET /slow"`
+	Echo    func(at time.Time) (map[string]int64, error) `route:"GET /echo/:at"`
+}
+
+func newSettingsServer(t *testing.T, configure func(s *Server)) *Server {
+	t.Helper()
+	c := &settingsController{
+		Time: func() (map[string]time.Time, error) {
+			return map[string]time.Time{"at": time.Unix(1700000000, 0).UTC()}, nil
+		},
+		Message: func() (string, error) { return "hello", nil },
+		Fail:    func() (string, error) { return "", errSettingsTest },
+		Slow: func(ctx *controller.Context) error {
+			<-ctx.Context().Done()
+			return ctx.Context().Err()
+		},
+		Echo: func(at time.Time) (map[string]int64, error) {
+			return map[string]int64{"unix": at.Unix()}, nil
+		},
+	}
+
+	s := NewServer()
+	configure(s)
+	s.RegisterController("SettingsModule", c, "/settings")
+	return s
+}
+
+func TestServerSettings(t *testing.T) {
+	quietLogger(t)
+
+	configured := newSettingsServer(t, func(s *Server) {
+		s.SetTimePolicy(codec.TimePolicy{Output: codec.FormatEpoch, Accepted: []string{"2006-01-02"}})
+		s.SetJSONStyle(encoder.JSONStyle{Compact: true})
+		s.RegisterErrorMapping(errSettingsTest, domain.Mapping{Status: http.StatusTeapot})
+		s.RegisterEncoder("text/csv", encoder.Func(func(value interface{}) ([]byte, error) {
+			return []byte("csv"), nil
+		}))
+		s.SetRequestLimits(limits.Options{Deadline: 10 * time.Millisecond})
+	})
+	plain := newSettingsServer(t, func(s *Server) {})
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		accept     string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "time policy", server: configured, path: "/settings/time", wantStatus: http.StatusOK, wantBody: `{"at":1700000000}`},
+		{name: "default time policy", server: plain, path: "/settings/time", wantStatus: http.StatusOK, wantBody: `{"at":"2023-11-14T22:13:20Z"}`},
+		{name: "accepted layouts", server: configured, path: "/settings/echo/2024-01-02", wantStatus: http.StatusOK, wantBody: `{"unix":1704153600}`},
+		{name: "default accepted layouts", server: plain, path: "/settings/echo/2024-01-02", wantStatus: http.StatusBadRequest},
+		{name: "compact style", server: configured, path: "/settings/message", wantStatus: http.StatusOK, wantBody: `"hello"`},
+		{name: "default style", server: plain, path: "/settings/message", wantStatus: http.StatusOK, wantBody: `{"message":"hello"}`},
+		{name: "error mapping", server: configured, path: "/settings/fail", wantStatus: http.StatusTeapot},
+		{name: "unmapped error", server: plain, path: "/settings/fail", wantStatus: http.StatusInternalServerError},
+		{name: "encoder", server: configured, path: "/settings/message", accept: "text/csv", wantStatus: http.StatusOK, wantBody: "csv"},
+		{name: "encoder of another server", server: plain, path: "/settings/message", accept: "text/csv", wantStatus: http.StatusOK, wantBody: `{"message":"hello"}`},
+		{name: "request limits", server: configured, path: "/settings/slow", wantStatus: http.StatusRequestTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.accept != "" {
+				request.Header.Set("Accept", tt.accept)
+			}
+			recorder := httptest.NewRecorder()
+			tt.server.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("GET %s = %d %s, want %d", tt.path, recorder.Code, recorder.Body.String(), tt.wantStatus)
+			}
+			if body := strings.TrimSpace(recorder.Body.String()); tt.wantBody != "" && body != tt.wantBody {
+				t.Errorf("GET %s body = %s, want %s", tt.path, body, tt.wantBody)
+			}
+		})
+	}
+
+	if got := codec.CurrentTimePolicy().Output; got != codec.DefaultTimePolicy().Output {
+		t.Errorf("package time policy changed to %q", got)
+	}
+	if encoder.CurrentJSONStyle().Compact {
+		t.Error("package JSON style changed to compact")
+	}
+	if _, ok := domain.Lookup(errSettingsTest); ok {
+		t.Error("package error mappings changed")
+	}
+}
@@ -0,0 +1,199 @@
+package smoke
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/kevenmiano/nestgo/pkg/server"
+)
+
+// TagExample declares an example request of a route field, replayed by Run
+// and expected to succeed:
+//
+//	GetUser func(id int) (*User, error) `route:"GET /:id" example:"{\"params\":{\"id\":1}}"`
+//	CreateUser func(u CreateUserDTO) (*User, error) `route:"POST /" example:"{\"body\":{\"name\":\"Ana\"}}"`
+const TagExample = "example"
+
+// Example is the request declared by an example tag. Path parameters fill
+// the route pattern, query values may be arrays for repeated parameters and
+// the body is sent as JSON.
+type Example struct {
+	Params  map[string]interface{} `json:"params"`
+	Query   map[string]interface{} `json:"query"`
+	Headers map[string]string      `json:"headers"`
+	Body    json.RawMessage        `json:"body"`
+}
+
+// ParseExample parses an example tag
+func ParseExample(tag string) (Example, error) {
+	var example Example
+	decoder := json.NewDecoder(strings.NewReader(tag))
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&example); err != nil {
+		return Example{}, fmt.Errorf("invalid %s tag: %w", TagExample, err)
+	}
+	return example, nil
+}
+
+// Request builds the request of an example for a route pattern
+func (e Example) Request(method, pattern string) (*http.Request, error) {
+	path, err := server.ParsePath(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing error
+	target := path.Format(func(part server.PathPart) string {
+		value, ok := e.Params[part.Param]
+		if !ok {
+			missing = fmt.Errorf("example has no value for path parameter %s", part.Param)
+			return ""
+		}
+		if part.Wildcard {
+			return fmt.Sprint(value)
+		}
+		return url.PathEscape(fmt.Sprint(value))
+	})
+	if missing != nil {
+		return nil, missing
+	}
+
+	query := url.Values{}
+	for name, value := range e.Query {
+		if values, ok := value.([]interface{}); ok {
+			for _, item := range values {
+				query.Add(name, fmt.Sprint(item))
+			}
+			continue
+		}
+		query.Set(name, fmt.Sprint(value))
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if len(e.Body) > 0 {
+		body = bytes.NewReader(e.Body)
+	}
+	r := httptest.NewRequest(method, target, body)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range e.Headers {
+		r.Header.Set(name, value)
+	}
+	return r, nil
+}
+
+// Result is the outcome of replaying the example of a route
+type Result struct {
+	Method string
+	// Path is the route pattern, e.g. /users/:id
+	Path string
+	// Target is the path and query string requested
+	Target string
+	Status int
+	// Err is set when the example could not be replayed
+	Err error
+}
+
+// Passed reports whether the example was answered with a 2xx status
+func (r Result) Passed() bool {
+	return r.Err == nil && r.Status >= 200 && r.Status < 300
+}
+
+func (r Result) String() string {
+	switch {
+	case r.Err != nil:
+		return fmt.Sprintf("%s %s: %v", r.Method, r.Path, r.Err)
+	case !r.Passed():
+		return fmt.Sprintf("%s %s: %s answered %d, want 2xx", r.Method, r.Path, r.Target, r.Status)
+	}
+	return fmt.Sprintf("%s %s: %s answered %d", r.Method, r.Path, r.Target, r.Status)
+}
+
+// Run replays the examples of the routes declaring one against handler in
+// process, in route order
+func Run(handler http.Handler, routes []server.RouteInfo) []Result {
+	results := make([]Result, 0)
+	for _, route := range routes {
+		tag, ok := route.Tag.Lookup(TagExample)
+		if !ok {
+			continue
+		}
+		result := Result{Method: route.Method, Path: route.Path}
+
+		example, err := ParseExample(tag)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+		r, err := example.Request(route.Method, route.Path)
+		if err != nil {
+			result.Err = err
+			results = append(results, result)
+			continue
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		result.Target, result.Status = r.URL.RequestURI(), rec.Code
+		results = append(results, result)
+	}
+	return results
+}
+
+// Test replays the route examples, failing t for each one not answered
+// with a 2xx status:
+//
+//	func TestExamples(t *testing.T) {
+//		smoke.Test(t, app.GetServer(), app.GetServer().Routes())
+//	}
+func Test(t testing.TB, handler http.Handler, routes []server.RouteInfo) {
+	t.Helper()
+
+	results := Run(handler, routes)
+	if len(results) == 0 {
+		t.Logf("smoke: no route declares an %s tag", TagExample)
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			t.Errorf("smoke: %s", result)
+		}
+	}
+}
+
+// Report writes a table of results, failures first
+func Report(w io.Writer, results []Result) {
+	sorted := append([]Result{}, results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return !sorted[i].Passed() && sorted[j].Passed()
+	})
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "RESULT\tROUTE\tSTATUS\tDETAIL")
+	for _, result := range sorted {
+		outcome := "ok"
+		if !result.Passed() {
+			outcome = "FAIL"
+		}
+		detail := result.Target
+		if result.Err != nil {
+			detail = result.Err.Error()
+		}
+		fmt.Fprintf(table, "%s\t%s %s\t%d\t%s\n", outcome, result.Method, result.Path, result.Status, detail)
+	}
+	table.Flush()
+}
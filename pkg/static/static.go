@@ -0,0 +1,176 @@
+package static
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+	"github.com/kevenmiano/nestgo/pkg/module"
+)
+
+// DefaultIndex is the file served for directories when Options.Index is empty
+const DefaultIndex = "index.html"
+
+// Options configures a directory served under a URL prefix
+type Options struct {
+	// Prefix is the URL prefix of the files, e.g. "/assets"; "/" serves
+	// them at the root, after the routes of the controllers
+	Prefix string
+	// Root is the directory served, unless FS is set
+	Root string
+	// FS is the file system served, e.g. an embed.FS
+	FS fs.FS
+	// Index is the file served for directories, DefaultIndex when empty
+	Index string
+	// Fallback is served for missing paths without a file extension, e.g.
+	// "index.html" for single page applications routing on the client
+	Fallback string
+	// MaxAge sets Cache-Control max-age on files; HTML files are always
+	// revalidated so new deploys reach clients
+	MaxAge time.Duration
+	// Immutable marks cached files immutable, for fingerprinted assets
+	Immutable bool
+}
+
+// StaticController serves the mounted directories. It can be listed in the
+// Controllers of any module; NewModule registers one in its own module.
+type StaticController struct {
+	controller.BaseController `baseUrl:"/"`
+}
+
+// StaticModule is the module registered by NewModule
+type StaticModule struct{}
+
+// NewModule registers a StaticModule serving the mounts, before the
+// application starts:
+//
+//	var _ = static.NewModule(static.Options{Prefix: "/assets", Root: "./public", MaxAge: time.Hour})
+func NewModule(mounts ...Options) *StaticModule {
+	m := &StaticModule{}
+	module.New(module.ModuleConfig{
+		Controllers: []interface{}{NewController(mounts...)},
+	})(m)
+	return m
+}
+
+// NewController creates a StaticController serving the mounts
+func NewController(mounts ...Options) *StaticController {
+	c := &StaticController{}
+	for _, mount := range mounts {
+		handler, err := newHandler(mount)
+		if err != nil {
+			logger.Error("Skipping invalid static mount", "prefix", mount.Prefix, "error", err)
+			continue
+		}
+		route := strings.TrimSuffix(mount.Prefix, "/") + "/*filepath"
+		c.Route("GET "+route, handler.serve)
+		c.Route("HEAD "+route, handler.serve)
+	}
+	return c
+}
+
+// handler serves the files of a mount
+type handler struct {
+	options      Options
+	fsys         fs.FS
+	cacheControl string
+}
+
+// newHandler validates a mount and fills its defaults
+func newHandler(options Options) (*handler, error) {
+	if options.Prefix == "" || !strings.HasPrefix(options.Prefix, "/") {
+		return nil, fmt.Errorf("static: prefix %q must start with /", options.Prefix)
+	}
+
+	fsys := options.FS
+	if fsys == nil {
+		if options.Root == "" {
+			return nil, errors.New("static: either Root or FS is required")
+		}
+		if info, err := os.Stat(options.Root); err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("static: %s is not a directory", options.Root)
+		}
+		fsys = os.DirFS(options.Root)
+	}
+	if options.Index == "" {
+		options.Index = DefaultIndex
+	}
+
+	cacheControl := "no-cache"
+	if options.MaxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", int(options.MaxAge.Seconds()))
+		if options.Immutable {
+			cacheControl += ", immutable"
+		}
+	}
+	return &handler{options: options, fsys: fsys, cacheControl: cacheControl}, nil
+}
+
+// serve writes the file matching the request path, the index of a
+// directory, or the fallback for client side routes
+func (h *handler) serve(ctx *controller.Context) {
+	name := strings.TrimPrefix(path.Clean("/"+ctx.Param("filepath")), "/")
+	if name == "" {
+		name = "."
+	}
+
+	if hidden(name) {
+		http.NotFound(ctx.Writer, ctx.Request)
+		return
+	}
+	if h.serveFile(ctx, name) {
+		return
+	}
+	if h.options.Fallback == "" || path.Ext(name) != "" || !h.serveFile(ctx, h.options.Fallback) {
+		http.NotFound(ctx.Writer, ctx.Request)
+	}
+}
+
+// serveFile writes a file, or the index of a directory, reporting false
+// when there is none
+func (h *handler) serveFile(ctx *controller.Context, name string) bool {
+	file, err := h.fsys.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		return h.serveFile(ctx, path.Join(name, h.options.Index))
+	}
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+
+	cacheControl := h.cacheControl
+	if ext := path.Ext(name); ext == ".html" || ext == ".htm" {
+		cacheControl = "no-cache"
+	}
+	ctx.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(ctx.Writer, ctx.Request, info.Name(), info.ModTime(), content)
+	return true
+}
+
+// hidden reports whether a path goes through a dot file or directory
+func hidden(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") && segment != "." {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Generate runs fn as the source of the pipeline, emitting values on the
+// returned channel until fn returns. emit reports false once the pipeline
+// stops, and fn should return then.
+func Generate[T any](p *Pipeline, fn func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	out := make(chan T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+		return fn(ctx, func(value T) bool {
+			return send(ctx, out, value)
+		})
+	})
+	return out
+}
+
+// FanOut processes the values of in with workers concurrent calls of fn,
+// merging their results in completion order
+func FanOut[In, Out any](p *Pipeline, in <-chan In, workers int, fn func(ctx context.Context, value In) (Out, error)) <-chan Out {
+	if workers < 1 {
+		workers = 1
+	}
+
+	out := make(chan Out)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		p.Go(func(ctx context.Context) error {
+			defer wg.Done()
+			for value := range receive(ctx, in) {
+				result, err := fn(ctx, value)
+				if err != nil {
+					return err
+				}
+				if !send(ctx, out, result) {
+					return nil
+				}
+			}
+			return nil
+		})
+	}
+	p.Go(func(ctx context.Context) error {
+		wg.Wait()
+		close(out)
+		return nil
+	})
+	return out
+}
+
+// Batch groups the values of in into slices of up to size values, emitting
+// a partial batch when maxWait passes after its first value. A zero
+// maxWait waits for full batches, and the last one when in closes.
+func Batch[T any](p *Pipeline, in <-chan T, size int, maxWait time.Duration) <-chan []T {
+	if size < 1 {
+		size = 1
+	}
+
+	out := make(chan []T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+
+		batch := make([]T, 0, size)
+		var timer *time.Timer
+		var expired <-chan time.Time
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, expired = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+			full := batch
+			batch = make([]T, 0, size)
+			return send(ctx, out, full)
+		}
+
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				batch = append(batch, value)
+				if len(batch) == 1 && maxWait > 0 {
+					timer = time.NewTimer(maxWait)
+					expired = timer.C
+				}
+				if len(batch) >= size && !flush() {
+					return nil
+				}
+			case <-expired:
+				timer, expired = nil, nil
+				if !flush() {
+					return nil
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Window groups the values of in received during each period into tumbling
+// windows, skipping empty ones
+func Window[T any](p *Pipeline, in <-chan T, period time.Duration) <-chan []T {
+	out := make(chan []T)
+	p.Go(func(ctx context.Context) error {
+		defer close(out)
+
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		window := make([]T, 0)
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok {
+					if len(window) > 0 {
+						send(ctx, out, window)
+					}
+					return nil
+				}
+				window = append(window, value)
+			case <-ticker.C:
+				if len(window) == 0 {
+					continue
+				}
+				if !send(ctx, out, window) {
+					return nil
+				}
+				window = make([]T, 0)
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	return out
+}
+
+// Sink consumes the values of in with workers concurrent calls of fn. Wait
+// returns once in is drained.
+func Sink[T any](p *Pipeline, in <-chan T, workers int, fn func(ctx context.Context, value T) error) {
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		p.Go(func(ctx context.Context) error {
+			for value := range receive(ctx, in) {
+				if err := fn(ctx, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// receive yields the values of in until it closes or ctx is done
+func receive[T any](ctx context.Context, in <-chan T) func(yield func(T) bool) {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case value, ok := <-in:
+				if !ok || !yield(value) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// ErrStopped is returned by Wait when the pipeline was stopped before its
+// stages finished
+var ErrStopped = errors.New("stream: pipeline stopped")
+
+// Pipeline runs the stages of a channel based flow, such as an ingestion
+// job, under a shared context. The first stage failure cancels the other
+// stages. Providers start their pipelines in OnApplicationBootstrap and
+// hold or embed the *Pipeline, whose OnApplicationShutdown stops the
+// stages and waits for them during the application shutdown:
+//
+//	type Ingestor struct {
+//		*stream.Pipeline
+//	}
+//
+//	func (i *Ingestor) OnApplicationBootstrap(ctx context.Context) error {
+//		events := stream.Generate(i.Pipeline, i.poll)
+//		batches := stream.Batch(i.Pipeline, events, 500, time.Second)
+//		stream.Sink(i.Pipeline, batches, 4, i.store)
+//		return nil
+//	}
+type Pipeline struct {
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// New creates a pipeline, named in its logs
+func New(name string) *Pipeline {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Pipeline{name: name, ctx: ctx, cancel: cancel}
+}
+
+// Name returns the name of the pipeline
+func (p *Pipeline) Name() string {
+	return p.name
+}
+
+// Context returns the context of the stages, done once the pipeline stops
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Go runs fn as a stage of the pipeline. A returned error, or a panic,
+// fails the pipeline and cancels the other stages.
+func (p *Pipeline) Go(fn func(ctx context.Context) error) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				p.fail(fmt.Errorf("stream: stage panicked: %v", recovered))
+			}
+		}()
+		if err := fn(p.ctx); err != nil && !errors.Is(err, context.Canceled) {
+			p.fail(err)
+		}
+	}()
+}
+
+// fail records the first stage error and cancels the pipeline
+func (p *Pipeline) fail(err error) {
+	p.mu.Lock()
+	first := p.err == nil
+	if first {
+		p.err = err
+	}
+	p.mu.Unlock()
+
+	if first {
+		logger.Error("Pipeline stage failed", "pipeline", p.name, "error", err)
+	}
+	p.cancel()
+}
+
+// Err returns the error that failed the pipeline, if any
+func (p *Pipeline) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Wait waits for the stages to finish and returns the first stage error
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	if err := p.Err(); err != nil {
+		return err
+	}
+	if p.ctx.Err() != nil {
+		return ErrStopped
+	}
+	return nil
+}
+
+// Stop cancels the stages and waits for them until ctx is done
+func (p *Pipeline) Stop(ctx context.Context) error {
+	p.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return p.Err()
+	case <-ctx.Done():
+		return fmt.Errorf("stream: pipeline %s did not stop: %w", p.name, ctx.Err())
+	}
+}
+
+// OnApplicationShutdown stops the pipeline with the application
+func (p *Pipeline) OnApplicationShutdown(ctx context.Context, signal string) error {
+	return p.Stop(ctx)
+}
+
+// send delivers value on out, reporting false once ctx is done
+func send[T any](ctx context.Context, out chan<- T, value T) bool {
+	select {
+	case out <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,111 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+const (
+	// AttrTenant is the log attribute carrying the tenant
+	AttrTenant = "tenant"
+	// SpanAttrTenant is the span attribute carrying the tenant
+	SpanAttrTenant = "tenant.id"
+	// LabelOther replaces the tenants over the label limit in metric labels
+	LabelOther = "other"
+)
+
+// Labels bounds the cardinality of tenant metric labels: the first max
+// distinct tenants keep their ID and the rest share LabelOther
+type Labels struct {
+	mu   sync.Mutex
+	max  int
+	seen map[string]struct{}
+}
+
+// NewLabels returns Labels keeping at most max tenant IDs; zero or less
+// keeps every ID
+func NewLabels(max int) *Labels {
+	return &Labels{max: max, seen: make(map[string]struct{})}
+}
+
+// Label returns the metric label of a tenant
+func (l *Labels) Label(id string) string {
+	if id == "" || l.max <= 0 {
+		return id
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[id]; ok {
+		return id
+	}
+	if len(l.seen) >= l.max {
+		return LabelOther
+	}
+	l.seen[id] = struct{}{}
+	return id
+}
+
+var (
+	labelsMu sync.RWMutex
+	labels   = NewLabels(0)
+)
+
+// labelsKey is the context key of the Labels of an application
+type labelsKey struct{}
+
+// SetLabelLimit bounds the tenant IDs used as metric labels by Label for
+// requests without their own Labels, see NewLabels and LabelsMiddleware
+func SetLabelLimit(max int) {
+	labelsMu.Lock()
+	defer labelsMu.Unlock()
+	labels = NewLabels(max)
+}
+
+// WithLabels returns a context whose tenant metric labels come from l
+func WithLabels(ctx context.Context, l *Labels) context.Context {
+	return context.WithValue(ctx, labelsKey{}, l)
+}
+
+// LabelsMiddleware returns an HTTP middleware labelling the tenants of its
+// requests with l, e.g. one Labels per application. It must run before the
+// middlewares recording metrics.
+func LabelsMiddleware(l *Labels) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(WithLabels(r.Context(), l)))
+		})
+	}
+}
+
+// Label returns the metric label of the tenant stored in ctx, or "" without
+// a tenant
+func Label(ctx context.Context) string {
+	id, _ := FromContext(ctx)
+	if l, ok := ctx.Value(labelsKey{}).(*Labels); ok {
+		return l.Label(id)
+	}
+	labelsMu.RLock()
+	defer labelsMu.RUnlock()
+	return labels.Label(id)
+}
+
+// LogAttrs returns the tenant stored in ctx as logger attributes
+func LogAttrs(ctx context.Context) []interface{} {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return []interface{}{AttrTenant, id}
+}
+
+// SpanAttributes returns the tenant stored in ctx as span attributes, to be
+// set on the span carried by ctx
+func SpanAttributes(ctx context.Context) map[string]string {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return map[string]string{SpanAttrTenant: id}
+}
@@ -0,0 +1,65 @@
+package tenant
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// HeaderTenant is the header read by the default resolver
+const HeaderTenant = "X-Tenant-ID"
+
+// contextKey is the key used to store the tenant in the request context
+type contextKey struct{}
+
+// Resolver returns the tenant or principal a request belongs to, reporting
+// false when it cannot tell
+type Resolver func(r *http.Request) (string, bool)
+
+// Header resolves the tenant from a request header
+func Header(name string) Resolver {
+	return func(r *http.Request) (string, bool) {
+		value := strings.TrimSpace(r.Header.Get(name))
+		return value, value != ""
+	}
+}
+
+// Middleware returns an HTTP middleware that stores the tenant resolved by
+// the first successful resolver in the request context. Without resolvers
+// the X-Tenant-ID header is used.
+func Middleware(resolvers ...Resolver) func(http.Handler) http.Handler {
+	if len(resolvers) == 0 {
+		resolvers = []Resolver{Header(HeaderTenant)}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, resolve := range resolvers {
+				if id, ok := resolve(r); ok {
+					r = r.WithContext(NewContext(r.Context(), id))
+					break
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewContext returns a copy of ctx carrying the tenant
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant stored in ctx, if any
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok
+}
+
+// FromRequest returns the tenant resolved for a request, if any
+func FromRequest(r *http.Request) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	return FromContext(r.Context())
+}
@@ -0,0 +1,166 @@
+package twofactor
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/ids"
+	"github.com/kevenmiano/nestgo/pkg/service"
+)
+
+const (
+	// DefaultRecoveryCodes is the number of recovery codes generated per user
+	DefaultRecoveryCodes = 10
+
+	// recoveryAlphabet avoids ambiguous characters
+	recoveryAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+	usedCodeKeyPrefix = "twofactor:used:"
+)
+
+// Options configures the TwoFactorService
+type Options struct {
+	// Issuer is shown by authenticator apps
+	Issuer string
+	// Skew is the number of periods of clock drift accepted on each side;
+	// zero selects DefaultSkew and a negative value disables drift
+	Skew int
+	// Store remembers used codes to prevent reuse within their validity (optional)
+	Store cache.Store
+}
+
+// Enrollment holds the data shown to a user when enabling 2FA
+type Enrollment struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioningUri"`
+	RecoveryCodes   []string `json:"recoveryCodes"`
+	// RecoveryHashes must be persisted instead of the plain recovery codes
+	RecoveryHashes []string `json:"-"`
+}
+
+// TwoFactorService manages TOTP enrollment and verification.
+// Register it as a provider and inject it with `inject:"TwoFactorService"`.
+type TwoFactorService struct {
+	service.BaseService
+	options Options
+}
+
+// NewTwoFactorService creates a two-factor service
+func NewTwoFactorService(opts Options) *TwoFactorService {
+	if opts.Issuer == "" {
+		opts.Issuer = "NestGo"
+	}
+	if opts.Skew < 0 {
+		opts.Skew = 0
+	} else if opts.Skew == 0 {
+		opts.Skew = DefaultSkew
+	}
+
+	return &TwoFactorService{options: opts}
+}
+
+// Enroll generates a secret, provisioning URI and recovery codes for an account
+func (tfs *TwoFactorService) Enroll(account string) (*Enrollment, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := GenerateRecoveryCodes(DefaultRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret:          secret,
+		ProvisioningURI: ProvisioningURI(tfs.options.Issuer, account, secret),
+		RecoveryCodes:   codes,
+		RecoveryHashes:  hashes,
+	}, nil
+}
+
+// Verify validates a TOTP code, rejecting codes already used when a store is configured
+func (tfs *TwoFactorService) Verify(secret, code string) (bool, error) {
+	valid, err := ValidateCode(secret, code, clock.Now(), tfs.options.Skew)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	if tfs.options.Store == nil {
+		return true, nil
+	}
+
+	// A code stays valid for (2*skew+1) periods, so remember it that long
+	ttl := time.Duration(2*tfs.options.Skew+1) * DefaultPeriod
+	fresh, err := tfs.options.Store.SetIfAbsent(usedCodeKeyPrefix+HashRecoveryCode(secret+":"+code), []byte("1"), ttl)
+	if err != nil {
+		return false, err
+	}
+	return fresh, nil
+}
+
+// VerifyRequest verifies the code carried by the HeaderCode header of a
+// request against the secret of the user, consuming it when a store is
+// configured. Call it once per session, e.g. from the second step of a
+// login, then mark the issued session with MarkVerified.
+func (tfs *TwoFactorService) VerifyRequest(r *http.Request, secret string) (bool, error) {
+	code := r.Header.Get(HeaderCode)
+	if secret == "" || code == "" {
+		return false, nil
+	}
+	return tfs.Verify(secret, code)
+}
+
+// VerifyRecoveryCode checks a recovery code against the stored hashes and returns
+// the remaining hashes with the used one removed
+func (tfs *TwoFactorService) VerifyRecoveryCode(code string, hashes []string) (bool, []string) {
+	hash := HashRecoveryCode(code)
+	for i, stored := range hashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			remaining := append(append([]string{}, hashes[:i]...), hashes[i+1:]...)
+			return true, remaining
+		}
+	}
+	return false, hashes
+}
+
+// GenerateRecoveryCodes returns plain recovery codes and their hashes
+func GenerateRecoveryCodes(count int) ([]string, []string, error) {
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+
+	for i := 0; i < count; i++ {
+		raw, err := ids.Bytes(10)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		var builder strings.Builder
+		for j, b := range raw {
+			if j == 5 {
+				builder.WriteByte('-')
+			}
+			builder.WriteByte(recoveryAlphabet[int(b)%len(recoveryAlphabet)])
+		}
+
+		code := builder.String()
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the hash persisted for a recovery code
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
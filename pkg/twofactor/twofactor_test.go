@@ -0,0 +1,169 @@
+package twofactor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/auth"
+	"github.com/kevenmiano/nestgo/pkg/cache"
+	"github.com/kevenmiano/nestgo/pkg/clock"
+)
+
+// rfcSecret is the base32 encoding of the RFC 6238 SHA-1 test key
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateCode(t *testing.T) {
+	at := time.Unix(1111111109, 0)
+	tests := []struct {
+		name    string
+		secret  string
+		code    string
+		at      time.Time
+		skew    int
+		want    bool
+		wantErr bool
+	}{
+		{name: "RFC 6238 vector", secret: rfcSecret, code: "287082", at: time.Unix(59, 0), want: true},
+		{name: "RFC 6238 vector with leading zero", secret: rfcSecret, code: "081804", at: at, want: true},
+		{name: "lowercase spaced secret", secret: "gezd gnbv gy3t qojq gezd gnbv gy3t qojq", code: "081804", at: at, want: true},
+		{name: "wrong code", secret: rfcSecret, code: "000000", at: at},
+		{name: "wrong length", secret: rfcSecret, code: "81804", at: at},
+		{name: "previous period within skew", secret: rfcSecret, code: "081804", at: at.Add(DefaultPeriod), skew: 1, want: true},
+		{name: "previous period without skew", secret: rfcSecret, code: "081804", at: at.Add(DefaultPeriod)},
+		{name: "beyond skew", secret: rfcSecret, code: "081804", at: at.Add(2 * DefaultPeriod), skew: 1},
+		{name: "invalid secret", secret: "not base32!", code: "081804", at: at, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := ValidateCode(tt.secret, tt.code, tt.at, tt.skew)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateCode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if valid != tt.want {
+				t.Errorf("ValidateCode() = %v, want %v", valid, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyConsumesCodes(t *testing.T) {
+	frozen := clock.NewFrozen(time.Unix(1111111109, 0))
+	clock.SetDefault(frozen)
+	defer clock.SetDefault(nil)
+
+	store := cache.NewMemoryStore()
+	defer store.Close()
+	service := NewTwoFactorService(Options{Store: store})
+	code, err := GenerateCode(rfcSecret, frozen.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		code    string
+		want    bool
+	}{
+		{name: "first use", code: code, want: true},
+		{name: "reuse", code: code},
+		{name: "reuse in the next period", advance: DefaultPeriod, code: code},
+		{name: "wrong code", code: "000000"},
+		{name: "expired code", advance: 2 * DefaultPeriod, code: code},
+	}
+	for _, step := range steps {
+		frozen.Advance(step.advance)
+		valid, err := service.Verify(rfcSecret, step.code)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if valid != step.want {
+			t.Errorf("%s: Verify() = %v, want %v", step.name, valid, step.want)
+		}
+	}
+}
+
+func TestVerifyRecoveryCode(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewTwoFactorService(Options{})
+
+	tests := []struct {
+		name          string
+		code          string
+		want          bool
+		wantRemaining int
+	}{
+		{name: "valid code", code: codes[1], want: true, wantRemaining: 2},
+		{name: "lowercase with spaces", code: " " + strings.ToLower(codes[2][:3]) + " " + codes[2][3:] + " ", want: true, wantRemaining: 2},
+		{name: "unknown code", code: "AAAAA-AAAAA", wantRemaining: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, remaining := service.VerifyRecoveryCode(tt.code, hashes)
+			if valid != tt.want || len(remaining) != tt.wantRemaining {
+				t.Errorf("VerifyRecoveryCode() = %v with %d remaining, want %v with %d", valid, len(remaining), tt.want, tt.wantRemaining)
+			}
+		})
+	}
+	if len(hashes) != 3 {
+		t.Errorf("stored hashes changed to %d", len(hashes))
+	}
+}
+
+func TestGuard(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		want      int
+	}{
+		{name: "anonymous", want: http.StatusUnauthorized},
+		{name: "password only", principal: &auth.Principal{Subject: "ana", Claims: map[string]interface{}{ClaimAMR: []interface{}{"pwd"}}}, want: http.StatusUnauthorized},
+		{name: "verified token claims", principal: &auth.Principal{Subject: "ana", Claims: map[string]interface{}{ClaimAMR: []interface{}{"pwd", MethodOTP}}}, want: http.StatusNoContent},
+		{name: "marked session", principal: &auth.Principal{Subject: "ana", Claims: MarkVerified(map[string]interface{}{ClaimAMR: []string{"pwd"}})}, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/account", nil)
+			// A code in the request never stands in for a verified session
+			r.Header.Set(HeaderCode, "081804")
+			if tt.principal != nil {
+				r = r.WithContext(auth.NewContext(r.Context(), tt.principal))
+			}
+			w := httptest.NewRecorder()
+			NewGuard(nil).Middleware(ok).ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkVerified(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   int
+	}{
+		{name: "nil claims", want: 1},
+		{name: "string methods", claims: map[string]interface{}{ClaimAMR: []string{"pwd"}}, want: 2},
+		{name: "decoded methods", claims: map[string]interface{}{ClaimAMR: []interface{}{"pwd", "mfa"}}, want: 3},
+		{name: "already verified", claims: map[string]interface{}{ClaimAMR: []interface{}{MethodOTP}}, want: 1},
+	}
+	for _, tt := range tests {
+		claims := MarkVerified(tt.claims)
+		methods, _ := claims[ClaimAMR].([]interface{})
+		if !Verified(claims) || len(methods) != tt.want {
+			t.Errorf("%s: amr = %v, want %d methods including %s", tt.name, claims[ClaimAMR], tt.want, MethodOTP)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package union
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+)
+
+// TagDiscriminator names the JSON property selecting the concrete type of
+// a union field: `json:"method" discriminator:"kind"`. It applies to the
+// union values of the field, including slice and map elements.
+const TagDiscriminator = "discriminator"
+
+// DefaultDiscriminator is the property used when a field has no tag
+const DefaultDiscriminator = "type"
+
+// union is an interface type with its registered variants
+type union struct {
+	iface    reflect.Type
+	variants map[string]reflect.Type
+	names    map[reflect.Type]string
+}
+
+var (
+	unions      = make(map[reflect.Type]*union)
+	unionsMutex sync.RWMutex
+
+	// containsCache caches whether a type holds union values
+	containsCache sync.Map
+)
+
+// Register adds a variant to the union of interface type T. Bodies carrying
+// the discriminator value decode into the variant's concrete type, and
+// serialized variants get the value added to their JSON object.
+//
+//	union.Register[PaymentMethod]("card", CardPayment{})
+//	union.Register[PaymentMethod]("pix", &PixPayment{})
+func Register[T any](value string, variant T) {
+	iface := reflect.TypeOf((*T)(nil)).Elem()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("union: %s is not an interface type", iface))
+	}
+	concrete := reflect.TypeOf(any(variant))
+	if concrete == nil {
+		panic(fmt.Sprintf("union: nil variant %q for %s", value, iface))
+	}
+
+	unionsMutex.Lock()
+	defer unionsMutex.Unlock()
+
+	u, ok := unions[iface]
+	if !ok {
+		u = &union{iface: iface, variants: make(map[string]reflect.Type), names: make(map[reflect.Type]string)}
+		unions[iface] = u
+	}
+	u.variants[value] = concrete
+	u.names[concrete] = value
+
+	// Registering can turn types seen before into union holders
+	containsCache.Range(func(key, _ interface{}) bool {
+		containsCache.Delete(key)
+		return true
+	})
+}
+
+// IsUnion reports whether t is an interface type with registered variants
+func IsUnion(t reflect.Type) bool {
+	return lookup(t) != nil
+}
+
+// Variants returns the variants of the union of interface type t by
+// discriminator value, or nil when t is not a union
+func Variants(t reflect.Type) map[string]reflect.Type {
+	u := lookup(t)
+	if u == nil {
+		return nil
+	}
+	unionsMutex.RLock()
+	defer unionsMutex.RUnlock()
+	variants := make(map[string]reflect.Type, len(u.variants))
+	for value, variant := range u.variants {
+		variants[value] = variant
+	}
+	return variants
+}
+
+// Contains reports whether values of type t hold union values, directly
+// or through fields, pointers, slices and maps
+func Contains(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if cached, ok := containsCache.Load(t); ok {
+		return cached.(bool)
+	}
+
+	// Store false first so recursive types terminate
+	containsCache.Store(t, false)
+	has := false
+	switch t.Kind() {
+	case reflect.Interface:
+		has = IsUnion(t)
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		has = Contains(t.Elem())
+	case reflect.Struct:
+		for _, field := range fields(t) {
+			if Contains(t.FieldByIndex(field.Index).Type) {
+				has = true
+				break
+			}
+		}
+	}
+	containsCache.Store(t, has)
+	return has
+}
+
+// Unmarshal decodes JSON into v like json.Unmarshal, resolving union
+// values to the variant named by their discriminator property
+func Unmarshal(data []byte, v interface{}) error {
+	target := reflect.ValueOf(v)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("union: Unmarshal requires a non-nil pointer, got %T", v)
+	}
+	return decode(data, target.Elem(), DefaultDiscriminator)
+}
+
+// Marshal encodes v like json.Marshal, adding the discriminator property
+// to the JSON object of every union value
+func Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || v == nil || !Contains(reflect.TypeOf(v)) {
+		return data, err
+	}
+
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	tagValue(reflect.ValueOf(v), tree, DefaultDiscriminator)
+	return json.Marshal(tree)
+}
+
+// lookup returns the union of an interface type
+func lookup(t reflect.Type) *union {
+	unionsMutex.RLock()
+	defer unionsMutex.RUnlock()
+	return unions[t]
+}
+
+// decode decodes data into v, delegating to encoding/json for types
+// without union values
+func decode(data []byte, v reflect.Value, discriminator string) error {
+	if !Contains(v.Type()) {
+		return json.Unmarshal(data, v.Addr().Interface())
+	}
+	if isNull(data) {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		return decodeVariant(data, v, discriminator)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return decode(data, v.Elem(), discriminator)
+	case reflect.Slice:
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := decode(item, slice.Index(i), discriminator); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("union: unsupported map key type %s", v.Type().Key())
+		}
+		var entries map[string]json.RawMessage
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return err
+		}
+		result := reflect.MakeMapWithSize(v.Type(), len(entries))
+		for key, entry := range entries {
+			item := reflect.New(v.Type().Elem()).Elem()
+			if err := decode(entry, item, discriminator); err != nil {
+				return err
+			}
+			result.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), item)
+		}
+		v.Set(result)
+	case reflect.Struct:
+		return decodeStruct(data, v)
+	default:
+		return fmt.Errorf("union: unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// decodeStruct decodes a JSON object into the fields of a struct
+func decodeStruct(data []byte, v reflect.Value) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	for _, field := range fields(v.Type()) {
+		raw, _, ok := codec.Property(object, field.Name)
+		if !ok {
+			continue
+		}
+
+		if err := decode(raw, v.FieldByIndex(field.Index), field.discriminator); err != nil {
+			return fmt.Errorf("%s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// decodeVariant decodes a JSON object into the variant named by its
+// discriminator property
+func decodeVariant(data []byte, v reflect.Value, discriminator string) error {
+	u := lookup(v.Type())
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("%s must be a JSON object", v.Type().Name())
+	}
+
+	var name string
+	if raw, ok := object[discriminator]; !ok || json.Unmarshal(raw, &name) != nil || name == "" {
+		return fmt.Errorf("missing %q discriminator for %s", discriminator, v.Type().Name())
+	}
+
+	concrete, ok := u.variantOf(name)
+	if !ok {
+		return fmt.Errorf("unknown %s %q for %s, expected one of: %s", discriminator, name, v.Type().Name(), strings.Join(u.variantNames(), ", "))
+	}
+
+	variant := reflect.New(concrete).Elem()
+	if concrete.Kind() == reflect.Ptr {
+		variant.Set(reflect.New(concrete.Elem()))
+	}
+	if err := decode(data, variant, DefaultDiscriminator); err != nil {
+		return err
+	}
+	v.Set(variant)
+	return nil
+}
+
+// variantOf returns the variant type of a discriminator value
+func (u *union) variantOf(name string) (reflect.Type, bool) {
+	unionsMutex.RLock()
+	defer unionsMutex.RUnlock()
+	concrete, ok := u.variants[name]
+	return concrete, ok
+}
+
+// variantNames returns the sorted discriminator values of a union
+func (u *union) variantNames() []string {
+	unionsMutex.RLock()
+	defer unionsMutex.RUnlock()
+
+	names := make([]string, 0, len(u.variants))
+	for name := range u.variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tagValue walks a value alongside its decoded JSON tree, adding the
+// discriminator property to the objects of union values
+func tagValue(v reflect.Value, tree interface{}, discriminator string) {
+	if !v.IsValid() || tree == nil || !Contains(v.Type()) {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		concrete := v.Elem()
+		if object, ok := tree.(map[string]interface{}); ok {
+			if u := lookup(v.Type()); u != nil {
+				if name, ok := u.nameOf(concrete.Type()); ok {
+					if _, exists := object[discriminator]; !exists {
+						object[discriminator] = name
+					}
+				}
+			}
+		}
+		tagValue(concrete, tree, DefaultDiscriminator)
+	case reflect.Ptr:
+		if !v.IsNil() {
+			tagValue(v.Elem(), tree, discriminator)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := tree.([]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < v.Len() && i < len(items); i++ {
+			tagValue(v.Index(i), items[i], discriminator)
+		}
+	case reflect.Map:
+		entries, ok := tree.(map[string]interface{})
+		if !ok {
+			return
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			tagValue(iter.Value(), entries[fmt.Sprint(iter.Key().Interface())], discriminator)
+		}
+	case reflect.Struct:
+		object, ok := tree.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, field := range fields(v.Type()) {
+			tagValue(v.FieldByIndex(field.Index), object[field.Name], field.discriminator)
+		}
+	}
+}
+
+// nameOf returns the discriminator value of a variant type
+func (u *union) nameOf(concrete reflect.Type) (string, bool) {
+	unionsMutex.RLock()
+	defer unionsMutex.RUnlock()
+	name, ok := u.names[concrete]
+	return name, ok
+}
+
+// field is a JSON field of a struct with the discriminator of its union values
+type field struct {
+	codec.Field
+	discriminator string
+}
+
+// fields lists the JSON fields of a struct with their discriminators
+func fields(t reflect.Type) []field {
+	jsonFields := codec.Fields(t)
+	result := make([]field, len(jsonFields))
+	for i, jsonField := range jsonFields {
+		discriminator := jsonField.Tag.Get(TagDiscriminator)
+		if discriminator == "" {
+			discriminator = DefaultDiscriminator
+		}
+		result[i] = field{Field: jsonField, discriminator: discriminator}
+	}
+	return result
+}
+
+// isNull reports whether data is the JSON null literal
+func isNull(data []byte) bool {
+	return string(bytes.TrimSpace(data)) == "null"
+}
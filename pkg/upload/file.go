@@ -0,0 +1,285 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// maxValuesSize bounds the non-file form values of a request
+const maxValuesSize = 1 << 20
+
+// sniffSize is the number of bytes used to detect the content type
+const sniffSize = 512
+
+// octetStream is the type sniffed for content that is not recognized
+const octetStream = "application/octet-stream"
+
+// File is an uploaded file, bound to handler arguments declared as *File
+// or []*File:
+//
+//	Upload func(file *upload.File) error `route:"POST /avatar" upload:"max=2MB,types=image/*"`
+type File struct {
+	// Field is the form field of the file
+	Field string `json:"field"`
+	// Filename is the base name sent by the client, not safe as a path
+	// without checks against the destination
+	Filename string `json:"filename"`
+	// ContentType is detected from the content, falling back to the type
+	// sent by the client for content that is not recognized when
+	// application/octet-stream is accepted
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// Path is the temporary file of disk stored uploads
+	Path string `json:"-"`
+
+	data  []byte
+	saved bool
+}
+
+// Open returns the content of the file
+func (f *File) Open() (io.ReadCloser, error) {
+	if f.Path != "" {
+		return os.Open(f.Path)
+	}
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+// Bytes returns the content of the file
+func (f *File) Bytes() ([]byte, error) {
+	if f.Path != "" {
+		return os.ReadFile(f.Path)
+	}
+	return f.data, nil
+}
+
+// SaveTo stores the file at dest, which is kept after the request
+func (f *File) SaveTo(dest string) error {
+	if f.Path != "" {
+		if err := os.Rename(f.Path, dest); err == nil {
+			f.Path, f.saved = dest, true
+			return nil
+		}
+	}
+
+	source, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
+
+// remove deletes the temporary file of a disk stored upload
+func (f *File) remove() {
+	if f.Path == "" || f.saved {
+		return
+	}
+	if err := os.Remove(f.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to remove uploaded file", "path", f.Path, "error", err)
+	}
+}
+
+// parsed holds the files and form values of a request
+type parsed struct {
+	files  []*File
+	values url.Values
+}
+
+// contextKey is the key used to store the parsed upload in the request context
+type contextKey struct{}
+
+// Middleware returns an HTTP middleware parsing the multipart uploads of a
+// request with the limits of options, the FileInterceptor of a route.
+// Files are read by Files and Values, and disk stored files are removed
+// once the handler returns.
+func Middleware(options Options) func(http.Handler) http.Handler {
+	options = options.withDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upload, err := parse(r, options)
+			defer func() {
+				for _, file := range upload.files {
+					file.remove()
+				}
+			}()
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, upload)))
+		})
+	}
+}
+
+// Files returns the files uploaded to the request
+func Files(r *http.Request) []*File {
+	upload, _ := r.Context().Value(contextKey{}).(*parsed)
+	if upload == nil {
+		return nil
+	}
+	return upload.files
+}
+
+// Values returns the non-file form values sent with the uploads
+func Values(r *http.Request) url.Values {
+	upload, _ := r.Context().Value(contextKey{}).(*parsed)
+	if upload == nil {
+		return url.Values{}
+	}
+	return upload.values
+}
+
+// parse reads the multipart body of a request, keeping the files of the
+// configured field and the form values. The files read before a failure
+// are returned for removal.
+func parse(r *http.Request, options Options) (*parsed, error) {
+	upload := &parsed{files: make([]*File, 0, 1), values: url.Values{}}
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return upload, controller.NewHTTPError(http.StatusBadRequest, "Expected a multipart/form-data request")
+	}
+
+	valuesSize := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return upload, nil
+		}
+		if err != nil {
+			return upload, controller.NewHTTPError(http.StatusBadRequest, "Invalid multipart body")
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxValuesSize-int64(valuesSize)+1))
+			valuesSize += len(value)
+			if err != nil || valuesSize > maxValuesSize {
+				return upload, controller.NewHTTPError(http.StatusRequestEntityTooLarge, "Form values too large")
+			}
+			upload.values.Add(part.FormName(), string(value))
+			continue
+		}
+		if part.FormName() != options.Field {
+			return upload, controller.NewHTTPError(http.StatusBadRequest, "Unexpected file field "+part.FormName())
+		}
+		if len(upload.files) >= options.MaxFiles {
+			return upload, controller.NewHTTPError(http.StatusBadRequest, "Too many files")
+		}
+
+		file, err := readFile(part, options)
+		if file != nil {
+			upload.files = append(upload.files, file)
+		}
+		if err != nil {
+			return upload, err
+		}
+	}
+}
+
+// readFile stores a file part, checking its type and size
+func readFile(part *multipart.Part, options Options) (*File, error) {
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(part, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, controller.NewHTTPError(http.StatusBadRequest, "Invalid multipart body")
+	}
+	head = head[:n]
+
+	contentType, accepted := detectType(head, part.Header.Get("Content-Type"), options)
+	file := &File{
+		Field:       part.FormName(),
+		Filename:    filepath.Base(filepath.Clean("/" + part.FileName())),
+		ContentType: contentType,
+	}
+	if !accepted {
+		return nil, controller.NewHTTPError(http.StatusUnsupportedMediaType, "File type "+file.ContentType+" is not accepted")
+	}
+
+	content := io.MultiReader(bytes.NewReader(head), io.LimitReader(part, options.MaxSize+1-int64(n)))
+	if options.Storage == DiskStorage {
+		temp, err := os.CreateTemp(options.Dir, "nestgo-upload-*")
+		if err != nil {
+			return nil, err
+		}
+		file.Path = temp.Name()
+		file.Size, err = io.Copy(temp, content)
+		if closeErr := temp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return file, controller.NewHTTPError(http.StatusBadRequest, "Invalid multipart body")
+		}
+	} else {
+		file.data, err = io.ReadAll(content)
+		file.Size = int64(len(file.data))
+		if err != nil {
+			return nil, controller.NewHTTPError(http.StatusBadRequest, "Invalid multipart body")
+		}
+	}
+
+	if file.Size > options.MaxSize {
+		return file, controller.NewHTTPError(http.StatusRequestEntityTooLarge, "File "+file.Filename+" is too large")
+	}
+	return file, nil
+}
+
+// detectType sniffs the content type and reports whether it is accepted.
+// The declared type is trusted for content recognized as plain text when it
+// declares a text type such as text/csv or application/json. Content
+// recognized as generic binary may be anything, so it is accepted only when
+// application/octet-stream is, reported with its declared type when that
+// one is accepted too.
+func detectType(head []byte, declared string, options Options) (string, bool) {
+	detected := http.DetectContentType(head)
+	switch {
+	case detected == octetStream:
+		if !options.accepts(octetStream) {
+			return detected, false
+		}
+		if declared != "" && options.accepts(declared) {
+			return declared, true
+		}
+		return detected, true
+	case declared != "" && detected == "text/plain; charset=utf-8" &&
+		(strings.HasPrefix(declared, "text/") || strings.HasPrefix(declared, "application/")):
+		return declared, options.accepts(declared)
+	}
+	return detected, options.accepts(detected)
+}
+
+// writeError writes a parse failure as the JSON error of the server
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	message := "Internal server error"
+	var httpErr *controller.HTTPError
+	if errors.As(err, &httpErr) {
+		status, message = httpErr.Status, httpErr.Message
+	} else {
+		logger.Error("Failed to read upload", "error", err)
+	}
+
+	jsonData, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
@@ -0,0 +1,105 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"testing"
+)
+
+var (
+	pngHead  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	binary   = []byte{0x00, 0x01, 0x02, 0x03, 0xfe, 0xff}
+	htmlHead = []byte("<!DOCTYPE html><html><script>alert(1)</script>")
+	csvHead  = []byte("name,email\nmaria,maria@example.com\n")
+)
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name         string
+		head         []byte
+		declared     string
+		types        []string
+		wantType     string
+		wantAccepted bool
+	}{
+		{name: "sniffed type", head: pngHead, declared: "image/png", types: []string{"image/*"}, wantType: "image/png", wantAccepted: true},
+		{name: "declared type ignored for recognized content", head: htmlHead, declared: "image/png", types: []string{"image/*"}, wantType: "text/html; charset=utf-8"},
+		{name: "text declared as csv", head: csvHead, declared: "text/csv", types: []string{"text/csv"}, wantType: "text/csv", wantAccepted: true},
+		{name: "binary rejected by the allowlist", head: binary, declared: "image/png", types: []string{"image/png"}, wantType: octetStream},
+		{name: "binary allowed as octet-stream", head: binary, declared: "application/zip", types: []string{"application/zip", "application/octet-stream"}, wantType: "application/zip", wantAccepted: true},
+		{name: "binary with a declared type not accepted", head: binary, declared: "image/png", types: []string{"application/octet-stream"}, wantType: octetStream, wantAccepted: true},
+		{name: "binary matched by a pattern", head: binary, declared: "application/pdf", types: []string{"application/*"}, wantType: "application/pdf", wantAccepted: true},
+		{name: "no allowlist", head: binary, declared: "application/pdf", wantType: "application/pdf", wantAccepted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotAccepted := detectType(tt.head, tt.declared, Options{Types: tt.types})
+			if gotType != tt.wantType || gotAccepted != tt.wantAccepted {
+				t.Errorf("detectType() = %q, %v, want %q, %v", gotType, gotAccepted, tt.wantType, tt.wantAccepted)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    Options
+		content    []byte
+		declared   string
+		wantStatus int
+	}{
+		{name: "accepted", options: Options{Types: []string{"image/png"}}, content: pngHead, declared: "image/png", wantStatus: http.StatusOK},
+		{name: "spoofed type", options: Options{Types: []string{"image/png"}}, content: binary, declared: "image/png", wantStatus: http.StatusUnsupportedMediaType},
+		{name: "too large", options: Options{MaxSize: 4}, content: pngHead, declared: "image/png", wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "on disk", options: Options{Storage: DiskStorage, Dir: t.TempDir()}, content: pngHead, declared: "image/png", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			header := make(textproto.MIMEHeader)
+			header.Set("Content-Disposition", `form-data; name="file"; filename="../avatar.png"`)
+			header.Set("Content-Type", tt.declared)
+			part, _ := form.CreatePart(header)
+			part.Write(tt.content)
+			form.Close()
+
+			var received []*File
+			var content []byte
+			handler := Middleware(tt.options)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = Files(r)
+				if len(received) == 1 {
+					reader, _ := received[0].Open()
+					content, _ = io.ReadAll(reader)
+					reader.Close()
+				}
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/", &body)
+			request.Header.Set("Content-Type", form.FormDataContentType())
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", recorder.Code, recorder.Body.String(), tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if len(received) != 1 || received[0].Filename != "avatar.png" || !bytes.Equal(content, tt.content) {
+				t.Fatalf("received %+v with %q, want avatar.png with %q", received, content, tt.content)
+			}
+			if path := received[0].Path; path != "" {
+				if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("temporary file %s kept after the request: %v", path, err)
+				}
+			}
+		})
+	}
+}
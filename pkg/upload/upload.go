@@ -0,0 +1,180 @@
+package upload
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TagUpload configures the uploads of a route, e.g.
+// `upload:"field=avatar,max=5MB,types=image/png|image/jpeg,storage=disk"`
+const TagUpload = "upload"
+
+// DefaultField is the form field of uploaded files when none is set
+const DefaultField = "file"
+
+// DefaultMaxSize bounds the size of each uploaded file when none is set
+const DefaultMaxSize = 10 << 20
+
+// DefaultMaxFiles bounds the number of files of a request when none is set
+const DefaultMaxFiles = 10
+
+// Storage is where uploaded files are kept while the request is handled
+type Storage int
+
+const (
+	// MemoryStorage keeps files in memory, for small files
+	MemoryStorage Storage = iota
+	// DiskStorage streams files to temporary files, removed after the
+	// request unless saved with File.SaveTo
+	DiskStorage
+)
+
+// Options configures the handling of uploaded files
+type Options struct {
+	// Field is the form field holding the files, DefaultField when empty
+	Field string
+	// MaxSize bounds the size of each file in bytes, DefaultMaxSize when zero
+	MaxSize int64
+	// MaxFiles bounds the number of files, DefaultMaxFiles when zero
+	MaxFiles int
+	// Types lists the accepted MIME types, such as "image/png" or
+	// "image/*", checked against the sniffed content; empty accepts any.
+	// Content that is not recognized is accepted only by
+	// application/octet-stream or a pattern matching it.
+	Types   []string
+	Storage Storage
+	// Dir holds disk stored files, the system temporary directory when empty
+	Dir string
+}
+
+// withDefaults fills the unset options
+func (o Options) withDefaults() Options {
+	if o.Field == "" {
+		o.Field = DefaultField
+	}
+	if o.MaxSize <= 0 {
+		o.MaxSize = DefaultMaxSize
+	}
+	if o.MaxFiles <= 0 {
+		o.MaxFiles = DefaultMaxFiles
+	}
+	if o.Dir == "" {
+		o.Dir = os.TempDir()
+	}
+	return o
+}
+
+var (
+	defaults      = Options{}
+	defaultsMutex sync.RWMutex
+)
+
+// SetDefaults sets the options of routes without an upload tag, and the
+// base of upload tags
+func SetDefaults(options Options) {
+	defaultsMutex.Lock()
+	defer defaultsMutex.Unlock()
+	defaults = options
+}
+
+// Defaults returns the options of routes without an upload tag
+func Defaults() Options {
+	defaultsMutex.RLock()
+	defer defaultsMutex.RUnlock()
+	return defaults
+}
+
+// ParseTag parses an upload tag over base: comma separated field=, max=,
+// files=, types= (separated by |), storage=memory|disk and dir= settings
+func ParseTag(tag string, base Options) (Options, error) {
+	options := base
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Options{}, fmt.Errorf("invalid upload setting %q", part)
+		}
+
+		switch key {
+		case "field":
+			options.Field = value
+		case "max":
+			size, err := ParseSize(value)
+			if err != nil {
+				return Options{}, err
+			}
+			options.MaxSize = size
+		case "files":
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 1 {
+				return Options{}, fmt.Errorf("invalid upload file count %q", value)
+			}
+			options.MaxFiles = count
+		case "types":
+			options.Types = strings.Split(value, "|")
+			for _, pattern := range options.Types {
+				if _, _, err := mime.ParseMediaType(pattern); err != nil {
+					return Options{}, fmt.Errorf("invalid upload type %q", pattern)
+				}
+			}
+		case "storage":
+			switch value {
+			case "memory":
+				options.Storage = MemoryStorage
+			case "disk":
+				options.Storage = DiskStorage
+			default:
+				return Options{}, fmt.Errorf("unknown upload storage %q", value)
+			}
+		case "dir":
+			options.Dir = value
+		default:
+			return Options{}, fmt.Errorf("unknown upload setting %q", part)
+		}
+	}
+	return options, nil
+}
+
+// ParseSize parses a size in bytes with an optional KB, MB or GB suffix
+func ParseSize(text string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(text))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			multiplier = unit.size
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || size < 1 {
+		return 0, fmt.Errorf("invalid upload size %q", text)
+	}
+	return size * multiplier, nil
+}
+
+// accepts reports whether a MIME type matches one of the accepted types
+func (o Options) accepts(contentType string) bool {
+	if len(o.Types) == 0 {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, pattern := range o.Types {
+		if matched, _ := path.Match(pattern, mediaType); matched {
+			return true
+		}
+	}
+	return false
+}
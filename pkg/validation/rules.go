@@ -0,0 +1,132 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kevenmiano/nestgo/pkg/codec"
+)
+
+// uuidPattern matches canonical UUID strings of any version
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// check applies a rule to a field value, reporting a FieldError on failure.
+// Rules other than required accept zero values, so optional fields are
+// only checked when present. Values of types with a registered codec are
+// checked on their text form.
+func check(r rule, v reflect.Value, path string) (FieldError, bool) {
+	fail := func(message string) (FieldError, bool) {
+		return FieldError{Field: path, Rule: r.name, Param: r.param, Message: message}, true
+	}
+
+	if r.name == "required" {
+		if isZero(v) {
+			return fail(fmt.Sprintf("%s is required", path))
+		}
+		return FieldError{}, false
+	}
+
+	if r.name == "omitempty" || isZero(v) {
+		return FieldError{}, false
+	}
+	v = indirect(v)
+	if text, ok := codec.Text(v); ok {
+		v = reflect.ValueOf(text)
+	}
+
+	switch r.name {
+	case "email":
+		if address, err := mail.ParseAddress(v.String()); err != nil || address.Address != v.String() {
+			return fail(fmt.Sprintf("%s must be a valid email address", path))
+		}
+	case "url":
+		if parsed, err := url.ParseRequestURI(v.String()); err != nil || parsed.Host == "" {
+			return fail(fmt.Sprintf("%s must be a valid URL", path))
+		}
+	case "uuid":
+		if !uuidPattern.MatchString(v.String()) {
+			return fail(fmt.Sprintf("%s must be a valid UUID", path))
+		}
+	case "min", "max", "len":
+		return checkSize(r, v, path)
+	case "oneof":
+		allowed := strings.Fields(r.param)
+		actual := fmt.Sprint(v.Interface())
+		for _, option := range allowed {
+			if option == actual {
+				return FieldError{}, false
+			}
+		}
+		return fail(fmt.Sprintf("%s must be one of: %s", path, strings.Join(allowed, ", ")))
+	default:
+		return fail(fmt.Sprintf("%s has unknown validation rule %q", path, r.name))
+	}
+
+	return FieldError{}, false
+}
+
+// checkSize applies min, max and len to string lengths, collection sizes and numbers
+func checkSize(r rule, v reflect.Value, path string) (FieldError, bool) {
+	limit, err := strconv.ParseFloat(r.param, 64)
+	if err != nil {
+		return FieldError{Field: path, Rule: r.name, Param: r.param, Message: fmt.Sprintf("%s has invalid %s parameter %q", path, r.name, r.param)}, true
+	}
+
+	var size float64
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		size = float64(utf8.RuneCountInString(v.String()))
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		size = float64(v.Len())
+		unit = " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	default:
+		return FieldError{}, false
+	}
+
+	var message string
+	switch {
+	case r.name == "min" && size < limit:
+		message = fmt.Sprintf("%s must be at least %s%s", path, r.param, unit)
+	case r.name == "max" && size > limit:
+		message = fmt.Sprintf("%s must be at most %s%s", path, r.param, unit)
+	case r.name == "len" && size != limit:
+		message = fmt.Sprintf("%s must be exactly %s%s", path, r.param, unit)
+	default:
+		return FieldError{}, false
+	}
+
+	return FieldError{Field: path, Rule: r.name, Param: r.param, Message: message}, true
+}
+
+// isZero reports whether a value is missing: zero, nil or empty
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// indirect dereferences pointers
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v
+}
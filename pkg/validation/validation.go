@@ -0,0 +1,190 @@
+package validation
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/kevenmiano/nestgo/pkg/controller"
+)
+
+// FieldError describes a field failing a validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of field errors of a validated value. It maps to
+// 422 Unprocessable Entity when returned from a handler or binding.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return "Validation failed: " + strings.Join(messages, "; ")
+}
+
+// StatusCode returns the HTTP status code of validation failures
+func (e Errors) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// ErrorBody returns the structured JSON body listing the field errors
+func (e Errors) ErrorBody() interface{} {
+	return map[string]interface{}{
+		"error":  "Validation failed",
+		"fields": []FieldError(e),
+	}
+}
+
+// rule is a parsed validate tag entry
+type rule struct {
+	name  string
+	param string
+}
+
+// rulesCache caches whether a type declares validation rules
+var rulesCache sync.Map
+
+// HasRules reports whether a struct type, or a struct it contains, declares
+// validate tags. Interface types may hold such structs, e.g. union variants,
+// and are checked on their dynamic value.
+func HasRules(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Interface {
+		return true
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.(bool)
+	}
+
+	// Store false first so recursive types terminate
+	rulesCache.Store(t, false)
+	has := false
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get(controller.TagValidate) != "" || field.Tag.Get(controller.TagRequired) == controller.TagValueTrue || HasRules(field.Type) {
+			has = true
+			break
+		}
+	}
+	rulesCache.Store(t, has)
+	return has
+}
+
+// Validate checks a struct, or pointer to struct, against its validate tags,
+// e.g. `validate:"required,email,min=3"`. Fields tagged `required:"true"`
+// are treated as required. Nested structs and slices of structs are
+// validated too. It returns nil or Errors.
+func Validate(value interface{}) error {
+	errs := make(Errors, 0)
+	validateValue(reflect.ValueOf(value), "", &errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateValue validates the fields of a struct value found at path
+func validateValue(v reflect.Value, path string, errs *Errors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		validateStruct(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// validateStruct applies the rules of each field of a struct
+func validateStruct(v reflect.Value, path string, errs *Errors) {
+	structType := v.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		fieldPath := joinPath(path, jsonName(field))
+
+		rules := parseRules(field.Tag.Get(controller.TagValidate))
+		if field.Tag.Get(controller.TagRequired) == controller.TagValueTrue && !hasRule(rules, "required") {
+			rules = append([]rule{{name: "required"}}, rules...)
+		}
+
+		for _, r := range rules {
+			if fieldErr, failed := check(r, fieldValue, fieldPath); failed {
+				*errs = append(*errs, fieldErr)
+				// Stop at the first failing rule of a field
+				break
+			}
+		}
+
+		if HasRules(field.Type) {
+			validateValue(fieldValue, fieldPath, errs)
+		}
+	}
+}
+
+// parseRules parses a validate tag
+func parseRules(tag string) []rule {
+	rules := make([]rule, 0)
+	for _, entry := range strings.Split(tag, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(entry, "=")
+		rules = append(rules, rule{name: name, param: param})
+	}
+	return rules
+}
+
+// hasRule reports whether rules contains a rule by name
+func hasRule(rules []rule, name string) bool {
+	for _, r := range rules {
+		if r.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonName returns the JSON name of a struct field
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get(controller.TagJSON), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// joinPath appends a field name to a path
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
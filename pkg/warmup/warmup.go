@@ -0,0 +1,313 @@
+package warmup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kevenmiano/nestgo/pkg/clock"
+	"github.com/kevenmiano/nestgo/pkg/logger"
+)
+
+// ProviderName is the name the warmup runner is injected under, e.g.
+// Warmup *warmup.Runner `inject:"Warmup"`
+const ProviderName = "Warmup"
+
+// Warmer is implemented by providers and controllers that precompute caches
+// once the application is bootstrapped, reporting their progress
+type Warmer interface {
+	Warmup(ctx context.Context, progress *Progress) error
+}
+
+// Scheduled is implemented by warmers that must run again periodically,
+// e.g. to refresh precomputed aggregates
+type Scheduled interface {
+	WarmupInterval() time.Duration
+}
+
+// Mode tells how the warmup gates the application
+type Mode int
+
+const (
+	// Blocking delays serving until every warmer finished; a failure aborts
+	// startup
+	Blocking Mode = iota
+	// Gated serves right away but answers 503 Service Unavailable until
+	// every warmer finished
+	Gated
+	// Background serves right away without waiting for the warmers
+	Background
+)
+
+// Options configures a Runner
+type Options struct {
+	Mode Mode
+	// Timeout bounds the initial warmup; zero waits indefinitely
+	Timeout time.Duration
+	// ReadinessPath, if set, serves the warmup status, answering 503 until
+	// the application is ready
+	ReadinessPath string
+	// Exempt lists the paths served while a Gated warmup runs, e.g. liveness
+	// probes. The readiness path is always served.
+	Exempt []string
+}
+
+// Progress is the progress of one warmer, e.g. the keys loaded out of the
+// keys to load
+type Progress struct {
+	mu    sync.Mutex
+	done  int
+	total int
+}
+
+// SetTotal sets the number of steps of the warmup
+func (p *Progress) SetTotal(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total = total
+}
+
+// Add records n completed steps
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += n
+}
+
+// counts returns the completed and total steps
+func (p *Progress) counts() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done, p.total
+}
+
+// TaskStatus is the status of one warmer
+type TaskStatus struct {
+	Name     string    `json:"name"`
+	Done     int       `json:"done"`
+	Total    int       `json:"total"`
+	Finished bool      `json:"finished"`
+	Error    string    `json:"error,omitempty"`
+	Runs     int       `json:"runs"`
+	LastRun  time.Time `json:"lastRun"`
+	Duration string    `json:"duration,omitempty"`
+}
+
+// Status is the warmup status of the application
+type Status struct {
+	Ready bool         `json:"ready"`
+	Tasks []TaskStatus `json:"tasks"`
+}
+
+// task is a registered warmer and its last run
+type task struct {
+	name     string
+	warmer   Warmer
+	progress *Progress
+	finished bool
+	err      error
+	runs     int
+	lastRun  time.Time
+	duration time.Duration
+}
+
+// Runner runs the warmers of the application and tracks its readiness
+type Runner struct {
+	options Options
+	mu      sync.RWMutex
+	tasks   []*task
+	ready   bool
+}
+
+// New creates a Runner
+func New(options Options) *Runner {
+	return &Runner{options: options, tasks: make([]*task, 0)}
+}
+
+// Mode returns how the runner gates the application
+func (r *Runner) Mode() Mode {
+	return r.options.Mode
+}
+
+// ReadinessPath returns the path serving the warmup status, if any
+func (r *Runner) ReadinessPath() string {
+	return r.options.ReadinessPath
+}
+
+// Add registers a warmer under a name
+func (r *Runner) Add(name string, warmer Warmer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, &task{name: name, warmer: warmer, progress: &Progress{}})
+}
+
+// Run runs every warmer concurrently, bounded by the timeout, and marks the
+// application ready once they finished. Errors are reported in the status
+// and joined in the returned error.
+func (r *Runner) Run(ctx context.Context) error {
+	if r.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.options.Timeout)
+		defer cancel()
+	}
+
+	r.mu.RLock()
+	tasks := append([]*task(nil), r.tasks...)
+	r.mu.RUnlock()
+
+	logger.Info("Warming up", "warmers", len(tasks))
+	start := clock.Now()
+	errs := make([]error, len(tasks))
+	var wg sync.WaitGroup
+	for i, t := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.run(ctx, t)
+		}()
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	r.ready = true
+	r.mu.Unlock()
+	logger.Info("Warmup completed", "warmers", len(tasks), "duration", clock.Now().Sub(start))
+	return errors.Join(errs...)
+}
+
+// Schedule runs the warmers implementing Scheduled again at their interval
+// until ctx is done
+func (r *Runner) Schedule(ctx context.Context) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, t := range r.tasks {
+		scheduled, ok := t.warmer.(Scheduled)
+		if !ok || scheduled.WarmupInterval() <= 0 {
+			continue
+		}
+		go func() {
+			ticker := time.NewTicker(scheduled.WarmupInterval())
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					r.run(ctx, t)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// run runs one warmer, recording its outcome
+func (r *Runner) run(ctx context.Context, t *task) error {
+	progress := &Progress{}
+	r.mu.Lock()
+	t.progress = progress
+	t.finished = false
+	r.mu.Unlock()
+
+	start := clock.Now()
+	err := t.warmer.Warmup(ctx, progress)
+	duration := clock.Now().Sub(start)
+	done, total := progress.counts()
+
+	r.mu.Lock()
+	t.finished = true
+	t.err = err
+	t.runs++
+	t.lastRun = start
+	t.duration = duration
+	r.mu.Unlock()
+
+	if err != nil {
+		logger.Error("Warmup failed", "warmer", t.name, "done", done, "total", total, "duration", duration, "error", err)
+		return fmt.Errorf("warmer %s: %w", t.name, err)
+	}
+	logger.Info("Warmup finished", "warmer", t.name, "done", done, "total", total, "duration", duration)
+	return nil
+}
+
+// Ready reports whether the initial warmup finished
+func (r *Runner) Ready() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ready
+}
+
+// Status returns the readiness and the progress of every warmer
+func (r *Runner) Status() Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := Status{Ready: r.ready, Tasks: make([]TaskStatus, 0, len(r.tasks))}
+	for _, t := range r.tasks {
+		done, total := t.progress.counts()
+		taskStatus := TaskStatus{
+			Name:     t.name,
+			Done:     done,
+			Total:    total,
+			Finished: t.finished,
+			Runs:     t.runs,
+			LastRun:  t.lastRun,
+		}
+		if t.err != nil {
+			taskStatus.Error = t.err.Error()
+		}
+		if t.runs > 0 {
+			taskStatus.Duration = t.duration.String()
+		}
+		status.Tasks = append(status.Tasks, taskStatus)
+	}
+	return status
+}
+
+// ServeHTTP answers the warmup status, with 503 Service Unavailable until
+// the application is ready
+func (r *Runner) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := r.Status()
+	jsonData, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to serialize response"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	w.Write(jsonData)
+}
+
+// Middleware returns an HTTP middleware answering 503 Service Unavailable
+// with Retry-After until a Gated warmup finished. Other modes pass every
+// request through.
+func (r *Runner) Middleware(next http.Handler) http.Handler {
+	if r.options.Mode != Gated {
+		return next
+	}
+
+	exempt := make(map[string]bool, len(r.options.Exempt)+1)
+	for _, path := range r.options.Exempt {
+		exempt[path] = true
+	}
+	if r.options.ReadinessPath != "" {
+		exempt[r.options.ReadinessPath] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if r.Ready() || exempt[req.URL.Path] {
+			next.ServeHTTP(w, req)
+			return
+		}
+		jsonData, _ := json.Marshal(map[string]string{"error": "Warming up"})
+		w.Header().Set("Retry-After", "1")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write(jsonData)
+	})
+}